@@ -2,6 +2,6 @@ package main
 
 import "binance/internal/api/http"
 
-func (a *App) registerHTTPEndpoints() {
-	http.RegisterHTTPEndpoints(a.Fiber, a.LogRus)
+func (a *App) registerHTTPEndpoints(signals http.SignalProcessor) {
+	http.RegisterHTTPEndpoints(a.Fiber, signals, a.Config.WebhookSecret, a.LogRus)
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+)
+
+// close releases the connections opened by the init functions
+func (a *App) close(ctx context.Context) {
+	if a.TGM != nil {
+		a.TGM.StopReceivingUpdates()
+	}
+
+	if a.Mongo != nil {
+		if err := a.Mongo.Disconnect(ctx); err != nil {
+			a.LogRus.WithField("func", "Disconnect").Error(err)
+		}
+	}
+
+	if a.DB != nil {
+		if err := a.DB.Close(); err != nil {
+			a.LogRus.WithField("func", "Close").Error(err)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,17 +14,29 @@ import (
 type Config struct {
 	TelegramApiToken string
 	TelegramChatID   string
+	TelegramAdmins   []int64
 	BinanceApiKey    string
 	BinanceSecretKey string
 	BinanceUrl       string
+	BinanceSpotUrl   string
 	AppPort          string
 	AppName          string
 	LogLevel         string
+	ShutdownPolicy   string
+	ShutdownTimeout  time.Duration
+	WebhookSecret    string
+	MigrateOnStart   bool
+	PriceRetention   time.Duration
+	PriceArchive     bool
+	SettingsFile     string
 	DB               *DB
 	Mongo            *Mongo
 }
 
 type DB struct {
+	// Driver is postgres or sqlite3, Path is the file of the sqlite database
+	Driver   string
+	Path     string
 	Host     string
 	User     string
 	Password string
@@ -58,6 +73,20 @@ func (a *App) loadConfig(confFileName string) error {
 		return err
 	}
 
+	// the users allowed to press the bot buttons, all the members of the chat without them
+	for _, id := range strings.Split(cfg.get("TELEGRAM_ADMINS", ""), ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+
+		admin, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return fmt.Errorf("TELEGRAM_ADMINS: %w", err)
+		}
+
+		cfg.TelegramAdmins = append(cfg.TelegramAdmins, admin)
+	}
+
 	if cfg.BinanceApiKey, err = cfg.set("BINANCE_API_KEY"); err != nil {
 		return err
 	}
@@ -70,6 +99,8 @@ func (a *App) loadConfig(confFileName string) error {
 		return err
 	}
 
+	cfg.BinanceSpotUrl = cfg.get("BINANCE_SPOT_URL", "https://api.binance.com")
+
 	if cfg.AppPort, err = cfg.set("APP_PORT"); err != nil {
 		return err
 	}
@@ -78,42 +109,78 @@ func (a *App) loadConfig(confFileName string) error {
 		return err
 	}
 
-	if db.Host, err = cfg.set("PG_HOST"); err != nil {
+	cfg.ShutdownPolicy = cfg.get("SHUTDOWN_POLICY", "LEAVE_ORDERS")
+
+	if cfg.ShutdownTimeout, err = time.ParseDuration(cfg.get("SHUTDOWN_TIMEOUT", "45s")); err != nil {
 		return err
 	}
 
-	if db.User, err = cfg.set("PG_USER"); err != nil {
+	// the signal webhook is off without the secret
+	cfg.WebhookSecret = cfg.get("WEBHOOK_SECRET", "")
+
+	if cfg.MigrateOnStart, err = strconv.ParseBool(cfg.get("MIGRATE_ON_START", "false")); err != nil {
 		return err
 	}
 
-	if db.Password, err = cfg.set("PG_PASSWORD"); err != nil {
+	// the raw prices older than the retention are pruned after they are rolled up into the candles
+	if cfg.PriceRetention, err = time.ParseDuration(cfg.get("PRICE_RETENTION", "168h")); err != nil {
 		return err
 	}
 
-	if db.DBName, err = cfg.set("PG_DBNAME"); err != nil {
+	if cfg.PriceArchive, err = strconv.ParseBool(cfg.get("PRICE_ARCHIVE", "false")); err != nil {
 		return err
 	}
 
-	if db.SSLMode, err = cfg.set("PG_SSL_MODE"); err != nil {
-		return err
+	db.Driver = cfg.get("DB_DRIVER", "postgres")
+
+	switch db.Driver {
+	case "postgres":
+		if db.Host, err = cfg.set("PG_HOST"); err != nil {
+			return err
+		}
+
+		if db.User, err = cfg.set("PG_USER"); err != nil {
+			return err
+		}
+
+		if db.Password, err = cfg.set("PG_PASSWORD"); err != nil {
+			return err
+		}
+
+		if db.DBName, err = cfg.set("PG_DBNAME"); err != nil {
+			return err
+		}
+
+		if db.SSLMode, err = cfg.set("PG_SSL_MODE"); err != nil {
+			return err
+		}
+	case "sqlite3":
+		db.Path = cfg.get("SQLITE_PATH", "binance.db")
+	default:
+		return fmt.Errorf("unknown DB_DRIVER '%s'", db.Driver)
 	}
 
 	cfg.DB = &db
 
-	if mongo.Host, err = cfg.set("MONGO_HOST"); err != nil {
-		return err
-	}
+	// the settings are kept in the YAML or JSON file instead of Mongo when the file is set
+	cfg.SettingsFile = cfg.get("SETTINGS_FILE", "")
 
-	if mongo.User, err = cfg.set("MONGO_USER"); err != nil {
-		return err
-	}
+	if cfg.SettingsFile == "" {
+		if mongo.Host, err = cfg.set("MONGO_HOST"); err != nil {
+			return err
+		}
 
-	if mongo.Password, err = cfg.set("MONGO_PASSWORD"); err != nil {
-		return err
-	}
+		if mongo.User, err = cfg.set("MONGO_USER"); err != nil {
+			return err
+		}
 
-	if mongo.DBName, err = cfg.set("MONGO_DBNAME"); err != nil {
-		return err
+		if mongo.Password, err = cfg.set("MONGO_PASSWORD"); err != nil {
+			return err
+		}
+
+		if mongo.DBName, err = cfg.set("MONGO_DBNAME"); err != nil {
+			return err
+		}
 	}
 
 	cfg.Mongo = &mongo
@@ -143,3 +210,11 @@ func (c *Config) set(key string) (string, error) {
 
 	return os.Getenv(key), nil
 }
+
+func (c *Config) get(key, def string) string {
+	if os.Getenv(key) == "" {
+		return def
+	}
+
+	return os.Getenv(key)
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"binance/internal/repository/postgres"
+	"binance/internal/repository/sqlite"
+	"binance/migrations"
 	"log"
 
 	"github.com/jmoiron/sqlx"
@@ -8,6 +11,16 @@ import (
 )
 
 func (a *App) InitDB(dbConfig *DB) error {
+	if dbConfig.Driver == sqlite.DriverName {
+		db, err := sqlite.Open(dbConfig.Path)
+		if err != nil {
+			return err
+		}
+		a.DB = db
+
+		return nil
+	}
+
 	db, err := sqlx.Connect("postgres", dbConfig.DSN())
 	if err != nil {
 		log.Fatalln(err)
@@ -16,3 +29,65 @@ func (a *App) InitDB(dbConfig *DB) error {
 
 	return nil
 }
+
+// the repositories and the migrations below are the ones of the configured driver
+
+func (a *App) isSQLite() bool {
+	return a.Config.DB.Driver == sqlite.DriverName
+}
+
+func (a *App) loadMigrations() ([]migrations.Migration, error) {
+	if a.isSQLite() {
+		return migrations.LoadSQLite()
+	}
+
+	return migrations.Load()
+}
+
+func (a *App) newOrderRepo(market string) postgres.OrderRepo {
+	if a.isSQLite() {
+		return sqlite.NewOrderRepository(a.DB, market)
+	}
+
+	return postgres.NewOrderRepository(a.DB, market)
+}
+
+func (a *App) newPriceRepo() postgres.PriceRepo {
+	if a.isSQLite() {
+		return sqlite.NewPriceRepository(a.DB)
+	}
+
+	return postgres.NewPriceRepository(a.DB)
+}
+
+func (a *App) newGridRepo() postgres.GridRepo {
+	if a.isSQLite() {
+		return sqlite.NewGridRepository(a.DB)
+	}
+
+	return postgres.NewGridRepository(a.DB)
+}
+
+func (a *App) newFillRepo(market string) postgres.FillRepo {
+	if a.isSQLite() {
+		return sqlite.NewFillRepository(a.DB, market)
+	}
+
+	return postgres.NewFillRepository(a.DB, market)
+}
+
+func (a *App) newIncomeRepo() postgres.IncomeRepo {
+	if a.isSQLite() {
+		return sqlite.NewIncomeRepository(a.DB)
+	}
+
+	return postgres.NewIncomeRepository(a.DB)
+}
+
+func (a *App) newAuditRepo() postgres.AuditRepo {
+	if a.isSQLite() {
+		return sqlite.NewAuditRepository(a.DB)
+	}
+
+	return postgres.NewAuditRepository(a.DB)
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"binance/internal/usecasees"
+	"encoding/json"
+
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/opts"
 	"github.com/go-echarts/go-echarts/v2/types"
@@ -33,3 +36,18 @@ func generateLineItems() []opts.LineData {
 	}
 	return items
 }
+
+func (a *App) monitorsHandler(supervisors ...*usecasees.Supervisor) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		out := make([]usecasees.MonitorInfo, 0)
+		for _, supervisor := range supervisors {
+			out = append(out, supervisor.Running()...)
+		}
+
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			a.LogRus.WithField("func", "monitorsHandler").Debug(err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"binance/internal/controllers"
+	"binance/internal/usecasees"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// income runs the income subcommand: import <file.csv>, sync or summary [days]
+func (a *App) income(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: income import <file.csv>|sync|summary [days]")
+	}
+
+	a.initHTTPClient()
+
+	incomeUseCase := usecasees.NewIncomeUseCase(
+		controllers.NewClientController(
+			a.HTTPClient,
+			a.Config.BinanceApiKey,
+			a.LogRus,
+		),
+		controllers.NewCryptoController(
+			a.Config.BinanceSecretKey,
+		),
+		a.newIncomeRepo(),
+		a.Config.BinanceUrl,
+		a.LogRus,
+	)
+
+	switch args[0] {
+	case "import":
+		if len(args) < 2 {
+			return errors.New("usage: income import <file.csv>")
+		}
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		n, err := incomeUseCase.Import(file)
+		if err != nil {
+			return err
+		}
+
+		a.LogRus.Infof("Imported %d incomes", n)
+	case "sync":
+		n, err := incomeUseCase.Sync()
+		if err != nil {
+			return err
+		}
+
+		a.LogRus.Infof("Synced %d incomes", n)
+	case "summary":
+		days := 1
+
+		if len(args) > 1 {
+			var err error
+			if days, err = strconv.Atoi(args[1]); err != nil || days < 1 {
+				return fmt.Errorf("wrong days '%s'", args[1])
+			}
+		}
+
+		to := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+
+		summary, err := incomeUseCase.Summary(to.Add(-time.Duration(days)*24*time.Hour), to)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range summary {
+			fmt.Printf("%s %-12s %-20s %-6s %6d %.8f\n", s.Day, s.Symbol, s.IncomeType, s.Asset, s.Count, s.Income)
+		}
+	default:
+		return fmt.Errorf("unknown income command '%s'", args[0])
+	}
+
+	return nil
+}
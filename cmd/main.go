@@ -2,12 +2,17 @@ package main
 
 import (
 	"binance/internal/controllers"
-	"binance/internal/repository/mongo"
+	mongoStructs "binance/internal/repository/mongo/structs"
 	"binance/internal/repository/postgres"
+	"context"
 	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"syscall"
 
 	"binance/internal/usecasees"
 )
@@ -29,15 +34,52 @@ func main() {
 	//	panic(err)
 	//}
 
-	if err := app.initMongo(); err != nil {
+	if err := app.InitDB(app.Config.DB); err != nil {
 		panic(err)
 	}
 
-	if err := app.InitDB(app.Config.DB); err != nil {
+	if flag.Arg(0) == "migrate" {
+		if err := app.migrate(flag.Args()[1:]); err != nil {
+			app.LogRus.Fatal(err)
+		}
+
+		app.close(context.Background())
+
+		return
+	}
+
+	if flag.Arg(0) == "income" {
+		if err := app.income(flag.Args()[1:]); err != nil {
+			app.LogRus.Fatal(err)
+		}
+
+		app.close(context.Background())
+
+		return
+	}
+
+	if flag.Arg(0) == "settings" {
+		if err := app.settings(flag.Args()[1:]); err != nil {
+			app.LogRus.Fatal(err)
+		}
+
+		app.close(context.Background())
+
+		return
+	}
+
+	if app.Config.MigrateOnStart {
+		if err := app.migrate([]string{"up"}); err != nil {
+			panic(err)
+		}
+	}
+
+	mongoRepo, err := app.initSettingsRepo()
+	if err != nil {
 		panic(err)
 	}
 
-	//app.initFiber()
+	app.initFiber()
 	//app.InitMetrics()
 	app.initHTTPClient()
 
@@ -50,12 +92,20 @@ func main() {
 		panic(err)
 	}
 
-	// Init Repository
-	priceRepo := postgres.NewPriceRepository(app.DB)
-	//orderRepoSpot := postgres.NewOrderRepository(app.DB, postgres.Spot)
-	orderRepoFeatures := postgres.NewOrderRepository(app.DB, postgres.Features)
+	shutdownPolicy, err := usecasees.ParseShutdownPolicy(app.Config.ShutdownPolicy)
+	if err != nil {
+		panic(err)
+	}
 
-	mongoRepo := mongo.NewSettingsRepository(app.Mongo)
+	// Init Repository
+	priceRepo := app.newPriceRepo()
+	orderRepoSpot := app.newOrderRepo(postgres.Spot)
+	orderRepoFeatures := app.newOrderRepo(postgres.Features)
+	gridRepo := app.newGridRepo()
+	fillRepoSpot := app.newFillRepo(postgres.Spot)
+	fillRepoFeatures := app.newFillRepo(postgres.Features)
+	incomeRepo := app.newIncomeRepo()
+	auditRepo := app.newAuditRepo()
 
 	if err := mongoRepo.SetDefault(); err != nil {
 		panic(err)
@@ -73,6 +123,8 @@ func main() {
 	tgmController := controllers.NewTgmController(
 		app.TGM,
 		chatId,
+		app.Config.TelegramAdmins,
+		app.LogRus,
 	)
 
 	// Init UseCases
@@ -84,39 +136,55 @@ func main() {
 		app.LogRus,
 	)
 
-	//orderUseCaseSpot := usecasees.NewOrderUseCase(
-	//	clientController,
-	//	cryptoController,
-	//	mongoRepo,
-	//	orderRepoSpot,
-	//	priceUseCase,
-	//	app.Config.BinanceUrl,
-	//	app.LogRus,
-	//	app.PromTail,
-	//	app.Metrics.Order,
-	//)
+	settingsWatcher := usecasees.NewSettingsWatcher(mongoRepo, app.LogRus)
+
+	orderUseCaseSpot := usecasees.NewOrderUseCase(
+		clientController,
+		cryptoController,
+		tgmController,
+		mongoRepo,
+		settingsWatcher,
+		orderRepoSpot,
+		gridRepo,
+		fillRepoSpot,
+		mongoStructs.MarketSpot,
+		priceUseCase,
+		app.Config.BinanceSpotUrl,
+		app.LogRus,
+	)
 
 	orderUseCaseFeatures := usecasees.NewOrderUseCase(
 		clientController,
 		cryptoController,
 		tgmController,
 		mongoRepo,
+		settingsWatcher,
 		orderRepoFeatures,
+		gridRepo,
+		fillRepoFeatures,
+		mongoStructs.MarketFeatures,
 		priceUseCase,
 		app.Config.BinanceUrl,
 		app.LogRus,
 	)
 
-	//tgmUseCase := usecasees.NewTgmUseCase(
-	//	priceUseCase,
-	//	orderUseCase,
-	//	mongoRepo,
-	//	orderRepo,
-	//	tgmController,
-	//	app.LogRus,
-	//)
+	incomeUseCase := usecasees.NewIncomeUseCase(
+		clientController,
+		cryptoController,
+		incomeRepo,
+		app.Config.BinanceUrl,
+		app.LogRus,
+	)
 
-	//go tgmUseCase.CommandProcessor()
+	tgmUseCase := usecasees.NewTgmUseCase(
+		priceUseCase,
+		orderUseCaseFeatures,
+		mongoRepo,
+		orderRepoFeatures,
+		auditRepo,
+		tgmController,
+		app.LogRus,
+	)
 
 	//for _, symbol := range usecasees.SymbolList {
 	//	if err := orderUseCase.Monitoring(symbol); err != nil {
@@ -124,24 +192,99 @@ func main() {
 	//	}
 	//}
 
-	for _, symbol := range usecasees.SymbolList {
-		go func(s string) {
-			_ = tgmController.Send(fmt.Sprintf("Init\t%s", s))
+	supervisors := []*usecasees.Supervisor{
+		usecasees.NewSupervisor(
+			orderUseCaseFeatures,
+			mongoRepo,
+			tgmController,
+			app.LogRus,
+		),
+		usecasees.NewSupervisor(
+			orderUseCaseSpot,
+			mongoRepo,
+			tgmController,
+			app.LogRus,
+		),
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	// the notifications outlive the supervisors, the queue is flushed after they are stopped
+	tgmCtx, tgmStop := context.WithCancel(context.Background())
+	go tgmController.Run(tgmCtx)
+
+	var supervisorsWg sync.WaitGroup
+	for _, supervisor := range supervisors {
+		supervisorsWg.Add(1)
 
-			if err := orderUseCaseFeatures.FeaturesMonitoring(s); err != nil {
-				app.LogRus.Error(err)
-			}
-		}(symbol)
+		go func(supervisor *usecasees.Supervisor) {
+			defer supervisorsWg.Done()
+
+			supervisor.Run(ctx)
+		}(supervisor)
 	}
 
-	//app.registerHTTPEndpoints()
+	go settingsWatcher.Run(ctx)
+	go incomeUseCase.Run(ctx)
+	go tgmUseCase.CommandProcessor(ctx)
+	go priceUseCase.RunRetention(ctx, app.Config.PriceRetention, app.Config.PriceArchive)
 
-	//if err := app.Fiber.Listen(fmt.Sprintf(":%s", app.Config.AppPort)); err != nil {
-	//	panic(err)
-	//}
+	app.registerHTTPEndpoints(orderUseCaseFeatures)
+
+	go func() {
+		if err := app.Fiber.Listen(fmt.Sprintf(":%s", app.Config.AppPort)); err != nil {
+			app.LogRus.Error(err)
+		}
+	}()
 
 	http.HandleFunc("/", app.initHTTPServer)
+	http.HandleFunc("/monitors", app.monitorsHandler(supervisors...))
 	//http.Handle("/static", http.FileServer(http.Dir("./static")))
 
-	http.ListenAndServe(":8081", nil)
+	server := &http.Server{Addr: ":8081"}
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			app.LogRus.Error(err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-signals
+	app.LogRus.Infof("Shutdown [%s] policy %s", sig, shutdownPolicy)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), app.Config.ShutdownTimeout)
+	defer cancel()
+
+	var shutdownWg sync.WaitGroup
+	for _, supervisor := range supervisors {
+		shutdownWg.Add(1)
+
+		go func(supervisor *usecasees.Supervisor) {
+			defer shutdownWg.Done()
+
+			supervisor.Shutdown(shutdownCtx, shutdownPolicy)
+		}(supervisor)
+	}
+	shutdownWg.Wait()
+
+	stop()
+	supervisorsWg.Wait()
+
+	if err := tgmController.Flush(shutdownCtx); err != nil {
+		app.LogRus.Error(err)
+	}
+	tgmStop()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		app.LogRus.Error(err)
+	}
+
+	if err := app.Fiber.Shutdown(); err != nil {
+		app.LogRus.Error(err)
+	}
+
+	app.close(shutdownCtx)
 }
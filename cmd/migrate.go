@@ -0,0 +1,70 @@
+package main
+
+import (
+	"binance/migrations"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// migrate runs the migrate subcommand: up, down [steps] or status
+func (a *App) migrate(args []string) error {
+	list, err := a.loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	migrator := migrations.NewMigrator(a.DB, list)
+
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, id := range applied {
+			a.LogRus.Infof("Applied %s", id)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		a.LogRus.Infof("Applied %d migrations", len(applied))
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("wrong steps '%s'", args[1])
+			}
+		}
+
+		rolledBack, err := migrator.Down(steps)
+		for _, id := range rolledBack {
+			a.LogRus.Infof("Rolled back %s", id)
+		}
+
+		return err
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%-60s %s\n", s.ID, appliedAt)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command '%s'", args[0])
+	}
+
+	return nil
+}
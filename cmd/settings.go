@@ -0,0 +1,129 @@
+package main
+
+import (
+	"binance/internal/repository/mongo"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/repository/postgres"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// settings runs the settings subcommand: history <symbol>, diff <symbol> <from> <to> or rollback <symbol> <version> [reason]
+func (a *App) settings(args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: settings history <symbol>|diff <symbol> <from> <to>|rollback <symbol> <version> [reason]")
+	}
+
+	settingsRepo, err := a.initSettingsRepo()
+	if err != nil {
+		return err
+	}
+
+	symbol := args[1]
+
+	switch args[0] {
+	case "history":
+		versions, err := settingsRepo.Versions(symbol)
+		if err != nil {
+			return err
+		}
+
+		settings, err := settingsRepo.Load(symbol)
+		if err != nil {
+			return err
+		}
+
+		market := postgres.Features
+		if settings.GetMarket() == mongoStructs.MarketSpot {
+			market = postgres.Spot
+		}
+
+		// the profit of the sessions opened by each version
+		pnl, err := a.newFillRepo(market).VersionPnL(symbol)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			var profit []string
+			for _, p := range pnl {
+				if p.Key == strconv.Itoa(v.Version) {
+					profit = append(profit, fmt.Sprintf("%d trades %.8f %s", p.Trades, p.Net(), p.CommissionAsset))
+				}
+			}
+
+			fmt.Printf("%4d %s %-12s %-40s %s\n", v.Version, v.CreatedAt.Format("2006-01-02 15:04:05"), v.Author, v.Reason, strings.Join(profit, ", "))
+		}
+	case "diff":
+		if len(args) < 4 {
+			return errors.New("usage: settings diff <symbol> <from> <to>")
+		}
+
+		from, err := settingsVersion(settingsRepo, symbol, args[2])
+		if err != nil {
+			return err
+		}
+
+		to, err := settingsVersion(settingsRepo, symbol, args[3])
+		if err != nil {
+			return err
+		}
+
+		for _, d := range mongoStructs.Diff(&from.Settings, &to.Settings) {
+			fmt.Printf("%-24s %v -> %v\n", d.Field, d.From, d.To)
+		}
+	case "rollback":
+		if len(args) < 3 {
+			return errors.New("usage: settings rollback <symbol> <version> [reason]")
+		}
+
+		version, err := strconv.Atoi(args[2])
+		if err != nil {
+			return fmt.Errorf("wrong version '%s'", args[2])
+		}
+
+		author := os.Getenv("USER")
+		if author == "" {
+			author = "cli"
+		}
+
+		settings, err := settingsRepo.Rollback(symbol, version, mongoStructs.SettingsChange{
+			Author: author,
+			Reason: strings.Join(args[3:], " "),
+		})
+		if err != nil {
+			return err
+		}
+
+		a.LogRus.Infof("Settings %s are rolled back to version %d as version %d", symbol, version, settings.Version)
+	default:
+		return fmt.Errorf("unknown settings command '%s'", args[0])
+	}
+
+	return nil
+}
+
+// initSettingsRepo returns the repository of the settings file when it is set, Mongo otherwise
+func (a *App) initSettingsRepo() (mongo.SettingsRepo, error) {
+	if a.Config.SettingsFile != "" {
+		return mongo.NewSettingsFileRepository(a.Config.SettingsFile), nil
+	}
+
+	if err := a.initMongo(); err != nil {
+		return nil, err
+	}
+
+	return mongo.NewSettingsRepository(a.Mongo), nil
+}
+
+func settingsVersion(settingsRepo mongo.SettingsRepo, symbol, version string) (*mongoStructs.SettingsVersion, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, fmt.Errorf("wrong version '%s'", version)
+	}
+
+	return settingsRepo.GetVersion(symbol, v)
+}
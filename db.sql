@@ -1,28 +1,12 @@
 create table orders
-(
-    id           text primary key,
-    order_id     bigint,
-    session_id   text,
-    symbol       text,
-    side         text,
-    quantity     real,
-    actual_price real,
-    price        real,
-    stop_price   real,
-    try          integer,
-    status       text,
-    type         text,
-    created_at   timestamp with time zone default CURRENT_TIMESTAMP
-);
-
-create table features_orders
 (
     id            text primary key,
     order_id      bigint,
     session_id    text,
+    market        text not null default 'SPOT',
     symbol        text,
     side          text,
-    position_side text,
+    position_side text    default '',
     quantity      real,
     actual_price  real,
     price         real,
@@ -30,5 +14,164 @@ create table features_orders
     try           integer,
     status        text,
     type          text,
+    exit_model    text    default '',
+    volatility    real    default 0,
+    safe_delta    real    default 0,
+    trigger_delta real    default 0,
+    take_profit   real    default 0,
+    stop_loss     real    default 0,
+    submit_attempts integer not null default 0,
+    claimed_at    timestamp with time zone,
+    settings_version integer not null default 0,
     created_at    timestamp with time zone default CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
+
+create table grids
+(
+    id              text primary key,
+    symbol          text,
+    market          text,
+    min_price       real,
+    max_price       real,
+    levels          integer,
+    quantity        real,
+    realized_profit real    default 0,
+    cycles          integer default 0,
+    status          text,
+    created_at      timestamp with time zone default CURRENT_TIMESTAMP,
+    updated_at      timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create table grid_levels
+(
+    grid_id    text references grids (id),
+    level      integer,
+    state      text,
+    order_id   text    default '',
+    quantity   real    default 0,
+    buy_price  real    default 0,
+    profit     real    default 0,
+    cycles     integer default 0,
+    updated_at timestamp with time zone default CURRENT_TIMESTAMP,
+    primary key (grid_id, level)
+);
+
+create table prices
+(
+    id         serial primary key,
+    symbol     text,
+    price      real,
+    created_at timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create table candles
+(
+    id          serial primary key,
+    symbol      text,
+    open_price  real,
+    close_price real,
+    max_price   real,
+    min_price   real,
+    time_frame  text,
+    open_time   timestamp with time zone,
+    close_time  timestamp with time zone,
+    created_at  timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create table prices_archive
+(
+    id         integer primary key,
+    symbol     text,
+    price      real,
+    created_at timestamp with time zone
+);
+
+create table fills
+(
+    id               bigint,
+    market           text,
+    symbol           text,
+    order_id         bigint,
+    client_order_id  text    default '',
+    session_id       text    default '',
+    side             text,
+    position_side    text    default '',
+    price            double precision,
+    quantity         double precision,
+    quote_quantity   double precision,
+    commission       double precision,
+    commission_asset text,
+    realized_pnl     double precision default 0,
+    maker            boolean default false,
+    traded_at        timestamp with time zone,
+    created_at       timestamp with time zone default CURRENT_TIMESTAMP,
+    primary key (market, symbol, id)
+);
+
+create table incomes
+(
+    id          text primary key,
+    tran_id     bigint default 0,
+    trade_id    text   default '',
+    source      text,
+    symbol      text   default '',
+    income_type text,
+    income      double precision,
+    asset       text,
+    info        text   default '',
+    income_at   timestamp with time zone,
+    created_at  timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create table audit_records
+(
+    id         bigserial primary key,
+    source     text,
+    chat_id    bigint default 0,
+    user_id    bigint default 0,
+    user_name  text   default '',
+    action     text,
+    target     text   default '',
+    payload    text   default '',
+    result     text,
+    error      text   default '',
+    created_at timestamp with time zone default clock_timestamp()
+);
+
+create table order_events
+(
+    id         bigserial primary key,
+    order_ref  text,
+    session_id text    default '',
+    market     text,
+    symbol     text,
+    event      text,
+    source     text,
+    status     text    default '',
+    price      real    default 0,
+    order_id   bigint  default 0,
+    payload    text    default '',
+    created_at timestamp with time zone default clock_timestamp()
+);
+
+create index orders_market_symbol_created_at_idx on orders (market, symbol, created_at, id);
+create index orders_session_id_idx on orders (session_id);
+create index orders_market_order_id_idx on orders (market, order_id);
+create index orders_market_outbox_idx on orders (market, created_at) where status = 'IN PROGRESS';
+
+create index order_events_market_session_id_idx on order_events (market, session_id, created_at, id);
+create index order_events_order_ref_idx on order_events (order_ref);
+
+create index fills_market_order_id_idx on fills (market, order_id);
+create index fills_market_session_id_idx on fills (market, session_id);
+create index fills_market_symbol_traded_at_idx on fills (market, symbol, traded_at);
+
+create index incomes_source_income_at_idx on incomes (source, income_at);
+create index incomes_income_at_idx on incomes (income_at, symbol, income_type);
+
+create index audit_records_created_at_idx on audit_records (created_at, id);
+
+create index prices_symbol_created_at_idx on prices (symbol, created_at);
+create index prices_archive_symbol_created_at_idx on prices_archive (symbol, created_at);
+
+create unique index candles_symbol_time_frame_open_time_idx on candles (symbol, time_frame, open_time);
\ No newline at end of file
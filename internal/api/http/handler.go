@@ -1,19 +1,29 @@
 package http
 
 import (
+	"binance/internal/usecasees/structs"
+	"context"
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
 )
 
+// signalTimeout bounds the wait for the monitor to apply the signal
+const signalTimeout = 10 * time.Second
+
 type Handler struct {
-	fiber  *fiber.App
-	logger *logrus.Logger
+	fiber   *fiber.App
+	signals SignalProcessor
+	logger  *logrus.Logger
 }
 
-func NewHandler(f *fiber.App, l *logrus.Logger) *Handler {
+func NewHandler(f *fiber.App, signals SignalProcessor, l *logrus.Logger) *Handler {
 	return &Handler{
-		fiber:  f,
-		logger: l,
+		fiber:   f,
+		signals: signals,
+		logger:  l,
 	}
 }
 
@@ -30,3 +40,52 @@ func (h *Handler) HealthCheck(c *fiber.Ctx) error {
 
 	return nil
 }
+
+// Signal opens or closes a session by the signal of the webhook, it replies 201 with the created
+// orders and 200 with the orders of the first time on a retry
+func (h *Handler) Signal(c *fiber.Ctx) error {
+	var s structs.Signal
+
+	if err := c.BodyParser(&s); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), signalTimeout)
+	defer cancel()
+
+	out, err := h.signals.Signal(ctx, &s)
+	if err != nil {
+		h.logger.
+			WithField("func", "Signal").
+			WithField("symbol", s.Symbol).
+			WithField("key", s.IdempotencyKey).
+			Debug(err)
+
+		return fiber.NewError(signalStatus(err), err.Error())
+	}
+
+	if out.Duplicate {
+		c.Status(fiber.StatusOK)
+	} else {
+		c.Status(fiber.StatusCreated)
+	}
+
+	return c.JSON(out)
+}
+
+func signalStatus(err error) int {
+	switch {
+	case errors.Is(err, structs.ErrSignalInvalid):
+		return fiber.StatusBadRequest
+	case errors.Is(err, structs.ErrSignalRejected):
+		return fiber.StatusUnprocessableEntity
+	case errors.Is(err, structs.ErrSignalConflict):
+		return fiber.StatusConflict
+	case errors.Is(err, structs.ErrSignalNoMonitor):
+		return fiber.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return fiber.StatusGatewayTimeout
+	}
+
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"binance/internal/api/http/mocks"
+	"binance/internal/usecasees/structs"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_Signal(t *testing.T) {
+	const secret = "secret"
+
+	tests := []struct {
+		name   string
+		token  string
+		body   string
+		result *structs.SignalResult
+		err    error
+		status int
+	}{
+		{
+			name:   "no token",
+			body:   `{"symbol":"BTCUSDT"}`,
+			status: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "wrong token",
+			token:  "secreT",
+			body:   `{"symbol":"BTCUSDT"}`,
+			status: fiber.StatusUnauthorized,
+		},
+		{
+			name:   "bad json",
+			token:  secret,
+			body:   `{"symbol":`,
+			status: fiber.StatusBadRequest,
+		},
+		{
+			name:   "created",
+			token:  secret,
+			body:   `{"symbol":"BTCUSDT","side":"BUY","quantity":0.005,"idempotency_key":"alert-1"}`,
+			result: &structs.SignalResult{SessionID: "session", OrderIDs: []string{"order"}},
+			status: fiber.StatusCreated,
+		},
+		{
+			name:   "duplicate",
+			token:  secret,
+			body:   `{"symbol":"BTCUSDT","side":"BUY","quantity":0.005,"idempotency_key":"alert-1"}`,
+			result: &structs.SignalResult{SessionID: "session", OrderIDs: []string{"order"}, Duplicate: true},
+			status: fiber.StatusOK,
+		},
+		{
+			name:   "invalid",
+			token:  secret,
+			body:   `{"symbol":"BTCUSDT"}`,
+			err:    fmt.Errorf("%w: no idempotency key", structs.ErrSignalInvalid),
+			status: fiber.StatusBadRequest,
+		},
+		{
+			name:   "rejected",
+			token:  secret,
+			body:   `{"symbol":"BTCUSDT"}`,
+			err:    fmt.Errorf("%w: risk", structs.ErrSignalRejected),
+			status: fiber.StatusUnprocessableEntity,
+		},
+		{
+			name:   "conflict",
+			token:  secret,
+			body:   `{"symbol":"BTCUSDT"}`,
+			err:    fmt.Errorf("%w: session is open", structs.ErrSignalConflict),
+			status: fiber.StatusConflict,
+		},
+		{
+			name:   "no monitor",
+			token:  secret,
+			body:   `{"symbol":"ETHUSDT"}`,
+			err:    fmt.Errorf("%w: ETHUSDT", structs.ErrSignalNoMonitor),
+			status: fiber.StatusNotFound,
+		},
+	}
+
+	// the metrics are registered once per process
+	signals := mocks.NewSignalProcessor(t)
+
+	app := fiber.New()
+	RegisterHTTPEndpoints(app, signals, secret, logrus.New())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result != nil || tt.err != nil {
+				signals.On("Signal", mock.Anything, mock.AnythingOfType("*structs.Signal")).
+					Return(tt.result, tt.err).
+					Once()
+			}
+
+			req := httptest.NewRequest("POST", "/api/webhook/signal", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.token != "" {
+				req.Header.Set(webhookTokenHeader, tt.token)
+			}
+
+			resp, err := app.Test(req)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, tt.status, resp.StatusCode)
+
+			if tt.result != nil {
+				body, _ := io.ReadAll(resp.Body)
+				assert.Contains(t, string(body), `"order_ids":["order"]`)
+			}
+		})
+	}
+}
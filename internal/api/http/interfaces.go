@@ -0,0 +1,12 @@
+package http
+
+import (
+	"binance/internal/usecasees/structs"
+	"context"
+)
+
+//go:generate mockery --case=snake --name=SignalProcessor
+
+type SignalProcessor interface {
+	Signal(ctx context.Context, s *structs.Signal) (*structs.SignalResult, error)
+}
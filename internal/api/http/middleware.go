@@ -1,10 +1,15 @@
 package http
 
 import (
+	"crypto/subtle"
+
 	"github.com/ansrivas/fiberprometheus/v2"
 	"github.com/gofiber/fiber/v2"
 )
 
+// webhookTokenHeader carries the shared secret of the webhook
+const webhookTokenHeader = "X-Webhook-Token"
+
 type Middleware struct {
 	appName string
 	fiber   *fiber.App
@@ -21,3 +26,16 @@ func (m *Middleware) useMetrics() {
 	prometheus.RegisterAt(m.fiber, "/metrics")
 	m.fiber.Use(prometheus.Middleware)
 }
+
+// webhookAuth rejects the requests without the shared secret
+func (m *Middleware) webhookAuth(secret string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Get(webhookTokenHeader)
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			return fiber.NewError(fiber.StatusUnauthorized, "invalid webhook token")
+		}
+
+		return c.Next()
+	}
+}
@@ -5,12 +5,17 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func RegisterHTTPEndpoints(f *fiber.App, l *logrus.Logger) {
+// RegisterHTTPEndpoints registers the API, the signal webhook is registered when its secret is set
+func RegisterHTTPEndpoints(f *fiber.App, signals SignalProcessor, webhookSecret string, l *logrus.Logger) {
 	m := NewMiddleware(f)
 	m.useMetrics()
 
-	h := NewHandler(f, l)
+	h := NewHandler(f, signals, l)
 
 	router := f.Group("api")
 	router.Get("/healthcheck", h.HealthCheck)
+
+	if webhookSecret != "" {
+		router.Post("/webhook/signal", m.webhookAuth(webhookSecret), h.Signal)
+	}
 }
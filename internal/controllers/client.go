@@ -38,6 +38,12 @@ var (
 	ErrCodeUnknownOrderSent = -2011
 	ErrUnknownOrderSent     = fmt.Errorf("%s", "Unknown order sent.")
 
+	ErrCodeOrderDoesNotExist = -2013
+	ErrOrderDoesNotExist     = fmt.Errorf("%s", "Order does not exist.")
+
+	ErrCodeDuplicateClientOrderID = -4116
+	ErrDuplicateClientOrderID     = fmt.Errorf("%s", "ClientOrderId is duplicated.")
+
 	ErrCodeInternalError = -1001
 	ErrErrInternalError  = fmt.Errorf("%s", "Internal error; unable to process your request. Please try again.")
 )
@@ -83,6 +89,10 @@ func (c *ClientController) Send(method string, url *url.URL, body []byte, useApi
 				return nil, ErrErrInternalError
 			case ErrCodeUnknownOrderSent:
 				return nil, ErrUnknownOrderSent
+			case ErrCodeOrderDoesNotExist:
+				return nil, ErrOrderDoesNotExist
+			case ErrCodeDuplicateClientOrderID:
+				return nil, ErrDuplicateClientOrderID
 			}
 
 			return nil, fmt.Errorf("%s Err:%+v", "Unknown error", errMsg)
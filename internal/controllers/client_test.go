@@ -4,7 +4,6 @@ import (
 	"binance/internal/controllers"
 	"binance/internal/usecasees"
 	"binance/internal/usecasees/structs"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"github.com/google/uuid"
@@ -1125,42 +1124,3 @@ func TestPrice(t *testing.T) {
 	priceSELL := 20310 + d
 	fmt.Println(priceSELL, d/2)
 }
-
-func TestStat(t *testing.T) {
-	file, err := os.Open("binance.csv")
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-	reader.FieldsPerRecord = 5
-	reader.Comment = '#'
-	var totalProfit, totalComission float64
-
-	for {
-		record, e := reader.Read()
-		if e != nil {
-			fmt.Println(e)
-			break
-		}
-
-		switch record[1] {
-		case "COMMISSION":
-			f, err := strconv.ParseFloat(record[2], 64)
-			if err != nil {
-				panic(err)
-			}
-			totalComission += f
-		case "REALIZED_PNL":
-			f, err := strconv.ParseFloat(record[2], 64)
-			if err != nil {
-				panic(err)
-			}
-			totalProfit += f
-		}
-	}
-
-	fmt.Println(totalProfit)
-	fmt.Println(totalComission)
-}
@@ -20,7 +20,12 @@ type CryptoCtrl interface {
 
 type TgmCtrl interface {
 	Send(text string) error
+	Notify(event string, data interface{}) error
 	CheckChatID(chatID int64) bool
+	CheckUserID(userID int64) bool
 	Update(msgID int, text string) error
+	SendKeyboard(text string, keyboard tgmBotAPI.InlineKeyboardMarkup) (int, error)
+	UpdateKeyboard(msgID int, text string, keyboard *tgmBotAPI.InlineKeyboardMarkup) error
+	AnswerCallback(callbackID string, text string) error
 	GetUpdates() tgmBotAPI.UpdatesChannel
 }
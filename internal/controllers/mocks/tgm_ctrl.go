@@ -12,6 +12,20 @@ type TgmCtrl struct {
 	mock.Mock
 }
 
+// AnswerCallback provides a mock function with given fields: callbackID, text
+func (_m *TgmCtrl) AnswerCallback(callbackID string, text string) error {
+	ret := _m.Called(callbackID, text)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(callbackID, text)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CheckChatID provides a mock function with given fields: chatID
 func (_m *TgmCtrl) CheckChatID(chatID int64) bool {
 	ret := _m.Called(chatID)
@@ -26,6 +40,20 @@ func (_m *TgmCtrl) CheckChatID(chatID int64) bool {
 	return r0
 }
 
+// CheckUserID provides a mock function with given fields: userID
+func (_m *TgmCtrl) CheckUserID(userID int64) bool {
+	ret := _m.Called(userID)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(int64) bool); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // GetUpdates provides a mock function with given fields:
 func (_m *TgmCtrl) GetUpdates() tgbotapi.UpdatesChannel {
 	ret := _m.Called()
@@ -42,6 +70,20 @@ func (_m *TgmCtrl) GetUpdates() tgbotapi.UpdatesChannel {
 	return r0
 }
 
+// Notify provides a mock function with given fields: event, data
+func (_m *TgmCtrl) Notify(event string, data interface{}) error {
+	ret := _m.Called(event, data)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, interface{}) error); ok {
+		r0 = rf(event, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Send provides a mock function with given fields: text
 func (_m *TgmCtrl) Send(text string) error {
 	ret := _m.Called(text)
@@ -56,6 +98,27 @@ func (_m *TgmCtrl) Send(text string) error {
 	return r0
 }
 
+// SendKeyboard provides a mock function with given fields: text, keyboard
+func (_m *TgmCtrl) SendKeyboard(text string, keyboard tgbotapi.InlineKeyboardMarkup) (int, error) {
+	ret := _m.Called(text, keyboard)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string, tgbotapi.InlineKeyboardMarkup) int); ok {
+		r0 = rf(text, keyboard)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, tgbotapi.InlineKeyboardMarkup) error); ok {
+		r1 = rf(text, keyboard)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Update provides a mock function with given fields: msgID, text
 func (_m *TgmCtrl) Update(msgID int, text string) error {
 	ret := _m.Called(msgID, text)
@@ -70,6 +133,20 @@ func (_m *TgmCtrl) Update(msgID int, text string) error {
 	return r0
 }
 
+// UpdateKeyboard provides a mock function with given fields: msgID, text, keyboard
+func (_m *TgmCtrl) UpdateKeyboard(msgID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) error {
+	ret := _m.Called(msgID, text, keyboard)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, string, *tgbotapi.InlineKeyboardMarkup) error); ok {
+		r0 = rf(msgID, text, keyboard)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewTgmCtrl interface {
 	mock.TestingT
 	Cleanup(func())
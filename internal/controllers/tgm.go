@@ -1,24 +1,70 @@
 package controllers
 
-import tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"context"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
 
 type TgmController struct {
 	tgmBot *tgmBotAPI.BotAPI
 	chatID int64
+	// admins are the users allowed to press the buttons, all the members of the chat without them
+	admins map[int64]bool
+	// queue sends the notifications in the background, it is run by Run
+	queue *tgmQueue
 }
 
 func NewTgmController(
 	tgmBot *tgmBotAPI.BotAPI,
 	chatID int64,
+	admins []int64,
+	logger *logrus.Logger,
 ) *TgmController {
-	return &TgmController{
+	c := &TgmController{
 		tgmBot: tgmBot,
 		chatID: chatID,
+		admins: make(map[int64]bool, len(admins)),
 	}
+
+	c.queue = newTgmQueue(c, logger)
+
+	for _, id := range admins {
+		c.admins[id] = true
+	}
+
+	return c
 }
 
+// Send queues the text to the chat, it does not wait for the message to be sent
 func (c *TgmController) Send(text string) error {
-	msg := tgmBotAPI.NewMessage(c.chatID, text)
+	return c.Notify(TgmEventMessage, text)
+}
+
+// Notify queues the notification of the event rendered by its template, the duplicates of the event
+// within its collapse window are dropped
+func (c *TgmController) Notify(event string, data interface{}) error {
+	m, err := renderTgmEvent(c.chatID, event, data)
+	if err != nil {
+		return err
+	}
+
+	return c.queue.push(m)
+}
+
+// Run sends the queued notifications until the context is done
+func (c *TgmController) Run(ctx context.Context) {
+	c.queue.Run(ctx)
+}
+
+// Flush waits until the queued notifications are sent or the context is done
+func (c *TgmController) Flush(ctx context.Context) error {
+	return c.queue.Flush(ctx)
+}
+
+func (c *TgmController) send(chatID int64, text string) error {
+	msg := tgmBotAPI.NewMessage(chatID, text)
 	msg.DisableWebPagePreview = true
 
 	if _, err := c.tgmBot.Send(msg); err != nil {
@@ -35,6 +81,45 @@ func (c *TgmController) CheckChatID(chatID int64) bool {
 	return false
 }
 
+func (c *TgmController) CheckUserID(userID int64) bool {
+	return len(c.admins) == 0 || c.admins[userID]
+}
+
+// SendKeyboard sends the message with the inline keyboard and returns its id
+func (c *TgmController) SendKeyboard(text string, keyboard tgmBotAPI.InlineKeyboardMarkup) (int, error) {
+	msg := tgmBotAPI.NewMessage(c.chatID, text)
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = keyboard
+
+	sent, err := c.tgmBot.Send(msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return sent.MessageID, nil
+}
+
+// UpdateKeyboard edits the message and its inline keyboard, the keyboard is removed when it is nil
+func (c *TgmController) UpdateKeyboard(msgID int, text string, keyboard *tgmBotAPI.InlineKeyboardMarkup) error {
+	msg := tgmBotAPI.NewEditMessageText(c.chatID, msgID, text)
+	msg.ReplyMarkup = keyboard
+
+	if _, err := c.tgmBot.Send(msg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// AnswerCallback stops the progress of the pressed button, the text is shown to the user
+func (c *TgmController) AnswerCallback(callbackID string, text string) error {
+	if _, err := c.tgmBot.Request(tgmBotAPI.NewCallback(callbackID, text)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (c *TgmController) Update(msgID int, text string) error {
 	msg := tgmBotAPI.EditMessageTextConfig{
 		BaseEdit: tgmBotAPI.BaseEdit{
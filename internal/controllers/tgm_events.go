@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// the events of the notifications, the event selects the template, the priority and the collapse window
+const (
+	// TgmEventMessage is the plain text as the replies to the commands, it is never collapsed
+	TgmEventMessage     = "message"
+	TgmEventMonitor     = "monitor"
+	TgmEventLiquidation = "liquidation"
+	TgmEventGrid        = "grid"
+	TgmEventSettings    = "settings"
+	TgmEventPattern     = "pattern"
+	TgmEventAlert       = "alert"
+	TgmEventError       = "error"
+	TgmEventReport      = "report"
+)
+
+// TgmMonitor is the data of TgmEventMonitor: the start, the stop, the drain and the crash of the monitor
+type TgmMonitor struct {
+	Action string
+	Symbol string
+	Detail string
+}
+
+// TgmSettings is the data of TgmEventSettings, the invalid settings disable the symbol
+type TgmSettings struct {
+	Symbol string
+	Reason string
+	Status string
+}
+
+// TgmPattern is the data of TgmEventPattern, the candle pattern detected
+type TgmPattern struct {
+	Name    string
+	Candles interface{}
+}
+
+// TgmError is the data of TgmEventError, the error of the exchange request
+type TgmError struct {
+	Op   string
+	Code int
+	Msg  string
+}
+
+type tgmEvent struct {
+	priority TgmPriority
+	// collapse is the window the same text of the event is sent once in, zero sends every one
+	collapse time.Duration
+	template *template.Template
+}
+
+func newTgmEvent(name string, priority TgmPriority, collapse time.Duration, text string) tgmEvent {
+	return tgmEvent{
+		priority: priority,
+		collapse: collapse,
+		template: template.Must(template.New(name).Parse(text)),
+	}
+}
+
+// tgmEvents are the templates of the events, the text events are rendered as they are
+var tgmEvents = map[string]tgmEvent{
+	TgmEventMessage:     newTgmEvent(TgmEventMessage, TgmPriorityHigh, 0, "{{.}}"),
+	TgmEventMonitor:     newTgmEvent(TgmEventMonitor, TgmPriorityNormal, time.Minute, "{{.Action}}\t{{.Symbol}}{{with .Detail}}\n{{.}}{{end}}"),
+	TgmEventLiquidation: newTgmEvent(TgmEventLiquidation, TgmPriorityHigh, time.Minute, "{{.}}"),
+	TgmEventGrid:        newTgmEvent(TgmEventGrid, TgmPriorityNormal, time.Minute, "{{.}}"),
+	TgmEventSettings:    newTgmEvent(TgmEventSettings, TgmPriorityHigh, 10*time.Minute, "Invalid settings\t{{.Symbol}}\n{{.Reason}}\nStatus:\t{{.Status}}"),
+	TgmEventPattern:     newTgmEvent(TgmEventPattern, TgmPriorityLow, 5*time.Minute, "[ Pattern Detected ]\n{{.Name}}\n{{printf \"%+v\" .Candles}}"),
+	TgmEventAlert:       newTgmEvent(TgmEventAlert, TgmPriorityHigh, time.Minute, "{{.}}"),
+	TgmEventError:       newTgmEvent(TgmEventError, TgmPriorityHigh, time.Minute, "[ Err {{.Op}} ]\nCode:\t{{.Code}}\nMsg:\t{{.Msg}}"),
+	TgmEventReport:      newTgmEvent(TgmEventReport, TgmPriorityHigh, 0, "{{.}}"),
+}
+
+// renderTgmEvent builds the message of the event to the chat
+func renderTgmEvent(chatID int64, event string, data interface{}) (*tgmMessage, error) {
+	e, ok := tgmEvents[event]
+	if !ok {
+		return nil, fmt.Errorf("unknown telegram event '%s'", event)
+	}
+
+	var text strings.Builder
+	if err := e.template.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	m := tgmMessage{
+		chatID:   chatID,
+		priority: e.priority,
+		text:     text.String(),
+		collapse: e.collapse,
+	}
+
+	if e.collapse > 0 {
+		m.key = fmt.Sprintf("%d:%s:%s", chatID, event, m.text)
+	}
+
+	return &m, nil
+}
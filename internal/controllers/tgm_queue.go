@@ -0,0 +1,319 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// TgmPriority orders the notifications of the queue, the higher ones are sent first
+type TgmPriority int
+
+const (
+	TgmPriorityLow TgmPriority = iota
+	TgmPriorityNormal
+	TgmPriorityHigh
+)
+
+const (
+	// tgmChatInterval is the pause between the messages of the chat, Telegram allows about one a second
+	tgmChatInterval = time.Second
+	// tgmQueueSize caps the waiting messages, the lowest priority ones are dropped over it
+	tgmQueueSize = 1000
+
+	tgmMaxAttempts  = 5
+	tgmRetryBackoff = time.Second
+	tgmMaxBackoff   = time.Minute
+
+	tgmFlushInterval = 50 * time.Millisecond
+)
+
+var ErrTgmQueueFull = errors.New("telegram queue is full")
+
+// tgmSender sends the message to the chat right away
+type tgmSender interface {
+	send(chatID int64, text string) error
+}
+
+type tgmMessage struct {
+	chatID   int64
+	priority TgmPriority
+	text     string
+	// key collapses the duplicates within the collapse window, the empty key is never collapsed
+	key      string
+	collapse time.Duration
+
+	seq      uint64
+	attempts int
+	// notBefore is the backoff of the failed message
+	notBefore time.Time
+}
+
+// tgmQueue sends the messages in the background by the priority and the order they are pushed in,
+// the chat gets one message per interval and waits for the retry_after of the rate limited one
+type tgmQueue struct {
+	sender   tgmSender
+	interval time.Duration
+	backoff  time.Duration
+	size     int
+
+	mu       sync.Mutex
+	messages []*tgmMessage
+	seq      uint64
+	inFlight int
+	// pending are the keys of the waiting messages, sent are the keys of the sent ones until their window ends
+	pending map[string]bool
+	sent    map[string]time.Time
+	// next is the time the chat takes the next message
+	next map[int64]time.Time
+
+	wake chan struct{}
+
+	logger *logrus.Logger
+}
+
+func newTgmQueue(sender tgmSender, logger *logrus.Logger) *tgmQueue {
+	return &tgmQueue{
+		sender:   sender,
+		interval: tgmChatInterval,
+		backoff:  tgmRetryBackoff,
+		size:     tgmQueueSize,
+		pending:  make(map[string]bool),
+		sent:     make(map[string]time.Time),
+		next:     make(map[int64]time.Time),
+		wake:     make(chan struct{}, 1),
+		logger:   logger,
+	}
+}
+
+// push queues the message, the duplicate of the waiting or recently sent message is dropped
+func (q *tgmQueue) push(m *tgmMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	for key, until := range q.sent {
+		if now.After(until) {
+			delete(q.sent, key)
+		}
+	}
+
+	if m.key != "" {
+		if _, ok := q.sent[m.key]; ok || q.pending[m.key] {
+			return nil
+		}
+	}
+
+	if len(q.messages) >= q.size {
+		i := q.lowest()
+		if q.messages[i].priority >= m.priority {
+			return ErrTgmQueueFull
+		}
+
+		q.logger.
+			WithField("func", "push").
+			Debugf("the message is dropped by the full queue: %s", q.messages[i].text)
+
+		q.remove(i)
+	}
+
+	q.seq++
+	m.seq = q.seq
+
+	q.messages = append(q.messages, m)
+	if m.key != "" {
+		q.pending[m.key] = true
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// lowest is the newest message of the lowest priority, it is dropped first
+func (q *tgmQueue) lowest() int {
+	out := 0
+
+	for i, m := range q.messages {
+		if m.priority < q.messages[out].priority ||
+			(m.priority == q.messages[out].priority && m.seq > q.messages[out].seq) {
+			out = i
+		}
+	}
+
+	return out
+}
+
+func (q *tgmQueue) remove(i int) {
+	if key := q.messages[i].key; key != "" {
+		delete(q.pending, key)
+	}
+
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+}
+
+// pop takes the message of the highest priority ready to be sent, the wait is the time until
+// the next one is ready, it is zero for the empty queue
+func (q *tgmQueue) pop(now time.Time) (*tgmMessage, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := -1
+	var wait time.Duration
+
+	for i, m := range q.messages {
+		ready := m.notBefore
+		if next := q.next[m.chatID]; next.After(ready) {
+			ready = next
+		}
+
+		if ready.After(now) {
+			if d := ready.Sub(now); wait == 0 || d < wait {
+				wait = d
+			}
+
+			continue
+		}
+
+		if out == -1 || m.priority > q.messages[out].priority ||
+			(m.priority == q.messages[out].priority && m.seq < q.messages[out].seq) {
+			out = i
+		}
+	}
+
+	if out == -1 {
+		return nil, wait
+	}
+
+	m := q.messages[out]
+	q.messages = append(q.messages[:out], q.messages[out+1:]...)
+	q.next[m.chatID] = now.Add(q.interval)
+	q.inFlight++
+
+	return m, 0
+}
+
+// done completes the sent message, the rate limited message is retried after retry_after and
+// the failed one with the backoff until it runs out of the attempts
+func (q *tgmQueue) done(m *tgmMessage, err error, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.inFlight--
+
+	if err == nil {
+		if m.key != "" {
+			delete(q.pending, m.key)
+
+			if m.collapse > 0 {
+				q.sent[m.key] = now.Add(m.collapse)
+			}
+		}
+
+		return
+	}
+
+	var tgmErr *tgmBotAPI.Error
+	if errors.As(err, &tgmErr) && tgmErr.Code == 429 {
+		// the rate limit holds all the messages of the chat, the message keeps its place
+		q.next[m.chatID] = now.Add(time.Duration(tgmErr.RetryAfter) * time.Second)
+		q.messages = append(q.messages, m)
+
+		q.logger.
+			WithField("func", "send").
+			WithField("chatID", m.chatID).
+			Debugf("rate limited, retry after %ds", tgmErr.RetryAfter)
+
+		return
+	}
+
+	m.attempts++
+
+	if m.attempts >= tgmMaxAttempts {
+		if m.key != "" {
+			delete(q.pending, m.key)
+		}
+
+		q.logger.
+			WithField("func", "send").
+			WithField("chatID", m.chatID).
+			Errorf("the message is dropped after %d attempts: %v", m.attempts, err)
+
+		return
+	}
+
+	backoff := q.backoff << (m.attempts - 1)
+	if backoff > tgmMaxBackoff {
+		backoff = tgmMaxBackoff
+	}
+
+	m.notBefore = now.Add(backoff)
+	q.messages = append(q.messages, m)
+
+	q.logger.
+		WithField("func", "send").
+		WithField("chatID", m.chatID).
+		Debug(err)
+}
+
+// Run sends the queued messages until the context is done
+func (q *tgmQueue) Run(ctx context.Context) {
+	for {
+		m, wait := q.pop(time.Now())
+		if m != nil {
+			q.done(m, q.sender.send(m.chatID, m.text), time.Now())
+
+			continue
+		}
+
+		// the empty queue waits for the push only
+		var timer *time.Timer
+		var ready <-chan time.Time
+
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			ready = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ready:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// Flush waits until the queue is sent or the context is done
+func (q *tgmQueue) Flush(ctx context.Context) error {
+	ticker := time.NewTicker(tgmFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		q.mu.Lock()
+		empty := len(q.messages) == 0 && q.inFlight == 0
+		q.mu.Unlock()
+
+		if empty {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTgmSender struct {
+	mu    sync.Mutex
+	texts []string
+	errs  []error
+}
+
+func (s *fakeTgmSender) send(_ int64, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.errs) != 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+
+		if err != nil {
+			return err
+		}
+	}
+
+	s.texts = append(s.texts, text)
+
+	return nil
+}
+
+func (s *fakeTgmSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.texts...)
+}
+
+func newTestTgmQueue(sender tgmSender) *tgmQueue {
+	q := newTgmQueue(sender, logrus.New())
+	q.interval = 0
+	q.backoff = time.Millisecond
+
+	return q
+}
+
+func mustRender(t *testing.T, event string, data interface{}) *tgmMessage {
+	m, err := renderTgmEvent(1, event, data)
+	assert.NoError(t, err)
+
+	return m
+}
+
+func Test_TgmQueuePriority(t *testing.T) {
+	q := newTestTgmQueue(&fakeTgmSender{})
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventPattern, TgmPattern{Name: "Base"})))
+	assert.NoError(t, q.push(mustRender(t, TgmEventGrid, "grid 1")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventAlert, "alert")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventGrid, "grid 2")))
+
+	now := time.Now()
+
+	var texts []string
+	for {
+		m, _ := q.pop(now)
+		if m == nil {
+			break
+		}
+
+		texts = append(texts, m.text)
+		q.done(m, nil, now)
+	}
+
+	assert.Equal(t, []string{"alert", "grid 1", "grid 2", "[ Pattern Detected ]\nBase\n<nil>"}, texts)
+}
+
+func Test_TgmQueueCollapse(t *testing.T) {
+	q := newTestTgmQueue(&fakeTgmSender{})
+
+	monitor := TgmMonitor{Action: "Crash", Symbol: "BTCUSDT", Detail: "Restart in 1s"}
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventMonitor, monitor)))
+	assert.NoError(t, q.push(mustRender(t, TgmEventMonitor, monitor)))
+	assert.Len(t, q.messages, 1)
+	assert.Equal(t, "Crash\tBTCUSDT\nRestart in 1s", q.messages[0].text)
+
+	now := time.Now()
+	m, _ := q.pop(now)
+	q.done(m, nil, now)
+
+	// the sent message holds the duplicates until its window ends
+	assert.NoError(t, q.push(mustRender(t, TgmEventMonitor, monitor)))
+	assert.Len(t, q.messages, 0)
+
+	q.sent[m.key] = now.Add(-time.Second)
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventMonitor, monitor)))
+	assert.Len(t, q.messages, 1)
+
+	// the plain messages are never collapsed
+	assert.NoError(t, q.push(mustRender(t, TgmEventMessage, "pong")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventMessage, "pong")))
+	assert.Len(t, q.messages, 3)
+}
+
+func Test_TgmQueueFull(t *testing.T) {
+	q := newTestTgmQueue(&fakeTgmSender{})
+	q.size = 2
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventGrid, "grid 1")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventPattern, TgmPattern{Name: "Base"})))
+
+	assert.ErrorIs(t, q.push(mustRender(t, TgmEventPattern, TgmPattern{Name: "Молот"})), ErrTgmQueueFull)
+
+	// the higher priority message drops the lowest one
+	assert.NoError(t, q.push(mustRender(t, TgmEventAlert, "alert")))
+	assert.Len(t, q.messages, 2)
+	assert.Equal(t, "grid 1", q.messages[0].text)
+	assert.Equal(t, "alert", q.messages[1].text)
+}
+
+func Test_TgmQueueRateLimit(t *testing.T) {
+	q := newTestTgmQueue(&fakeTgmSender{})
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventAlert, "alert")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventGrid, "grid")))
+
+	now := time.Now()
+	m, _ := q.pop(now)
+	assert.Equal(t, "alert", m.text)
+
+	q.done(m, &tgmBotAPI.Error{
+		Code:               429,
+		Message:            "Too Many Requests",
+		ResponseParameters: tgmBotAPI.ResponseParameters{RetryAfter: 3},
+	}, now)
+
+	// the chat waits for retry_after
+	next, wait := q.pop(now.Add(time.Second))
+	assert.Nil(t, next)
+	assert.Equal(t, 2*time.Second, wait)
+
+	next, _ = q.pop(now.Add(3 * time.Second))
+	assert.Equal(t, "alert", next.text)
+	assert.Equal(t, 0, next.attempts)
+}
+
+func Test_TgmQueueRetry(t *testing.T) {
+	q := newTestTgmQueue(&fakeTgmSender{})
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventAlert, "alert")))
+
+	now := time.Now()
+	sendErr := errors.New("connection reset")
+
+	for attempt := 1; attempt < tgmMaxAttempts; attempt++ {
+		m, _ := q.pop(now)
+		assert.NotNil(t, m)
+
+		q.done(m, sendErr, now)
+
+		// the backoff doubles with the attempts
+		m, wait := q.pop(now)
+		assert.Nil(t, m)
+		assert.Equal(t, q.backoff<<(attempt-1), wait)
+
+		now = now.Add(wait)
+	}
+
+	m, _ := q.pop(now)
+	q.done(m, sendErr, now)
+
+	// the message is dropped after the last attempt, its key is released
+	assert.Len(t, q.messages, 0)
+	assert.Len(t, q.pending, 0)
+}
+
+func Test_TgmQueueFlush(t *testing.T) {
+	sender := &fakeTgmSender{errs: []error{errors.New("connection reset")}}
+	q := newTestTgmQueue(sender)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go q.Run(ctx)
+
+	assert.NoError(t, q.push(mustRender(t, TgmEventMessage, "one")))
+	assert.NoError(t, q.push(mustRender(t, TgmEventMessage, "two")))
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), time.Second)
+	defer flushCancel()
+
+	assert.NoError(t, q.Flush(flushCtx))
+	assert.ElementsMatch(t, []string{"one", "two"}, sender.sent())
+}
@@ -2,6 +2,7 @@ package mongo
 
 import (
 	"binance/internal/repository/mongo/structs"
+	"context"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -11,7 +12,13 @@ import (
 type SettingsRepo interface {
 	SetDefault() error
 	Load(symbol string) (*structs.Settings, error)
+	LoadAll() ([]structs.Settings, error)
 	ReLoad(settings *structs.Settings) error
-	UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus) error
-	UpdateDepthLimit(id primitive.ObjectID, depthLimit float64) error
+	UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus, c structs.SettingsChange) error
+	UpdateDepthLimit(id primitive.ObjectID, depthLimit float64, c structs.SettingsChange) error
+	Track(settings *structs.Settings) (bool, error)
+	Versions(symbol string) ([]structs.SettingsVersion, error)
+	GetVersion(symbol string, version int) (*structs.SettingsVersion, error)
+	Rollback(symbol string, version int, c structs.SettingsChange) (*structs.Settings, error)
+	Watch(ctx context.Context, f func(settings *structs.Settings)) error
 }
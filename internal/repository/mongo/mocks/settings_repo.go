@@ -3,11 +3,13 @@
 package mocks
 
 import (
+	structs "binance/internal/repository/mongo/structs"
+
+	context "context"
+
 	mock "github.com/stretchr/testify/mock"
 
 	primitive "go.mongodb.org/mongo-driver/bson/primitive"
-
-	structs "binance/internal/repository/mongo/structs"
 )
 
 // SettingsRepo is an autogenerated mock type for the SettingsRepo type
@@ -15,6 +17,29 @@ type SettingsRepo struct {
 	mock.Mock
 }
 
+// GetVersion provides a mock function with given fields: symbol, version
+func (_m *SettingsRepo) GetVersion(symbol string, version int) (*structs.SettingsVersion, error) {
+	ret := _m.Called(symbol, version)
+
+	var r0 *structs.SettingsVersion
+	if rf, ok := ret.Get(0).(func(string, int) *structs.SettingsVersion); ok {
+		r0 = rf(symbol, version)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*structs.SettingsVersion)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(symbol, version)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Load provides a mock function with given fields: symbol
 func (_m *SettingsRepo) Load(symbol string) (*structs.Settings, error) {
 	ret := _m.Called(symbol)
@@ -38,6 +63,29 @@ func (_m *SettingsRepo) Load(symbol string) (*structs.Settings, error) {
 	return r0, r1
 }
 
+// LoadAll provides a mock function with given fields:
+func (_m *SettingsRepo) LoadAll() ([]structs.Settings, error) {
+	ret := _m.Called()
+
+	var r0 []structs.Settings
+	if rf, ok := ret.Get(0).(func() []structs.Settings); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]structs.Settings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReLoad provides a mock function with given fields: settings
 func (_m *SettingsRepo) ReLoad(settings *structs.Settings) error {
 	ret := _m.Called(settings)
@@ -52,6 +100,29 @@ func (_m *SettingsRepo) ReLoad(settings *structs.Settings) error {
 	return r0
 }
 
+// Rollback provides a mock function with given fields: symbol, version, c
+func (_m *SettingsRepo) Rollback(symbol string, version int, c structs.SettingsChange) (*structs.Settings, error) {
+	ret := _m.Called(symbol, version, c)
+
+	var r0 *structs.Settings
+	if rf, ok := ret.Get(0).(func(string, int, structs.SettingsChange) *structs.Settings); ok {
+		r0 = rf(symbol, version, c)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*structs.Settings)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int, structs.SettingsChange) error); ok {
+		r1 = rf(symbol, version, c)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SetDefault provides a mock function with given fields:
 func (_m *SettingsRepo) SetDefault() error {
 	ret := _m.Called()
@@ -66,13 +137,85 @@ func (_m *SettingsRepo) SetDefault() error {
 	return r0
 }
 
-// UpdateStatus provides a mock function with given fields: id, status
-func (_m *SettingsRepo) UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus) error {
-	ret := _m.Called(id, status)
+// Track provides a mock function with given fields: settings
+func (_m *SettingsRepo) Track(settings *structs.Settings) (bool, error) {
+	ret := _m.Called(settings)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*structs.Settings) bool); ok {
+		r0 = rf(settings)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*structs.Settings) error); ok {
+		r1 = rf(settings)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateDepthLimit provides a mock function with given fields: id, depthLimit, c
+func (_m *SettingsRepo) UpdateDepthLimit(id primitive.ObjectID, depthLimit float64, c structs.SettingsChange) error {
+	ret := _m.Called(id, depthLimit, c)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(primitive.ObjectID, float64, structs.SettingsChange) error); ok {
+		r0 = rf(id, depthLimit, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateStatus provides a mock function with given fields: id, status, c
+func (_m *SettingsRepo) UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus, c structs.SettingsChange) error {
+	ret := _m.Called(id, status, c)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(primitive.ObjectID, structs.SymbolStatus, structs.SettingsChange) error); ok {
+		r0 = rf(id, status, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Versions provides a mock function with given fields: symbol
+func (_m *SettingsRepo) Versions(symbol string) ([]structs.SettingsVersion, error) {
+	ret := _m.Called(symbol)
+
+	var r0 []structs.SettingsVersion
+	if rf, ok := ret.Get(0).(func(string) []structs.SettingsVersion); ok {
+		r0 = rf(symbol)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]structs.SettingsVersion)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(symbol)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Watch provides a mock function with given fields: ctx, f
+func (_m *SettingsRepo) Watch(ctx context.Context, f func(settings *structs.Settings)) error {
+	ret := _m.Called(ctx, f)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(primitive.ObjectID, structs.SymbolStatus) error); ok {
-		r0 = rf(id, status)
+	if rf, ok := ret.Get(0).(func(context.Context, func(settings *structs.Settings)) error); ok {
+		r0 = rf(ctx, f)
 	} else {
 		r0 = ret.Error(0)
 	}
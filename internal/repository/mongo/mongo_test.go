@@ -3,11 +3,14 @@ package mongo
 import (
 	"binance/internal/repository/mongo/structs"
 	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -28,9 +31,137 @@ func TestSetDefault(t *testing.T) {
 	s, err := repo.Load("BTCBUSD")
 	assert.NoError(t, err)
 
-	assert.NoError(t, repo.UpdateStatus(s.ID, structs.Liquidation))
+	assert.NoError(t, repo.UpdateStatus(s.ID, structs.Liquidation, structs.SettingsChange{Author: "test", Reason: "liquidation"}))
 
 	assert.NoError(t, repo.ReLoad(s))
 
 	fmt.Println(s)
 }
+
+func TestSettingsVersions(t *testing.T) {
+	credential := options.Credential{
+		Username: "binance",
+		Password: "binance",
+	}
+
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(credential))
+	assert.NoError(t, err)
+
+	repo := NewSettingsRepository(client)
+
+	assert.NoError(t, repo.SetDefault())
+
+	s, err := repo.Load("BTCUSDT")
+	assert.NoError(t, err)
+
+	change := structs.SettingsChange{Author: "test", Reason: "depth limit"}
+
+	assert.NoError(t, repo.UpdateDepthLimit(s.ID, s.DepthLimit+1, change))
+	// the same value is not versioned
+	assert.NoError(t, repo.UpdateDepthLimit(s.ID, s.DepthLimit+1, change))
+
+	versions, err := repo.Versions("BTCUSDT")
+	assert.NoError(t, err)
+
+	if assert.NotEmpty(t, versions) {
+		last := versions[len(versions)-1]
+		assert.Equal(t, s.Version+1, last.Version)
+		assert.Equal(t, "test", last.Author)
+	}
+
+	rolled, err := repo.Rollback("BTCUSDT", s.Version, structs.SettingsChange{Author: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, s.DepthLimit, rolled.DepthLimit)
+	assert.Equal(t, s.Version+2, rolled.Version)
+}
+
+func TestSettingsTrackLegacy(t *testing.T) {
+	credential := options.Credential{
+		Username: "binance",
+		Password: "binance",
+	}
+
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(credential))
+	assert.NoError(t, err)
+
+	repo := NewSettingsRepository(client).(*SettingsRepository)
+
+	assert.NoError(t, repo.SetDefault())
+
+	symbol := fmt.Sprintf("LEGACY%d", time.Now().UnixNano())
+
+	// the document stored before the versioning has no version field
+	_, err = repo.collection.InsertOne(context.TODO(), bson.D{
+		{Key: "symbol", Value: symbol},
+		{Key: "status", Value: structs.Enabled.ToString()},
+		{Key: "depth_limit", Value: 45.0},
+	})
+	assert.NoError(t, err)
+
+	s, err := repo.Load(symbol)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, s.Version)
+
+	tracked, err := repo.Track(s)
+	assert.NoError(t, err)
+	assert.True(t, tracked)
+	assert.Equal(t, 1, s.Version)
+
+	versions, err := repo.Versions(symbol)
+	assert.NoError(t, err)
+
+	if assert.Len(t, versions, 1) {
+		assert.Equal(t, structs.SettingsAuthorExternal, versions[0].Author)
+		assert.Equal(t, 1, versions[0].Version)
+	}
+
+	// the tracked version is not recorded again
+	tracked, err = repo.Track(s)
+	assert.NoError(t, err)
+	assert.False(t, tracked)
+}
+
+func TestSettingsWatch(t *testing.T) {
+	credential := options.Credential{
+		Username: "binance",
+		Password: "binance",
+	}
+
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI("mongodb://localhost:27017").SetAuth(credential))
+	assert.NoError(t, err)
+
+	repo := NewSettingsRepository(client)
+
+	assert.NoError(t, repo.SetDefault())
+
+	s, err := repo.Load("BTCUSDT")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	changed := make(chan *structs.Settings, 1)
+	watched := make(chan error, 1)
+
+	go func() {
+		watched <- repo.Watch(ctx, func(settings *structs.Settings) {
+			select {
+			case changed <- settings:
+			default:
+			}
+		})
+	}()
+
+	// the stream is opened before the change
+	time.Sleep(time.Second)
+
+	assert.NoError(t, repo.UpdateDepthLimit(s.ID, s.DepthLimit+1, structs.SettingsChange{Author: "test", Reason: "watch"}))
+
+	select {
+	case err := <-watched:
+		// the standalone server is polled
+		assert.True(t, errors.Is(err, ErrWatchNotSupported), err)
+	case settings := <-changed:
+		assert.Equal(t, s.DepthLimit+1, settings.DepthLimit)
+	}
+}
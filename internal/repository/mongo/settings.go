@@ -3,26 +3,72 @@ package mongo
 import (
 	"binance/internal/repository/mongo/structs"
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// changeStreamNotSupported is the error code of the change stream opened on the standalone server
+const changeStreamNotSupported = 40573
+
+// ErrWatchNotSupported is returned by Watch when the server has no change streams
+var ErrWatchNotSupported = errors.New("settings change streams are not supported")
+
 type SettingsRepository struct {
 	conn       *mongo.Client
 	collection *mongo.Collection
+	// versions keeps the snapshot of the settings after every change
+	versions *mongo.Collection
 }
 
 func NewSettingsRepository(conn *mongo.Client) SettingsRepo {
-	collection := conn.Database("settings").Collection("symbols")
+	db := conn.Database("settings")
 
-	return &SettingsRepository{conn: conn, collection: collection}
+	return &SettingsRepository{conn: conn, collection: db.Collection("symbols"), versions: db.Collection("versions")}
 }
 
 func (r *SettingsRepository) SetDefault() error {
-	symbols := []structs.Settings{
+	if _, err := r.versions.Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "symbol", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+
+	for _, symbol := range defaultSettings() {
+		check, err := r.Load(symbol.Symbol)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		if primitive.ObjectID.IsZero(check.ID) {
+			symbol.Version = 1
+
+			res, err := r.collection.InsertOne(context.TODO(), symbol)
+			if err != nil {
+				return err
+			}
+
+			symbol.ID, _ = res.InsertedID.(primitive.ObjectID)
+
+			if err := r.record(&symbol, structs.SettingsChange{Author: structs.SettingsAuthorSystem, Reason: "default settings"}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// defaultSettings are the settings of the symbols added on the first start
+func defaultSettings() []structs.Settings {
+	return []structs.Settings{
 		{
 			Symbol:     "ETHUSDT",
 			Limit:      0.02,
@@ -34,6 +80,30 @@ func (r *SettingsRepository) SetDefault() error {
 			Status:     structs.Enabled.ToString(),
 			MaxPrice:   20500.00,
 			MinPrice:   19800.00,
+
+			ExitModel:          "ATR",
+			VolatilityInterval: "1m",
+			VolatilityWindow:   14,
+			SafeDeltaFactor:    structs.DefaultSafeDeltaFactor,
+			TriggerDeltaFactor: 0.01,
+			MinDelta:           2,
+			MaxDelta:           10,
+
+			CVDWindows:          []int{10, 60, 300},
+			ImbalanceLevels:     20,
+			ImbalanceMidPercent: 0.1,
+			WallMinRatio:        10,
+
+			LiquidationOrderType:   "MARKET",
+			LiquidationPriceOffset: 0.05,
+			LiquidationRequote:     10,
+
+			Timezone:              "UTC",
+			FundingBlackoutBefore: 5,
+			FundingBlackoutAfter:  5,
+
+			Strategy:   structs.StrategySession.ToString(),
+			GridLevels: 6,
 		}, {
 			Symbol:     "BTCUSDT",
 			Limit:      0.02,
@@ -45,6 +115,30 @@ func (r *SettingsRepository) SetDefault() error {
 			Status:     structs.Enabled.ToString(),
 			MaxPrice:   20500.00,
 			MinPrice:   19800.00,
+
+			ExitModel:          "ATR",
+			VolatilityInterval: "1m",
+			VolatilityWindow:   14,
+			SafeDeltaFactor:    structs.DefaultSafeDeltaFactor,
+			TriggerDeltaFactor: 0.01,
+			MinDelta:           25,
+			MaxDelta:           120,
+
+			CVDWindows:          []int{10, 60, 300},
+			ImbalanceLevels:     20,
+			ImbalanceMidPercent: 0.1,
+			WallMinRatio:        10,
+
+			LiquidationOrderType:   "MARKET",
+			LiquidationPriceOffset: 0.05,
+			LiquidationRequote:     10,
+
+			Timezone:              "UTC",
+			FundingBlackoutBefore: 5,
+			FundingBlackoutAfter:  5,
+
+			Strategy:   structs.StrategySession.ToString(),
+			GridLevels: 6,
 		}, {
 			Symbol:     "AMBUSDT",
 			Limit:      0.02,
@@ -58,65 +152,249 @@ func (r *SettingsRepository) SetDefault() error {
 			MinPrice:   19800.00,
 		},
 	}
-
-	for _, symbol := range symbols {
-		check, err := r.Load(symbol.Symbol)
-		if err != nil && err != mongo.ErrNoDocuments {
-			return err
-		}
-
-		if primitive.ObjectID.IsZero(check.ID) {
-			_, err := r.collection.InsertOne(context.TODO(), symbol)
-			if err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
 }
 
 func (r *SettingsRepository) Load(symbol string) (*structs.Settings, error) {
 	var result structs.Settings
 
-	if err := r.collection.FindOne(context.TODO(), bson.D{{"symbol", symbol}}).Decode(&result); err != nil {
+	if err := r.collection.FindOne(context.TODO(), bson.D{{Key: "symbol", Value: symbol}}).Decode(&result); err != nil {
 		return &result, err
 	}
 
 	return &result, nil
 }
 
+func (r *SettingsRepository) LoadAll() ([]structs.Settings, error) {
+	var result []structs.Settings
+
+	cursor, err := r.collection.Find(context.TODO(), bson.D{})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(context.TODO(), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
 func (r *SettingsRepository) ReLoad(settings *structs.Settings) error {
 
-	if err := r.collection.FindOne(context.TODO(), bson.D{{"symbol", settings.Symbol}}).Decode(&settings); err != nil {
+	if err := r.collection.FindOne(context.TODO(), bson.D{{Key: "symbol", Value: settings.Symbol}}).Decode(&settings); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (r *SettingsRepository) UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus) error {
-	_, err := r.collection.UpdateOne(
-		context.TODO(),
-		bson.D{{"_id", id}},
-		bson.D{{"$set", bson.D{{"status", status}}}},
+// Watch calls f with the settings inserted or updated in the collection until ctx is done or the
+// stream fails, the standalone server returns ErrWatchNotSupported
+func (r *SettingsRepository) Watch(ctx context.Context, f func(settings *structs.Settings)) error {
+	stream, err := r.collection.Watch(
+		ctx,
+		mongo.Pipeline{{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "update", "replace"}}}}}}}},
+		options.ChangeStream().SetFullDocument(options.UpdateLookup),
 	)
 	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == changeStreamNotSupported {
+			return ErrWatchNotSupported
+		}
+
 		return err
 	}
+	defer stream.Close(context.TODO())
 
-	return nil
+	for stream.Next(ctx) {
+		var event struct {
+			FullDocument *structs.Settings `bson:"fullDocument"`
+		}
+
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+
+		// the document is deleted before it is looked up
+		if event.FullDocument == nil {
+			continue
+		}
+
+		f(event.FullDocument)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return stream.Err()
+}
+
+// UpdateStatus sets the status, the version is recorded when the status is changed
+func (r *SettingsRepository) UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus, c structs.SettingsChange) error {
+	return r.update(id, bson.D{{Key: "status", Value: status}}, c)
 }
 
-func (r *SettingsRepository) UpdateDepthLimit(id primitive.ObjectID, depthLimit float64) error {
-	_, err := r.collection.UpdateOne(
+// UpdateDepthLimit sets the depth limit, the version is recorded when the limit is changed
+func (r *SettingsRepository) UpdateDepthLimit(id primitive.ObjectID, depthLimit float64, c structs.SettingsChange) error {
+	return r.update(id, bson.D{{Key: "depth_limit", Value: depthLimit}}, c)
+}
+
+// Track records the version of the settings changed in the collection out of the bot, it reports
+// whether the version is recorded. The settings without a version are recorded as they are.
+func (r *SettingsRepository) Track(settings *structs.Settings) (bool, error) {
+	last, err := r.GetVersion(settings.Symbol, settings.Version)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+	case err != nil:
+		return false, err
+	case len(structs.Diff(&last.Settings, settings)) == 0:
+		return false, nil
+	}
+
+	var doc structs.Settings
+
+	// the version is not taken when the settings are changed by the bot meanwhile
+	if err := r.collection.FindOneAndUpdate(
 		context.TODO(),
-		bson.D{{"_id", id}},
-		bson.D{{"$set", bson.D{{"depth_limit", depthLimit}}}},
-	)
+		bson.D{{Key: "_id", Value: settings.ID}, {Key: "version", Value: versionFilter(settings.Version)}},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	if err := r.record(&doc, structs.SettingsChange{Author: structs.SettingsAuthorExternal, Reason: "changed in the collection"}); err != nil {
+		return false, err
+	}
+
+	*settings = doc
+
+	return true, nil
+}
+
+// versionFilter matches the version of the settings, the documents stored before the versioning have no
+// version and are the version 0
+func versionFilter(version int) interface{} {
+	if version == 0 {
+		return bson.D{{Key: "$in", Value: bson.A{0, nil}}}
+	}
+
+	return version
+}
+
+// Versions returns the versions of the symbol from the first one
+func (r *SettingsRepository) Versions(symbol string) ([]structs.SettingsVersion, error) {
+	var result []structs.SettingsVersion
+
+	cursor, err := r.versions.Find(context.TODO(), bson.D{{Key: "symbol", Value: symbol}}, options.Find().SetSort(bson.D{{Key: "version", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cursor.All(context.TODO(), &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *SettingsRepository) GetVersion(symbol string, version int) (*structs.SettingsVersion, error) {
+	var result structs.SettingsVersion
+
+	if err := r.versions.FindOne(context.TODO(), bson.D{{Key: "symbol", Value: symbol}, {Key: "version", Value: version}}).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Rollback sets the settings of the version, the rollback is recorded as the new version
+func (r *SettingsRepository) Rollback(symbol string, version int, c structs.SettingsChange) (*structs.Settings, error) {
+	v, err := r.GetVersion(symbol, version)
 	if err != nil {
+		return nil, err
+	}
+
+	raw, err := bson.Marshal(v.Settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var set bson.D
+	if err := bson.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+
+	fields := set[:0]
+	for _, e := range set {
+		if e.Key != "_id" && e.Key != "version" {
+			fields = append(fields, e)
+		}
+	}
+
+	if c.Reason == "" {
+		c.Reason = fmt.Sprintf("rollback to version %d", version)
+	}
+
+	var doc structs.Settings
+
+	if err := r.collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.D{{Key: "_id", Value: v.SettingsID}},
+		bson.D{{Key: "$set", Value: fields}, {Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := r.record(&doc, c); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// update sets the fields and takes the next version, nothing is changed when the fields have the values
+func (r *SettingsRepository) update(id primitive.ObjectID, set bson.D, c structs.SettingsChange) error {
+	changed := make(bson.A, 0, len(set))
+	for _, e := range set {
+		changed = append(changed, bson.D{{Key: e.Key, Value: bson.D{{Key: "$ne", Value: e.Value}}}})
+	}
+
+	var doc structs.Settings
+
+	if err := r.collection.FindOneAndUpdate(
+		context.TODO(),
+		bson.D{{Key: "_id", Value: id}, {Key: "$or", Value: changed}},
+		bson.D{{Key: "$set", Value: set}, {Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&doc); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+
 		return err
 	}
 
-	return nil
+	return r.record(&doc, c)
+}
+
+// record stores the snapshot of the settings as their version
+func (r *SettingsRepository) record(settings *structs.Settings, c structs.SettingsChange) error {
+	_, err := r.versions.InsertOne(context.TODO(), structs.SettingsVersion{
+		SettingsID: settings.ID,
+		Symbol:     settings.Symbol,
+		Version:    settings.Version,
+		Author:     c.Author,
+		Reason:     c.Reason,
+		Settings:   *settings,
+		CreatedAt:  time.Now().UTC(),
+	})
+
+	return err
 }
@@ -0,0 +1,631 @@
+package mongo
+
+import (
+	"binance/internal/repository/mongo/structs"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gopkg.in/yaml.v3"
+)
+
+// settingsFilePollInterval is the period of the modification checks of the watched file
+const settingsFilePollInterval = time.Second
+
+// SettingsFileRepository keeps the settings in a YAML or a JSON file instead of the collection, the file
+// is the list of the symbols with the collection field names:
+//
+//	symbols:
+//	  - symbol: BTCUSDT
+//	    step: 0.003
+//	    depth_limit: 35
+//
+// The file edited by hand is reloaded once it is modified. The changes made by the bot rewrite the whole
+// file atomically, the versions are appended to the <file>.versions JSON lines next to it.
+type SettingsFileRepository struct {
+	path     string
+	versions string
+
+	mu      sync.Mutex
+	modTime time.Time
+	list    []structs.Settings
+}
+
+func NewSettingsFileRepository(path string) SettingsRepo {
+	return &SettingsFileRepository{path: path, versions: path + ".versions"}
+}
+
+// SetDefault creates the file of the default settings when there is none, the symbols of the existing
+// file are managed by hand
+func (r *SettingsFileRepository) SetDefault() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := os.Stat(r.path); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	list := defaultSettings()
+	for i := range list {
+		list[i].ID = fileSettingsID(list[i].Symbol)
+		list[i].Version = 1
+	}
+
+	if err := r.write(list); err != nil {
+		return err
+	}
+
+	for i := range list {
+		if err := r.record(&list[i], structs.SettingsChange{Author: structs.SettingsAuthorSystem, Reason: "default settings"}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *SettingsFileRepository) Load(symbol string) (*structs.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return &structs.Settings{}, err
+	}
+
+	i := r.find(symbol)
+	if i < 0 {
+		return &structs.Settings{}, mongo.ErrNoDocuments
+	}
+
+	result := r.list[i]
+
+	return &result, nil
+}
+
+func (r *SettingsFileRepository) LoadAll() ([]structs.Settings, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return append([]structs.Settings(nil), r.list...), nil
+}
+
+func (r *SettingsFileRepository) ReLoad(settings *structs.Settings) error {
+	result, err := r.Load(settings.Symbol)
+	if err != nil {
+		return err
+	}
+
+	*settings = *result
+
+	return nil
+}
+
+// UpdateStatus sets the status, the version is recorded when the status is changed
+func (r *SettingsFileRepository) UpdateStatus(id primitive.ObjectID, status structs.SymbolStatus, c structs.SettingsChange) error {
+	return r.update(id, c, func(s *structs.Settings) bool {
+		if s.Status == status.ToString() {
+			return false
+		}
+
+		s.Status = status.ToString()
+
+		return true
+	})
+}
+
+// UpdateDepthLimit sets the depth limit, the version is recorded when the limit is changed
+func (r *SettingsFileRepository) UpdateDepthLimit(id primitive.ObjectID, depthLimit float64, c structs.SettingsChange) error {
+	return r.update(id, c, func(s *structs.Settings) bool {
+		if s.DepthLimit == depthLimit {
+			return false
+		}
+
+		s.DepthLimit = depthLimit
+
+		return true
+	})
+}
+
+// Track records the version of the settings edited in the file, it reports whether the version is recorded
+func (r *SettingsFileRepository) Track(settings *structs.Settings) (bool, error) {
+	last, err := r.GetVersion(settings.Symbol, settings.Version)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+	case err != nil:
+		return false, err
+	case len(structs.Diff(&last.Settings, settings)) == 0:
+		return false, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return false, err
+	}
+
+	// the version is not taken when the settings are changed by the bot meanwhile
+	i := r.find(settings.Symbol)
+	if i < 0 || r.list[i].Version != settings.Version {
+		return false, nil
+	}
+
+	list := append([]structs.Settings(nil), r.list...)
+	list[i].Version++
+
+	if err := r.write(list); err != nil {
+		return false, err
+	}
+
+	if err := r.record(&list[i], structs.SettingsChange{Author: structs.SettingsAuthorExternal, Reason: "changed in the file"}); err != nil {
+		return false, err
+	}
+
+	*settings = list[i]
+
+	return true, nil
+}
+
+// Versions returns the versions of the symbol from the first one
+func (r *SettingsFileRepository) Versions(symbol string) ([]structs.SettingsVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	all, err := r.readVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []structs.SettingsVersion
+	for _, v := range all {
+		if v.Symbol == symbol {
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *SettingsFileRepository) GetVersion(symbol string, version int) (*structs.SettingsVersion, error) {
+	versions, err := r.Versions(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		if v.Version == version {
+			return &v, nil
+		}
+	}
+
+	return nil, mongo.ErrNoDocuments
+}
+
+// Rollback sets the settings of the version, the rollback is recorded as the new version
+func (r *SettingsFileRepository) Rollback(symbol string, version int, c structs.SettingsChange) (*structs.Settings, error) {
+	v, err := r.GetVersion(symbol, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Reason == "" {
+		c.Reason = fmt.Sprintf("rollback to version %d", version)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	i := r.find(symbol)
+	if i < 0 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	list := append([]structs.Settings(nil), r.list...)
+
+	doc := v.Settings
+	doc.ID = list[i].ID
+	doc.Version = list[i].Version + 1
+	list[i] = doc
+
+	if err := r.write(list); err != nil {
+		return nil, err
+	}
+
+	if err := r.record(&doc, c); err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// Watch calls f with every settings of the file once the file is modified until ctx is done
+func (r *SettingsFileRepository) Watch(ctx context.Context, f func(settings *structs.Settings)) error {
+	var modTime time.Time
+
+	if info, err := os.Stat(r.path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	ticker := time.NewTicker(settingsFilePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			return err
+		}
+
+		if info.ModTime().Equal(modTime) {
+			continue
+		}
+
+		list, err := r.LoadAll()
+		if err != nil {
+			// the file is being edited, it is read again on the next modification
+			modTime = info.ModTime()
+
+			continue
+		}
+
+		modTime = info.ModTime()
+
+		for i := range list {
+			f(&list[i])
+		}
+	}
+}
+
+// update changes the settings by set and takes the next version, nothing is written when set reports no change
+func (r *SettingsFileRepository) update(id primitive.ObjectID, c structs.SettingsChange, set func(s *structs.Settings) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	list := append([]structs.Settings(nil), r.list...)
+
+	for i := range list {
+		if list[i].ID != id {
+			continue
+		}
+
+		if !set(&list[i]) {
+			return nil
+		}
+
+		list[i].Version++
+
+		if err := r.write(list); err != nil {
+			return err
+		}
+
+		return r.record(&list[i], c)
+	}
+
+	return nil
+}
+
+func (r *SettingsFileRepository) find(symbol string) int {
+	for i := range r.list {
+		if r.list[i].Symbol == symbol {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// load reads the file when it is modified after the last read, r.mu must be held
+func (r *SettingsFileRepository) load() error {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	if r.list != nil && info.ModTime().Equal(r.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	list, err := decodeSettingsFile(r.path, data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+
+	r.list = list
+	r.modTime = info.ModTime()
+
+	return nil
+}
+
+// write replaces the file by the list, the file is renamed so the readers never see it half written.
+// r.mu must be held.
+func (r *SettingsFileRepository) write(list []structs.Settings) error {
+	data, err := encodeSettingsFile(r.path, list)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.list = list
+	r.modTime = info.ModTime()
+
+	return nil
+}
+
+// record appends the snapshot of the settings to the versions file, r.mu must be held
+func (r *SettingsFileRepository) record(settings *structs.Settings, c structs.SettingsChange) error {
+	line, err := bson.MarshalExtJSON(structs.SettingsVersion{
+		ID:         primitive.NewObjectID(),
+		SettingsID: settings.ID,
+		Symbol:     settings.Symbol,
+		Version:    settings.Version,
+		Author:     c.Author,
+		Reason:     c.Reason,
+		Settings:   *settings,
+		CreatedAt:  time.Now().UTC(),
+	}, false, false)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.versions, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// readVersions reads the versions file, r.mu must be held
+func (r *SettingsFileRepository) readVersions() ([]structs.SettingsVersion, error) {
+	f, err := os.Open(r.versions)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var result []structs.SettingsVersion
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var v structs.SettingsVersion
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), false, &v); err != nil {
+			return nil, err
+		}
+
+		result = append(result, v)
+	}
+
+	return result, scanner.Err()
+}
+
+// fileSettingsID is the id of the settings of the file, it is made of the symbol so it is kept between the reads
+func fileSettingsID(symbol string) primitive.ObjectID {
+	sum := sha1.Sum([]byte(symbol))
+
+	var id primitive.ObjectID
+	copy(id[:], sum[:])
+
+	return id
+}
+
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// decodeSettingsFile reads the symbols by their bson names, the file has the same fields as the collection
+func decodeSettingsFile(path string, data []byte) ([]structs.Settings, error) {
+	var doc struct {
+		Symbols []map[string]interface{} `json:"symbols" yaml:"symbols"`
+	}
+
+	var err error
+	if isJSONFile(path) {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]structs.Settings, 0, len(doc.Symbols))
+
+	for i, fields := range doc.Symbols {
+		delete(fields, "_id")
+
+		raw, err := bson.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("symbol %d: %w", i, err)
+		}
+
+		var settings structs.Settings
+		if err := bson.Unmarshal(raw, &settings); err != nil {
+			return nil, fmt.Errorf("symbol %d: %w", i, err)
+		}
+
+		settings.ID = fileSettingsID(settings.Symbol)
+
+		list = append(list, settings)
+	}
+
+	return list, nil
+}
+
+// encodeSettingsFile writes the symbols in the order of the struct fields, the ids are not written
+func encodeSettingsFile(path string, list []structs.Settings) ([]byte, error) {
+	symbols := make(bson.A, 0, len(list))
+
+	for _, settings := range list {
+		raw, err := bson.Marshal(settings)
+		if err != nil {
+			return nil, err
+		}
+
+		var fields bson.D
+		if err := bson.Unmarshal(raw, &fields); err != nil {
+			return nil, err
+		}
+
+		doc := fields[:0]
+		for _, e := range fields {
+			if e.Key != "_id" {
+				doc = append(doc, e)
+			}
+		}
+
+		symbols = append(symbols, doc)
+	}
+
+	if isJSONFile(path) {
+		data, err := bson.MarshalExtJSON(bson.D{{Key: "symbols", Value: symbols}}, false, false)
+		if err != nil {
+			return nil, err
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return nil, err
+		}
+
+		return append(out.Bytes(), '\n'), nil
+	}
+
+	node, err := yamlNode(bson.D{{Key: "symbols", Value: symbols}})
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// yamlNode keeps the order of the document fields, the maps are written by yaml in the order of the keys
+func yamlNode(v interface{}) (*yaml.Node, error) {
+	switch v := v.(type) {
+	case bson.D:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+
+		for _, e := range v {
+			value, err := yamlNode(e.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: e.Key}, value)
+		}
+
+		return node, nil
+	case bson.A:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+
+		for _, item := range v {
+			value, err := yamlNode(item)
+			if err != nil {
+				return nil, err
+			}
+
+			node.Content = append(node.Content, value)
+		}
+
+		return node, nil
+	default:
+		node := &yaml.Node{}
+
+		return node, node.Encode(v)
+	}
+}
@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"binance/internal/repository/mongo/structs"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSettingsFile(t *testing.T) {
+	for _, name := range []string{"settings.yaml", "settings.json"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			repo := NewSettingsFileRepository(path)
+
+			assert.NoError(t, repo.SetDefault())
+
+			list, err := repo.LoadAll()
+			assert.NoError(t, err)
+			assert.Len(t, list, len(defaultSettings()))
+
+			s, err := repo.Load("BTCUSDT")
+			assert.NoError(t, err)
+			assert.Equal(t, 1, s.Version)
+			assert.Equal(t, []int{10, 60, 300}, s.CVDWindows)
+			assert.Equal(t, fileSettingsID("BTCUSDT"), s.ID)
+
+			_, err = repo.Load("SOLUSDT")
+			assert.True(t, errors.Is(err, mongo.ErrNoDocuments))
+
+			change := structs.SettingsChange{Author: "test", Reason: "depth limit"}
+
+			assert.NoError(t, repo.UpdateDepthLimit(s.ID, s.DepthLimit+1, change))
+			// the same value is not versioned
+			assert.NoError(t, repo.UpdateDepthLimit(s.ID, s.DepthLimit+1, change))
+			assert.NoError(t, repo.UpdateStatus(s.ID, structs.Disabled, change))
+
+			// the file is read by the other process
+			reread, err := NewSettingsFileRepository(path).Load("BTCUSDT")
+			assert.NoError(t, err)
+			assert.Equal(t, s.DepthLimit+1, reread.DepthLimit)
+			assert.Equal(t, structs.Disabled.ToString(), reread.Status)
+			assert.Equal(t, 3, reread.Version)
+
+			versions, err := repo.Versions("BTCUSDT")
+			assert.NoError(t, err)
+			if assert.Len(t, versions, 3) {
+				assert.Equal(t, structs.SettingsAuthorSystem, versions[0].Author)
+				assert.Equal(t, "test", versions[1].Author)
+			}
+
+			rolled, err := repo.Rollback("BTCUSDT", 1, structs.SettingsChange{Author: "test"})
+			if assert.NoError(t, err) {
+				assert.Equal(t, s.DepthLimit, rolled.DepthLimit)
+				assert.Equal(t, 4, rolled.Version)
+			}
+
+			v, err := repo.GetVersion("BTCUSDT", 4)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "rollback to version 1", v.Reason)
+			}
+		})
+	}
+}
+
+func TestSettingsFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yml")
+	repo := NewSettingsFileRepository(path)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`symbols:
+  - symbol: BTCUSDT
+    step: 0.003
+    delta: 45
+    depth_limit: 35
+    status: ENABLED
+`), 0644))
+
+	s, err := repo.Load("BTCUSDT")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(45), s.Delta)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	changed := make(chan *structs.Settings, 1)
+	go func() {
+		_ = repo.Watch(ctx, func(settings *structs.Settings) {
+			changed <- settings
+		})
+	}()
+
+	// the modification time differs from the first write
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`symbols:
+  - symbol: BTCUSDT
+    step: 0.003
+    delta: 50
+    depth_limit: 35
+    status: ENABLED
+`), 0644))
+
+	select {
+	case settings := <-changed:
+		assert.Equal(t, float64(50), settings.Delta)
+	case <-ctx.Done():
+		t.Fatal("the change is not watched")
+	}
+
+	// the edit is versioned as the external one
+	s, err = repo.Load("BTCUSDT")
+	assert.NoError(t, err)
+
+	tracked, err := repo.Track(s)
+	assert.NoError(t, err)
+	assert.True(t, tracked)
+	assert.Equal(t, 1, s.Version)
+
+	tracked, err = repo.Track(s)
+	assert.NoError(t, err)
+	assert.False(t, tracked)
+}
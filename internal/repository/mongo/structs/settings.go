@@ -1,6 +1,7 @@
 package structs
 
 import (
+	"errors"
 	"fmt"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -21,6 +22,35 @@ func (s SymbolStatus) ToString() string {
 	return fmt.Sprintf("%s", s)
 }
 
+// DefaultSafeDeltaFactor is the multiplier of the volatility into the safe delta of the settings without it
+const DefaultSafeDeltaFactor = 1.5
+
+// Market is the market traded by the symbol, the futures are traded by default
+type Market string
+
+const (
+	MarketFeatures Market = "FEATURES"
+	MarketSpot     Market = "SPOT"
+)
+
+func (m Market) ToString() string {
+	return fmt.Sprintf("%s", m)
+}
+
+// Strategy is the way the symbol is traded, the sessions are traded by default
+type Strategy string
+
+const (
+	StrategySession Strategy = "SESSION"
+	StrategyGrid    Strategy = "GRID"
+	// StrategySignal opens the sessions by the webhook signals only
+	StrategySignal Strategy = "SIGNAL"
+)
+
+func (s Strategy) ToString() string {
+	return fmt.Sprintf("%s", s)
+}
+
 type Settings struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty"`
 	Symbol     string             `bson:"symbol"`
@@ -33,4 +63,131 @@ type Settings struct {
 	MinPrice   float64            `bson:"min_price"`
 	SpotURL    string             `bson:"spot_url"`
 	Status     string             `bson:"status"`
+	Market     string             `bson:"market"`
+	Strategy   string             `bson:"strategy"`
+
+	ExitModel          string  `bson:"exit_model"`
+	VolatilityInterval string  `bson:"volatility_interval"`
+	VolatilityWindow   int     `bson:"volatility_window"`
+	SafeDeltaFactor    float64 `bson:"safe_delta_factor"`
+	TriggerDeltaFactor float64 `bson:"trigger_delta_factor"`
+	MinDelta           float64 `bson:"min_delta"`
+	MaxDelta           float64 `bson:"max_delta"`
+
+	CVDWindows          []int   `bson:"cvd_windows"`
+	ImbalanceLevels     int     `bson:"imbalance_levels"`
+	ImbalanceMidPercent float64 `bson:"imbalance_mid_percent"`
+	WallMinRatio        float64 `bson:"wall_min_ratio"`
+
+	LiquidationOrderType   string  `bson:"liquidation_order_type"`
+	LiquidationPriceOffset float64 `bson:"liquidation_price_offset"`
+	LiquidationRequote     int     `bson:"liquidation_requote"`
+
+	Timezone       string          `bson:"timezone"`
+	TradingWindows []TradingWindow `bson:"trading_windows"`
+	// FundingBlackoutBefore and FundingBlackoutAfter are the minutes around the funding times
+	FundingBlackoutBefore int    `bson:"funding_blackout_before"`
+	FundingBlackoutAfter  int    `bson:"funding_blackout_after"`
+	BlackoutFile          string `bson:"blackout_file"`
+
+	// GridLevels is the number of the grid prices from MinPrice to MaxPrice, Step is bought on each of them
+	GridLevels int `bson:"grid_levels"`
+
+	// SignalMaxRisk is the loss at the stop loss allowed to a signal, Limit caps its quantity
+	SignalMaxRisk float64 `bson:"signal_max_risk"`
+
+	// Version is the number of the last change, the changes are kept in the versions collection
+	Version int `bson:"version"`
+}
+
+// GetMarket returns the market of the symbol, no market means the futures
+func (s *Settings) GetMarket() Market {
+	if s.Market == "" {
+		return MarketFeatures
+	}
+
+	return Market(s.Market)
+}
+
+// GetStrategy returns the strategy of the symbol, no strategy means the sessions
+func (s *Settings) GetStrategy() Strategy {
+	if s.Strategy == "" {
+		return StrategySession
+	}
+
+	return Strategy(s.Strategy)
+}
+
+// Validate checks the settings before they are applied to the running monitor
+func (s *Settings) Validate() error {
+	if s.Symbol == "" {
+		return errors.New("no symbol")
+	}
+
+	switch SymbolStatus(s.Status) {
+	case Disabled, Enabled, Liquidation, LiquidationBUY, LiquidationSELL, New:
+	default:
+		return fmt.Errorf("unknown status '%s'", s.Status)
+	}
+
+	switch s.GetMarket() {
+	case MarketFeatures, MarketSpot:
+	default:
+		return fmt.Errorf("unknown market '%s'", s.Market)
+	}
+
+	switch s.GetStrategy() {
+	case StrategySession, StrategyGrid, StrategySignal:
+	default:
+		return fmt.Errorf("unknown strategy '%s'", s.Strategy)
+	}
+
+	switch s.LiquidationOrderType {
+	case "", "MARKET", "LIMIT":
+	default:
+		return fmt.Errorf("unknown liquidation order type '%s'", s.LiquidationOrderType)
+	}
+
+	if s.MaxDelta != 0 && s.MaxDelta < s.MinDelta {
+		return fmt.Errorf("max delta %v is less than min delta %v", s.MaxDelta, s.MinDelta)
+	}
+
+	if err := s.Params().Validate(); err != nil {
+		return fmt.Errorf("%s: %w", s.GetStrategy(), err)
+	}
+
+	return nil
+}
+
+// Params returns the typed parameters of the strategy
+func (s *Settings) Params() StrategyParams {
+	switch s.GetStrategy() {
+	case StrategyGrid:
+		return GridParams{Step: s.Step, MinPrice: s.MinPrice, MaxPrice: s.MaxPrice, Levels: s.GridLevels}
+	case StrategySignal:
+		return SignalParams{Limit: s.Limit, MaxRisk: s.SignalMaxRisk, Delta: s.Delta}
+	default:
+		return SessionParams{
+			Step:       s.Step,
+			Delta:      s.Delta,
+			DeltaStep:  s.DeltaStep,
+			DepthLimit: s.DepthLimit,
+			MinPrice:   s.MinPrice,
+			MaxPrice:   s.MaxPrice,
+		}
+	}
+}
+
+// ValidateLimits checks the order quantity of the strategy by the exchange filters of the symbol
+func (s *Settings) ValidateLimits(limits SymbolLimits) error {
+	name, quantity := s.Params().Quantity()
+
+	return limits.Check(name, quantity)
+}
+
+// TradingWindow is a "HH:MM" time range on the days ("MON", "TUE", ...), no day means every day
+type TradingWindow struct {
+	Days []string `bson:"days"`
+	From string   `bson:"from"`
+	To   string   `bson:"to"`
 }
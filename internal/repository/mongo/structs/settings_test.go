@@ -0,0 +1,98 @@
+package structs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Validate(t *testing.T) {
+	valid := map[string]Settings{
+		"session": {
+			Symbol:     "BTCUSDT",
+			Step:       0.003,
+			Delta:      45,
+			DepthLimit: 35,
+			Status:     Enabled.ToString(),
+			MaxPrice:   20500,
+			MinPrice:   19800,
+		},
+		"grid": {
+			Symbol:     "BTCUSDT",
+			Step:       0.003,
+			Status:     Enabled.ToString(),
+			Strategy:   StrategyGrid.ToString(),
+			MinPrice:   19000,
+			MaxPrice:   20000,
+			GridLevels: 5,
+		},
+		"signal": {
+			Symbol:        "BTCUSDT",
+			Limit:         0.02,
+			Status:        Enabled.ToString(),
+			Strategy:      StrategySignal.ToString(),
+			SignalMaxRisk: 10,
+		},
+	}
+
+	for name, s := range valid {
+		assert.NoError(t, s.Validate(), name)
+	}
+
+	for name, c := range map[string]struct {
+		strategy string
+		change   func(s *Settings)
+	}{
+		"no symbol":           {"session", func(s *Settings) { s.Symbol = "" }},
+		"unknown status":      {"session", func(s *Settings) { s.Status = "PAUSED" }},
+		"unknown market":      {"session", func(s *Settings) { s.Market = "OPTIONS" }},
+		"unknown strategy":    {"session", func(s *Settings) { s.Strategy = "DCA" }},
+		"unknown liquidation": {"session", func(s *Settings) { s.LiquidationOrderType = "STOP" }},
+		"delta range":         {"session", func(s *Settings) { s.MinDelta, s.MaxDelta = 10, 2 }},
+		"no step":             {"session", func(s *Settings) { s.Step = 0 }},
+		"no depth limit":      {"session", func(s *Settings) { s.DepthLimit = 0 }},
+		"price range":         {"session", func(s *Settings) { s.MaxPrice = 19000 }},
+		"grid levels":         {"grid", func(s *Settings) { s.GridLevels = 1 }},
+		"grid prices":         {"grid", func(s *Settings) { s.MinPrice = 0 }},
+		"signal limit":        {"signal", func(s *Settings) { s.Limit = -1 }},
+	} {
+		s := valid[c.strategy]
+		c.change(&s)
+
+		assert.Error(t, s.Validate(), name)
+	}
+}
+
+func Test_ValidateLimits(t *testing.T) {
+	limits := SymbolLimits{MinQty: 0.001, MaxQty: 1000, StepSize: 0.001}
+
+	s := Settings{Symbol: "BTCUSDT", Step: 0.003}
+	assert.NoError(t, s.ValidateLimits(limits))
+
+	s.Step = 0.0005
+	assert.EqualError(t, s.ValidateLimits(limits), "step 0.0005 is less than the exchange min quantity 0.001")
+
+	s.Step = 0.0035
+	assert.Error(t, s.ValidateLimits(limits))
+
+	// the signal quantity is not capped without the limit
+	s.Strategy = StrategySignal.ToString()
+	assert.NoError(t, s.ValidateLimits(limits))
+}
+
+func Test_SymbolLimitsFormat(t *testing.T) {
+	spot := SymbolLimits{MinQty: 0.00001, StepSize: 0.00001, TickSize: 0.01}
+
+	assert.Equal(t, "0.00300", spot.FormatQuantity(0.003))
+	// the quantity is rounded down, the price to the nearest tick
+	assert.Equal(t, "0.12345", spot.FormatQuantity(0.123459))
+	assert.Equal(t, "19500.13", spot.FormatPrice(19500.126))
+
+	alt := SymbolLimits{StepSize: 1, TickSize: 0.0001}
+	assert.Equal(t, "500", alt.FormatQuantity(500.9))
+	assert.Equal(t, "0.2605", alt.FormatPrice(0.26049))
+
+	// the value is formatted as it is without the filter
+	assert.Equal(t, "0.0123", SymbolLimits{}.FormatQuantity(0.0123))
+	assert.Equal(t, 0.0, spot.FloorQuantity(0.000009))
+}
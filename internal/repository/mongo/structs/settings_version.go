@@ -0,0 +1,74 @@
+package structs
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the authors of the settings changes made by the bot, the people sign the changes by their names
+const (
+	SettingsAuthorSystem     = "system"
+	SettingsAuthorSupervisor = "supervisor"
+	SettingsAuthorMonitor    = "monitor"
+	SettingsAuthorTelegram   = "telegram"
+	// SettingsAuthorExternal is the change made in the collection out of the bot
+	SettingsAuthorExternal = "external"
+)
+
+// SettingsChange is the author and the reason of the change, they are kept with the version
+type SettingsChange struct {
+	Author string
+	Reason string
+}
+
+// SettingsVersion is the snapshot of the symbol settings after the change
+type SettingsVersion struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"`
+	SettingsID primitive.ObjectID `bson:"settings_id"`
+	Symbol     string             `bson:"symbol"`
+	Version    int                `bson:"version"`
+	Author     string             `bson:"author"`
+	Reason     string             `bson:"reason"`
+	Settings   Settings           `bson:"settings"`
+	CreatedAt  time.Time          `bson:"created_at"`
+}
+
+// SettingsDiff is the changed field, Field is the bson name
+type SettingsDiff struct {
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+// Diff returns the fields changed from a to b in the order of the struct, the id and the version are skipped
+func Diff(a, b *Settings) []SettingsDiff {
+	var out []SettingsDiff
+
+	va, vb := reflect.ValueOf(*a), reflect.ValueOf(*b)
+	t := va.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("bson"), ",")[0]
+
+		switch name {
+		case "", "_id", "version":
+			continue
+		}
+
+		fa, fb := va.Field(i), vb.Field(i)
+
+		// the missing list is decoded as nil, the empty one is the same
+		if fa.Kind() == reflect.Slice && fa.Len() == 0 && fb.Len() == 0 {
+			continue
+		}
+
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			out = append(out, SettingsDiff{Field: name, From: fa.Interface(), To: fb.Interface()})
+		}
+	}
+
+	return out
+}
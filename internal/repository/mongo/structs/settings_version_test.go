@@ -0,0 +1,39 @@
+package structs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_Diff(t *testing.T) {
+	a := Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     "BTCUSDT",
+		DepthLimit: 35,
+		Status:     Enabled.ToString(),
+		Version:    1,
+	}
+
+	t.Run("no change", func(t *testing.T) {
+		b := a
+		b.Version = 2
+		b.CVDWindows = []int{}
+
+		assert.Empty(t, Diff(&a, &b))
+	})
+
+	t.Run("changed fields", func(t *testing.T) {
+		b := a
+		b.DepthLimit = 45
+		b.Status = Disabled.ToString()
+		b.CVDWindows = []int{10, 60}
+
+		assert.Equal(t, []SettingsDiff{
+			{Field: "depth_limit", From: float64(35), To: float64(45)},
+			{Field: "status", From: Enabled.ToString(), To: Disabled.ToString()},
+			{Field: "cvd_windows", From: []int(nil), To: []int{10, 60}},
+		}, Diff(&a, &b))
+	})
+}
@@ -0,0 +1,182 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// gridMaxLevels caps the orders of the grid, every level is an open order on the exchange
+const gridMaxLevels = 100
+
+// StrategyParams are the typed parameters of the strategy read from the flat settings
+type StrategyParams interface {
+	// Validate checks the ranges of the parameters and the rules between them
+	Validate() error
+	// Quantity is the named order quantity checked by the exchange filters, zero is not checked
+	Quantity() (string, float64)
+}
+
+// SessionParams are the parameters of the sessions: Step is the quantity of the session,
+// Delta is the static exit distance in the price points and DepthLimit is the depth delta opening it
+type SessionParams struct {
+	Step       float64
+	Delta      float64
+	DeltaStep  float64
+	DepthLimit float64
+	MinPrice   float64
+	MaxPrice   float64
+}
+
+func (p SessionParams) Validate() error {
+	switch {
+	case p.Step <= 0:
+		return fmt.Errorf("step %v is not positive", p.Step)
+	case p.Delta <= 0:
+		return fmt.Errorf("delta %v is not positive", p.Delta)
+	case p.DeltaStep < 0:
+		return fmt.Errorf("delta step %v is negative", p.DeltaStep)
+	case p.DepthLimit <= 0:
+		return fmt.Errorf("depth limit %v is not positive", p.DepthLimit)
+	}
+
+	return validatePriceRange(p.MinPrice, p.MaxPrice, false)
+}
+
+func (p SessionParams) Quantity() (string, float64) {
+	return "step", p.Step
+}
+
+// GridParams are the parameters of the grid: Step is bought on each of the Levels prices from MinPrice to MaxPrice
+type GridParams struct {
+	Step     float64
+	MinPrice float64
+	MaxPrice float64
+	Levels   int
+}
+
+func (p GridParams) Validate() error {
+	switch {
+	case p.Step <= 0:
+		return fmt.Errorf("step %v is not positive", p.Step)
+	case p.Levels < 2 || p.Levels > gridMaxLevels:
+		return fmt.Errorf("grid levels %d are out of [2, %d]", p.Levels, gridMaxLevels)
+	}
+
+	return validatePriceRange(p.MinPrice, p.MaxPrice, true)
+}
+
+func (p GridParams) Quantity() (string, float64) {
+	return "step", p.Step
+}
+
+// SignalParams are the parameters of the signals: Limit caps the quantity and SignalMaxRisk the loss
+// at the stop loss, zero is no cap. Delta is the static exit distance of the signal without the exits.
+type SignalParams struct {
+	Limit   float64
+	MaxRisk float64
+	Delta   float64
+}
+
+func (p SignalParams) Validate() error {
+	switch {
+	case p.Limit < 0:
+		return fmt.Errorf("limit %v is negative", p.Limit)
+	case p.MaxRisk < 0:
+		return fmt.Errorf("signal max risk %v is negative", p.MaxRisk)
+	case p.Delta < 0:
+		return fmt.Errorf("delta %v is negative", p.Delta)
+	}
+
+	return nil
+}
+
+func (p SignalParams) Quantity() (string, float64) {
+	return "limit", p.Limit
+}
+
+// SymbolLimits are the exchange filters of the symbol quantities and prices, zero is no filter
+type SymbolLimits struct {
+	MinQty   float64
+	MaxQty   float64
+	StepSize float64
+	// TickSize is the price step of the PRICE_FILTER
+	TickSize float64
+}
+
+// FloorQuantity rounds the quantity down to the step size
+func (l SymbolLimits) FloorQuantity(quantity float64) float64 {
+	if l.StepSize <= 0 {
+		return quantity
+	}
+
+	// the quantities are floats, the steps are taken with the precision of the step
+	return math.Floor(quantity/l.StepSize+1e-6) * l.StepSize
+}
+
+// FormatQuantity is the quantity of the order rounded down to the step size
+func (l SymbolLimits) FormatQuantity(quantity float64) string {
+	return formatStep(l.FloorQuantity(quantity), l.StepSize)
+}
+
+// FormatPrice is the price of the order rounded to the tick size
+func (l SymbolLimits) FormatPrice(price float64) string {
+	if l.TickSize > 0 {
+		price = math.Round(price/l.TickSize) * l.TickSize
+	}
+
+	return formatStep(price, l.TickSize)
+}
+
+// formatStep formats the value with the decimals of the step, the value without the step is formatted as it is
+func formatStep(v, step float64) string {
+	if step <= 0 {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	decimals := 0
+
+	s := strconv.FormatFloat(step, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		decimals = len(s) - i - 1
+	}
+
+	return strconv.FormatFloat(v, 'f', decimals, 64)
+}
+
+// Check reports the quantity rejected by the exchange filters
+func (l SymbolLimits) Check(name string, quantity float64) error {
+	switch {
+	case quantity == 0:
+		return nil
+	case quantity < l.MinQty:
+		return fmt.Errorf("%s %v is less than the exchange min quantity %v", name, quantity, l.MinQty)
+	case l.MaxQty > 0 && quantity > l.MaxQty:
+		return fmt.Errorf("%s %v is over the exchange max quantity %v", name, quantity, l.MaxQty)
+	}
+
+	if l.StepSize > 0 {
+		// the quantities are floats, the remainder is compared with the precision of the step
+		steps := quantity / l.StepSize
+		if math.Abs(steps-math.Round(steps)) > 1e-6 {
+			return fmt.Errorf("%s %v is not a multiple of the exchange step size %v", name, quantity, l.StepSize)
+		}
+	}
+
+	return nil
+}
+
+func validatePriceRange(min, max float64, required bool) error {
+	switch {
+	case min < 0 || max < 0:
+		return errors.New("negative min or max price")
+	case required && (min == 0 || max == 0):
+		return errors.New("no min or max price")
+	case max != 0 && max <= min:
+		return fmt.Errorf("max price %v is not greater than min price %v", max, min)
+	}
+
+	return nil
+}
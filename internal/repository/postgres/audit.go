@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"binance/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditRepository struct {
+	conn *sqlx.DB
+}
+
+func NewAuditRepository(conn *sqlx.DB) AuditRepo {
+	return &AuditRepository{
+		conn: conn,
+	}
+}
+
+// Store appends the record, the time is set by the database
+func (r *AuditRepository) Store(m *models.AuditRecord) error {
+	_, err := r.conn.NamedExec("INSERT INTO audit_records (source,chat_id,user_id,user_name,action,target,payload,result,error) VALUES (:source,:chat_id,:user_id,:user_name,:action,:target,:payload,:result,:error)", m)
+
+	return err
+}
+
+// GetLast returns the last records from the newest one
+func (r *AuditRepository) GetLast(limit int) ([]models.AuditRecord, error) {
+	var out []models.AuditRecord
+
+	if err := r.conn.Select(&out, "SELECT * FROM audit_records ORDER BY created_at DESC, id DESC LIMIT $1", limit); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"binance/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type FillRepository struct {
+	conn   *sqlx.DB
+	market string
+}
+
+func NewFillRepository(conn *sqlx.DB, market string) FillRepo {
+	return &FillRepository{
+		conn:   conn,
+		market: market,
+	}
+}
+
+// Store stores the new fills and links them to the orders in one transaction, the stored fills are skipped
+func (r *FillRepository) Store(fills []models.Fill) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for i := range fills {
+		fills[i].Market = r.market
+
+		if _, err := tx.NamedExec("INSERT INTO fills (id,market,symbol,order_id,side,position_side,price,quantity,quote_quantity,commission,commission_asset,realized_pnl,maker,traded_at) VALUES (:id,:market,:symbol,:order_id,:side,:position_side,:price,:quantity,:quote_quantity,:commission,:commission_asset,:realized_pnl,:maker,:traded_at) ON CONFLICT DO NOTHING", &fills[i]); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	if err := link(tx, r.market); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Link links the fills to the orders stored after them
+func (r *FillRepository) Link() error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := link(tx, r.market); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func link(tx *sqlx.Tx, market string) error {
+	_, err := tx.Exec("UPDATE fills f SET client_order_id = o.id, session_id = coalesce(o.session_id, '') FROM orders o WHERE f.market = $1 AND f.client_order_id = '' AND o.market = f.market AND o.order_id = f.order_id;", market)
+
+	return err
+}
+
+// GetLastID returns the id of the last stored fill of the symbol, it is 0 when there is no fill
+func (r *FillRepository) GetLastID(symbol string) (int64, error) {
+	var id int64
+
+	if err := r.conn.Get(&id, "SELECT coalesce(max(id), 0) FROM fills WHERE market = $1 AND symbol = $2;", r.market, symbol); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *FillRepository) GetBySessionID(sessionID string) ([]models.Fill, error) {
+	var fills []models.Fill
+
+	if err := r.conn.Select(&fills, "SELECT * FROM fills WHERE market = $1 AND session_id = $2 ORDER BY traded_at, id;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return fills, nil
+}
+
+// SessionPnL sums the fills of the session by the commission asset
+func (r *FillRepository) SessionPnL(sessionID string) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT session_id AS key, commission_asset, count(*) AS trades, sum(quote_quantity) AS volume, sum(realized_pnl) AS realized_pnl, sum(commission) AS commission FROM fills WHERE market = $1 AND session_id = $2 GROUP BY session_id, commission_asset ORDER BY commission_asset;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DailyPnL sums the fills of the symbol by the UTC day and the commission asset, the empty symbol sums
+// every symbol. From is inclusive, to is exclusive.
+func (r *FillRepository) DailyPnL(symbol string, from, to time.Time) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT to_char(traded_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS key, commission_asset, count(*) AS trades, sum(quote_quantity) AS volume, sum(realized_pnl) AS realized_pnl, sum(commission) AS commission FROM fills WHERE market = $1 AND ($2 = '' OR symbol = $2) AND traded_at >= $3 AND traded_at < $4 GROUP BY key, commission_asset ORDER BY key, commission_asset;", r.market, symbol, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// VersionPnL sums the fills of the symbol by the settings version the sessions are opened by, the
+// version of the session is the one of its entry
+func (r *FillRepository) VersionPnL(symbol string) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT o.settings_version::text AS key, f.commission_asset, count(*) AS trades, sum(f.quote_quantity) AS volume, sum(f.realized_pnl) AS realized_pnl, sum(f.commission) AS commission FROM fills f JOIN (SELECT DISTINCT ON (session_id) session_id, settings_version FROM orders WHERE market = $1 AND symbol = $2 AND type = 'LIMIT' ORDER BY session_id, created_at) o ON o.session_id = f.session_id WHERE f.market = $1 AND f.symbol = $2 GROUP BY o.settings_version, f.commission_asset ORDER BY o.settings_version, f.commission_asset;", r.market, symbol); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"binance/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type IncomeRepository struct {
+	conn *sqlx.DB
+}
+
+func NewIncomeRepository(conn *sqlx.DB) IncomeRepo {
+	return &IncomeRepository{
+		conn: conn,
+	}
+}
+
+// Store stores the new incomes in one transaction, it returns the number of the stored ones
+func (r *IncomeRepository) Store(incomes []models.Income) (int, error) {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	var out int
+
+	for i := range incomes {
+		res, err := tx.NamedExec("INSERT INTO incomes (id,tran_id,trade_id,source,symbol,income_type,income,asset,info,income_at) VALUES (:id,:tran_id,:trade_id,:source,:symbol,:income_type,:income,:asset,:info,:income_at) ON CONFLICT DO NOTHING", &incomes[i])
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		out += int(n)
+	}
+
+	return out, tx.Commit()
+}
+
+func (r *IncomeRepository) GetFirst(source string) (*models.Income, error) {
+	var income models.Income
+
+	if err := r.conn.QueryRowx("SELECT * FROM incomes WHERE source = $1 ORDER BY income_at, id LIMIT 1", source).StructScan(&income); err != nil {
+		return nil, err
+	}
+
+	return &income, nil
+}
+
+func (r *IncomeRepository) GetLast(source string) (*models.Income, error) {
+	var income models.Income
+
+	if err := r.conn.QueryRowx("SELECT * FROM incomes WHERE source = $1 ORDER BY income_at DESC, id DESC LIMIT 1", source).StructScan(&income); err != nil {
+		return nil, err
+	}
+
+	return &income, nil
+}
+
+// Summary sums the incomes by the UTC day, the symbol, the type and the asset. From is inclusive, to is exclusive.
+func (r *IncomeRepository) Summary(from, to time.Time) ([]models.IncomeSummary, error) {
+	var out []models.IncomeSummary
+
+	if err := r.conn.Select(&out, "SELECT to_char(income_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, symbol, income_type, asset, count(*) AS count, sum(income) AS income FROM incomes WHERE income_at >= $1 AND income_at < $2 GROUP BY day, symbol, income_type, asset ORDER BY day, symbol, income_type, asset;", from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
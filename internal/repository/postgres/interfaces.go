@@ -7,20 +7,25 @@ import (
 
 //go:generate mockery --case=snake --name=OrderRepo
 //go:generate mockery --case=snake --name=PriceRepo
+//go:generate mockery --case=snake --name=GridRepo
+//go:generate mockery --case=snake --name=FillRepo
+//go:generate mockery --case=snake --name=IncomeRepo
+//go:generate mockery --case=snake --name=AuditRepo
 
 type OrderRepo interface {
-	Store(m *models.Order) error
+	Store(m *models.Order, c OrderChange) error
 	GetLast(symbol string) (*models.Order, error)
-	GetFirst(symbol string) (*models.Order, error)
 	GetByID(id string) (*models.Order, error)
 	GetBySessionID(sessionID string) ([]models.Order, error)
-	GetBySessionIDWithSide(sessionID, side string) ([]models.Order, error)
-	GetLastWithInterval(symbol string, sTime, eTime time.Time) ([]models.Order, error)
-	SetActualPrice(id string, price float64) error
-	SetTry(id, try int) error
-	SetStatus(id string, status string) error
-	Delete(id string) error
-	SetOrderID(id string, orderID int64) error
+	Find(filter OrderFilter) (*OrderPage, error)
+	SetActualPrice(id string, price float64, c OrderChange) error
+	SetStatus(id string, status string, c OrderChange) error
+	Delete(id string, c OrderChange) error
+	SetOrderID(id string, orderID int64, c OrderChange) error
+	Record(o *models.Order, event string, c OrderChange) error
+	GetTimeline(sessionID string) ([]models.OrderEvent, error)
+	Claim(id string, ttl time.Duration) (int, error)
+	Release(id string) error
 }
 
 type PriceRepo interface {
@@ -29,4 +34,37 @@ type PriceRepo interface {
 	Store(m *models.Price) (err error)
 	GetLast(symbol string, sTime, eTime time.Time) (*models.Price, error)
 	GetByID(symbol string, id uint) (*models.Price, error)
+	RollUp(until time.Time) (int64, error)
+	Prune(before time.Time, archive bool) (int64, error)
+}
+
+type GridRepo interface {
+	Store(m *models.Grid, levels []models.GridLevel) error
+	GetActive(symbol, market string) (*models.Grid, error)
+	GetLevels(gridID string) ([]models.GridLevel, error)
+	SetStatus(id string, status string) error
+	UpdateLevel(m *models.GridLevel) error
+	CloseCycle(m *models.GridLevel, profit float64) error
+}
+
+type FillRepo interface {
+	Store(fills []models.Fill) error
+	Link() error
+	GetLastID(symbol string) (int64, error)
+	GetBySessionID(sessionID string) ([]models.Fill, error)
+	SessionPnL(sessionID string) ([]models.PnL, error)
+	DailyPnL(symbol string, from, to time.Time) ([]models.PnL, error)
+	VersionPnL(symbol string) ([]models.PnL, error)
+}
+
+type IncomeRepo interface {
+	Store(incomes []models.Income) (int, error)
+	GetFirst(source string) (*models.Income, error)
+	GetLast(source string) (*models.Income, error)
+	Summary(from, to time.Time) ([]models.IncomeSummary, error)
+}
+
+type AuditRepo interface {
+	Store(m *models.AuditRecord) error
+	GetLast(limit int) ([]models.AuditRecord, error)
 }
@@ -3,6 +3,8 @@
 package mocks
 
 import (
+	postgres "binance/internal/repository/postgres"
+
 	models "binance/models"
 
 	mock "github.com/stretchr/testify/mock"
@@ -15,12 +17,70 @@ type OrderRepo struct {
 	mock.Mock
 }
 
+// Claim provides a mock function with given fields: id, ttl
+func (_m *OrderRepo) Claim(id string, ttl time.Duration) (int, error) {
+	ret := _m.Called(id, ttl)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string, time.Duration) int); ok {
+		r0 = rf(id, ttl)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = rf(id, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Delete provides a mock function with given fields: id, c
+func (_m *OrderRepo) Delete(id string, c postgres.OrderChange) error {
+	ret := _m.Called(id, c)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, postgres.OrderChange) error); ok {
+		r0 = rf(id, c)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Find provides a mock function with given fields: filter
+func (_m *OrderRepo) Find(filter postgres.OrderFilter) (*postgres.OrderPage, error) {
+	ret := _m.Called(filter)
+
+	var r0 *postgres.OrderPage
+	if rf, ok := ret.Get(0).(func(postgres.OrderFilter) *postgres.OrderPage); ok {
+		r0 = rf(filter)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*postgres.OrderPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(postgres.OrderFilter) error); ok {
+		r1 = rf(filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetByID provides a mock function with given fields: id
-func (_m *OrderRepo) GetByID(id int) (*models.Order, error) {
+func (_m *OrderRepo) GetByID(id string) (*models.Order, error) {
 	ret := _m.Called(id)
 
 	var r0 *models.Order
-	if rf, ok := ret.Get(0).(func(int) *models.Order); ok {
+	if rf, ok := ret.Get(0).(func(string) *models.Order); ok {
 		r0 = rf(id)
 	} else {
 		if ret.Get(0) != nil {
@@ -29,7 +89,7 @@ func (_m *OrderRepo) GetByID(id int) (*models.Order, error) {
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(int) error); ok {
+	if rf, ok := ret.Get(1).(func(string) error); ok {
 		r1 = rf(id)
 	} else {
 		r1 = ret.Error(1)
@@ -61,8 +121,8 @@ func (_m *OrderRepo) GetBySessionID(sessionID string) ([]models.Order, error) {
 	return r0, r1
 }
 
-// GetFirst provides a mock function with given fields: symbol
-func (_m *OrderRepo) GetFirst(symbol string) (*models.Order, error) {
+// GetLast provides a mock function with given fields: symbol
+func (_m *OrderRepo) GetLast(symbol string) (*models.Order, error) {
 	ret := _m.Called(symbol)
 
 	var r0 *models.Order
@@ -84,22 +144,22 @@ func (_m *OrderRepo) GetFirst(symbol string) (*models.Order, error) {
 	return r0, r1
 }
 
-// GetLast provides a mock function with given fields: symbol
-func (_m *OrderRepo) GetLast(symbol string) (*models.Order, error) {
-	ret := _m.Called(symbol)
+// GetTimeline provides a mock function with given fields: sessionID
+func (_m *OrderRepo) GetTimeline(sessionID string) ([]models.OrderEvent, error) {
+	ret := _m.Called(sessionID)
 
-	var r0 *models.Order
-	if rf, ok := ret.Get(0).(func(string) *models.Order); ok {
-		r0 = rf(symbol)
+	var r0 []models.OrderEvent
+	if rf, ok := ret.Get(0).(func(string) []models.OrderEvent); ok {
+		r0 = rf(sessionID)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*models.Order)
+			r0 = ret.Get(0).([]models.OrderEvent)
 		}
 	}
 
 	var r1 error
 	if rf, ok := ret.Get(1).(func(string) error); ok {
-		r1 = rf(symbol)
+		r1 = rf(sessionID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -107,36 +167,41 @@ func (_m *OrderRepo) GetLast(symbol string) (*models.Order, error) {
 	return r0, r1
 }
 
-// GetLastWithInterval provides a mock function with given fields: symbol, sTime, eTime
-func (_m *OrderRepo) GetLastWithInterval(symbol string, sTime time.Time, eTime time.Time) ([]models.Order, error) {
-	ret := _m.Called(symbol, sTime, eTime)
+// Record provides a mock function with given fields: o, event, c
+func (_m *OrderRepo) Record(o *models.Order, event string, c postgres.OrderChange) error {
+	ret := _m.Called(o, event, c)
 
-	var r0 []models.Order
-	if rf, ok := ret.Get(0).(func(string, time.Time, time.Time) []models.Order); ok {
-		r0 = rf(symbol, sTime, eTime)
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.Order, string, postgres.OrderChange) error); ok {
+		r0 = rf(o, event, c)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).([]models.Order)
-		}
+		r0 = ret.Error(0)
 	}
 
-	var r1 error
-	if rf, ok := ret.Get(1).(func(string, time.Time, time.Time) error); ok {
-		r1 = rf(symbol, sTime, eTime)
+	return r0
+}
+
+// Release provides a mock function with given fields: id
+func (_m *OrderRepo) Release(id string) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(id)
 	} else {
-		r1 = ret.Error(1)
+		r0 = ret.Error(0)
 	}
 
-	return r0, r1
+	return r0
 }
 
-// SetActualPrice provides a mock function with given fields: id, price
-func (_m *OrderRepo) SetActualPrice(id int, price float64) error {
-	ret := _m.Called(id, price)
+// SetActualPrice provides a mock function with given fields: id, price, c
+func (_m *OrderRepo) SetActualPrice(id string, price float64, c postgres.OrderChange) error {
+	ret := _m.Called(id, price, c)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, float64) error); ok {
-		r0 = rf(id, price)
+	if rf, ok := ret.Get(0).(func(string, float64, postgres.OrderChange) error); ok {
+		r0 = rf(id, price, c)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -144,13 +209,13 @@ func (_m *OrderRepo) SetActualPrice(id int, price float64) error {
 	return r0
 }
 
-// SetStatus provides a mock function with given fields: id, status
-func (_m *OrderRepo) SetStatus(id int, status string) error {
-	ret := _m.Called(id, status)
+// SetOrderID provides a mock function with given fields: id, orderID, c
+func (_m *OrderRepo) SetOrderID(id string, orderID int64, c postgres.OrderChange) error {
+	ret := _m.Called(id, orderID, c)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, string) error); ok {
-		r0 = rf(id, status)
+	if rf, ok := ret.Get(0).(func(string, int64, postgres.OrderChange) error); ok {
+		r0 = rf(id, orderID, c)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -158,13 +223,13 @@ func (_m *OrderRepo) SetStatus(id int, status string) error {
 	return r0
 }
 
-// SetTry provides a mock function with given fields: id, try
-func (_m *OrderRepo) SetTry(id int, try int) error {
-	ret := _m.Called(id, try)
+// SetStatus provides a mock function with given fields: id, status, c
+func (_m *OrderRepo) SetStatus(id string, status string, c postgres.OrderChange) error {
+	ret := _m.Called(id, status, c)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(int, int) error); ok {
-		r0 = rf(id, try)
+	if rf, ok := ret.Get(0).(func(string, string, postgres.OrderChange) error); ok {
+		r0 = rf(id, status, c)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -172,13 +237,13 @@ func (_m *OrderRepo) SetTry(id int, try int) error {
 	return r0
 }
 
-// Store provides a mock function with given fields: m
-func (_m *OrderRepo) Store(m *models.Order) error {
-	ret := _m.Called(m)
+// Store provides a mock function with given fields: m, c
+func (_m *OrderRepo) Store(m *models.Order, c postgres.OrderChange) error {
+	ret := _m.Called(m, c)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(*models.Order) error); ok {
-		r0 = rf(m)
+	if rf, ok := ret.Get(0).(func(*models.Order, postgres.OrderChange) error); ok {
+		r0 = rf(m, c)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -126,6 +126,48 @@ func (_m *PriceRepo) GetMaxMinByCreatedByInterval(symbol string, sTime time.Time
 	return r0, r1, r2, r3, r4
 }
 
+// Prune provides a mock function with given fields: before, archive
+func (_m *PriceRepo) Prune(before time.Time, archive bool) (int64, error) {
+	ret := _m.Called(before, archive)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(time.Time, bool) int64); ok {
+		r0 = rf(before, archive)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time, bool) error); ok {
+		r1 = rf(before, archive)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RollUp provides a mock function with given fields: until
+func (_m *PriceRepo) RollUp(until time.Time) (int64, error) {
+	ret := _m.Called(until)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(time.Time) int64); ok {
+		r0 = rf(until)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(time.Time) error); ok {
+		r1 = rf(until)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Store provides a mock function with given fields: m
 func (_m *PriceRepo) Store(m *models.Price) error {
 	ret := _m.Called(m)
@@ -1,74 +1,66 @@
 package postgres
 
 import (
-	"time"
-
 	"binance/models"
+	"database/sql"
+	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// the markets of the orders table
 const (
-	Spot     = "SPOT"
-	Features = "FEATURES"
+	Spot = "SPOT"
+	// Features is the USDT-M futures
+	Features = "USDT_M"
+	CoinM    = "COIN_M"
 )
 
+// ErrNotClaimed is returned when the order is not pending or another submitter holds its claim
+var ErrNotClaimed = errors.New("order is not claimed")
+
 type OrderRepository struct {
-	conn  *sqlx.DB
-	table string
+	conn   *sqlx.DB
+	market string
 }
 
-func NewOrderRepository(conn *sqlx.DB, table string) OrderRepo {
+func NewOrderRepository(conn *sqlx.DB, market string) OrderRepo {
 	return &OrderRepository{
-		conn:  conn,
-		table: table,
+		conn:   conn,
+		market: market,
 	}
 }
 
-func (r *OrderRepository) Store(m *models.Order) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.NamedExec("INSERT INTO orders (id,order_id,session_id,symbol,side,position_side,quantity,actual_price,price,stop_price,status,type,try) VALUES (:id,:order_id,:session_id,:symbol,:side,:position_side,:quantity,:actual_price,:price,:stop_price,:status,:type,:try)", m); err != nil {
-			return err
-		}
-	case Features:
-		if _, err := r.conn.NamedExec("INSERT INTO features_orders (id,order_id,session_id,symbol,side,position_side,quantity,actual_price,price,stop_price,status,type,try) VALUES (:id,:order_id,:session_id,:symbol,:side,:position_side,:quantity,:actual_price,:price,:stop_price,:status,:type,:try)", m); err != nil {
-			return err
-		}
+// Store stores the order with its CREATED event in one transaction
+func (r *OrderRepository) Store(m *models.Order, c OrderChange) error {
+	m.Market = r.market
+
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
 	}
 
-	return nil
-}
+	if _, err := tx.NamedExec("INSERT INTO orders (id,order_id,session_id,market,symbol,side,position_side,quantity,actual_price,price,stop_price,status,type,try,exit_model,volatility,safe_delta,trigger_delta,take_profit,stop_loss,settings_version) VALUES (:id,:order_id,:session_id,:market,:symbol,:side,:position_side,:quantity,:actual_price,:price,:stop_price,:status,:type,:try,:exit_model,:volatility,:safe_delta,:trigger_delta,:take_profit,:stop_loss,:settings_version)", m); err != nil {
+		_ = tx.Rollback()
 
-func (r *OrderRepository) GetLast(symbol string) (*models.Order, error) {
-	var order models.Order
+		return err
+	}
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.QueryRowx("SELECT * FROM orders WHERE symbol = $1 AND type = 'LIMIT' ORDER BY created_at DESC LIMIT 1", symbol).StructScan(&order); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.QueryRowx("SELECT * FROM features_orders WHERE symbol = $1 AND type = 'LIMIT'  ORDER BY created_at DESC LIMIT 1", symbol).StructScan(&order); err != nil {
-			return nil, err
-		}
+	if err := recordOrderEvent(tx, r.market, m.ID, models.OrderEventCreated, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
 	}
 
-	return &order, nil
+	return tx.Commit()
 }
 
-func (r *OrderRepository) GetFirst(symbol string) (*models.Order, error) {
+func (r *OrderRepository) GetLast(symbol string) (*models.Order, error) {
 	var order models.Order
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.QueryRowx("SELECT * FROM orders WHERE symbol = $1 AND side = $2 AND try = $3 ORDER BY id DESC LIMIT 1", symbol, "BUY", 1).StructScan(&order); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.QueryRowx("SELECT * FROM features_orders WHERE symbol = $1 AND side = $2 AND try = $3 ORDER BY id DESC LIMIT 1", symbol, "BUY", 1).StructScan(&order); err != nil {
-			return nil, err
-		}
+	if err := r.conn.QueryRowx("SELECT * FROM orders WHERE market = $1 AND symbol = $2 AND type = 'LIMIT' ORDER BY created_at DESC LIMIT 1", r.market, symbol).StructScan(&order); err != nil {
+		return nil, err
 	}
 
 	return &order, nil
@@ -77,15 +69,8 @@ func (r *OrderRepository) GetFirst(symbol string) (*models.Order, error) {
 func (r *OrderRepository) GetByID(id string) (*models.Order, error) {
 	var order models.Order
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.QueryRowx("SELECT * FROM orders WHERE id = $1 LIMIT 1", id).StructScan(&order); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.QueryRowx("SELECT * FROM features_orders WHERE id = $1 LIMIT 1", id).StructScan(&order); err != nil {
-			return nil, err
-		}
+	if err := r.conn.QueryRowx("SELECT * FROM orders WHERE market = $1 AND id = $2 LIMIT 1", r.market, id).StructScan(&order); err != nil {
+		return nil, err
 	}
 
 	return &order, nil
@@ -94,128 +79,159 @@ func (r *OrderRepository) GetByID(id string) (*models.Order, error) {
 func (r *OrderRepository) GetBySessionID(sessionID string) ([]models.Order, error) {
 	var orders []models.Order
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.Select(&orders, "SELECT * FROM orders where session_id = $1 ORDER BY id DESC;", sessionID); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.Select(&orders, "SELECT * FROM features_orders where session_id = $1 ORDER BY created_at;", sessionID); err != nil {
-			return nil, err
-		}
+	if err := r.conn.Select(&orders, "SELECT * FROM orders WHERE market = $1 AND session_id = $2 ORDER BY created_at;", r.market, sessionID); err != nil {
+		return nil, err
 	}
 
 	return orders, nil
 }
 
-func (r *OrderRepository) GetBySessionIDWithSide(sessionID, side string) ([]models.Order, error) {
+// Find returns the page of the orders selected by the filter, no market in the filter means the market of the repository
+func (r *OrderRepository) Find(filter OrderFilter) (*OrderPage, error) {
+	if len(filter.Markets) == 0 {
+		filter.Markets = []string{r.market}
+	}
+
+	query, args, limit, err := filter.query()
+	if err != nil {
+		return nil, err
+	}
+
 	var orders []models.Order
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.Select(&orders, "SELECT * FROM orders where session_id = $1 AND side = $2 ORDER BY id DESC;", sessionID, side); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.Select(&orders, "SELECT * FROM features_orders where session_id = $1 AND side = $2 ORDER BY id DESC;", sessionID, side); err != nil {
-			return nil, err
-		}
+	if err := r.conn.Select(&orders, query, args...); err != nil {
+		return nil, err
 	}
 
-	return orders, nil
-}
+	var out OrderPage
 
-func (r *OrderRepository) GetLastWithInterval(symbol string, sTime, eTime time.Time) ([]models.Order, error) {
-	var orders []models.Order
+	if len(orders) > limit {
+		orders = orders[:limit]
 
-	switch r.table {
-	case Spot:
-		if err := r.conn.Select(&orders, "SELECT * FROM orders where created_at > $1 AND created_at < $2 AND symbol = $3;", sTime.UTC(), eTime.UTC(), symbol); err != nil {
-			return nil, err
-		}
-	case Features:
-		if err := r.conn.Select(&orders, "SELECT * FROM features_orders where created_at > $1 AND created_at < $2 AND symbol = $3;", sTime.UTC(), eTime.UTC(), symbol); err != nil {
-			return nil, err
-		}
+		last := orders[limit-1]
+		out.NextCursor = EncodeOrderCursor(last.CreatedAt, last.ID)
 	}
 
-	return orders, nil
+	out.Orders = orders
+
+	return &out, nil
 }
 
-func (r *OrderRepository) SetActualPrice(id string, price float64) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.Exec("UPDATE orders SET price = $1 where id = $2;", price, id); err != nil {
-			return err
-		}
-	case Features:
-		if _, err := r.conn.Exec("UPDATE features_orders SET price = $1 where id = $2;", price, id); err != nil {
-			return err
-		}
+// SetActualPrice updates the price, the event is recorded when the price is changed
+func (r *OrderRepository) SetActualPrice(id string, price float64, c OrderChange) error {
+	return r.change(id, models.OrderEventPrice, c, "UPDATE orders SET price = $1 WHERE market = $2 AND id = $3 AND price IS DISTINCT FROM $1;", price, r.market, id)
+}
+
+// Delete removes the order, the DELETED event is kept
+func (r *OrderRepository) Delete(id string, c OrderChange) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := recordOrderEvent(tx, r.market, id, models.OrderEventDeleted, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
 	}
 
-	return nil
+	if _, err := tx.Exec("DELETE FROM orders WHERE market = $1 AND id = $2;", r.market, id); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *OrderRepository) SetTry(id, try int) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.Exec("UPDATE orders SET try = $1 where id = $2;", try, id); err != nil {
-			return err
-		}
-	case Features:
-		if _, err := r.conn.Exec("UPDATE features_orders SET try = $1 where id = $2;", try, id); err != nil {
-			return err
-		}
+// SetStatus updates the status, the event is recorded when the status is changed
+func (r *OrderRepository) SetStatus(id string, status string, c OrderChange) error {
+	return r.change(id, models.OrderEventStatus, c, "UPDATE orders SET status = $1 WHERE market = $2 AND id = $3 AND status IS DISTINCT FROM $1;", status, r.market, id)
+}
+
+// SetOrderID sets the exchange id of the acknowledged order, the event is recorded when the id is changed
+func (r *OrderRepository) SetOrderID(id string, orderID int64, c OrderChange) error {
+	return r.change(id, models.OrderEventAcknowledged, c, "UPDATE orders SET order_id = $1 WHERE market = $2 AND id = $3 AND order_id IS DISTINCT FROM $1;", orderID, r.market, id)
+}
+
+// Record appends the event which does not change the order: the submission and the cancel. The order
+// which is not stored is recorded with the state of o.
+func (r *OrderRepository) Record(o *models.Order, event string, c OrderChange) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	if err := recordOrderEventOf(tx, r.market, o, event, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *OrderRepository) Delete(id string) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.Exec("DELETE from orders where id = $1;", id); err != nil {
-			return err
-		}
+// Claim takes the pending order for the submission, the claim is held for ttl. The attempts are counted
+// before the order is sent, it returns their number with this one. The concurrent claims of the order
+// are serialized by the row lock, one of them wins.
+func (r *OrderRepository) Claim(id string, ttl time.Duration) (int, error) {
+	var attempts int
 
-	case Features:
-		if _, err := r.conn.Exec("DELETE from features_orders where id = $1;", id); err != nil {
-			return err
-		}
+	err := r.conn.QueryRowx("UPDATE orders SET submit_attempts = submit_attempts + 1, claimed_at = now() WHERE market = $1 AND id = $2 AND status = 'IN PROGRESS' AND (claimed_at IS NULL OR claimed_at < now() - $3 * interval '1 second') RETURNING submit_attempts;", r.market, id, ttl.Seconds()).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotClaimed
 	}
 
-	return nil
+	return attempts, err
 }
 
-func (r *OrderRepository) SetStatus(id string, status string) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.Exec("UPDATE orders SET status = $1 where id = $2;", status, id); err != nil {
-			return err
-		}
+// Release drops the claim of the order rejected by the exchange, the attempts are kept
+func (r *OrderRepository) Release(id string) error {
+	_, err := r.conn.Exec("UPDATE orders SET claimed_at = NULL WHERE market = $1 AND id = $2 AND status = 'IN PROGRESS';", r.market, id)
 
-	case Features:
-		if _, err := r.conn.Exec("UPDATE features_orders SET status = $1 where id = $2;", status, id); err != nil {
-			return err
-		}
+	return err
+}
+
+// GetTimeline returns the events of the session orders in the order they happened
+func (r *OrderRepository) GetTimeline(sessionID string) ([]models.OrderEvent, error) {
+	var events []models.OrderEvent
+
+	if err := r.conn.Select(&events, "SELECT * FROM order_events WHERE market = $1 AND session_id = $2 ORDER BY created_at, id;", r.market, sessionID); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return events, nil
 }
 
-func (r *OrderRepository) SetOrderID(id string, orderID int64) error {
-	switch r.table {
-	case Spot:
-		if _, err := r.conn.Exec("UPDATE orders SET order_id = $1 where id = $2;", orderID, id); err != nil {
-			return err
-		}
+// change runs the update of the order and records the event in one transaction, nothing is recorded
+// when the update changes no row
+func (r *OrderRepository) change(id string, event string, c OrderChange, query string, args ...interface{}) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if n != 0 {
+		if err := recordOrderEvent(tx, r.market, id, event, c); err != nil {
+			_ = tx.Rollback()
 
-	case Features:
-		if _, err := r.conn.Exec("UPDATE features_orders SET order_id = $1 where id = $2;", orderID, id); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"binance/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	DefaultOrderLimit = 100
+	MaxOrderLimit     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// OrderFilter selects the orders, the zero fields do not filter. The orders are sorted
+// by the creation time, Cursor is the NextCursor of the previous page.
+type OrderFilter struct {
+	Markets   []string
+	Symbol    string
+	Statuses  []string
+	Types     []string
+	Side      string
+	SessionID string
+	// Acknowledged selects the orders acknowledged by the exchange, they have the exchange order id
+	Acknowledged bool
+	// From is inclusive, To is exclusive
+	From time.Time
+	To   time.Time
+
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+type OrderPage struct {
+	Orders []models.Order
+	// NextCursor continues the query, it is empty on the last page
+	NextCursor string
+}
+
+// query builds the select of the page, one order more than the limit is read to know there is the next page
+func (f *OrderFilter) query() (string, []interface{}, int, error) {
+	var where []string
+	var args []interface{}
+
+	arg := func(v interface{}) string {
+		args = append(args, v)
+
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(f.Markets) != 0 {
+		where = append(where, "market = ANY("+arg(pq.Array(f.Markets))+")")
+	}
+	if f.Symbol != "" {
+		where = append(where, "symbol = "+arg(f.Symbol))
+	}
+	if len(f.Statuses) != 0 {
+		where = append(where, "status = ANY("+arg(pq.Array(f.Statuses))+")")
+	}
+	if len(f.Types) != 0 {
+		where = append(where, "type = ANY("+arg(pq.Array(f.Types))+")")
+	}
+	if f.Side != "" {
+		where = append(where, "side = "+arg(f.Side))
+	}
+	if f.SessionID != "" {
+		where = append(where, "session_id = "+arg(f.SessionID))
+	}
+	if f.Acknowledged {
+		where = append(where, "order_id > 0")
+	}
+	if !f.From.IsZero() {
+		where = append(where, "created_at >= "+arg(f.From.UTC()))
+	}
+	if !f.To.IsZero() {
+		where = append(where, "created_at < "+arg(f.To.UTC()))
+	}
+
+	order, cmp := "ASC", ">"
+	if f.Descending {
+		order, cmp = "DESC", "<"
+	}
+
+	if f.Cursor != "" {
+		createdAt, id, err := DecodeOrderCursor(f.Cursor)
+		if err != nil {
+			return "", nil, 0, err
+		}
+
+		where = append(where, fmt.Sprintf("(created_at, id) %s (%s, %s)", cmp, arg(createdAt), arg(id)))
+	}
+
+	limit := f.PageLimit()
+
+	query := "SELECT * FROM orders"
+	if len(where) != 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT %s;", order, order, arg(limit+1))
+
+	return query, args, limit, nil
+}
+
+// PageLimit is the limit of the page, the default one when it is not set
+func (f *OrderFilter) PageLimit() int {
+	switch {
+	case f.Limit <= 0:
+		return DefaultOrderLimit
+	case f.Limit > MaxOrderLimit:
+		return MaxOrderLimit
+	}
+
+	return f.Limit
+}
+
+// EncodeOrderCursor points at the order, the next page starts after it
+func EncodeOrderCursor(createdAt time.Time, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id))
+}
+
+// DecodeOrderCursor returns the creation time and the id of the order the cursor points at
+func DecodeOrderCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	nsec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+
+	return time.Unix(0, nsec).UTC(), parts[1], nil
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OrderFilterQuery(t *testing.T) {
+	from := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		filter    OrderFilter
+		wantQuery string
+		wantArgs  []interface{}
+		wantLimit int
+	}{
+		{
+			name:      "no filter",
+			wantQuery: "SELECT * FROM orders ORDER BY created_at ASC, id ASC LIMIT $1;",
+			wantArgs:  []interface{}{DefaultOrderLimit + 1},
+			wantLimit: DefaultOrderLimit,
+		},
+		{
+			name: "all filters",
+			filter: OrderFilter{
+				Markets:      []string{Features},
+				Symbol:       "BTCUSDT",
+				Statuses:     []string{"FILLED", "CANCELED"},
+				Types:        []string{"LIMIT"},
+				Side:         "BUY",
+				SessionID:    "session",
+				Acknowledged: true,
+				From:         from,
+				To:           to,
+				Descending:   true,
+				Limit:        5000,
+			},
+			wantQuery: "SELECT * FROM orders WHERE market = ANY($1) AND symbol = $2 AND status = ANY($3) AND type = ANY($4) AND side = $5 AND session_id = $6 AND order_id > 0 AND created_at >= $7 AND created_at < $8 ORDER BY created_at DESC, id DESC LIMIT $9;",
+			wantArgs: []interface{}{
+				pq.Array([]string{Features}),
+				"BTCUSDT",
+				pq.Array([]string{"FILLED", "CANCELED"}),
+				pq.Array([]string{"LIMIT"}),
+				"BUY",
+				"session",
+				from,
+				to,
+				MaxOrderLimit + 1,
+			},
+			wantLimit: MaxOrderLimit,
+		},
+		{
+			name: "cursor",
+			filter: OrderFilter{
+				Symbol: "BTCUSDT",
+				Cursor: EncodeOrderCursor(from, "order"),
+				Limit:  10,
+			},
+			wantQuery: "SELECT * FROM orders WHERE symbol = $1 AND (created_at, id) > ($2, $3) ORDER BY created_at ASC, id ASC LIMIT $4;",
+			wantArgs:  []interface{}{"BTCUSDT", from, "order", 11},
+			wantLimit: 10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, limit, err := tt.filter.query()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantQuery, query)
+			assert.Equal(t, tt.wantArgs, args)
+			assert.Equal(t, tt.wantLimit, limit)
+		})
+	}
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		for _, cursor := range []string{"%%%", "bm8tc2VwYXJhdG9y", EncodeOrderCursor(from, "")} {
+			f := OrderFilter{Cursor: cursor}
+
+			_, _, _, err := f.query()
+			assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+		}
+	})
+}
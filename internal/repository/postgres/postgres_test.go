@@ -5,9 +5,9 @@ import (
 	"binance/models"
 	"fmt"
 	"math/rand"
+	"os"
 	"time"
 
-	"log"
 	"testing"
 
 	"github.com/google/uuid"
@@ -21,11 +21,18 @@ type PGTest struct {
 	conn *sqlx.DB
 }
 
-func initPGTest() *PGTest {
+// initPGTest connects to the database of PG_TEST_DSN, the repository tests are skipped without it.
+// The same repositories are tested on SQLite in memory by the sqlite package.
+func initPGTest(t *testing.T) *PGTest {
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+
 	var out PGTest
-	db, err := sqlx.Connect("postgres", "host=localhost user=binance password=binance dbname=binance sslmode=disable")
+	db, err := sqlx.Connect("postgres", dsn)
 	if err != nil {
-		log.Fatalln(err)
+		t.Fatal(err)
 	}
 
 	out.conn = db
@@ -34,7 +41,7 @@ func initPGTest() *PGTest {
 }
 
 func Test_GetBySessionID(t *testing.T) {
-	c := initPGTest()
+	c := initPGTest(t)
 	pgStore := postgres.NewOrderRepository(c.conn, postgres.Features)
 
 	oList, err := pgStore.GetBySessionID("cc3336da-432f-4e9e-9152-d976732f9b8d")
@@ -44,7 +51,7 @@ func Test_GetBySessionID(t *testing.T) {
 }
 
 func Test_OrderStore(t *testing.T) {
-	c := initPGTest()
+	c := initPGTest(t)
 	pgStore := postgres.NewOrderRepository(c.conn, postgres.Features)
 
 	rand.Seed(time.Now().UnixNano())
@@ -67,7 +74,7 @@ func Test_OrderStore(t *testing.T) {
 			Try:         1,
 			Type:        "OCO",
 			CreatedAt:   time.Now(),
-		})
+		}, postgres.OrderChange{Source: models.OrderSourcePoll})
 
 		assert.NoError(t, err)
 	})
@@ -82,14 +89,59 @@ func Test_OrderStore(t *testing.T) {
 		t.Logf("%+v", o)
 	})
 
-	t.Run("GetFirst", func(t *testing.T) {
-		o, err := pgStore.GetFirst(symbol)
+	t.Run("Find", func(t *testing.T) {
+		page, err := pgStore.Find(postgres.OrderFilter{
+			Symbol: symbol,
+			Side:   "BUY",
+			Limit:  1,
+		})
 		assert.NoError(t, err)
 
-		assert.Equal(t, o.Side, "BUY")
-		firstID = o.ID
+		if assert.Len(t, page.Orders, 1) {
+			assert.Equal(t, page.Orders[0].Side, "BUY")
+			firstID = page.Orders[0].ID
+		}
 
-		t.Logf("%+v", o)
+		next, err := pgStore.Find(postgres.OrderFilter{
+			Symbol: symbol,
+			Side:   "BUY",
+			Cursor: page.NextCursor,
+			Limit:  1,
+		})
+		assert.NoError(t, err)
+
+		for _, o := range next.Orders {
+			assert.NotEqual(t, firstID, o.ID)
+		}
+
+		t.Logf("%+v", page)
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		o, err := pgStore.GetByID(lastID)
+		assert.NoError(t, err)
+
+		change := postgres.OrderChange{Source: models.OrderSourcePoll, Payload: `{"status":"FILLED"}`}
+
+		assert.NoError(t, pgStore.SetStatus(o.ID, "FILLED", change))
+		// the same status is not recorded again
+		assert.NoError(t, pgStore.SetStatus(o.ID, "FILLED", change))
+		assert.NoError(t, pgStore.Record(&models.Order{ID: o.ID}, models.OrderEventCanceled, change))
+
+		// the liquidation orders are only sent, the cancel takes the session of the submission
+		liq := models.Order{ID: "liq-" + uuid.NewString(), SessionID: o.SessionID, Symbol: o.Symbol}
+		assert.NoError(t, pgStore.Record(&liq, models.OrderEventSubmitted, change))
+		assert.NoError(t, pgStore.Record(&models.Order{ID: liq.ID, Symbol: o.Symbol, Status: "CANCELED"}, models.OrderEventCanceled, change))
+
+		events, err := pgStore.GetTimeline(o.SessionID)
+		assert.NoError(t, err)
+
+		var got []string
+		for _, e := range events {
+			got = append(got, e.Event)
+		}
+
+		assert.Equal(t, []string{models.OrderEventCreated, models.OrderEventStatus, models.OrderEventCanceled, models.OrderEventSubmitted, models.OrderEventCanceled}, got)
 	})
 
 	t.Run("GetByID", func(t *testing.T) {
@@ -104,4 +156,197 @@ func Test_OrderStore(t *testing.T) {
 		t.Logf("%+v", l)
 	})
 
+	t.Run("Claim", func(t *testing.T) {
+		o := models.Order{
+			ID:        uuid.NewString(),
+			SessionID: uuid.NewString(),
+			Symbol:    symbol,
+			Side:      "BUY",
+			Status:    "IN PROGRESS",
+			Type:      "LIMIT",
+		}
+		assert.NoError(t, pgStore.Store(&o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		attempts, err := pgStore.Claim(o.ID, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+
+		// the claim is held
+		_, err = pgStore.Claim(o.ID, time.Minute)
+		assert.ErrorIs(t, err, postgres.ErrNotClaimed)
+
+		assert.NoError(t, pgStore.Release(o.ID))
+
+		attempts, err = pgStore.Claim(o.ID, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+}
+
+func Test_FillStore(t *testing.T) {
+	c := initPGTest(t)
+	orderStore := postgres.NewOrderRepository(c.conn, postgres.Features)
+	fillStore := postgres.NewFillRepository(c.conn, postgres.Features)
+
+	rand.Seed(time.Now().UnixNano())
+
+	symbol := "BTCUSDT"
+	sessionID := uuid.NewString()
+	orderID := rand.Int63()
+
+	t.Run("Store", func(t *testing.T) {
+		lastID, err := fillStore.GetLastID(symbol)
+		assert.NoError(t, err)
+
+		// the fill is stored before its order
+		err = fillStore.Store([]models.Fill{{
+			ID:              lastID + 1,
+			Symbol:          symbol,
+			OrderID:         orderID,
+			Side:            "BUY",
+			PositionSide:    "LONG",
+			Price:           20000,
+			Quantity:        0.001,
+			QuoteQuantity:   20,
+			Commission:      0.008,
+			CommissionAsset: "USDT",
+			RealizedPnL:     1.5,
+			TradedAt:        time.Now(),
+		}})
+		assert.NoError(t, err)
+
+		err = orderStore.Store(&models.Order{
+			ID:        uuid.NewString(),
+			OrderID:   orderID,
+			SessionID: sessionID,
+			Symbol:    symbol,
+			Side:      "BUY",
+			Status:    "FILLED",
+			Type:      "LIMIT",
+		}, postgres.OrderChange{Source: models.OrderSourcePoll})
+		assert.NoError(t, err)
+
+		assert.NoError(t, fillStore.Link())
+
+		got, err := fillStore.GetLastID(symbol)
+		assert.NoError(t, err)
+		assert.Equal(t, lastID+1, got)
+	})
+
+	t.Run("SessionPnL", func(t *testing.T) {
+		pnl, err := fillStore.SessionPnL(sessionID)
+		assert.NoError(t, err)
+
+		if assert.Len(t, pnl, 1) {
+			assert.Equal(t, 1, pnl[0].Trades)
+			assert.InDelta(t, 1.5, pnl[0].RealizedPnL, 1e-9)
+		}
+	})
+
+	t.Run("DailyPnL", func(t *testing.T) {
+		pnl, err := fillStore.DailyPnL(symbol, time.Now().Add(-24*time.Hour), time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+
+		t.Logf("%+v", pnl)
+	})
+}
+
+func Test_IncomeStore(t *testing.T) {
+	c := initPGTest(t)
+	incomeStore := postgres.NewIncomeRepository(c.conn)
+
+	incomeAt := time.Now().UTC()
+	income := models.Income{
+		ID:         uuid.NewString(),
+		TranID:     rand.Int63(),
+		Source:     models.IncomeSourceAPI,
+		Symbol:     "BTCUSDT",
+		IncomeType: "FUNDING_FEE",
+		Income:     -0.0123,
+		Asset:      "USDT",
+		IncomeAt:   incomeAt,
+	}
+
+	t.Run("Store", func(t *testing.T) {
+		n, err := incomeStore.Store([]models.Income{income})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		// the stored income is skipped
+		n, err = incomeStore.Store([]models.Income{income})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+
+	t.Run("GetLast", func(t *testing.T) {
+		i, err := incomeStore.GetLast(models.IncomeSourceAPI)
+		assert.NoError(t, err)
+
+		t.Logf("%+v", i)
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		summary, err := incomeStore.Summary(incomeAt.Add(-time.Hour), incomeAt.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, summary)
+
+		t.Logf("%+v", summary)
+	})
+}
+
+func Test_AuditStore(t *testing.T) {
+	c := initPGTest(t)
+	auditStore := postgres.NewAuditRepository(c.conn)
+
+	record := models.AuditRecord{
+		Source:   models.AuditSourceTelegram,
+		ChatID:   42,
+		UserID:   7,
+		UserName: "operator",
+		Action:   "disable",
+		Target:   "BTCUSDT",
+		Result:   models.AuditResultOK,
+	}
+
+	assert.NoError(t, auditStore.Store(&record))
+
+	record.Action = "enable"
+	assert.NoError(t, auditStore.Store(&record))
+
+	last, err := auditStore.GetLast(1)
+	if assert.NoError(t, err) && assert.Len(t, last, 1) {
+		assert.Equal(t, "enable", last[0].Action)
+		assert.Equal(t, int64(7), last[0].UserID)
+		assert.False(t, last[0].CreatedAt.IsZero())
+	}
+}
+
+func Test_PriceRollUp(t *testing.T) {
+	c := initPGTest(t)
+	priceStore := postgres.NewPriceRepository(c.conn)
+
+	symbol := "TEST" + uuid.NewString()[:8]
+	start := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+
+	for i := 0; i < 600; i++ {
+		_, err := c.conn.Exec("INSERT INTO prices (symbol, price, created_at) VALUES ($1, $2, $3)", symbol, 20000+rand.Float64()*100, start.Add(time.Duration(i)*17*time.Second))
+		assert.NoError(t, err)
+	}
+
+	sTime, eTime := start.Add(7*time.Minute+3*time.Second), start.Add(2*time.Hour+11*time.Minute+5*time.Second)
+
+	open, close, max, min, err := priceStore.GetMaxMinByCreatedByInterval(symbol, sTime, eTime)
+	assert.NoError(t, err)
+
+	_, err = priceStore.RollUp(time.Now())
+	assert.NoError(t, err)
+
+	// the rolled up lookup is the same
+	rOpen, rClose, rMax, rMin, err := priceStore.GetMaxMinByCreatedByInterval(symbol, sTime, eTime)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{open, close, max, min}, []float64{rOpen, rClose, rMax, rMin})
+
+	_, err = priceStore.Prune(start.Add(time.Hour), false)
+	assert.NoError(t, err)
 }
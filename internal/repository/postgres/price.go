@@ -28,25 +28,54 @@ func (r *PriceRepository) GetByCreatedByInterval(symbol string, sTime, eTime tim
 	return out, nil
 }
 
+// GetMaxMinByCreatedByInterval returns the open, close, max and min prices between the times, both ends are
+// excluded. The whole minutes are read from the rolled up candles and the rest from the prices, the open
+// and the close are the first and the last ticks as in the prices. The minutes older than the retention
+// keep their candles only, the lookup of them is rounded to the minutes.
 func (r *PriceRepository) GetMaxMinByCreatedByInterval(symbol string, sTime, eTime time.Time) (float64, float64, float64, float64, error) {
-	var maxID, minID uint
-	var maxPrice, minPrice sql.NullFloat64
+	// the tick at the start is excluded, the first candle starts after it
+	start := sTime.Truncate(time.Minute).Add(time.Minute)
+	end := eTime.Truncate(time.Minute)
 
-	if err := r.conn.QueryRowx("SELECT max(id),min(id),max(price),min(price) FROM prices where created_at > $1 AND created_at < $2 AND symbol = $3;", sTime.UTC(), eTime.UTC(), symbol).Scan(&maxID, &minID, &maxPrice, &minPrice); err != nil {
+	rolled, err := r.rolledUntil(symbol)
+	if err != nil {
 		return 0, 0, 0, 0, err
 	}
 
-	openPrice, err := r.GetByID(symbol, minID)
-	if err != nil {
-		return 0, 0, 0, 0, err
+	if rolled.Before(end) {
+		end = rolled
 	}
 
-	closePrice, err := r.GetByID(symbol, maxID)
-	if err != nil {
-		return 0, 0, 0, 0, err
+	var out OHLC
+
+	if !start.Before(end) {
+		if out, err = r.rawOHLC(symbol, "created_at > $2 AND created_at < $3", sTime.UTC(), eTime.UTC()); err != nil {
+			return 0, 0, 0, 0, err
+		}
+	} else {
+		head, err := r.rawOHLC(symbol, "created_at > $2 AND created_at < $3", sTime.UTC(), start.UTC())
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		middle, err := r.candlesOHLC(symbol, CandleSegments(start, end, CandleFrames))
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		tail, err := r.rawOHLC(symbol, "created_at >= $2 AND created_at < $3", end.UTC(), eTime.UTC())
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		out = head.Merge(middle).Merge(tail)
+	}
+
+	if !out.OK {
+		return 0, 0, 0, 0, sql.ErrNoRows
 	}
 
-	return openPrice.Price, closePrice.Price, maxPrice.Float64, minPrice.Float64, nil
+	return out.Open, out.Close, out.Max, out.Min, nil
 }
 
 func (r *PriceRepository) Store(m *models.Price) (err error) {
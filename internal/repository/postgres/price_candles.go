@@ -0,0 +1,246 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the time frames of the candles rolled up from the prices, from the smallest one
+const (
+	TimeFrame1m = "1m"
+	TimeFrame5m = "5m"
+	TimeFrame1h = "1h"
+)
+
+// CandleFrame is the time frame of the candles rolled up from the prices
+type CandleFrame struct {
+	Name     string
+	Duration time.Duration
+}
+
+// CandleFrames are the rolled up time frames, from the smallest one
+var CandleFrames = []CandleFrame{
+	{Name: TimeFrame1m, Duration: time.Minute},
+	{Name: TimeFrame5m, Duration: 5 * time.Minute},
+	{Name: TimeFrame1h, Duration: time.Hour},
+}
+
+// CandleSegment is the range of the candles of one time frame, From is inclusive, To is exclusive
+type CandleSegment struct {
+	Frame string
+	From  time.Time
+	To    time.Time
+}
+
+// CandleSegments covers the range by the largest candles, the range is aligned to the smallest frame
+func CandleSegments(from, to time.Time, frames []CandleFrame) []CandleSegment {
+	if !from.Before(to) || len(frames) == 0 {
+		return nil
+	}
+
+	largest := frames[len(frames)-1]
+	smaller := frames[:len(frames)-1]
+
+	if len(smaller) == 0 {
+		return []CandleSegment{{Frame: largest.Name, From: from, To: to}}
+	}
+
+	start := ceilTime(from, largest.Duration)
+	end := to.Truncate(largest.Duration)
+
+	if !start.Before(end) {
+		return CandleSegments(from, to, smaller)
+	}
+
+	out := CandleSegments(from, start, smaller)
+	out = append(out, CandleSegment{Frame: largest.Name, From: start, To: end})
+
+	return append(out, CandleSegments(end, to, smaller)...)
+}
+
+func ceilTime(t time.Time, d time.Duration) time.Time {
+	out := t.Truncate(d)
+	if out.Before(t) {
+		out = out.Add(d)
+	}
+
+	return out
+}
+
+// OHLC is the open, close, max and min prices of the range, OK is false when the range has no price
+type OHLC struct {
+	Open  float64
+	Close float64
+	Max   float64
+	Min   float64
+	OK    bool
+}
+
+// Merge adds the range which goes after the current one
+func (o OHLC) Merge(next OHLC) OHLC {
+	switch {
+	case !next.OK:
+		return o
+	case !o.OK:
+		return next
+	}
+
+	o.Close = next.Close
+	if next.Max > o.Max {
+		o.Max = next.Max
+	}
+	if next.Min < o.Min {
+		o.Min = next.Min
+	}
+
+	return o
+}
+
+// rawOHLC reads the range from the prices, the open and the close are the first and the last ticks by id
+func (r *PriceRepository) rawOHLC(symbol, where string, args ...interface{}) (OHLC, error) {
+	var open, close, max, min sql.NullFloat64
+
+	query := "SELECT (array_agg(price ORDER BY id))[1], (array_agg(price ORDER BY id DESC))[1], max(price), min(price) FROM prices WHERE symbol = $1 AND " + where + ";"
+
+	if err := r.conn.QueryRowx(query, append([]interface{}{symbol}, args...)...).Scan(&open, &close, &max, &min); err != nil {
+		return OHLC{}, err
+	}
+
+	return OHLC{
+		Open:  open.Float64,
+		Close: close.Float64,
+		Max:   max.Float64,
+		Min:   min.Float64,
+		OK:    open.Valid,
+	}, nil
+}
+
+// candlesOHLC reads the segments from the rolled up candles
+func (r *PriceRepository) candlesOHLC(symbol string, segments []CandleSegment) (OHLC, error) {
+	var out OHLC
+
+	if len(segments) == 0 {
+		return out, nil
+	}
+
+	args := []interface{}{symbol}
+	where := make([]string, 0, len(segments))
+
+	for _, s := range segments {
+		args = append(args, s.Frame, s.From.UTC(), s.To.UTC())
+
+		n := len(args)
+		where = append(where, fmt.Sprintf("(time_frame = $%d AND open_time >= $%d AND close_time <= $%d)", n-2, n-1, n))
+	}
+
+	rows, err := r.conn.Queryx("SELECT open_price, close_price, max_price, min_price FROM candles WHERE symbol = $1 AND ("+strings.Join(where, " OR ")+") ORDER BY open_time;", args...)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		next := OHLC{OK: true}
+
+		if err := rows.Scan(&next.Open, &next.Close, &next.Max, &next.Min); err != nil {
+			return out, err
+		}
+
+		out = out.Merge(next)
+	}
+
+	return out, rows.Err()
+}
+
+// rolledUntil is the close time of the last minute candle of the symbol, the zero time when nothing is rolled up
+func (r *PriceRepository) rolledUntil(symbol string) (time.Time, error) {
+	var out sql.NullTime
+
+	if err := r.conn.QueryRowx("SELECT max(close_time) FROM candles WHERE symbol = $1 AND time_frame = $2;", symbol, TimeFrame1m).Scan(&out); err != nil {
+		return time.Time{}, err
+	}
+
+	return out.Time, nil
+}
+
+// RollUp rolls the prices into the candles of the closed ranges before until, the last candle of each
+// frame is rolled up again so the run goes on from where the previous one stopped. The frames are
+// rolled up in one transaction, the lookups never see the minutes without the hours.
+func (r *PriceRepository) RollUp(until time.Time) (int64, error) {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	var out int64
+
+	for i, frame := range CandleFrames {
+		var from sql.NullTime
+
+		if err := tx.QueryRowx("SELECT max(open_time) FROM candles WHERE time_frame = $1;", frame.Name).Scan(&from); err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		if !from.Valid {
+			from.Time = time.Unix(0, 0)
+		}
+
+		seconds := strconv.Itoa(int(frame.Duration.Seconds()))
+		bucket := "to_timestamp(floor(extract(epoch FROM %s) / " + seconds + ") * " + seconds + ")"
+
+		var query string
+
+		if i == 0 {
+			query = "INSERT INTO candles (symbol, open_price, close_price, max_price, min_price, time_frame, open_time, close_time) " +
+				"SELECT symbol, (array_agg(price ORDER BY id))[1], (array_agg(price ORDER BY id DESC))[1], max(price), min(price), $1, bucket, bucket + $4 * interval '1 second' " +
+				"FROM (SELECT *, " + fmt.Sprintf(bucket, "created_at") + " AS bucket FROM prices WHERE created_at >= $2 AND created_at < $3) p GROUP BY symbol, bucket "
+		} else {
+			query = "INSERT INTO candles (symbol, open_price, close_price, max_price, min_price, time_frame, open_time, close_time) " +
+				"SELECT symbol, (array_agg(open_price ORDER BY open_time))[1], (array_agg(close_price ORDER BY open_time DESC))[1], max(max_price), min(min_price), $1, bucket, bucket + $4 * interval '1 second' " +
+				"FROM (SELECT *, " + fmt.Sprintf(bucket, "open_time") + " AS bucket FROM candles WHERE time_frame = '" + CandleFrames[0].Name + "' AND open_time >= $2 AND open_time < $3) c GROUP BY symbol, bucket "
+		}
+
+		query += "ON CONFLICT (symbol, time_frame, open_time) DO UPDATE SET open_price = excluded.open_price, close_price = excluded.close_price, max_price = excluded.max_price, min_price = excluded.min_price, close_time = excluded.close_time;"
+
+		res, err := tx.Exec(query, frame.Name, from.Time.UTC(), until.Truncate(frame.Duration).UTC(), frame.Duration.Seconds())
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		out += n
+	}
+
+	return out, tx.Commit()
+}
+
+// Prune removes the prices older than before, the prices which are not rolled up yet are kept. The
+// archived prices are moved to the prices_archive table.
+func (r *PriceRepository) Prune(before time.Time, archive bool) (int64, error) {
+	query := "DELETE FROM prices p USING (SELECT symbol, max(close_time) AS rolled FROM candles WHERE time_frame = $1 GROUP BY symbol) c " +
+		"WHERE p.symbol = c.symbol AND p.created_at < c.rolled AND p.created_at < $2"
+
+	if archive {
+		query = "WITH moved AS (" + query + " RETURNING p.*) INSERT INTO prices_archive (id, symbol, price, created_at) SELECT id, symbol, price, created_at FROM moved ON CONFLICT (id) DO NOTHING;"
+	}
+
+	res, err := r.conn.Exec(query, TimeFrame1m, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
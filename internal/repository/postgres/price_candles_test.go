@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CandleSegments(t *testing.T) {
+	at := func(h, m int) time.Time {
+		return time.Date(2022, 10, 1, h, m, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		want     []CandleSegment
+	}{
+		{
+			name: "empty",
+			from: at(10, 5),
+			to:   at(10, 5),
+		},
+		{
+			name: "minutes",
+			from: at(10, 1),
+			to:   at(10, 4),
+			want: []CandleSegment{{Frame: TimeFrame1m, From: at(10, 1), To: at(10, 4)}},
+		},
+		{
+			name: "five minutes",
+			from: at(10, 3),
+			to:   at(10, 17),
+			want: []CandleSegment{
+				{Frame: TimeFrame1m, From: at(10, 3), To: at(10, 5)},
+				{Frame: TimeFrame5m, From: at(10, 5), To: at(10, 15)},
+				{Frame: TimeFrame1m, From: at(10, 15), To: at(10, 17)},
+			},
+		},
+		{
+			name: "hours",
+			from: at(9, 58),
+			to:   at(13, 7),
+			want: []CandleSegment{
+				{Frame: TimeFrame1m, From: at(9, 58), To: at(10, 0)},
+				{Frame: TimeFrame1h, From: at(10, 0), To: at(13, 0)},
+				{Frame: TimeFrame5m, From: at(13, 0), To: at(13, 5)},
+				{Frame: TimeFrame1m, From: at(13, 5), To: at(13, 7)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CandleSegments(tt.from, tt.to, CandleFrames))
+		})
+	}
+}
+
+func Test_OHLCMerge(t *testing.T) {
+	first := OHLC{Open: 10, Close: 12, Max: 15, Min: 9, OK: true}
+	second := OHLC{Open: 12, Close: 11, Max: 14, Min: 8, OK: true}
+
+	assert.Equal(t, OHLC{Open: 10, Close: 11, Max: 15, Min: 8, OK: true}, first.Merge(second))
+	assert.Equal(t, first, first.Merge(OHLC{}))
+	assert.Equal(t, second, OHLC{}.Merge(second))
+}
@@ -0,0 +1,47 @@
+package sqlite
+
+import (
+	"binance/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CandleRepository struct {
+	conn *sqlx.DB
+}
+
+func NewCandlesRepository(conn *sqlx.DB) *CandleRepository {
+	return &CandleRepository{
+		conn: conn,
+	}
+}
+
+func (r *CandleRepository) Store(m *models.Candle) (err error) {
+
+	if _, err := r.conn.Exec("INSERT INTO candles (symbol,open_price,close_price,max_price,min_price,time_frame,open_time,close_time) VALUES (?,?,?,?,?,?,?,?)",
+		m.Symbol, m.OpenPrice, m.ClosePrice, m.MaxPrice, m.MinPrice, m.TimeFrame, m.OpenTime.UTC(), m.CloseTime.UTC()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *CandleRepository) GetLast(symbol string) (*models.Candle, error) {
+	var candle models.Candle
+
+	if err := r.conn.QueryRowx("SELECT * FROM candles WHERE symbol = ? ORDER BY id DESC LIMIT 1", symbol).StructScan(&candle); err != nil {
+		return nil, err
+	}
+
+	return &candle, nil
+}
+
+func (r *CandleRepository) GetLastList(symbol string, limit int) ([]models.Candle, error) {
+	var candles []models.Candle
+
+	if err := r.conn.Select(&candles, "SELECT * FROM candles WHERE symbol = ? ORDER BY id DESC LIMIT ?", symbol, limit); err != nil {
+		return nil, err
+	}
+
+	return candles, nil
+}
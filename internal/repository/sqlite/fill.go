@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type FillRepository struct {
+	conn   *sqlx.DB
+	market string
+}
+
+func NewFillRepository(conn *sqlx.DB, market string) postgres.FillRepo {
+	return &FillRepository{
+		conn:   conn,
+		market: market,
+	}
+}
+
+// Store stores the new fills and links them to the orders in one transaction, the stored fills are skipped
+func (r *FillRepository) Store(fills []models.Fill) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for i := range fills {
+		fills[i].Market = r.market
+
+		// the times are compared as the text, they are stored in UTC
+		fill := fills[i]
+		fill.TradedAt = fill.TradedAt.UTC()
+
+		if _, err := tx.NamedExec("INSERT INTO fills (id,market,symbol,order_id,side,position_side,price,quantity,quote_quantity,commission,commission_asset,realized_pnl,maker,traded_at) VALUES (:id,:market,:symbol,:order_id,:side,:position_side,:price,:quantity,:quote_quantity,:commission,:commission_asset,:realized_pnl,:maker,:traded_at) ON CONFLICT DO NOTHING", &fill); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	if err := link(tx, r.market); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Link links the fills to the orders stored after them
+func (r *FillRepository) Link() error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := link(tx, r.market); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func link(tx *sqlx.Tx, market string) error {
+	_, err := tx.Exec("UPDATE fills SET client_order_id = o.id, session_id = coalesce(o.session_id, '') FROM orders o WHERE fills.market = ? AND fills.client_order_id = '' AND o.market = fills.market AND o.order_id = fills.order_id;", market)
+
+	return err
+}
+
+// GetLastID returns the id of the last stored fill of the symbol, it is 0 when there is no fill
+func (r *FillRepository) GetLastID(symbol string) (int64, error) {
+	var id int64
+
+	if err := r.conn.Get(&id, "SELECT coalesce(max(id), 0) FROM fills WHERE market = ? AND symbol = ?;", r.market, symbol); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *FillRepository) GetBySessionID(sessionID string) ([]models.Fill, error) {
+	var fills []models.Fill
+
+	if err := r.conn.Select(&fills, "SELECT * FROM fills WHERE market = ? AND session_id = ? ORDER BY traded_at, id;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return fills, nil
+}
+
+// SessionPnL sums the fills of the session by the commission asset
+func (r *FillRepository) SessionPnL(sessionID string) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT session_id AS key, commission_asset, count(*) AS trades, sum(quote_quantity) AS volume, sum(realized_pnl) AS realized_pnl, sum(commission) AS commission FROM fills WHERE market = ? AND session_id = ? GROUP BY session_id, commission_asset ORDER BY commission_asset;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// DailyPnL sums the fills of the symbol by the UTC day and the commission asset, the empty symbol sums
+// every symbol. From is inclusive, to is exclusive.
+func (r *FillRepository) DailyPnL(symbol string, from, to time.Time) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT strftime('%Y-%m-%d', traded_at) AS key, commission_asset, count(*) AS trades, sum(quote_quantity) AS volume, sum(realized_pnl) AS realized_pnl, sum(commission) AS commission FROM fills WHERE market = ?1 AND (?2 = '' OR symbol = ?2) AND traded_at >= ?3 AND traded_at < ?4 GROUP BY key, commission_asset ORDER BY key, commission_asset;", r.market, symbol, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// VersionPnL sums the fills of the symbol by the settings version the sessions are opened by, the
+// version of the session is the one of its entry. The version of the bare column is read from the
+// row of the min creation time as sqlite does.
+func (r *FillRepository) VersionPnL(symbol string) ([]models.PnL, error) {
+	var out []models.PnL
+
+	if err := r.conn.Select(&out, "SELECT CAST(o.settings_version AS TEXT) AS key, f.commission_asset, count(*) AS trades, sum(f.quote_quantity) AS volume, sum(f.realized_pnl) AS realized_pnl, sum(f.commission) AS commission FROM fills f JOIN (SELECT session_id, settings_version, min(created_at) FROM orders WHERE market = ?1 AND symbol = ?2 AND type = 'LIMIT' GROUP BY session_id) o ON o.session_id = f.session_id WHERE f.market = ?1 AND f.symbol = ?2 GROUP BY o.settings_version, f.commission_asset ORDER BY o.settings_version, f.commission_asset;", r.market, symbol); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type GridRepository struct {
+	conn *sqlx.DB
+}
+
+func NewGridRepository(conn *sqlx.DB) postgres.GridRepo {
+	return &GridRepository{
+		conn: conn,
+	}
+}
+
+// Store stores the grid with its levels in one transaction
+func (r *GridRepository) Store(m *models.Grid, levels []models.GridLevel) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.NamedExec("INSERT INTO grids (id,symbol,market,min_price,max_price,levels,quantity,status) VALUES (:id,:symbol,:market,:min_price,:max_price,:levels,:quantity,:status)", m); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	for i := range levels {
+		if _, err := tx.NamedExec("INSERT INTO grid_levels (grid_id,level,state,order_id,quantity,buy_price) VALUES (:grid_id,:level,:state,:order_id,:quantity,:buy_price)", &levels[i]); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetActive returns the last grid of the symbol which is not stopped
+func (r *GridRepository) GetActive(symbol, market string) (*models.Grid, error) {
+	var grid models.Grid
+
+	if err := r.conn.QueryRowx("SELECT * FROM grids WHERE symbol = ? AND market = ? AND status <> 'STOPPED' ORDER BY created_at DESC LIMIT 1", symbol, market).StructScan(&grid); err != nil {
+		return nil, err
+	}
+
+	return &grid, nil
+}
+
+func (r *GridRepository) GetLevels(gridID string) ([]models.GridLevel, error) {
+	var levels []models.GridLevel
+
+	if err := r.conn.Select(&levels, "SELECT * FROM grid_levels WHERE grid_id = ? ORDER BY level;", gridID); err != nil {
+		return nil, err
+	}
+
+	return levels, nil
+}
+
+func (r *GridRepository) SetStatus(id string, status string) error {
+	if _, err := r.conn.Exec("UPDATE grids SET status = ?, updated_at = ? where id = ?;", status, time.Now().UTC(), id); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *GridRepository) UpdateLevel(m *models.GridLevel) error {
+	if _, err := r.conn.Exec("UPDATE grid_levels SET state = ?, order_id = ?, quantity = ?, buy_price = ?, updated_at = ? where grid_id = ? AND level = ?;",
+		m.State, m.OrderID, m.Quantity, m.BuyPrice, time.Now().UTC(), m.GridID, m.Level); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CloseCycle updates the level which has sold and adds the profit of the sale to the level and the grid
+func (r *GridRepository) CloseCycle(m *models.GridLevel, profit float64) error {
+	now := time.Now().UTC()
+
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE grid_levels SET state = ?, order_id = ?, quantity = ?, buy_price = ?, profit = profit + ?, cycles = cycles + 1, updated_at = ? where grid_id = ? AND level = ?;",
+		m.State, m.OrderID, m.Quantity, m.BuyPrice, profit, now, m.GridID, m.Level); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE grids SET realized_profit = realized_profit + ?, cycles = cycles + 1, updated_at = ? where id = ?;", profit, now, m.GridID); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
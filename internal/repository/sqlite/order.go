@@ -0,0 +1,253 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/models"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type OrderRepository struct {
+	conn   *sqlx.DB
+	market string
+}
+
+func NewOrderRepository(conn *sqlx.DB, market string) postgres.OrderRepo {
+	return &OrderRepository{
+		conn:   conn,
+		market: market,
+	}
+}
+
+// Store stores the order with its CREATED event in one transaction
+func (r *OrderRepository) Store(m *models.Order, c postgres.OrderChange) error {
+	m.Market = r.market
+
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.NamedExec("INSERT INTO orders (id,order_id,session_id,market,symbol,side,position_side,quantity,actual_price,price,stop_price,status,type,try,exit_model,volatility,safe_delta,trigger_delta,take_profit,stop_loss,settings_version) VALUES (:id,:order_id,:session_id,:market,:symbol,:side,:position_side,:quantity,:actual_price,:price,:stop_price,:status,:type,:try,:exit_model,:volatility,:safe_delta,:trigger_delta,:take_profit,:stop_loss,:settings_version)", m); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if err := recordOrderEvent(tx, r.market, m.ID, models.OrderEventCreated, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrderRepository) GetLast(symbol string) (*models.Order, error) {
+	var order models.Order
+
+	if err := r.conn.QueryRowx("SELECT * FROM orders WHERE market = ? AND symbol = ? AND type = 'LIMIT' ORDER BY created_at DESC LIMIT 1", r.market, symbol).StructScan(&order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (r *OrderRepository) GetByID(id string) (*models.Order, error) {
+	var order models.Order
+
+	if err := r.conn.QueryRowx("SELECT * FROM orders WHERE market = ? AND id = ? LIMIT 1", r.market, id).StructScan(&order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (r *OrderRepository) GetBySessionID(sessionID string) ([]models.Order, error) {
+	var orders []models.Order
+
+	if err := r.conn.Select(&orders, "SELECT * FROM orders WHERE market = ? AND session_id = ? ORDER BY created_at;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Find returns the page of the orders selected by the filter, no market in the filter means the market of the repository
+func (r *OrderRepository) Find(filter postgres.OrderFilter) (*postgres.OrderPage, error) {
+	if len(filter.Markets) == 0 {
+		filter.Markets = []string{r.market}
+	}
+
+	query, args, err := orderFilterQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []models.Order
+
+	if err := r.conn.Select(&orders, query, args...); err != nil {
+		return nil, err
+	}
+
+	var out postgres.OrderPage
+
+	if limit := filter.PageLimit(); len(orders) > limit {
+		orders = orders[:limit]
+
+		last := orders[limit-1]
+		out.NextCursor = postgres.EncodeOrderCursor(last.CreatedAt, last.ID)
+	}
+
+	out.Orders = orders
+
+	return &out, nil
+}
+
+// SetActualPrice updates the price, the event is recorded when the price is changed
+func (r *OrderRepository) SetActualPrice(id string, price float64, c postgres.OrderChange) error {
+	return r.change(id, models.OrderEventPrice, c, "UPDATE orders SET price = ?1 WHERE market = ?2 AND id = ?3 AND price IS NOT ?1;", price, r.market, id)
+}
+
+// Delete removes the order, the DELETED event is kept
+func (r *OrderRepository) Delete(id string, c postgres.OrderChange) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := recordOrderEvent(tx, r.market, id, models.OrderEventDeleted, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM orders WHERE market = ? AND id = ?;", r.market, id); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetStatus updates the status, the event is recorded when the status is changed
+func (r *OrderRepository) SetStatus(id string, status string, c postgres.OrderChange) error {
+	return r.change(id, models.OrderEventStatus, c, "UPDATE orders SET status = ?1 WHERE market = ?2 AND id = ?3 AND status IS NOT ?1;", status, r.market, id)
+}
+
+// SetOrderID sets the exchange id of the acknowledged order, the event is recorded when the id is changed
+func (r *OrderRepository) SetOrderID(id string, orderID int64, c postgres.OrderChange) error {
+	return r.change(id, models.OrderEventAcknowledged, c, "UPDATE orders SET order_id = ?1 WHERE market = ?2 AND id = ?3 AND order_id IS NOT ?1;", orderID, r.market, id)
+}
+
+// Record appends the event which does not change the order: the submission and the cancel. The order
+// which is not stored is recorded with the state of o.
+func (r *OrderRepository) Record(o *models.Order, event string, c postgres.OrderChange) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := recordOrderEventOf(tx, r.market, o, event, c); err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Claim takes the pending order for the submission, the claim is held for ttl. The attempts are counted
+// before the order is sent, it returns their number with this one. The update and the read of the
+// attempts are in one transaction, sqlite serializes the writers.
+func (r *OrderRepository) Claim(id string, ttl time.Duration) (int, error) {
+	now := time.Now().UTC()
+
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec("UPDATE orders SET submit_attempts = submit_attempts + 1, claimed_at = ? WHERE market = ? AND id = ? AND status = 'IN PROGRESS' AND (claimed_at IS NULL OR claimed_at < ?);", now, r.market, id, now.Add(-ttl))
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	if n == 0 {
+		_ = tx.Rollback()
+
+		return 0, postgres.ErrNotClaimed
+	}
+
+	var attempts int
+
+	if err := tx.Get(&attempts, "SELECT submit_attempts FROM orders WHERE market = ? AND id = ?;", r.market, id); err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	return attempts, tx.Commit()
+}
+
+// Release drops the claim of the order rejected by the exchange, the attempts are kept
+func (r *OrderRepository) Release(id string) error {
+	_, err := r.conn.Exec("UPDATE orders SET claimed_at = NULL WHERE market = ? AND id = ? AND status = 'IN PROGRESS';", r.market, id)
+
+	return err
+}
+
+// GetTimeline returns the events of the session orders in the order they happened
+func (r *OrderRepository) GetTimeline(sessionID string) ([]models.OrderEvent, error) {
+	var events []models.OrderEvent
+
+	if err := r.conn.Select(&events, "SELECT * FROM order_events WHERE market = ? AND session_id = ? ORDER BY created_at, id;", r.market, sessionID); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// change runs the update of the order and records the event in one transaction, nothing is recorded
+// when the update changes no row
+func (r *OrderRepository) change(id string, event string, c postgres.OrderChange, query string, args ...interface{}) error {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	if n != 0 {
+		if err := recordOrderEvent(tx, r.market, id, event, c); err != nil {
+			_ = tx.Rollback()
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
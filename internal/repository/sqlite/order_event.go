@@ -0,0 +1,37 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// recordOrderEvent appends the event with the current state of the order, nothing is recorded for the unknown order
+func recordOrderEvent(tx *sqlx.Tx, market, id, event string, c postgres.OrderChange) error {
+	_, err := insertOrderEvent(tx, market, id, event, c)
+
+	return err
+}
+
+func insertOrderEvent(tx *sqlx.Tx, market, id, event string, c postgres.OrderChange) (int64, error) {
+	res, err := tx.Exec("INSERT INTO order_events (order_ref,session_id,market,symbol,event,source,status,price,order_id,payload) SELECT id, coalesce(session_id, ''), market, coalesce(symbol, ''), ?3, ?4, coalesce(status, ''), coalesce(price, 0), coalesce(order_id, 0), ?5 FROM orders WHERE market = ?1 AND id = ?2;", market, id, event, c.Source, c.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// recordOrderEventOf appends the event of the order which may be not stored, the event of the unknown
+// order has the state of o and the session of its earlier events when o has none
+func recordOrderEventOf(tx *sqlx.Tx, market string, o *models.Order, event string, c postgres.OrderChange) error {
+	n, err := insertOrderEvent(tx, market, o.ID, event, c)
+	if err != nil || n != 0 {
+		return err
+	}
+
+	_, err = tx.Exec("INSERT INTO order_events (order_ref,session_id,market,symbol,event,source,status,price,order_id,payload) VALUES (?1, coalesce(nullif(?2, ''), (SELECT session_id FROM order_events WHERE market = ?3 AND order_ref = ?1 AND session_id <> '' ORDER BY id DESC LIMIT 1), ''), ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10);", o.ID, o.SessionID, market, o.Symbol, event, c.Source, o.Status, o.Price, o.OrderID, c.Payload)
+
+	return err
+}
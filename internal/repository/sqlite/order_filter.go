@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"fmt"
+	"strings"
+)
+
+// orderFilterQuery builds the select of the page, one order more than the limit is read to know there is the next page
+func orderFilterQuery(f postgres.OrderFilter) (string, []interface{}, error) {
+	var where []string
+	var args []interface{}
+
+	in := func(column string, values []string) string {
+		marks := make([]string, len(values))
+		for i, v := range values {
+			marks[i] = "?"
+			args = append(args, v)
+		}
+
+		return column + " IN (" + strings.Join(marks, ",") + ")"
+	}
+
+	eq := func(column string, v interface{}) string {
+		args = append(args, v)
+
+		return column + " ?"
+	}
+
+	if len(f.Markets) != 0 {
+		where = append(where, in("market", f.Markets))
+	}
+	if f.Symbol != "" {
+		where = append(where, eq("symbol =", f.Symbol))
+	}
+	if len(f.Statuses) != 0 {
+		where = append(where, in("status", f.Statuses))
+	}
+	if len(f.Types) != 0 {
+		where = append(where, in("type", f.Types))
+	}
+	if f.Side != "" {
+		where = append(where, eq("side =", f.Side))
+	}
+	if f.SessionID != "" {
+		where = append(where, eq("session_id =", f.SessionID))
+	}
+	if f.Acknowledged {
+		where = append(where, "order_id > 0")
+	}
+	if !f.From.IsZero() {
+		where = append(where, eq("created_at >=", f.From.UTC()))
+	}
+	if !f.To.IsZero() {
+		where = append(where, eq("created_at <", f.To.UTC()))
+	}
+
+	order, cmp := "ASC", ">"
+	if f.Descending {
+		order, cmp = "DESC", "<"
+	}
+
+	if f.Cursor != "" {
+		createdAt, id, err := postgres.DecodeOrderCursor(f.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+
+		args = append(args, createdAt, id)
+		where = append(where, fmt.Sprintf("(created_at, id) %s (?, ?)", cmp))
+	}
+
+	query := "SELECT * FROM orders"
+	if len(where) != 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	args = append(args, f.PageLimit()+1)
+	query += fmt.Sprintf(" ORDER BY created_at %s, id %s LIMIT ?;", order, order)
+
+	return query, args, nil
+}
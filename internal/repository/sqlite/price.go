@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/models"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type PriceRepository struct {
+	conn *sqlx.DB
+}
+
+func NewPriceRepository(conn *sqlx.DB) postgres.PriceRepo {
+	return &PriceRepository{
+		conn: conn,
+	}
+}
+
+func (r *PriceRepository) GetByCreatedByInterval(symbol string, sTime, eTime time.Time) ([]models.Price, error) {
+	var out []models.Price
+
+	if err := r.conn.Select(&out, "SELECT * FROM prices where created_at > ? AND created_at < ? AND symbol = ?;", sTime.UTC(), eTime.UTC(), symbol); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetMaxMinByCreatedByInterval returns the open, close, max and min prices between the times, both ends are
+// excluded. The whole minutes are read from the rolled up candles and the rest from the prices as the
+// postgres repository does.
+func (r *PriceRepository) GetMaxMinByCreatedByInterval(symbol string, sTime, eTime time.Time) (float64, float64, float64, float64, error) {
+	// the tick at the start is excluded, the first candle starts after it
+	start := sTime.Truncate(time.Minute).Add(time.Minute)
+	end := eTime.Truncate(time.Minute)
+
+	rolled, err := r.rolledUntil(symbol)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if rolled.Before(end) {
+		end = rolled
+	}
+
+	var out postgres.OHLC
+
+	if !start.Before(end) {
+		if out, err = r.rawOHLC(symbol, "created_at > ? AND created_at < ?", sTime.UTC(), eTime.UTC()); err != nil {
+			return 0, 0, 0, 0, err
+		}
+	} else {
+		head, err := r.rawOHLC(symbol, "created_at > ? AND created_at < ?", sTime.UTC(), start.UTC())
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		middle, err := r.candlesOHLC(symbol, postgres.CandleSegments(start, end, postgres.CandleFrames))
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		tail, err := r.rawOHLC(symbol, "created_at >= ? AND created_at < ?", end.UTC(), eTime.UTC())
+		if err != nil {
+			return 0, 0, 0, 0, err
+		}
+
+		out = head.Merge(middle).Merge(tail)
+	}
+
+	if !out.OK {
+		return 0, 0, 0, 0, sql.ErrNoRows
+	}
+
+	return out.Open, out.Close, out.Max, out.Min, nil
+}
+
+func (r *PriceRepository) Store(m *models.Price) (err error) {
+
+	if _, err := r.conn.NamedExec("INSERT INTO prices (symbol,price) VALUES (:symbol,:price)", m); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *PriceRepository) GetLast(symbol string, sTime, eTime time.Time) (*models.Price, error) {
+	var price models.Price
+	if err := r.conn.QueryRowx("SELECT * FROM prices where created_at >= ? AND created_at <= ? AND symbol = ? ORDER BY id DESC LIMIT 1", sTime.UTC(), eTime.UTC(), symbol).StructScan(&price); err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
+
+func (r *PriceRepository) GetByID(symbol string, id uint) (*models.Price, error) {
+	var price models.Price
+	if err := r.conn.QueryRowx("SELECT * FROM prices where id = ? AND symbol = ? ORDER BY id DESC LIMIT 1", id, symbol).StructScan(&price); err != nil {
+		return nil, err
+	}
+
+	return &price, nil
+}
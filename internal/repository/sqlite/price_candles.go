@@ -0,0 +1,179 @@
+package sqlite
+
+import (
+	"binance/internal/repository/postgres"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// candleTime is the text of the epoch seconds in the format of the driver
+const candleTime = "strftime('%%Y-%%m-%%d %%H:%%M:%%S+00:00', %s, 'unixepoch')"
+
+// rawOHLC reads the range from the prices, the open and the close are the first and the last ticks by id
+func (r *PriceRepository) rawOHLC(symbol, where string, args ...interface{}) (postgres.OHLC, error) {
+	var open, close, max, min sql.NullFloat64
+
+	query := "SELECT o.price, c.price, g.max_price, g.min_price " +
+		"FROM (SELECT min(id) AS first, max(id) AS last, max(price) AS max_price, min(price) AS min_price FROM prices WHERE symbol = ? AND " + where + ") g " +
+		"LEFT JOIN prices o ON o.id = g.first LEFT JOIN prices c ON c.id = g.last;"
+
+	if err := r.conn.QueryRowx(query, append([]interface{}{symbol}, args...)...).Scan(&open, &close, &max, &min); err != nil {
+		return postgres.OHLC{}, err
+	}
+
+	return postgres.OHLC{
+		Open:  open.Float64,
+		Close: close.Float64,
+		Max:   max.Float64,
+		Min:   min.Float64,
+		OK:    open.Valid,
+	}, nil
+}
+
+// candlesOHLC reads the segments from the rolled up candles
+func (r *PriceRepository) candlesOHLC(symbol string, segments []postgres.CandleSegment) (postgres.OHLC, error) {
+	var out postgres.OHLC
+
+	if len(segments) == 0 {
+		return out, nil
+	}
+
+	args := []interface{}{symbol}
+	where := make([]string, 0, len(segments))
+
+	for _, s := range segments {
+		args = append(args, s.Frame, s.From.UTC(), s.To.UTC())
+		where = append(where, "(time_frame = ? AND open_time >= ? AND close_time <= ?)")
+	}
+
+	rows, err := r.conn.Queryx("SELECT open_price, close_price, max_price, min_price FROM candles WHERE symbol = ? AND ("+strings.Join(where, " OR ")+") ORDER BY open_time;", args...)
+	if err != nil {
+		return out, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		next := postgres.OHLC{OK: true}
+
+		if err := rows.Scan(&next.Open, &next.Close, &next.Max, &next.Min); err != nil {
+			return out, err
+		}
+
+		out = out.Merge(next)
+	}
+
+	return out, rows.Err()
+}
+
+// rolledUntil is the close time of the last minute candle of the symbol, the zero time when nothing is rolled up
+func (r *PriceRepository) rolledUntil(symbol string) (time.Time, error) {
+	var out nullTime
+
+	if err := r.conn.QueryRowx("SELECT max(close_time) FROM candles WHERE symbol = ? AND time_frame = ?;", symbol, postgres.TimeFrame1m).Scan(&out); err != nil {
+		return time.Time{}, err
+	}
+
+	return out.Time, nil
+}
+
+// RollUp rolls the prices into the candles of the closed ranges before until, the last candle of each
+// frame is rolled up again so the run goes on from where the previous one stopped. The frames are
+// rolled up in one transaction, the lookups never see the minutes without the hours.
+func (r *PriceRepository) RollUp(until time.Time) (int64, error) {
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	var out int64
+
+	for i, frame := range postgres.CandleFrames {
+		var from nullTime
+
+		if err := tx.QueryRowx("SELECT max(open_time) FROM candles WHERE time_frame = ?;", frame.Name).Scan(&from); err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		if !from.Valid {
+			from.Time = time.Unix(0, 0)
+		}
+
+		// the first and the last ticks of the bucket are joined by their ids, the candles by their open times
+		var query string
+
+		if i == 0 {
+			query = "SELECT g.symbol, o.price, c.price, g.max_price, g.min_price, ?1, " + fmt.Sprintf(candleTime, "g.epoch") + ", " + fmt.Sprintf(candleTime, "g.epoch + ?4") + " " +
+				"FROM (SELECT symbol, epoch, min(id) AS first, max(id) AS last, max(price) AS max_price, min(price) AS min_price " +
+				"FROM (SELECT *, CAST(strftime('%s', created_at) AS INTEGER) / ?4 * ?4 AS epoch FROM prices WHERE created_at >= ?2 AND created_at < ?3) GROUP BY symbol, epoch) g " +
+				"JOIN prices o ON o.id = g.first JOIN prices c ON c.id = g.last "
+		} else {
+			join := "JOIN candles %[1]s ON %[1]s.symbol = g.symbol AND %[1]s.time_frame = '" + postgres.CandleFrames[0].Name + "' AND %[1]s.open_time = g.%[2]s "
+
+			query = "SELECT g.symbol, o.open_price, c.close_price, g.max_price, g.min_price, ?1, " + fmt.Sprintf(candleTime, "g.epoch") + ", " + fmt.Sprintf(candleTime, "g.epoch + ?4") + " " +
+				"FROM (SELECT symbol, epoch, min(open_time) AS first, max(open_time) AS last, max(max_price) AS max_price, min(min_price) AS min_price " +
+				"FROM (SELECT *, CAST(strftime('%s', open_time) AS INTEGER) / ?4 * ?4 AS epoch FROM candles WHERE time_frame = '" + postgres.CandleFrames[0].Name + "' AND open_time >= ?2 AND open_time < ?3) GROUP BY symbol, epoch) g " +
+				fmt.Sprintf(join, "o", "first") + fmt.Sprintf(join, "c", "last")
+		}
+
+		// sqlite needs the where clause to tell the upsert from the join constraint
+		query = "INSERT INTO candles (symbol, open_price, close_price, max_price, min_price, time_frame, open_time, close_time) " + query + "WHERE true " +
+			"ON CONFLICT (symbol, time_frame, open_time) DO UPDATE SET open_price = excluded.open_price, close_price = excluded.close_price, max_price = excluded.max_price, min_price = excluded.min_price, close_time = excluded.close_time;"
+
+		res, err := tx.Exec(query, frame.Name, from.Time.UTC(), until.Truncate(frame.Duration).UTC(), int64(frame.Duration.Seconds()))
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+
+		out += n
+	}
+
+	return out, tx.Commit()
+}
+
+// Prune removes the prices older than before, the prices which are not rolled up yet are kept. The
+// archived prices are copied to the prices_archive table in the transaction of the removal.
+func (r *PriceRepository) Prune(before time.Time, archive bool) (int64, error) {
+	where := "WHERE created_at < ?2 AND created_at < (SELECT max(close_time) FROM candles WHERE candles.symbol = prices.symbol AND time_frame = ?1)"
+
+	tx, err := r.conn.Beginx()
+	if err != nil {
+		return 0, err
+	}
+
+	if archive {
+		if _, err := tx.Exec("INSERT INTO prices_archive (id, symbol, price, created_at) SELECT id, symbol, price, created_at FROM prices "+where+" ON CONFLICT (id) DO NOTHING;", postgres.TimeFrame1m, before.UTC()); err != nil {
+			_ = tx.Rollback()
+
+			return 0, err
+		}
+	}
+
+	res, err := tx.Exec("DELETE FROM prices "+where+";", postgres.TimeFrame1m, before.UTC())
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		_ = tx.Rollback()
+
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
@@ -0,0 +1,68 @@
+// Package sqlite implements the repositories of the postgres package on the sqlite database, it is used
+// by the tests and the runs without the postgres server. The times are stored in UTC as the text the
+// driver writes, the text is ordered as the times are.
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// DriverName is the name of the sqlite driver in database/sql
+const DriverName = "sqlite3"
+
+// Open connects to the database file, ":memory:" is the database which lives with the connection. The
+// database is written by one connection, sqlite locks the whole file on the write.
+func Open(path string) (*sqlx.DB, error) {
+	conn, err := sqlx.Connect(DriverName, "file:"+path+"?_foreign_keys=1&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetMaxOpenConns(1)
+
+	return conn, nil
+}
+
+// nullTime scans the time of the aggregates, sqlite returns them as the text without the column type
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *nullTime) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time, t.Valid = time.Time{}, false
+
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+
+		return nil
+	case []byte:
+		return t.parse(string(v))
+	case string:
+		return t.parse(v)
+	}
+
+	return fmt.Errorf("unsupported time %T", value)
+}
+
+func (t *nullTime) parse(s string) error {
+	s = strings.TrimSuffix(s, "Z")
+
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if v, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			t.Time, t.Valid = v.UTC(), true
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported time '%s'", s)
+}
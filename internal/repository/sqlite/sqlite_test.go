@@ -0,0 +1,445 @@
+package sqlite_test
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/internal/repository/sqlite"
+	"binance/migrations"
+	"binance/models"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func initSQLiteTest(t *testing.T) *sqlx.DB {
+	conn, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	list, err := migrations.LoadSQLite()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrations.NewMigrator(conn, list).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return conn
+}
+
+func Test_OrderStore(t *testing.T) {
+	conn := initSQLiteTest(t)
+	store := sqlite.NewOrderRepository(conn, postgres.Features)
+
+	symbol := "BTCUSDT"
+	sessionID := uuid.NewString()
+
+	var ids []string
+
+	t.Run("Store", func(t *testing.T) {
+		for i := 0; i < 3; i++ {
+			o := models.Order{
+				ID:        uuid.NewString(),
+				OrderID:   rand.Int63(),
+				SessionID: sessionID,
+				Symbol:    symbol,
+				Side:      "BUY",
+				Quantity:  0.001,
+				Price:     20000 + float64(i),
+				Status:    "NEW",
+				Try:       1,
+				Type:      "LIMIT",
+			}
+
+			assert.NoError(t, store.Store(&o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+			ids = append(ids, o.ID)
+		}
+
+		// the other market is not seen by the repository
+		assert.NoError(t, sqlite.NewOrderRepository(conn, postgres.Spot).Store(&models.Order{
+			ID:     uuid.NewString(),
+			Symbol: symbol,
+			Type:   "LIMIT",
+		}, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		orders, err := store.GetBySessionID(sessionID)
+		assert.NoError(t, err)
+		assert.Len(t, orders, 3)
+
+		o, err := store.GetLast(symbol)
+		assert.NoError(t, err)
+		assert.Equal(t, postgres.Features, o.Market)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		var got []string
+		var cursor string
+
+		for {
+			page, err := store.Find(postgres.OrderFilter{
+				Symbol:   symbol,
+				Statuses: []string{"NEW", "FILLED"},
+				Cursor:   cursor,
+				Limit:    2,
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			for _, o := range page.Orders {
+				got = append(got, o.ID)
+			}
+
+			if cursor = page.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		assert.ElementsMatch(t, ids, got)
+
+		page, err := store.Find(postgres.OrderFilter{Markets: []string{postgres.Spot, postgres.Features}})
+		assert.NoError(t, err)
+		assert.Len(t, page.Orders, 4)
+
+		// the spot order is not acknowledged by the exchange
+		page, err = store.Find(postgres.OrderFilter{Markets: []string{postgres.Spot, postgres.Features}, Acknowledged: true})
+		assert.NoError(t, err)
+		assert.Len(t, page.Orders, 3)
+	})
+
+	t.Run("Timeline", func(t *testing.T) {
+		change := postgres.OrderChange{Source: models.OrderSourcePoll, Payload: `{"status":"FILLED"}`}
+
+		assert.NoError(t, store.SetStatus(ids[0], "FILLED", change))
+		// the same status is not recorded again
+		assert.NoError(t, store.SetStatus(ids[0], "FILLED", change))
+		assert.NoError(t, store.SetActualPrice(ids[0], 20000, change))
+		assert.NoError(t, store.Record(&models.Order{ID: ids[0]}, models.OrderEventCanceled, change))
+		assert.NoError(t, store.Delete(ids[0], change))
+
+		events, err := store.GetTimeline(sessionID)
+		assert.NoError(t, err)
+
+		var got []string
+		for _, e := range events {
+			if e.OrderRef == ids[0] {
+				got = append(got, e.Event)
+			}
+		}
+
+		assert.Equal(t, []string{models.OrderEventCreated, models.OrderEventStatus, models.OrderEventCanceled, models.OrderEventDeleted}, got)
+
+		_, err = store.GetByID(ids[0])
+		assert.Error(t, err)
+	})
+
+	t.Run("TimelineNotStored", func(t *testing.T) {
+		// the liquidation orders are only sent, the cancel takes the session of the submission
+		o := models.Order{ID: "liq-" + uuid.NewString(), SessionID: sessionID, Symbol: symbol, Status: "IN PROGRESS"}
+		change := postgres.OrderChange{Source: models.OrderSourceManual}
+
+		assert.NoError(t, store.Record(&o, models.OrderEventSubmitted, change))
+		assert.NoError(t, store.Record(&models.Order{ID: o.ID, OrderID: 42, Symbol: symbol, Status: "CANCELED"}, models.OrderEventCanceled, change))
+
+		events, err := store.GetTimeline(sessionID)
+		assert.NoError(t, err)
+
+		var got []models.OrderEvent
+		for _, e := range events {
+			if e.OrderRef == o.ID {
+				got = append(got, e)
+			}
+		}
+
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, models.OrderEventSubmitted, got[0].Event)
+			assert.Equal(t, models.OrderEventCanceled, got[1].Event)
+			assert.Equal(t, "CANCELED", got[1].Status)
+			assert.Equal(t, int64(42), got[1].OrderID)
+			assert.Equal(t, symbol, got[1].Symbol)
+		}
+	})
+
+	t.Run("Claim", func(t *testing.T) {
+		o := models.Order{
+			ID:        uuid.NewString(),
+			SessionID: uuid.NewString(),
+			Symbol:    symbol,
+			Side:      "BUY",
+			Status:    "IN PROGRESS",
+			Type:      "LIMIT",
+		}
+		assert.NoError(t, store.Store(&o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		attempts, err := store.Claim(o.ID, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+
+		// the claim is held
+		_, err = store.Claim(o.ID, time.Minute)
+		assert.ErrorIs(t, err, postgres.ErrNotClaimed)
+
+		assert.NoError(t, store.Release(o.ID))
+
+		attempts, err = store.Claim(o.ID, time.Minute)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, attempts)
+
+		// the expired claim is taken again
+		attempts, err = store.Claim(o.ID, -time.Second)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+	})
+}
+
+func Test_FillStore(t *testing.T) {
+	conn := initSQLiteTest(t)
+	orderStore := sqlite.NewOrderRepository(conn, postgres.Features)
+	fillStore := sqlite.NewFillRepository(conn, postgres.Features)
+
+	symbol := "BTCUSDT"
+	sessionID := uuid.NewString()
+	orderID := rand.Int63()
+
+	t.Run("Store", func(t *testing.T) {
+		// the fill is stored before its order
+		fill := models.Fill{
+			ID:              1,
+			Symbol:          symbol,
+			OrderID:         orderID,
+			Side:            "BUY",
+			PositionSide:    "LONG",
+			Price:           20000,
+			Quantity:        0.001,
+			QuoteQuantity:   20,
+			Commission:      0.008,
+			CommissionAsset: "USDT",
+			RealizedPnL:     1.5,
+			TradedAt:        time.Now(),
+		}
+		assert.NoError(t, fillStore.Store([]models.Fill{fill}))
+		// the stored fill is skipped
+		assert.NoError(t, fillStore.Store([]models.Fill{fill}))
+
+		assert.NoError(t, orderStore.Store(&models.Order{
+			ID:              uuid.NewString(),
+			OrderID:         orderID,
+			SessionID:       sessionID,
+			Symbol:          symbol,
+			Side:            "BUY",
+			Status:          "FILLED",
+			Type:            "LIMIT",
+			SettingsVersion: 7,
+		}, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		assert.NoError(t, fillStore.Link())
+
+		got, err := fillStore.GetLastID(symbol)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), got)
+
+		fills, err := fillStore.GetBySessionID(sessionID)
+		assert.NoError(t, err)
+		assert.Len(t, fills, 1)
+	})
+
+	t.Run("PnL", func(t *testing.T) {
+		pnl, err := fillStore.SessionPnL(sessionID)
+		assert.NoError(t, err)
+		if assert.Len(t, pnl, 1) {
+			assert.Equal(t, 1, pnl[0].Trades)
+			assert.InDelta(t, 1.5, pnl[0].RealizedPnL, 1e-9)
+		}
+
+		pnl, err = fillStore.DailyPnL("", time.Now().Add(-24*time.Hour), time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+		if assert.Len(t, pnl, 1) {
+			assert.Equal(t, time.Now().UTC().Format("2006-01-02"), pnl[0].Key)
+		}
+
+		pnl, err = fillStore.VersionPnL(symbol)
+		assert.NoError(t, err)
+		if assert.Len(t, pnl, 1) {
+			assert.Equal(t, "7", pnl[0].Key)
+		}
+	})
+}
+
+func Test_IncomeStore(t *testing.T) {
+	conn := initSQLiteTest(t)
+	incomeStore := sqlite.NewIncomeRepository(conn)
+
+	incomeAt := time.Now().UTC()
+	income := models.Income{
+		ID:         uuid.NewString(),
+		TranID:     rand.Int63(),
+		Source:     models.IncomeSourceAPI,
+		Symbol:     "BTCUSDT",
+		IncomeType: "FUNDING_FEE",
+		Income:     -0.0123,
+		Asset:      "USDT",
+		IncomeAt:   incomeAt,
+	}
+
+	n, err := incomeStore.Store([]models.Income{income})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// the stored income is skipped
+	n, err = incomeStore.Store([]models.Income{income})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	last, err := incomeStore.GetLast(models.IncomeSourceAPI)
+	if assert.NoError(t, err) {
+		assert.True(t, incomeAt.Equal(last.IncomeAt))
+	}
+
+	summary, err := incomeStore.Summary(incomeAt.Add(-time.Hour), incomeAt.Add(time.Hour))
+	assert.NoError(t, err)
+	if assert.Len(t, summary, 1) {
+		assert.Equal(t, 1, summary[0].Count)
+	}
+}
+
+func Test_AuditStore(t *testing.T) {
+	conn := initSQLiteTest(t)
+	auditStore := sqlite.NewAuditRepository(conn)
+
+	record := models.AuditRecord{
+		Source:   models.AuditSourceTelegram,
+		ChatID:   42,
+		UserID:   7,
+		UserName: "operator",
+		Action:   "disable",
+		Target:   "BTCUSDT",
+		Result:   models.AuditResultOK,
+	}
+
+	assert.NoError(t, auditStore.Store(&record))
+
+	record.Action = "enable"
+	assert.NoError(t, auditStore.Store(&record))
+
+	last, err := auditStore.GetLast(1)
+	if assert.NoError(t, err) && assert.Len(t, last, 1) {
+		assert.Equal(t, "enable", last[0].Action)
+		assert.Equal(t, int64(7), last[0].UserID)
+		assert.False(t, last[0].CreatedAt.IsZero())
+	}
+}
+
+func Test_GridStore(t *testing.T) {
+	conn := initSQLiteTest(t)
+	gridStore := sqlite.NewGridRepository(conn)
+
+	grid := models.Grid{
+		ID:       uuid.NewString(),
+		Symbol:   "BTCUSDT",
+		Market:   postgres.Spot,
+		MinPrice: 19000,
+		MaxPrice: 21000,
+		Levels:   2,
+		Quantity: 0.001,
+		Status:   "ACTIVE",
+	}
+
+	levels := []models.GridLevel{
+		{GridID: grid.ID, Level: 0, State: "BUYING"},
+		{GridID: grid.ID, Level: 1, State: "BUYING"},
+	}
+
+	assert.NoError(t, gridStore.Store(&grid, levels))
+
+	levels[1].State = "SELLING"
+	levels[1].BuyPrice = 20000
+	assert.NoError(t, gridStore.UpdateLevel(&levels[1]))
+
+	levels[1].State = "BUYING"
+	assert.NoError(t, gridStore.CloseCycle(&levels[1], 1.5))
+
+	got, err := gridStore.GetActive(grid.Symbol, grid.Market)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, got.Cycles)
+		assert.InDelta(t, 1.5, got.RealizedProfit, 1e-9)
+	}
+
+	stored, err := gridStore.GetLevels(grid.ID)
+	if assert.NoError(t, err) && assert.Len(t, stored, 2) {
+		assert.Equal(t, 1, stored[1].Cycles)
+	}
+
+	assert.NoError(t, gridStore.SetStatus(grid.ID, "STOPPED"))
+
+	_, err = gridStore.GetActive(grid.Symbol, grid.Market)
+	assert.Error(t, err)
+}
+
+func Test_PriceRollUp(t *testing.T) {
+	conn := initSQLiteTest(t)
+	priceStore := sqlite.NewPriceRepository(conn)
+
+	symbol := "BTCUSDT"
+	start := time.Now().UTC().Add(-3 * time.Hour).Truncate(time.Hour)
+
+	for i := 0; i < 600; i++ {
+		_, err := conn.Exec("INSERT INTO prices (symbol, price, created_at) VALUES (?, ?, ?)", symbol, 20000+rand.Float64()*100, start.Add(time.Duration(i)*17*time.Second))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, priceStore.Store(&models.Price{Symbol: symbol, Price: 20050}))
+
+	last, err := priceStore.GetLast(symbol, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if assert.NoError(t, err) {
+		assert.Equal(t, float64(20050), last.Price)
+	}
+
+	sTime, eTime := start.Add(7*time.Minute+3*time.Second), start.Add(2*time.Hour+11*time.Minute+5*time.Second)
+
+	open, close, max, min, err := priceStore.GetMaxMinByCreatedByInterval(symbol, sTime, eTime)
+	assert.NoError(t, err)
+
+	n, err := priceStore.RollUp(time.Now())
+	assert.NoError(t, err)
+	assert.NotZero(t, n)
+
+	// the rolled up lookup is the same
+	rOpen, rClose, rMax, rMin, err := priceStore.GetMaxMinByCreatedByInterval(symbol, sTime, eTime)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{open, close, max, min}, []float64{rOpen, rClose, rMax, rMin})
+
+	candles, err := sqlite.NewCandlesRepository(conn).GetLastList(symbol, 1000)
+	assert.NoError(t, err)
+	// 170 minutes, 34 five minutes and 3 hours of the ticks
+	assert.Len(t, candles, 170+34+3)
+
+	// the run goes on from the last candles
+	_, err = priceStore.RollUp(time.Now())
+	assert.NoError(t, err)
+
+	again, err := sqlite.NewCandlesRepository(conn).GetLastList(symbol, 1000)
+	assert.NoError(t, err)
+	assert.Len(t, again, len(candles))
+
+	n, err = priceStore.Prune(start.Add(time.Hour), true)
+	assert.NoError(t, err)
+	assert.NotZero(t, n)
+
+	var archived int64
+	assert.NoError(t, conn.Get(&archived, "SELECT count(*) FROM prices_archive;"))
+	assert.Equal(t, n, archived)
+
+	// the pruned head is read from the candles rounded to the minutes
+	_, rClose, _, _, err = priceStore.GetMaxMinByCreatedByInterval(symbol, sTime, eTime)
+	assert.NoError(t, err)
+	assert.Equal(t, close, rClose)
+}
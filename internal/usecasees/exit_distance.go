@@ -0,0 +1,169 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"sync"
+	"time"
+)
+
+const (
+	exitDistanceTTL = 30 * time.Second
+
+	defaultVolatilityInterval = "1m"
+	defaultVolatilityWindow   = 14
+	defaultTriggerDeltaFactor = 0.01
+)
+
+type exitDistanceCache struct {
+	mu   sync.Mutex
+	list map[string]exitDistanceCacheItem
+}
+
+type exitDistanceCacheItem struct {
+	distance  *structs.ExitDistance
+	params    exitDistanceParams
+	expiredAt time.Time
+}
+
+type exitDistanceParams struct {
+	exitModel          string
+	volatilityInterval string
+	volatilityWindow   int
+	safeDeltaFactor    float64
+	triggerDeltaFactor float64
+	minDelta           float64
+	maxDelta           float64
+}
+
+func newExitDistanceParams(settings *mongoStructs.Settings) exitDistanceParams {
+	return exitDistanceParams{
+		exitModel:          settings.ExitModel,
+		volatilityInterval: settings.VolatilityInterval,
+		volatilityWindow:   settings.VolatilityWindow,
+		safeDeltaFactor:    settings.SafeDeltaFactor,
+		triggerDeltaFactor: settings.TriggerDeltaFactor,
+		minDelta:           settings.MinDelta,
+		maxDelta:           settings.MaxDelta,
+	}
+}
+
+func newExitDistanceCache() *exitDistanceCache {
+	return &exitDistanceCache{
+		list: make(map[string]exitDistanceCacheItem),
+	}
+}
+
+func (c *exitDistanceCache) get(settings *mongoStructs.Settings) (*structs.ExitDistance, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.list[settings.Symbol]
+	if !ok || time.Now().After(item.expiredAt) || item.params != newExitDistanceParams(settings) {
+		return nil, false
+	}
+
+	return item.distance, true
+}
+
+func (c *exitDistanceCache) set(settings *mongoStructs.Settings, distance *structs.ExitDistance) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.list[settings.Symbol] = exitDistanceCacheItem{
+		distance:  distance,
+		params:    newExitDistanceParams(settings),
+		expiredAt: time.Now().Add(exitDistanceTTL),
+	}
+}
+
+func staticExitDistance(settings *mongoStructs.Settings) *structs.ExitDistance {
+	return &structs.ExitDistance{
+		Model:        structs.ExitModelStatic,
+		SafeDelta:    settings.Delta,
+		TriggerDelta: settings.Delta / 100 * 1,
+	}
+}
+
+// getExitDistance derives SafeDelta and TriggerDelta from the market volatility
+// according to settings.ExitModel and falls back to the static settings.Delta.
+func (u *orderUseCase) getExitDistance(settings *mongoStructs.Settings) *structs.ExitDistance {
+	model := structs.ExitModel(settings.ExitModel)
+
+	if model != structs.ExitModelATR && model != structs.ExitModelRealizedVol {
+		return staticExitDistance(settings)
+	}
+
+	if distance, ok := u.exitDistances.get(settings); ok {
+		return distance
+	}
+
+	interval := settings.VolatilityInterval
+	if interval == "" {
+		interval = defaultVolatilityInterval
+	}
+
+	window := settings.VolatilityWindow
+	if window < 2 {
+		window = defaultVolatilityWindow
+	}
+
+	safeDeltaFactor := settings.SafeDeltaFactor
+	if safeDeltaFactor <= 0 {
+		safeDeltaFactor = mongoStructs.DefaultSafeDeltaFactor
+	}
+
+	triggerDeltaFactor := settings.TriggerDeltaFactor
+	if triggerDeltaFactor <= 0 {
+		triggerDeltaFactor = defaultTriggerDeltaFactor
+	}
+
+	// one more candle for the previous close of the first true range / return and one for the open candle
+	candles, err := u.priceUseCase.GetKlines(settings.Symbol, interval, window+2)
+	if err != nil {
+		u.logRus.
+			WithField("func", "getExitDistance").
+			WithField("symbol", settings.Symbol).
+			Debug(err)
+
+		return staticExitDistance(settings)
+	}
+
+	candles = closedCandles(candles, time.Now(), window+1)
+
+	out := structs.ExitDistance{
+		Model: model,
+	}
+
+	switch model {
+	case structs.ExitModelATR:
+		out.Volatility = structs.ATR(candles)
+	case structs.ExitModelRealizedVol:
+		out.Volatility = structs.RealizedVolatility(candles)
+	}
+
+	if out.Volatility == 0 {
+		return staticExitDistance(settings)
+	}
+
+	out.SafeDelta = structs.Clamp(out.Volatility*safeDeltaFactor, settings.MinDelta, settings.MaxDelta)
+	out.TriggerDelta = out.SafeDelta * triggerDeltaFactor
+
+	u.exitDistances.set(settings, &out)
+
+	return &out
+}
+
+// closedCandles drops the candle still open at now, the exchange returns it last. It keeps the last limit candles.
+func closedCandles(candles []models.Candle, now time.Time, limit int) []models.Candle {
+	if n := len(candles); n != 0 && candles[n-1].CloseTime.After(now) {
+		candles = candles[:n-1]
+	}
+
+	if len(candles) > limit {
+		candles = candles[len(candles)-limit:]
+	}
+
+	return candles
+}
@@ -0,0 +1,41 @@
+package usecasees
+
+import (
+	"binance/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClosedCandles(t *testing.T) {
+	now := time.Date(2022, 11, 2, 9, 30, 15, 0, time.UTC)
+
+	candles := func(closeTimes ...time.Time) []models.Candle {
+		out := make([]models.Candle, 0, len(closeTimes))
+		for _, c := range closeTimes {
+			out = append(out, models.Candle{CloseTime: c})
+		}
+
+		return out
+	}
+
+	t.Run("open candle", func(t *testing.T) {
+		list := candles(now.Add(-2*time.Minute), now.Add(-time.Minute), now.Add(-time.Second), now.Add(45*time.Second))
+
+		got := closedCandles(list, now, 2)
+
+		// the open candle is dropped, the last closed ones are kept
+		assert.Equal(t, list[1:3], got)
+	})
+
+	t.Run("closed candles", func(t *testing.T) {
+		list := candles(now.Add(-2*time.Minute), now.Add(-time.Minute), now.Add(-time.Second))
+
+		assert.Equal(t, list[1:], closedCandles(list, now, 2))
+	})
+
+	t.Run("no candles", func(t *testing.T) {
+		assert.Empty(t, closedCandles(nil, now, 2))
+	})
+}
@@ -0,0 +1,208 @@
+package usecasees
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+const (
+	// fillsLimit is the max page of userTrades
+	fillsLimit = 1000
+	// fillsMaxPages bounds the requests of one sync, the rest is read by the next one
+	fillsMaxPages = 10
+)
+
+// SyncFills stores the trades of the symbol made after the last stored fill, it returns the number of the new fills.
+// The first sync starts at the oldest filled order, the trades made before the bot are not read.
+func (u *orderUseCase) SyncFills(symbol string) (int, error) {
+	lastID, err := u.fillRepo.GetLastID(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	fromID := lastID + 1
+
+	if lastID == 0 {
+		fromID, err = u.getFirstFillID(symbol)
+		if err != nil {
+			return 0, err
+		}
+
+		if fromID == 0 {
+			return 0, nil
+		}
+	}
+
+	var out int
+
+	for page := 0; page < fillsMaxPages; page++ {
+		trades, err := u.getFeatureUserTrades(symbol, "fromId", fromID)
+		if err != nil {
+			return out, err
+		}
+
+		if len(trades) == 0 {
+			break
+		}
+
+		fills := make([]models.Fill, 0, len(trades))
+
+		for _, t := range trades {
+			f, err := tradeToFill(&t)
+			if err != nil {
+				return out, err
+			}
+
+			fills = append(fills, *f)
+
+			if f.ID >= fromID {
+				fromID = f.ID + 1
+			}
+		}
+
+		if err := u.fillRepo.Store(fills); err != nil {
+			return out, err
+		}
+
+		out += len(fills)
+
+		if len(trades) < fillsLimit {
+			break
+		}
+	}
+
+	// the orders may be stored after their fills
+	if err := u.fillRepo.Link(); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
+
+// getFirstFillID returns the id of the first trade of the oldest filled order of the symbol,
+// it is 0 when no order is filled. The orders without the exchange order id have no trades to look up.
+func (u *orderUseCase) getFirstFillID(symbol string) (int64, error) {
+	page, err := u.orderRepo.Find(postgres.OrderFilter{
+		Symbol:       symbol,
+		Statuses:     []string{OrderStatusFilled},
+		Acknowledged: true,
+		Limit:        1,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if len(page.Orders) == 0 || page.Orders[0].OrderID == 0 {
+		return 0, nil
+	}
+
+	trades, err := u.getFeatureUserTrades(symbol, "orderId", page.Orders[0].OrderID)
+	if err != nil {
+		return 0, err
+	}
+
+	var out int64
+
+	for _, t := range trades {
+		if out == 0 || t.ID < out {
+			out = t.ID
+		}
+	}
+
+	return out, nil
+}
+
+// getFeatureUserTrades returns a page of the trades of the symbol, key is fromId or orderId
+func (u *orderUseCase) getFeatureUserTrades(symbol string, key string, id int64) ([]structs.UserTrade, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(featureUserTrades)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set(key, strconv.FormatInt(id, 10))
+	q.Set("limit", strconv.Itoa(fillsLimit))
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	req, err := u.clientController.Send(http.MethodGet, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []structs.UserTrade
+
+	if err := json.Unmarshal(req, &out); err != nil {
+		return nil, fmt.Errorf("%w %s", err, req)
+	}
+
+	return out, nil
+}
+
+func tradeToFill(t *structs.UserTrade) (*models.Fill, error) {
+	out := models.Fill{
+		ID:              t.ID,
+		Symbol:          t.Symbol,
+		OrderID:         t.OrderID,
+		Side:            t.Side,
+		PositionSide:    t.PositionSide,
+		CommissionAsset: t.CommissionAsset,
+		Maker:           t.Maker,
+		TradedAt:        time.UnixMilli(t.Time).UTC(),
+	}
+
+	for _, f := range []struct {
+		src string
+		dst *float64
+	}{
+		{t.Price, &out.Price},
+		{t.Qty, &out.Quantity},
+		{t.QuoteQty, &out.QuoteQuantity},
+		{t.Commission, &out.Commission},
+		{t.RealizedPnl, &out.RealizedPnL},
+	} {
+		v, err := strconv.ParseFloat(f.src, 64)
+		if err != nil {
+			return nil, fmt.Errorf("trade %d: %w", t.ID, err)
+		}
+
+		*f.dst = v
+	}
+
+	return &out, nil
+}
+
+// UpdateFills syncs the fills of the symbol, the state of the monitor is not used
+func (m *Monitor) UpdateFills(u *orderUseCase, symbol string) {
+	for m.sleep(monitorFillsTime) {
+		n, err := u.SyncFills(symbol)
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			continue
+		}
+
+		if n != 0 {
+			u.logRus.Debugf("Fills [%s] %d new", symbol, n)
+		}
+	}
+}
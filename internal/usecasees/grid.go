@@ -0,0 +1,761 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"database/sql"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	GridStatusActive = "ACTIVE"
+	// GridStatusPaused is the grid out of its range, it sells what it holds and buys nothing
+	GridStatusPaused = "PAUSED"
+	// GridStatusClosing is the grid changed by the settings, it is stopped once everything is sold
+	GridStatusClosing = "CLOSING"
+	GridStatusStopped = "STOPPED"
+
+	GridLevelEmpty   = "EMPTY"
+	GridLevelBuying  = "BUYING"
+	GridLevelHolding = "HOLDING"
+	GridLevelSelling = "SELLING"
+
+	gridSyncInterval = 2 * time.Second
+
+	// gridOrderPrefix marks the grid orders, clientOrderId is limited to 36 chars
+	gridOrderPrefix = "grd-"
+)
+
+// gridState is the grid traded by the monitor, prices are the prices of its levels
+type gridState struct {
+	grid     *models.Grid
+	levels   []models.GridLevel
+	prices   []float64
+	syncedAt time.Time
+
+	// open are the open orders of the grid seen by the last sync
+	open map[string]gridOrder
+}
+
+// gridOrder is the exchange state of a grid order on both markets
+type gridOrder struct {
+	orderID  int64
+	status   string
+	executed float64
+	avgPrice float64
+}
+
+// gridLoadedEvent carries the grid left by the previous monitor, grid is nil when there is none
+type gridLoadedEvent struct {
+	grid *gridState
+}
+
+func (e gridLoadedEvent) apply(m *Monitor) {
+	m.grid = e.grid
+	m.gridLoaded = true
+}
+
+// gridStep trades the grid of the symbol, it is called by the event loop. It reports whether the grid
+// owns the symbol, a session left by the sessions strategy is closed by step before the grid starts.
+func (u *orderUseCase) gridStep(m *Monitor, symbol string) bool {
+	if m.settings == nil || !m.gridLoaded {
+		return false
+	}
+
+	if m.grid == nil {
+		if m.settings.GetStrategy() != mongoStructs.StrategyGrid || m.settings.Status != mongoStructs.Enabled.ToString() ||
+			m.draining() || m.actualPrice == 0 || !m.sessionClosed() {
+			return false
+		}
+
+		g, err := u.newGrid(m.settings, symbol)
+		if err != nil {
+			u.logRus.
+				WithField("func", "newGrid").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			return false
+		}
+
+		m.async(func() func(m *Monitor) {
+			if err := u.startGrid(g); err != nil {
+				u.logRus.
+					WithField("func", "startGrid").
+					WithField("symbol", symbol).
+					Debug(err)
+
+				return nil
+			}
+
+			return func(m *Monitor) {
+				m.grid = g
+			}
+		})
+
+		return true
+	}
+
+	g := m.grid
+
+	if time.Since(g.syncedAt) < gridSyncInterval {
+		return true
+	}
+
+	g.syncedAt = time.Now()
+
+	// the grid is not touched by the loop until the task is done
+	status := gridStatus(m)
+	buys := status == GridStatusActive && !m.draining() && u.scheduleAllowed(m, symbol)
+	price := m.actualPrice
+
+	m.async(func() func(m *Monitor) {
+		if !u.tradeGrid(g, symbol, status, buys, price) {
+			return nil
+		}
+
+		return func(m *Monitor) {
+			m.grid = nil
+		}
+	})
+
+	return true
+}
+
+// tradeGrid moves the levels by the exchange and places their orders, buys allows the new buys.
+// It runs out of the event loop and reports whether the grid is stopped.
+func (u *orderUseCase) tradeGrid(g *gridState, symbol, status string, buys bool, price float64) bool {
+	if err := u.syncGrid(g, symbol); err != nil {
+		u.logRus.
+			WithField("func", "syncGrid").
+			WithField("symbol", symbol).
+			Debug(err)
+
+		return false
+	}
+
+	if status != g.grid.Status {
+		if err := u.gridRepo.SetStatus(g.grid.ID, status); err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			return false
+		}
+
+		u.notify(controllers.TgmEventGrid, gridSummary(g.grid, fmt.Sprintf("Price:\t%.2f\n%s", price, status)))
+
+		g.grid.Status = status
+	}
+
+	if status == GridStatusClosing && gridEmpty(g) {
+		if err := u.gridRepo.SetStatus(g.grid.ID, GridStatusStopped); err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			return false
+		}
+
+		g.grid.Status = GridStatusStopped
+
+		u.notify(controllers.TgmEventGrid, gridSummary(g.grid, GridStatusStopped))
+
+		return true
+	}
+
+	for i := range g.levels {
+		l := &g.levels[i]
+
+		switch l.State {
+		case GridLevelEmpty:
+			// the levels above the price wait for it to fall, the grid starts with no base asset
+			if buys && g.prices[l.Level] < price {
+				u.placeGridOrder(g, l, SideBuy, symbol)
+			}
+		case GridLevelBuying:
+			if status != GridStatusActive {
+				u.cancelGridOrder(g, l, symbol)
+			}
+		case GridLevelHolding:
+			u.placeGridOrder(g, l, SideSell, symbol)
+		}
+	}
+
+	return false
+}
+
+// gridStatus is the status of the grid by the settings and the price
+func gridStatus(m *Monitor) string {
+	g, s := m.grid.grid, m.settings
+
+	switch {
+	case g.Status == GridStatusClosing,
+		s.GetStrategy() != mongoStructs.StrategyGrid,
+		s.Status != mongoStructs.Enabled.ToString(),
+		!sameGridValue(g.MinPrice, s.MinPrice),
+		!sameGridValue(g.MaxPrice, s.MaxPrice),
+		!sameGridValue(g.Quantity, s.Step),
+		g.Levels != s.GridLevels:
+		return GridStatusClosing
+	case m.actualPrice < g.MinPrice || m.actualPrice > g.MaxPrice:
+		return GridStatusPaused
+	}
+
+	return GridStatusActive
+}
+
+// sameGridValue compares the stored value with the settings one, the repository keeps them as real
+func sameGridValue(stored, value float64) bool {
+	return math.Abs(stored-value) <= math.Abs(value)*1e-6
+}
+
+func gridEmpty(g *gridState) bool {
+	for _, l := range g.levels {
+		if l.State != GridLevelEmpty {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoadGrid reads the grid left by the previous monitor of the symbol, it retries until the grid is read
+func (m *Monitor) LoadGrid(u *orderUseCase, symbol string) {
+	for m.sleep(chkTime) {
+		g, err := u.loadGrid(symbol)
+		if err != nil {
+			u.logRus.
+				WithField("func", "loadGrid").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			continue
+		}
+
+		m.send(gridLoadedEvent{grid: g})
+
+		return
+	}
+}
+
+// loadGrid reads the active grid of the symbol, it is nil when there is none
+func (u *orderUseCase) loadGrid(symbol string) (*gridState, error) {
+	grid, err := u.gridRepo.GetActive(symbol, u.market.ToString())
+	if err != nil {
+		if err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	prices, err := structs.GridPrices(grid.MinPrice, grid.MaxPrice, grid.Levels)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, err := u.gridRepo.GetLevels(grid.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gridState{
+		grid:   grid,
+		levels: levels,
+		prices: prices,
+	}, nil
+}
+
+// newGrid builds the grid of the settings, it is stored by startGrid
+func (u *orderUseCase) newGrid(settings *mongoStructs.Settings, symbol string) (*gridState, error) {
+	p, ok := settings.Params().(mongoStructs.GridParams)
+	if !ok {
+		return nil, fmt.Errorf("%s settings are not the grid ones", settings.GetStrategy())
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	prices, err := structs.GridPrices(p.MinPrice, p.MaxPrice, p.Levels)
+	if err != nil {
+		return nil, err
+	}
+
+	grid := &models.Grid{
+		ID:        uuid.New().String(),
+		Symbol:    symbol,
+		Market:    u.market.ToString(),
+		MinPrice:  p.MinPrice,
+		MaxPrice:  p.MaxPrice,
+		Levels:    p.Levels,
+		Quantity:  p.Step,
+		Status:    GridStatusActive,
+		CreatedAt: time.Now(),
+	}
+
+	levels := make([]models.GridLevel, len(prices)-1)
+	for i := range levels {
+		levels[i] = models.GridLevel{
+			GridID: grid.ID,
+			Level:  i,
+			State:  GridLevelEmpty,
+		}
+	}
+
+	return &gridState{
+		grid:   grid,
+		levels: levels,
+		prices: prices,
+	}, nil
+}
+
+// startGrid stores the new grid
+func (u *orderUseCase) startGrid(g *gridState) error {
+	if err := u.gridRepo.Store(g.grid, g.levels); err != nil {
+		return err
+	}
+
+	u.notify(controllers.TgmEventGrid, gridSummary(g.grid, "Started"))
+
+	return nil
+}
+
+// syncGrid moves the levels by the exchange state of their orders
+func (u *orderUseCase) syncGrid(g *gridState, symbol string) error {
+	open, err := u.getGridOpenOrders(symbol)
+	if err != nil {
+		return err
+	}
+
+	g.open = open
+
+	for i := range g.levels {
+		l := &g.levels[i]
+
+		if l.State != GridLevelBuying && l.State != GridLevelSelling {
+			continue
+		}
+
+		if _, ok := open[l.OrderID]; ok {
+			continue
+		}
+
+		o, err := u.getGridOrder(l.OrderID, symbol)
+		if err != nil {
+			if err != controllers.ErrOrderDoesNotExist {
+				u.logRus.
+					WithField("func", "getGridOrder").
+					WithField("orderID", l.OrderID).
+					Debug(err)
+
+				continue
+			}
+
+			// the level was stored but the order was not sent
+			o = &gridOrder{status: OrderStatusNotFound}
+		}
+
+		u.applyGridOrder(g, l, o)
+	}
+
+	return nil
+}
+
+// applyGridOrder moves the level by its closed order. A filled buy is held and sold a level higher,
+// a filled sell adds its profit to the level and the grid. The canceled orders keep what they have filled.
+func (u *orderUseCase) applyGridOrder(g *gridState, l *models.GridLevel, o *gridOrder) {
+	switch o.status {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusExpired, OrderStatusNotFound, OrderStatusRejected:
+	default:
+		return
+	}
+
+	next := *l
+	next.OrderID = ""
+
+	var profit float64
+	var sold bool
+
+	switch l.State {
+	case GridLevelBuying:
+		next.State = GridLevelEmpty
+		next.Quantity = 0
+		next.BuyPrice = 0
+
+		if o.executed > 0 {
+			next.State = GridLevelHolding
+			next.Quantity = o.executed
+			next.BuyPrice = o.avgPrice
+		}
+	case GridLevelSelling:
+		next.State = GridLevelHolding
+
+		if o.executed > 0 {
+			sold = true
+			profit = structs.GridProfit(l.BuyPrice, o.avgPrice, o.executed)
+
+			// the dust under the step size of the symbol can not be sold
+			limits, err := u.getSymbolLimits(g.grid.Symbol)
+			if err != nil {
+				u.logRus.
+					WithField("func", "getSymbolLimits").
+					Debug(err)
+			}
+
+			next.Quantity = l.Quantity - o.executed
+			if limits.FloorQuantity(next.Quantity) <= 0 {
+				next.State = GridLevelEmpty
+				next.Quantity = 0
+				next.BuyPrice = 0
+			}
+		}
+	}
+
+	if !sold {
+		if err := u.gridRepo.UpdateLevel(&next); err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			return
+		}
+
+		*l = next
+
+		return
+	}
+
+	if err := u.gridRepo.CloseCycle(&next, profit); err != nil {
+		u.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return
+	}
+
+	next.Profit += profit
+	next.Cycles++
+	*l = next
+
+	g.grid.RealizedProfit += profit
+	g.grid.Cycles++
+
+	u.logRus.
+		WithField("symbol", g.grid.Symbol).
+		WithField("level", l.Level).
+		Infof("Grid [%s] sold %.5f by %.2f, profit %.4f, total %.4f", g.grid.Symbol, o.executed, o.avgPrice, profit, g.grid.RealizedProfit)
+}
+
+// placeGridOrder sends the order of the level. The level is stored with the order id first,
+// so the order lost between the two is found by syncGrid.
+func (u *orderUseCase) placeGridOrder(g *gridState, l *models.GridLevel, side, symbol string) {
+	next := *l
+	next.OrderID = gridOrderPrefix + strings.ReplaceAll(uuid.New().String(), "-", "")
+
+	price := g.prices[l.Level]
+
+	switch side {
+	case SideBuy:
+		next.State = GridLevelBuying
+		next.Quantity = g.grid.Quantity
+	case SideSell:
+		next.State = GridLevelSelling
+		price = g.prices[l.Level+1]
+	}
+
+	if err := u.gridRepo.UpdateLevel(&next); err != nil {
+		u.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return
+	}
+
+	*l = next
+
+	// the grid orders are not stored, the grid is their session in the order events
+	o := &models.Order{
+		ID:           next.OrderID,
+		SessionID:    g.grid.ID,
+		Symbol:       symbol,
+		Side:         side,
+		PositionSide: PositionSideLong,
+		Type:         OrderTypeLimit,
+		Quantity:     next.Quantity,
+		Price:        price,
+	}
+
+	if err := u.createGridOrder(o); err != nil {
+		u.logRus.
+			WithField("func", "createGridOrder").
+			WithField("orderID", o.ID).
+			Debug(err)
+	}
+}
+
+// cancelGridOrder cancels the open order of the level, the level is moved by the next sync
+func (u *orderUseCase) cancelGridOrder(g *gridState, l *models.GridLevel, symbol string) {
+	open, ok := g.open[l.OrderID]
+	if !ok {
+		return
+	}
+
+	var err error
+
+	switch u.market {
+	case mongoStructs.MarketSpot:
+		_, err = u.cancelSpotOrder(l.OrderID, symbol, models.OrderSourcePoll)
+	default:
+		_, err = u.cancelFeatureOrder(open.orderID, symbol, models.OrderSourcePoll)
+	}
+
+	if err != nil {
+		u.logRus.
+			WithField("func", "cancelGridOrder").
+			WithField("orderID", l.OrderID).
+			Debug(err)
+	}
+}
+
+// gridDrained cancels the grid buys when the monitor is drained. The sells are left on the exchange
+// and the grid is resumed by the next monitor. It reports whether no buy is left.
+func (u *orderUseCase) gridDrained(m *Monitor, symbol string) bool {
+	if !m.gridLoaded {
+		return false
+	}
+
+	g := m.grid
+	if g == nil {
+		return true
+	}
+
+	// the buys are placed by the step tasks only, no task is running when the drain is checked
+	if !gridBuying(g) {
+		return true
+	}
+
+	if time.Since(g.syncedAt) < gridSyncInterval {
+		return false
+	}
+
+	g.syncedAt = time.Now()
+
+	m.async(func() func(m *Monitor) {
+		if err := u.syncGrid(g, symbol); err != nil {
+			u.logRus.
+				WithField("func", "syncGrid").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			return nil
+		}
+
+		for i := range g.levels {
+			if g.levels[i].State == GridLevelBuying {
+				u.cancelGridOrder(g, &g.levels[i], symbol)
+			}
+		}
+
+		return nil
+	})
+
+	return false
+}
+
+// gridBuying reports whether a level of the grid has a buy order
+func gridBuying(g *gridState) bool {
+	for _, l := range g.levels {
+		if l.State == GridLevelBuying {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stopGrid stops the grid of the symbol, its orders and base asset are closed by the caller.
+// It reports whether the grid is stopped.
+func (u *orderUseCase) stopGrid(symbol string) bool {
+	if err := u.stopActiveGrid(symbol); err != nil {
+		u.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return false
+	}
+
+	return true
+}
+
+// stopActiveGrid stops the stored grid of the symbol
+func (u *orderUseCase) stopActiveGrid(symbol string) error {
+	grid, err := u.gridRepo.GetActive(symbol, u.market.ToString())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+
+		return err
+	}
+
+	if err := u.gridRepo.SetStatus(grid.ID, GridStatusStopped); err != nil {
+		return err
+	}
+
+	grid.Status = GridStatusStopped
+
+	u.notify(controllers.TgmEventGrid, gridSummary(grid, GridStatusStopped))
+
+	return nil
+}
+
+// gridHolding returns the base asset bought by the grid of the symbol and its cost
+func (u *orderUseCase) gridHolding(symbol string) (float64, float64, error) {
+	grid, err := u.gridRepo.GetActive(symbol, u.market.ToString())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, nil
+		}
+
+		return 0, 0, err
+	}
+
+	levels, err := u.gridRepo.GetLevels(grid.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var amount, cost float64
+
+	for _, l := range levels {
+		if l.State != GridLevelHolding && l.State != GridLevelSelling {
+			continue
+		}
+
+		amount += l.Quantity
+		cost += l.Quantity * l.BuyPrice
+	}
+
+	return amount, cost, nil
+}
+
+func gridSummary(grid *models.Grid, event string) string {
+	return fmt.Sprintf("[ Grid ]\n"+
+		"Symbol:\t%s\n"+
+		"Market:\t%s\n"+
+		"Range:\t%.2f - %.2f\n"+
+		"Levels:\t%d\n"+
+		"Quantity:\t%.5f\n"+
+		"Profit:\t%.4f\n"+
+		"Cycles:\t%d\n"+
+		"%s",
+		grid.Symbol,
+		grid.Market,
+		grid.MinPrice,
+		grid.MaxPrice,
+		grid.Levels,
+		grid.Quantity,
+		grid.RealizedProfit,
+		grid.Cycles,
+		event,
+	)
+}
+
+func (u *orderUseCase) createGridOrder(o *models.Order) error {
+	switch u.market {
+	case mongoStructs.MarketSpot:
+		return u.createSpotLimitOrder(o)
+	default:
+		_, err := u.createFeaturesCloseOrder(o)
+
+		return err
+	}
+}
+
+// getGridOpenOrders returns the open grid orders of the symbol by the client order id
+func (u *orderUseCase) getGridOpenOrders(symbol string) (map[string]gridOrder, error) {
+	out := make(map[string]gridOrder)
+
+	switch u.market {
+	case mongoStructs.MarketSpot:
+		orders, err := u.getSpotOpenOrders(symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range orders {
+			if strings.HasPrefix(o.ClientOrderId, gridOrderPrefix) {
+				out[o.ClientOrderId] = gridOrder{orderID: o.OrderId, status: o.Status}
+			}
+		}
+	default:
+		orders, err := u.getFeatureOpenOrders(symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, o := range orders {
+			if strings.HasPrefix(o.ClientOrderId, gridOrderPrefix) {
+				out[o.ClientOrderId] = gridOrder{orderID: o.OrderId, status: o.Status}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+func (u *orderUseCase) getGridOrder(id, symbol string) (*gridOrder, error) {
+	switch u.market {
+	case mongoStructs.MarketSpot:
+		o, err := u.getSpotOrderInfo(id, symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		executed, err := strconv.ParseFloat(o.ExecutedQty, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		out := &gridOrder{orderID: o.OrderId, status: o.Status, executed: executed}
+
+		if executed > 0 {
+			quote, err := strconv.ParseFloat(o.CummulativeQuoteQty, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			out.avgPrice = quote / executed
+		}
+
+		return out, nil
+	default:
+		o, err := u.getFeatureOrderInfo(id, symbol)
+		if err != nil {
+			return nil, err
+		}
+
+		executed, err := strconv.ParseFloat(o.ExecutedQty, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		avgPrice, err := strconv.ParseFloat(o.AvgPrice, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return &gridOrder{orderID: o.OrderId, status: o.Status, executed: executed, avgPrice: avgPrice}, nil
+	}
+}
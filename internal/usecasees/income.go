@@ -0,0 +1,262 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	"binance/internal/repository/postgres"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	featureIncome = "/fapi/v1/income"
+
+	// incomeLimit is the max page of the income history
+	incomeLimit = 1000
+	// incomeHistory is the period the income history is kept by the exchange, older incomes are imported from CSV
+	incomeHistory = 90 * 24 * time.Hour
+
+	incomeSyncInterval = 10 * time.Minute
+)
+
+type incomeUseCase struct {
+	clientController controllers.ClientCtrl
+	cryptoController controllers.CryptoCtrl
+
+	incomeRepo postgres.IncomeRepo
+
+	url string
+
+	logger *logrus.Logger
+}
+
+func NewIncomeUseCase(
+	client controllers.ClientCtrl,
+	crypto controllers.CryptoCtrl,
+	incomeRepo postgres.IncomeRepo,
+	url string,
+	logger *logrus.Logger,
+) *incomeUseCase {
+	return &incomeUseCase{
+		clientController: client,
+		cryptoController: crypto,
+		incomeRepo:       incomeRepo,
+		url:              url,
+		logger:           logger,
+	}
+}
+
+// Run syncs the income history until ctx is done
+func (u *incomeUseCase) Run(ctx context.Context) {
+	for {
+		n, err := u.Sync()
+		if err != nil {
+			u.logger.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		if n != 0 {
+			u.logger.Debugf("Income %d new", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(incomeSyncInterval):
+		}
+	}
+}
+
+// Sync stores the incomes made since the last synced one, the first sync reads the history of the exchange
+// after the imported one. It returns the number of the new incomes.
+//
+// The income endpoint has no fromId, so the cursor is the time of the last synced income. The page starts at
+// that millisecond again and the incomes synced already are skipped by their tranId key.
+func (u *incomeUseCase) Sync() (int, error) {
+	startTime, err := u.syncStart()
+	if err != nil {
+		return 0, err
+	}
+
+	var out int
+
+	for {
+		list, err := u.getIncome(startTime)
+		if err != nil {
+			return out, err
+		}
+
+		incomes := make([]models.Income, 0, len(list))
+
+		for _, v := range list {
+			income, err := strconv.ParseFloat(v.Income, 64)
+			if err != nil {
+				return out, fmt.Errorf("income %d: %w", v.TranID, err)
+			}
+
+			incomes = append(incomes, models.Income{
+				ID:         fmt.Sprintf("%d-%s-%s", v.TranID, v.IncomeType, v.Asset),
+				TranID:     v.TranID,
+				TradeID:    v.TradeID,
+				Source:     models.IncomeSourceAPI,
+				Symbol:     v.Symbol,
+				IncomeType: v.IncomeType,
+				Income:     income,
+				Asset:      v.Asset,
+				Info:       v.Info,
+				IncomeAt:   time.UnixMilli(v.Time).UTC(),
+			})
+		}
+
+		n, err := u.incomeRepo.Store(incomes)
+		if err != nil {
+			return out, err
+		}
+
+		out += n
+
+		if len(list) < incomeLimit {
+			break
+		}
+
+		next := time.UnixMilli(list[len(list)-1].Time)
+		if !next.After(startTime) {
+			// the page is one millisecond
+			next = startTime.Add(time.Millisecond)
+		}
+
+		startTime = next
+	}
+
+	return out, nil
+}
+
+// syncStart is the time the sync reads the incomes from
+func (u *incomeUseCase) syncStart() (time.Time, error) {
+	out := time.Now().Add(-incomeHistory)
+
+	last, err := u.incomeRepo.GetLast(models.IncomeSourceAPI)
+	if err == nil {
+		// the incomes of the same millisecond may be not synced yet, the stored ones are skipped
+		return last.IncomeAt, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return out, err
+	}
+
+	// the CSV rows have no tranId, so the first sync starts after them instead of storing them again.
+	// The time of the export is in seconds and the export has the whole last second.
+	imported, err := u.incomeRepo.GetLast(models.IncomeSourceCSV)
+	switch {
+	case err == nil:
+		if next := imported.IncomeAt.Truncate(time.Second).Add(time.Second); next.After(out) {
+			out = next
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return out, err
+	}
+
+	return out, nil
+}
+
+// Import stores the incomes of the CSV export made before the first synced income, the later ones are
+// in the synced history. The import of the same file again stores nothing.
+func (u *incomeUseCase) Import(r io.Reader) (int, error) {
+	rows, err := structs.ReadIncomeCSV(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var synced time.Time
+
+	first, err := u.incomeRepo.GetFirst(models.IncomeSourceAPI)
+	switch {
+	case err == nil:
+		synced = first.IncomeAt
+	case !errors.Is(err, sql.ErrNoRows):
+		return 0, err
+	}
+
+	// the rows have no id, the equal rows are numbered
+	seen := make(map[string]int)
+
+	incomes := make([]models.Income, 0, len(rows))
+
+	for _, row := range rows {
+		key := fmt.Sprintf("%d|%s|%s|%s|%s", row.Time.Unix(), row.IncomeType, strconv.FormatFloat(row.Amount, 'f', -1, 64), row.Asset, row.Symbol)
+		seen[key]++
+
+		if !synced.IsZero() && !row.Time.Before(synced) {
+			continue
+		}
+
+		sum := sha1.Sum([]byte(key + "|" + strconv.Itoa(seen[key])))
+
+		incomes = append(incomes, models.Income{
+			ID:         "csv-" + hex.EncodeToString(sum[:]),
+			Source:     models.IncomeSourceCSV,
+			Symbol:     row.Symbol,
+			IncomeType: row.IncomeType,
+			Income:     row.Amount,
+			Asset:      row.Asset,
+			IncomeAt:   row.Time,
+		})
+	}
+
+	return u.incomeRepo.Store(incomes)
+}
+
+// Summary sums the incomes by the UTC day, the symbol, the type and the asset
+func (u *incomeUseCase) Summary(from, to time.Time) ([]models.IncomeSummary, error) {
+	return u.incomeRepo.Summary(from, to)
+}
+
+func (u *incomeUseCase) getIncome(startTime time.Time) ([]structs.Income, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(featureIncome)
+
+	q := baseURL.Query()
+	q.Set("startTime", strconv.FormatInt(startTime.UnixMilli(), 10))
+	q.Set("limit", strconv.Itoa(incomeLimit))
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	req, err := u.clientController.Send(http.MethodGet, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []structs.Income
+
+	if err := json.Unmarshal(req, &out); err != nil {
+		return nil, fmt.Errorf("%w %s", err, req)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package usecasees
+
+import (
+	ctrlMocks "binance/internal/controllers/mocks"
+	pgMocks "binance/internal/repository/postgres/mocks"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_IncomeUseCase(t *testing.T) {
+	lastAt := time.Date(2022, 10, 17, 8, 0, 0, 0, time.UTC)
+
+	t.Run("sync", func(t *testing.T) {
+		u, clientCtrl, incomeRepo := initIncomeUseCase()
+
+		incomeRepo.On("GetLast", models.IncomeSourceAPI).
+			Return(&models.Income{IncomeAt: lastAt}, nil).Once()
+
+		incomes, err := json.Marshal([]structs.Income{
+			{Symbol: testSymbol, IncomeType: structs.IncomeFundingFee, Income: "-0.0123", Asset: USDT, Time: lastAt.UnixMilli(), TranID: 7},
+			{Symbol: testSymbol, IncomeType: structs.IncomeRealizedPnL, Income: "1.5", Asset: USDT, Time: lastAt.Add(time.Hour).UnixMilli(), TranID: 8, TradeID: "43"},
+		})
+		assert.NoError(t, err)
+
+		clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
+			return input.Path == featureIncome && input.Query().Get("startTime") == strconv.FormatInt(lastAt.UnixMilli(), 10)
+		}), []byte(nil), true).Return(incomes, nil).Once()
+
+		// the first one is stored already
+		incomeRepo.On("Store", mock.MatchedBy(func(incomes []models.Income) bool {
+			return len(incomes) == 2 &&
+				incomes[0].ID == "7-FUNDING_FEE-USDT" && incomes[0].Income == -0.0123 &&
+				incomes[1].TradeID == "43" && incomes[1].Source == models.IncomeSourceAPI &&
+				incomes[1].IncomeAt.Equal(lastAt.Add(time.Hour))
+		})).Return(1, nil).Once()
+
+		n, err := u.Sync()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		clientCtrl.AssertExpectations(t)
+		incomeRepo.AssertExpectations(t)
+	})
+
+	t.Run("first sync after import", func(t *testing.T) {
+		u, clientCtrl, incomeRepo := initIncomeUseCase()
+
+		importedAt := time.Now().Add(-24 * time.Hour).Truncate(time.Second).UTC()
+
+		incomeRepo.On("GetLast", models.IncomeSourceAPI).
+			Return(nil, sql.ErrNoRows).Once()
+		incomeRepo.On("GetLast", models.IncomeSourceCSV).
+			Return(&models.Income{IncomeAt: importedAt}, nil).Once()
+
+		// the imported incomes are not read again
+		clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
+			return input.Path == featureIncome && input.Query().Get("startTime") == strconv.FormatInt(importedAt.Add(time.Second).UnixMilli(), 10)
+		}), []byte(nil), true).Return([]byte("[]"), nil).Once()
+
+		incomeRepo.On("Store", mock.MatchedBy(func(incomes []models.Income) bool {
+			return len(incomes) == 0
+		})).Return(0, nil).Once()
+
+		n, err := u.Sync()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		clientCtrl.AssertExpectations(t)
+		incomeRepo.AssertExpectations(t)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		u, _, incomeRepo := initIncomeUseCase()
+
+		incomeRepo.On("GetFirst", models.IncomeSourceAPI).
+			Return(&models.Income{IncomeAt: lastAt}, nil).Once()
+
+		// the equal rows get their own ids, the synced period is skipped
+		incomeRepo.On("Store", mock.MatchedBy(func(incomes []models.Income) bool {
+			return len(incomes) == 2 &&
+				incomes[0].ID != incomes[1].ID &&
+				incomes[0].Source == models.IncomeSourceCSV &&
+				incomes[0].IncomeType == structs.IncomeCommission
+		})).Return(2, nil).Once()
+
+		n, err := u.Import(strings.NewReader(
+			"Time(UTC),Type,Amount,Asset,Symbol\n" +
+				"2022-10-16 10:00:00,COMMISSION,-0.0078,USDT,BTCUSDT\n" +
+				"2022-10-16 10:00:00,COMMISSION,-0.0078,USDT,BTCUSDT\n" +
+				"2022-10-17 08:00:00,FUNDING_FEE,-0.0123,USDT,BTCUSDT\n",
+		))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		incomeRepo.AssertExpectations(t)
+	})
+
+	t.Run("import without sync", func(t *testing.T) {
+		u, _, incomeRepo := initIncomeUseCase()
+
+		incomeRepo.On("GetFirst", models.IncomeSourceAPI).
+			Return(nil, sql.ErrNoRows).Once()
+
+		incomeRepo.On("Store", mock.MatchedBy(func(incomes []models.Income) bool {
+			return len(incomes) == 1
+		})).Return(1, nil).Once()
+
+		_, err := u.Import(strings.NewReader("2022-10-17 08:00:00,FUNDING_FEE,-0.0123,USDT,BTCUSDT\n"))
+		assert.NoError(t, err)
+
+		incomeRepo.AssertExpectations(t)
+	})
+}
+
+func initIncomeUseCase() (*incomeUseCase, *ctrlMocks.ClientCtrl, *pgMocks.IncomeRepo) {
+	clientCtrl := &ctrlMocks.ClientCtrl{}
+	cryptoCtrl := &ctrlMocks.CryptoCtrl{}
+	incomeRepo := &pgMocks.IncomeRepo{}
+
+	cryptoCtrl.On("GetSignature", mock.AnythingOfType("string")).Return("630e26f39d6728d0e7feffb9", nil)
+
+	return NewIncomeUseCase(clientCtrl, cryptoCtrl, incomeRepo, "https://fapi.binance.com", logrus.New()), clientCtrl, incomeRepo
+}
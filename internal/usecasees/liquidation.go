@@ -0,0 +1,408 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	liquidationInterval       = time.Second
+	defaultLiquidationRequote = 10 * time.Second
+
+	// liquidationOrderPrefix marks the closing orders, clientOrderId is limited to 36 chars
+	liquidationOrderPrefix = "liq-"
+)
+
+type liquidationState struct {
+	status    mongoStructs.SymbolStatus
+	startedAt time.Time
+
+	// positions are the positions seen at the start by position side
+	positions map[string]structs.Position
+	orders    map[string]liquidationOrder
+
+	canceled int
+	placed   int
+}
+
+type liquidationOrder struct {
+	id       string
+	placedAt time.Time
+}
+
+func newLiquidationState(status mongoStructs.SymbolStatus) *liquidationState {
+	return &liquidationState{
+		status:    status,
+		startedAt: time.Now(),
+		positions: make(map[string]structs.Position),
+		orders:    make(map[string]liquidationOrder),
+	}
+}
+
+func isLiquidationStatus(status string) bool {
+	switch mongoStructs.SymbolStatus(status) {
+	case mongoStructs.Liquidation, mongoStructs.LiquidationBUY, mongoStructs.LiquidationSELL:
+		return true
+	}
+
+	return false
+}
+
+// liquidates reports whether the position is closed by the status.
+// LIQUIDATION_BUY closes the long positions and LIQUIDATION_SELL the short ones.
+func liquidates(status mongoStructs.SymbolStatus, positionSide string, amount float64) bool {
+	switch status {
+	case mongoStructs.Liquidation:
+		return true
+	case mongoStructs.LiquidationBUY:
+		return positionSide == PositionSideLong || (positionSide == PositionSideBoth && amount > 0)
+	case mongoStructs.LiquidationSELL:
+		return positionSide == PositionSideShort || (positionSide == PositionSideBoth && amount < 0)
+	}
+
+	return false
+}
+
+// liquidatesOrders reports whether the orders of the position side are cancelled by the status.
+// In the one-way mode there is a single position, so all its orders are cancelled.
+func liquidatesOrders(status mongoStructs.SymbolStatus, positionSide string) bool {
+	return positionSide == PositionSideBoth || liquidates(status, positionSide, 0)
+}
+
+// liquidate runs one step of the wind-down out of the event loop: the orders of the liquidated sides
+// are cancelled, the positions are closed and once flat the symbol is disabled.
+func (u *orderUseCase) liquidate(m *Monitor, symbol string) {
+	l := u.startLiquidation(m, symbol)
+
+	// the wind-down state is not touched by the loop until the task is done
+	list := m.ordersList.clone()
+	settings, depth, sessionID := m.settings, m.depth, m.status.SessionID
+
+	m.async(func() func(m *Monitor) {
+		// the orders of the session which are not sent yet are not sent anymore
+		for _, o := range list {
+			if o == nil || o.Status != OrderStatusInProgress || !liquidatesOrders(l.status, o.PositionSide) {
+				continue
+			}
+
+			if err := u.orderRepo.SetStatus(o.ID, OrderStatusCanceled, orderChange(models.OrderSourceManual, nil)); err != nil {
+				u.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+			}
+		}
+
+		flat, err := u.windDown(l, symbol, func(positionSide string, amount float64) *models.Order {
+			return constructLiquidationOrder(settings, depth, sessionID, symbol, positionSide, amount)
+		}, liquidationRequote(settings))
+		if err != nil {
+			u.logRus.
+				WithField("func", "windDown").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			return nil
+		}
+
+		if !flat {
+			return nil
+		}
+
+		return u.finishLiquidation(l, settings, symbol)
+	})
+}
+
+// startLiquidation returns the wind-down state of the status, a new status starts a new wind-down
+func (u *orderUseCase) startLiquidation(m *Monitor, symbol string) *liquidationState {
+	status := mongoStructs.SymbolStatus(m.settings.Status)
+
+	if m.liquidation == nil || m.liquidation.status != status {
+		m.liquidation = newLiquidationState(status)
+
+		u.notify(controllers.TgmEventLiquidation, fmt.Sprintf("[ Liquidation ]\nSymbol:\t%s\nMode:\t%s\nStarted", symbol, status))
+	}
+
+	return m.liquidation
+}
+
+// finishLiquidation disables the flat symbol and posts the summary, it returns the change of the monitor
+func (u *orderUseCase) finishLiquidation(l *liquidationState, settings *mongoStructs.Settings, symbol string) func(m *Monitor) {
+	if err := u.settingsRepo.UpdateStatus(settings.ID, mongoStructs.Disabled, mongoStructs.SettingsChange{Author: mongoStructs.SettingsAuthorMonitor, Reason: "liquidated"}); err != nil {
+		u.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return nil
+	}
+
+	u.notify(controllers.TgmEventLiquidation, l.summary(symbol))
+
+	// the grid buys on the long side only, so it is over when the longs are closed
+	gridStopped := liquidates(l.status, PositionSideLong, 1) && u.stopGrid(symbol)
+
+	return func(m *Monitor) {
+		if gridStopped {
+			m.grid = nil
+			m.gridLoaded = true
+		}
+
+		m.liquidation = nil
+	}
+}
+
+// windDown cancels the orders of the liquidated sides and closes their positions with the orders
+// built by construct. It reports whether nothing is left to close.
+func (u *orderUseCase) windDown(
+	l *liquidationState,
+	symbol string,
+	construct func(positionSide string, amount float64) *models.Order,
+	requote time.Duration,
+) (bool, error) {
+	openOrders, err := u.getFeatureOpenOrders(symbol)
+	if err != nil {
+		return false, err
+	}
+
+	positions, err := u.getFeaturePositions(symbol)
+	if err != nil {
+		return false, err
+	}
+
+	flat := true
+	active := make(map[string]bool)
+
+	for _, o := range openOrders {
+		if !liquidatesOrders(l.status, o.PositionSide) {
+			continue
+		}
+
+		flat = false
+
+		if strings.HasPrefix(o.ClientOrderId, liquidationOrderPrefix) {
+			active[o.ClientOrderId] = true
+
+			placed, ok := l.orders[o.PositionSide]
+			if ok && placed.id == o.ClientOrderId && o.Type == OrderTypeLimit && time.Since(placed.placedAt) > requote {
+				if _, err := u.cancelFeatureOrder(o.OrderId, symbol, models.OrderSourceManual); err != nil {
+					u.logRus.
+						WithField("func", "cancelFeatureOrder").
+						WithField("orderID", o.ClientOrderId).
+						Debug(err)
+				}
+			}
+
+			continue
+		}
+
+		if _, err := u.cancelFeatureOrder(o.OrderId, symbol, models.OrderSourceManual); err != nil {
+			u.logRus.
+				WithField("func", "cancelFeatureOrder").
+				WithField("orderID", o.ClientOrderId).
+				Debug(err)
+
+			continue
+		}
+
+		l.canceled++
+	}
+
+	for side, placed := range l.orders {
+		if !active[placed.id] {
+			delete(l.orders, side)
+		}
+	}
+
+	for _, p := range positions {
+		amount, err := strconv.ParseFloat(p.PositionAmt, 64)
+		if err != nil {
+			u.logRus.WithField("func", "ParseFloat").Debug(err)
+
+			flat = false
+
+			continue
+		}
+
+		if amount == 0 || !liquidates(l.status, p.PositionSide, amount) {
+			continue
+		}
+
+		flat = false
+
+		if _, ok := l.positions[p.PositionSide]; !ok {
+			l.positions[p.PositionSide] = p
+		}
+
+		if _, ok := l.orders[p.PositionSide]; ok {
+			continue
+		}
+
+		order := construct(p.PositionSide, amount)
+
+		if _, err := u.createFeaturesCloseOrder(order); err != nil {
+			u.logRus.
+				WithField("func", "createFeaturesCloseOrder").
+				WithField("symbol", symbol).
+				WithField("positionSide", p.PositionSide).
+				Debug(err)
+
+			continue
+		}
+
+		l.orders[p.PositionSide] = liquidationOrder{id: order.ID, placedAt: time.Now()}
+		l.placed++
+	}
+
+	return flat, nil
+}
+
+// closePositions closes the positions of the sides liquidated by the status on all the futures symbols by
+// market, the settings of the symbols are not changed. It returns the symbols whose close orders are placed
+// and the ones failed.
+func (u *orderUseCase) closePositions(status mongoStructs.SymbolStatus) ([]string, []string, error) {
+	positions, err := u.getFeaturePositions("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var closed, failed []string
+	seen := make(map[string]bool)
+
+	for _, p := range positions {
+		amount, err := strconv.ParseFloat(p.PositionAmt, 64)
+		if err != nil || amount == 0 || !liquidates(status, p.PositionSide, amount) || seen[p.Symbol] {
+			continue
+		}
+
+		seen[p.Symbol] = true
+
+		symbol := p.Symbol
+		l := newLiquidationState(status)
+
+		if _, err := u.windDown(l, symbol, func(positionSide string, amount float64) *models.Order {
+			return constructCloseOrder("", symbol, positionSide, amount)
+		}, defaultLiquidationRequote); err != nil || l.placed == 0 {
+			u.logRus.
+				WithField("func", "windDown").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			failed = append(failed, symbol)
+
+			continue
+		}
+
+		closed = append(closed, symbol)
+	}
+
+	return closed, failed, nil
+}
+
+// constructCloseOrder builds the market order closing the position
+func constructCloseOrder(sessionID, symbol, positionSide string, amount float64) *models.Order {
+	o := models.Order{
+		ID:           liquidationOrderPrefix + strings.ReplaceAll(uuid.NewString(), "-", ""),
+		SessionID:    sessionID,
+		Symbol:       symbol,
+		PositionSide: positionSide,
+		Quantity:     math.Abs(amount),
+		Type:         OrderTypeMarket,
+	}
+
+	if amount > 0 {
+		o.Side = SideSell
+	} else {
+		o.Side = SideBuy
+	}
+
+	return &o
+}
+
+func constructLiquidationOrder(settings *mongoStructs.Settings, depth *structs.DepthInfo, sessionID, symbol, positionSide string, amount float64) *models.Order {
+	o := constructCloseOrder(sessionID, symbol, positionSide, amount)
+
+	if settings.LiquidationOrderType != OrderTypeLimit || depth == nil || depth.MidPrice == 0 {
+		return o
+	}
+
+	// the offset moves the price from the best own side price through the spread
+	offset := depth.MidPrice / 100 * settings.LiquidationPriceOffset
+
+	o.Type = OrderTypeLimit
+
+	switch o.Side {
+	case SideSell:
+		o.Price = depth.BestAskPrice - offset
+	case SideBuy:
+		o.Price = depth.BestBidPrice + offset
+	}
+
+	return o
+}
+
+func liquidationRequote(settings *mongoStructs.Settings) time.Duration {
+	if settings.LiquidationRequote > 0 {
+		return time.Duration(settings.LiquidationRequote) * time.Second
+	}
+
+	return defaultLiquidationRequote
+}
+
+func (l *liquidationState) summary(symbol string) string {
+	msg := fmt.Sprintf("[ Liquidation ]\n"+
+		"Symbol:\t%s\n"+
+		"Mode:\t%s\n",
+		symbol,
+		l.status,
+	)
+
+	sides := make([]string, 0, len(l.positions))
+	for side := range l.positions {
+		sides = append(sides, side)
+	}
+	sort.Strings(sides)
+
+	for _, side := range sides {
+		p := l.positions[side]
+
+		msg += fmt.Sprintf("Position:\t%s %s @ %s uPnL %s\n",
+			side,
+			p.PositionAmt,
+			p.EntryPrice,
+			p.UnRealizedProfit,
+		)
+	}
+
+	msg += fmt.Sprintf(
+		"Close orders:\t%d\n"+
+			"Canceled:\t%d\n"+
+			"Time:\t%s\n"+
+			"Status:\t%s",
+		l.placed,
+		l.canceled,
+		time.Since(l.startedAt).Round(time.Second),
+		mongoStructs.Disabled,
+	)
+
+	return msg
+}
+
+// notify queues the notification of the event, the data is rendered by the template of the event
+func (u *orderUseCase) notify(event string, data interface{}) {
+	if err := u.tgmController.Notify(event, data); err != nil {
+		u.logRus.
+			WithField("func", "notify").
+			WithField("event", event).
+			Debug(err)
+	}
+}
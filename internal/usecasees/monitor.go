@@ -0,0 +1,1187 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	chkTime = 50 * time.Millisecond
+
+	// monitorStepInterval is the interval of the trading decisions of the event loop
+	monitorStepInterval = 150 * time.Millisecond
+	monitorDepthTime    = 250 * time.Millisecond
+	monitorLogTime      = time.Second
+	monitorFillsTime    = time.Minute
+)
+
+//var DepthLimit = float64(35)
+
+const step = 40
+
+type ordersList [3]*models.Order
+
+// ordersListTypes are the order types by position in ordersList
+var ordersListTypes = [3]string{
+	OrderTypeLimitID:      OrderTypeLimit,
+	OrderTypeTakeProfitID: OrderTypeCurrentTakeProfit,
+	OrderTypeStopLossID:   OrderTypeCurrentStopLoss,
+}
+
+func (o *ordersList) SetLimit(order *models.Order) {
+	o[OrderTypeLimitID] = order
+}
+func (o *ordersList) SetTakeProfit(order *models.Order) {
+	o[OrderTypeTakeProfitID] = order
+}
+func (o *ordersList) SetStopLoss(order *models.Order) {
+	o[OrderTypeStopLossID] = order
+}
+
+func (o *ordersList) IsNil() bool {
+	return o[OrderTypeLimitID] == nil && o[OrderTypeTakeProfitID] == nil && o[OrderTypeStopLossID] == nil
+}
+
+func (o *ordersList) Has(orderType string) bool {
+	switch orderType {
+	case OrderTypeLimit:
+		return o[OrderTypeLimitID] != nil
+	case OrderTypeCurrentTakeProfit:
+		return o[OrderTypeTakeProfitID] != nil
+	case OrderTypeCurrentStopLoss:
+		return o[OrderTypeStopLossID] != nil
+	default:
+		panic("error type")
+	}
+}
+
+func (o *ordersList) Get(orderType string) *models.Order {
+	switch orderType {
+	case OrderTypeLimit:
+		return o[OrderTypeLimitID]
+	case OrderTypeCurrentTakeProfit:
+		return o[OrderTypeTakeProfitID]
+	case OrderTypeCurrentStopLoss:
+		return o[OrderTypeStopLossID]
+	default:
+		panic("error type")
+	}
+}
+
+// clone copies the orders, the copy does not share memory with the list
+func (o *ordersList) clone() ordersList {
+	var out ordersList
+
+	for i, order := range o {
+		if order == nil {
+			continue
+		}
+
+		c := *order
+		out[i] = &c
+	}
+
+	return out
+}
+
+// creating reports whether an order of the session is stored but not sent yet
+func (o *ordersList) creating() bool {
+	for _, order := range o {
+		if order != nil && order.Status == OrderStatusInProgress {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the order with the client order id
+func (o *ordersList) find(id string) *models.Order {
+	for _, order := range o {
+		if order != nil && order.ID == id {
+			return order
+		}
+	}
+
+	return nil
+}
+
+// monitorEvent changes the monitor state. Events are applied by the event loop only.
+type monitorEvent interface {
+	apply(m *Monitor)
+}
+
+type actualPriceEvent struct {
+	price float64
+}
+
+type depthEvent struct {
+	depth *structs.DepthInfo
+}
+
+type tradesEvent struct {
+	trades *structs.TradeInfo
+}
+
+type settingsEvent struct {
+	settings *mongoStructs.Settings
+}
+
+// lastOrderEvent carries the last stored order of the symbol, order is nil when there is none
+type lastOrderEvent struct {
+	order     *models.Order
+	fetchedAt time.Time
+}
+
+type ordersListEvent struct {
+	sessionID string
+	list      ordersList
+	fetchedAt time.Time
+}
+
+// orderSentEvent reports an order accepted by the exchange
+type orderSentEvent struct {
+	id string
+}
+
+// orderDeletedEvent reports an order removed from the repository
+type orderDeletedEvent struct {
+	id string
+}
+
+// taskDoneEvent carries the result of the step task, done is nil when the task changes nothing
+type taskDoneEvent struct {
+	done func(m *Monitor)
+}
+
+func (e actualPriceEvent) apply(m *Monitor) {
+	m.actualPrice = e.price
+}
+
+func (e depthEvent) apply(m *Monitor) {
+	m.depth = e.depth
+
+	if e.depth.DeltaAsks > m.status.MaxAsksDelta {
+		m.status.SetMaxAsksDelta(e.depth.DeltaAsks)
+	}
+
+	if e.depth.DeltaBids > m.status.MaxBidsDelta {
+		m.status.SetMaxBidsDelta(e.depth.DeltaBids)
+	}
+}
+
+func (e tradesEvent) apply(m *Monitor) {
+	m.trades = e.trades
+}
+
+func (e settingsEvent) apply(m *Monitor) {
+	m.settings = e.settings
+}
+
+func (e lastOrderEvent) apply(m *Monitor) {
+	// the session was started by the loop after the order was read
+	if e.fetchedAt.Before(m.sessionStartedAt) {
+		return
+	}
+
+	m.lastOrder = e.order
+	m.noLastOrder = e.order == nil
+
+	if e.order != nil {
+		m.status.SetSessionID(e.order.SessionID)
+	}
+}
+
+func (e ordersListEvent) apply(m *Monitor) {
+	// the list is of another session or the orders were changed by the loop after it was read
+	if e.sessionID != m.status.SessionID || e.fetchedAt.Before(m.ordersChangedAt) {
+		return
+	}
+
+	m.ordersList = e.list
+	m.ordersListLoaded = true
+}
+
+func (e orderSentEvent) apply(m *Monitor) {
+	if o := m.ordersList.find(e.id); o != nil && o.Status == OrderStatusInProgress {
+		o.Status = OrderStatusNew
+	}
+
+	m.ordersChangedAt = time.Now()
+}
+
+func (e orderDeletedEvent) apply(m *Monitor) {
+	for i, o := range m.ordersList {
+		if o != nil && o.ID == e.id {
+			m.ordersList[i] = nil
+		}
+	}
+
+	m.ordersChangedAt = time.Now()
+}
+
+func (e taskDoneEvent) apply(m *Monitor) {
+	m.busy = false
+
+	if e.done != nil {
+		e.done(m)
+	}
+
+	// the signals received while the task was running see its result
+	deferred := m.deferred
+	m.deferred = nil
+
+	for _, d := range deferred {
+		d.apply(m)
+	}
+}
+
+// monitorSnapshot is an immutable copy of the monitor state for the goroutines out of the event loop
+type monitorSnapshot struct {
+	actualPrice float64
+	depth       *structs.DepthInfo
+	trades      *structs.TradeInfo
+	settings    *mongoStructs.Settings
+	status      structs.Status
+	ordersList  ordersList
+}
+
+func (s *monitorSnapshot) liquidating() bool {
+	return s.settings != nil && isLiquidationStatus(s.settings.Status)
+}
+
+// Monitor trades one symbol. The state is owned by the event loop, the pollers send it
+// typed events and read the snapshot published after every change.
+type Monitor struct {
+	actualPrice float64
+	depth       *structs.DepthInfo
+	trades      *structs.TradeInfo
+	settings    *mongoStructs.Settings
+	status      *structs.Status
+
+	lastOrder *models.Order
+	// noLastOrder is set when the symbol has no order stored
+	noLastOrder bool
+
+	ordersList       ordersList
+	ordersListLoaded bool
+
+	// the events read before the loop changed the session or its orders are stale
+	sessionStartedAt time.Time
+	ordersChangedAt  time.Time
+
+	liquidation  *liquidationState
+	liquidatedAt time.Time
+
+	// blockedBy is the reason the schedule does not allow a new session
+	blockedBy string
+
+	// canceledEntry is the spot entry order canceled on timeout
+	canceledEntry string
+
+	// grid is the grid of the symbol, gridLoaded is set once the stored grid is read
+	grid       *gridState
+	gridLoaded bool
+
+	// busy is set while the step task is running, the signals received meanwhile are deferred
+	busy     bool
+	deferred []monitorEvent
+	tasks    sync.WaitGroup
+
+	events   chan monitorEvent
+	snapshot atomic.Value
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	drain   <-chan struct{}
+	err     error
+	errOnce sync.Once
+}
+
+func newMonitor(ctx context.Context, drain <-chan struct{}) *Monitor {
+	ctx, cancel := context.WithCancel(ctx)
+
+	m := &Monitor{
+		ctx:    ctx,
+		cancel: cancel,
+		drain:  drain,
+		events: make(chan monitorEvent),
+		status: &structs.Status{
+			OrderTry:  1,
+			SessionID: uuid.New().String(),
+			Mode:      "middle",
+		},
+	}
+
+	m.publish()
+
+	return m
+}
+
+// publish stores the snapshot of the current state, it is called by the event loop
+func (m *Monitor) publish() {
+	m.snapshot.Store(&monitorSnapshot{
+		actualPrice: m.actualPrice,
+		depth:       m.depth,
+		trades:      m.trades,
+		settings:    m.settings,
+		status:      *m.status,
+		ordersList:  m.ordersList.clone(),
+	})
+}
+
+// Snapshot returns the last published state, it is safe for concurrent use
+func (m *Monitor) Snapshot() *monitorSnapshot {
+	return m.snapshot.Load().(*monitorSnapshot)
+}
+
+// send passes the event to the event loop, it reports false when the monitor is stopped
+func (m *Monitor) send(e monitorEvent) bool {
+	select {
+	case m.events <- e:
+		return true
+	case <-m.ctx.Done():
+		return false
+	}
+}
+
+// sleep waits d, it reports false when the monitor is stopped
+func (m *Monitor) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-m.ctx.Done():
+		return false
+	}
+}
+
+// draining reports whether the monitor must not open new sessions
+func (m *Monitor) draining() bool {
+	select {
+	case <-m.drain:
+		return true
+	default:
+		return false
+	}
+}
+
+// liquidating reports whether the symbol is in a liquidation status
+func (m *Monitor) liquidating() bool {
+	return m.settings != nil && isLiquidationStatus(m.settings.Status)
+}
+
+// newSessionsAllowed reports whether a new session can be opened, the sessions wait for the grid to stop
+func (m *Monitor) newSessionsAllowed() bool {
+	return m.settings != nil && m.settings.Status == mongoStructs.Enabled.ToString() && !m.draining() &&
+		m.settings.GetStrategy() == mongoStructs.StrategySession && m.gridLoaded && m.grid == nil
+}
+
+// sessionClosed reports whether the current session has no order left on the exchange
+func (m *Monitor) sessionClosed() bool {
+	if !m.ordersListLoaded {
+		return false
+	}
+
+	if !m.ordersList.Has(OrderTypeLimit) {
+		return true
+	}
+
+	limit := m.ordersList.Get(OrderTypeLimit)
+
+	if limit.Status != OrderStatusFilled {
+		return isFinalOrderStatus(limit.Status)
+	}
+
+	return m.ordersList.Has(OrderTypeCurrentTakeProfit) && isFinalOrderStatus(m.ordersList.Get(OrderTypeCurrentTakeProfit).Status) &&
+		m.ordersList.Has(OrderTypeCurrentStopLoss) && isFinalOrderStatus(m.ordersList.Get(OrderTypeCurrentStopLoss).Status)
+}
+
+// isFinalOrderStatus reports whether the order is not on the exchange anymore
+func isFinalOrderStatus(status string) bool {
+	switch status {
+	case OrderStatusFilled, OrderStatusCanceled, OrderStatusExpired, OrderStatusError:
+		return true
+	}
+	return false
+}
+
+// crash stops the monitor with the first error
+func (m *Monitor) crash(err error) {
+	m.errOnce.Do(func() {
+		m.err = err
+	})
+	m.cancel()
+}
+
+// goSafe runs f in a goroutine and crashes the monitor if f panics
+func (m *Monitor) goSafe(f func()) {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				m.crash(fmt.Errorf("panic: %v\n%s", r, debug.Stack()))
+			}
+		}()
+
+		f()
+	}()
+}
+
+// async runs the I/O of the step out of the event loop. The loop goes on applying the events and takes
+// no step until the task is done. work must not touch the monitor state, it returns the change applied
+// by the loop.
+func (m *Monitor) async(work func() func(m *Monitor)) {
+	m.busy = true
+	m.tasks.Add(1)
+
+	m.goSafe(func() {
+		defer m.tasks.Done()
+
+		m.send(taskDoneEvent{done: work()})
+	})
+}
+
+// run is the event loop, it is the only goroutine changing the monitor state
+func (m *Monitor) run(u *orderUseCase, symbol string) error {
+	ticker := time.NewTicker(monitorStepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return m.err
+		case e := <-m.events:
+			e.apply(m)
+		case <-ticker.C:
+			// the drain and the step wait for the result of the running task
+			if m.busy {
+				continue
+			}
+
+			if m.draining() && m.sessionClosed() && u.gridDrained(m, symbol) {
+				u.logRus.Debugf("Monitoring [%s %s] drained", u.market, symbol)
+
+				return nil
+			}
+
+			u.step(m, symbol)
+		}
+
+		m.publish()
+	}
+}
+
+func (m *Monitor) UpdateCreateOrder(u *orderUseCase) {
+	for m.sleep(chkTime) {
+		s := m.Snapshot()
+
+		if s.liquidating() {
+			continue
+		}
+
+		for id, o := range s.ordersList {
+			if o == nil || o.Status != OrderStatusInProgress {
+				continue
+			}
+
+			if !m.createOrder(u, s.ordersList, id) {
+				break
+			}
+		}
+	}
+}
+
+// createOrder sends the stored order of the list to the exchange, it reports whether the order is accepted.
+// On the spot the take profit and the stop loss are sent together as an OCO.
+func (m *Monitor) createOrder(u *orderUseCase, list ordersList, id int) bool {
+	o := list[id]
+
+	if o.Type != ordersListTypes[id] {
+		u.logRus.Panicf("error order type\n id: %d\norder: %+v", id, o)
+	}
+
+	sent := []*models.Order{o}
+
+	var err error
+
+	switch {
+	case u.market == mongoStructs.MarketSpot && id == OrderTypeTakeProfitID:
+		stopLoss := list[OrderTypeStopLossID]
+		if stopLoss == nil || stopLoss.Status != OrderStatusInProgress {
+			u.logRus.
+				WithField("func", "createSpotOCOOrder").
+				WithField("orderID", o.ID).
+				Debug("stop loss is not stored")
+
+			return false
+		}
+
+		_, err = u.createSpotOCOOrder(o, stopLoss)
+
+		sent = append(sent, stopLoss)
+	case u.market == mongoStructs.MarketSpot && id == OrderTypeStopLossID:
+		// it is sent with the take profit
+		return true
+	case u.market == mongoStructs.MarketSpot:
+		err = u.createSpotLimitOrder(o)
+	default:
+		err = u.submitFeaturesOrder(o)
+	}
+
+	if err != nil {
+		if err == controllers.ErrUnknownOrderSent && id == OrderTypeLimitID {
+			if err := u.orderRepo.Delete(o.ID, orderChange(models.OrderSourcePoll, []byte(err.Error()))); err != nil {
+				u.logRus.
+					WithField("func", "Delete").
+					WithField("type", o.Type).
+					WithField("status", o.Status).
+					WithField("orderID", o.ID).
+					Debug(err)
+
+				return false
+			}
+
+			m.send(orderDeletedEvent{id: o.ID})
+		}
+
+		u.logRus.
+			WithField("func", "createOrder").
+			WithField("market", u.market).
+			WithField("type", o.Type).
+			WithField("status", o.Status).
+			WithField("orderID", o.ID).
+			Debug(err)
+
+		return false
+	}
+
+	for _, o := range sent {
+		if err := u.orderRepo.SetStatus(o.ID, OrderStatusNew, orderChange(models.OrderSourcePoll, nil)); err != nil {
+			u.logRus.
+				WithField("func", "SetStatus").
+				WithField("type", o.Type).
+				WithField("status", o.Status).
+				WithField("orderID", o.ID).
+				Debug(err)
+		}
+
+		if !m.send(orderSentEvent{id: o.ID}) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Monitor) UpdateOrderStatus(u *orderUseCase) {
+	for m.sleep(chkTime) {
+		list := m.Snapshot().ordersList
+
+		if u.market == mongoStructs.MarketSpot {
+			u.syncSpotOrders(list)
+
+			continue
+		}
+
+		for _, o := range list {
+			if o == nil {
+				continue
+			}
+
+			u.syncOrderStatus(o)
+		}
+	}
+}
+
+// syncOrderStatus writes the exchange state of the order to the repository
+func (u *orderUseCase) syncOrderStatus(o *models.Order) {
+	order, err := u.getFeatureOrderInfo(o.ID, o.Symbol)
+	if err != nil {
+		u.logRus.
+			WithField("orderId", o.ID).
+			WithField("func", "getFeatureOrderInfo").Debug(err)
+
+		return
+	}
+
+	payload, err := json.Marshal(order)
+	if err != nil {
+		u.logRus.WithField("func", "Marshal").Debug(err)
+
+		return
+	}
+
+	change := orderChange(models.OrderSourcePoll, payload)
+
+	if o.OrderID != order.OrderId {
+		if err := u.orderRepo.SetOrderID(order.ClientOrderId, order.OrderId, change); err != nil {
+			u.logRus.WithField("func", "SetOrderID").Debug(err)
+
+			return
+		}
+	}
+
+	if o.Status != order.Status {
+		if err := u.orderRepo.SetStatus(order.ClientOrderId, order.Status, change); err != nil {
+			u.logRus.WithField("func", "SetStatus").Debug(err)
+
+			return
+		}
+	}
+
+	avgPrice, err := strconv.ParseFloat(order.AvgPrice, 64)
+	if err != nil {
+		u.logRus.WithField("func", "ParseFloat").Debug(err)
+
+		return
+	}
+
+	if err := u.orderRepo.SetActualPrice(order.ClientOrderId, avgPrice, change); err != nil {
+		u.logRus.WithField("func", "SetActualPrice").Debug(err)
+	}
+}
+
+// flushOrders writes the last exchange state of the session orders when the monitor stops,
+// the orders which are not sent yet are left IN PROGRESS
+func (u *orderUseCase) flushOrders(m *Monitor) {
+	if u.market == mongoStructs.MarketSpot {
+		u.syncSpotOrders(m.ordersList)
+
+		return
+	}
+
+	for _, o := range m.ordersList {
+		if o == nil || o.Status == OrderStatusInProgress {
+			continue
+		}
+
+		u.syncOrderStatus(o)
+	}
+}
+
+func (m *Monitor) UpdateOrdersList(u *orderUseCase) {
+	for m.sleep(chkTime) {
+		var out ordersList
+
+		sessionID := m.Snapshot().status.SessionID
+		if sessionID == "" {
+			u.logRus.Debug("SessionID is nil")
+
+			continue
+		}
+
+		fetchedAt := time.Now()
+
+		list, err := u.orderRepo.GetBySessionID(sessionID)
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			continue
+		}
+
+		for _, o := range list {
+			switch o.Type {
+			case OrderTypeLimit:
+				order := o
+				out.SetLimit(&order)
+			case OrderTypeCurrentTakeProfit:
+				order := o
+				out.SetTakeProfit(&order)
+			case OrderTypeCurrentStopLoss:
+				order := o
+				out.SetStopLoss(&order)
+			}
+		}
+
+		m.send(ordersListEvent{sessionID: sessionID, list: out, fetchedAt: fetchedAt})
+	}
+}
+
+// UpdateSettings loads the settings and applies the changes pushed by the settings watcher, the symbol
+// of the invalid settings is disabled
+func (m *Monitor) UpdateSettings(u *orderUseCase, symbol string) {
+	// the monitor is subscribed before the load, no change is missed between them
+	updates, unsubscribe := u.settingsWatcher.Subscribe(symbol)
+	defer unsubscribe()
+
+	var settings *mongoStructs.Settings
+
+	for settings == nil && m.sleep(chkTime) {
+		loaded, err := u.settingsRepo.Load(symbol)
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			continue
+		}
+
+		if err := u.checkSettings(loaded); err != nil {
+			// the monitor does not trade the invalid settings, it is drained by the supervisor
+			if loaded.Status != mongoStructs.Disabled.ToString() {
+				u.disableSymbol(loaded, mongoStructs.SettingsAuthorMonitor, err)
+			}
+
+			return
+		}
+
+		settings = loaded
+		m.send(settingsEvent{settings: settings})
+	}
+
+	if settings == nil {
+		return
+	}
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case update := <-updates:
+			// the change pushed before the load is older than the loaded settings
+			if update.Version < settings.Version {
+				continue
+			}
+
+			// the monitor keeps the last valid settings
+			if err := u.checkSettings(update); err != nil {
+				if update.Status != mongoStructs.Disabled.ToString() {
+					u.disableSymbol(update, mongoStructs.SettingsAuthorMonitor, err)
+				}
+
+				continue
+			}
+
+			settings = update
+			m.send(settingsEvent{settings: settings})
+		}
+	}
+}
+
+func (m *Monitor) UpdateLastOrder(u *orderUseCase, symbol string) {
+	for m.sleep(chkTime) {
+		fetchedAt := time.Now()
+
+		order, err := u.orderRepo.GetLast(symbol)
+		if err != nil {
+			if err != sql.ErrNoRows {
+				u.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+
+				continue
+			}
+
+			order = nil
+		}
+
+		m.send(lastOrderEvent{order: order, fetchedAt: fetchedAt})
+	}
+}
+
+func (m *Monitor) UpdateActualPrice(u *orderUseCase, symbol string) {
+	for m.sleep(chkTime) {
+		var price float64
+		var err error
+
+		switch u.market {
+		case mongoStructs.MarketSpot:
+			price, err = u.getSpotPrice(symbol)
+		default:
+			price, err = u.priceUseCase.featuresGetPrice(symbol)
+		}
+
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			continue
+		}
+
+		m.send(actualPriceEvent{price: price})
+	}
+}
+
+func (m *Monitor) UpdateDepth(u *orderUseCase, symbol string) {
+	for m.sleep(monitorDepthTime) {
+		depth, err := u.priceUseCase.GetDepthInfo(symbol, orderFlowConfig(m.Snapshot().settings))
+		if err != nil {
+			u.logRus.Errorf("Depth [%s] %+v", symbol, err)
+
+			continue
+		}
+
+		m.send(depthEvent{depth: depth})
+	}
+}
+
+func (m *Monitor) UpdateTrades(u *orderUseCase, symbol string) {
+	for m.sleep(chkTime) {
+		trades, err := u.priceUseCase.GetTradeInfo(symbol, orderFlowConfig(m.Snapshot().settings))
+		if err != nil {
+			u.logRus.Errorf("Trades [%s] %+v", symbol, err)
+
+			continue
+		}
+
+		m.send(tradesEvent{trades: trades})
+	}
+}
+
+func (m *Monitor) LogStatus(u *orderUseCase, symbol string) {
+	for m.sleep(monitorLogTime) {
+		s := m.Snapshot()
+
+		if s.depth == nil || s.trades == nil {
+			continue
+		}
+
+		u.logRus.Printf("LastTopLevel [%s] %.2f", symbol, s.status.LastTopLevel)
+		u.logRus.Printf("LastBottomLevel [%s] %.2f", symbol, s.status.LastBottomLevel)
+
+		u.logRus.Printf("MaxBidsDelta [%s] %.2f", symbol, s.status.MaxBidsDelta)
+		u.logRus.Printf("MaxAsksDelta [%s] %.2f", symbol, s.status.MaxAsksDelta)
+
+		u.logRus.Printf("DeltaBids [%s] %.2f", symbol, s.depth.DeltaBids)
+		u.logRus.Printf("DeltaAsks [%s] %.2f", symbol, s.depth.DeltaAsks)
+
+		u.logRus.Printf("DeltaBuyer [%s] %.2f", symbol, s.trades.DeltaBuyer)
+		u.logRus.Printf("DeltaSeller [%s] %.2f", symbol, s.trades.DeltaSeller)
+
+		u.logRus.Printf("TopImbalance [%s] %.2f", symbol, s.depth.Top.Imbalance)
+		u.logRus.Printf("NearMidImbalance [%s] %.2f", symbol, s.depth.NearMid.Imbalance)
+		u.logRus.Printf("CVD [%s] %.3f", symbol, s.trades.CVD)
+	}
+}
+
+// Monitoring trades the symbol on the market of the use case until ctx is done. After drain is closed
+// no new session is opened and it returns once the current session is closed. The spot sessions are
+// opened on the futures depth and trades, the spot price is used for the orders.
+func (u *orderUseCase) Monitoring(ctx context.Context, symbol string, drain <-chan struct{}) error {
+	u.logRus.Debugf("Start Monitoring [%s %s]", u.market, symbol)
+
+	m := newMonitor(ctx, drain)
+
+	u.registerMonitor(symbol, m)
+	defer u.unregisterMonitor(symbol, m)
+
+	defer u.flushOrders(m)
+	defer m.tasks.Wait()
+	defer m.cancel()
+
+	m.goSafe(func() { m.UpdateDepth(u, symbol) })
+	m.goSafe(func() { m.UpdateTrades(u, symbol) })
+
+	m.goSafe(func() { m.UpdateSettings(u, symbol) })
+	m.goSafe(func() { m.UpdateActualPrice(u, symbol) })
+
+	m.goSafe(func() { m.UpdateLastOrder(u, symbol) })
+	m.goSafe(func() { m.LoadGrid(u, symbol) })
+	m.goSafe(func() { m.UpdateOrdersList(u) })
+	m.goSafe(func() { m.UpdateOrderStatus(u) })
+	m.goSafe(func() { m.UpdateCreateOrder(u) })
+
+	if u.market == mongoStructs.MarketFeatures {
+		m.goSafe(func() { m.UpdateFills(u, symbol) })
+	}
+
+	m.goSafe(func() { m.LogStatus(u, symbol) })
+
+	return m.run(u, symbol)
+}
+
+// step takes the trading decisions on the current state, it is called by the event loop.
+// The I/O of a decision runs in the step task, see async.
+func (u *orderUseCase) step(m *Monitor, symbol string) {
+	if !m.liquidating() && u.gridStep(m, symbol) {
+		return
+	}
+
+	if u.market == mongoStructs.MarketSpot {
+		u.spotStep(m, symbol)
+
+		return
+	}
+
+	if m.liquidating() {
+		if time.Since(m.liquidatedAt) >= liquidationInterval {
+			u.liquidate(m, symbol)
+
+			m.liquidatedAt = time.Now()
+		}
+
+		return
+	}
+
+	if m.settings == nil || m.depth == nil || m.trades == nil {
+		return
+	}
+
+	if m.noLastOrder {
+		if m.actualPrice != 0 && m.newSessionsAllowed() {
+			m.status.SetQuantity(m.settings.Step)
+
+			u.newSession(m, symbol, m.actualPrice, nil)
+		}
+
+		return
+	}
+
+	if m.ordersList.IsNil() {
+		return
+	}
+
+	if m.status.Quantity == 0 {
+		m.status.SetQuantity(m.settings.Step)
+	}
+
+	if chkCreateOrders(m.ordersList) {
+		u.storeExitOrders(m, symbol)
+
+		return
+	}
+
+	if chkTakeProfitCancel(m.ordersList) {
+		u.cancelExitOrder(m, m.ordersList.Get(OrderTypeCurrentTakeProfit).OrderID, symbol)
+
+		return
+	}
+
+	if chkStopLossCancel(m.ordersList) {
+		u.cancelExitOrder(m, m.ordersList.Get(OrderTypeCurrentStopLoss).OrderID, symbol)
+
+		return
+	}
+
+	if chkCreateLimitOrderTakeProfit(m.ordersList) && m.newSessionsAllowed() {
+		u.newSession(m, symbol, m.ordersList.Get(OrderTypeCurrentTakeProfit).Price, nil)
+
+		return
+	}
+
+	if chkCreateLimitOrderStopLoss(m.ordersList) && m.newSessionsAllowed() {
+		settings := m.settings
+
+		u.newSession(m, symbol, m.ordersList.Get(OrderTypeCurrentStopLoss).Price, func() {
+			if err := u.settingsRepo.UpdateDepthLimit(settings.ID, settings.DepthLimit, mongoStructs.SettingsChange{Author: mongoStructs.SettingsAuthorMonitor, Reason: "new session after the stop loss"}); err != nil {
+				u.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+			}
+		})
+	}
+}
+
+// storeExitOrders stores the take profit and the stop loss of the filled entry out of the event loop
+func (u *orderUseCase) storeExitOrders(m *Monitor, symbol string) {
+	// the task works on the copies, the loop changes the orders and the status meanwhile
+	limitOrder := *m.ordersList.Get(OrderTypeLimit)
+	settings, depth, trades := m.settings, m.depth, m.trades
+	status := *m.status
+
+	m.async(func() func(m *Monitor) {
+		pricePlan, _ := u.fillPricePlan(OrderTypeLimit, symbol, limitOrder.Price, settings, &status, depth, trades)
+
+		takeProfitOrder, err := u.storeFeatureTakeProfitOrder(pricePlan, &limitOrder, u.constructTakeProfitOrder(pricePlan, settings))
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		stopLossOrder, err := u.storeFeatureStopLossOrder(pricePlan, &limitOrder, u.constructStopLossOrder(pricePlan, settings), depth)
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		return func(m *Monitor) {
+			m.setLevels(status)
+			m.setExitOrders(limitOrder.ID, takeProfitOrder, stopLossOrder)
+		}
+	})
+}
+
+// cancelExitOrder cancels the exit left by the closed position out of the event loop
+func (u *orderUseCase) cancelExitOrder(m *Monitor, orderID int64, symbol string) {
+	m.async(func() func(m *Monitor) {
+		if _, err := u.cancelFeatureOrder(orderID, symbol, models.OrderSourcePoll); err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		return nil
+	})
+}
+
+// newSession stores the limit order of a new session out of the event loop, the session is started
+// by the loop once the order is stored. then is called by the task after the order is stored.
+func (u *orderUseCase) newSession(m *Monitor, symbol string, price float64, then func()) {
+	if !u.scheduleAllowed(m, symbol) {
+		return
+	}
+
+	settings, depth, trades := m.settings, m.depth, m.trades
+	status := *m.status
+
+	m.async(func() func(m *Monitor) {
+		var pricePlan *structs.PricePlan
+		var err error
+
+		switch u.market {
+		case mongoStructs.MarketSpot:
+			pricePlan, err = u.fillSpotPricePlan(symbol, price, settings, &status, depth)
+		default:
+			pricePlan, err = u.fillPricePlan(OrderTypeLimit, symbol, price, settings, &status, depth, trades)
+		}
+
+		if err != nil {
+			return func(m *Monitor) {
+				m.setLevels(status)
+			}
+		}
+
+		pricePlan.Status.NewSessionID()
+
+		limitOrder, err := u.storeFeaturesLimitOrder(pricePlan, models.OrderSourcePoll)
+		if err != nil {
+			u.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+
+			return func(m *Monitor) {
+				m.setLevels(status)
+			}
+		}
+
+		if then != nil {
+			then()
+		}
+
+		return func(m *Monitor) {
+			m.setLevels(status)
+			m.status.SetSessionID(status.SessionID)
+			m.startSession(limitOrder)
+		}
+	})
+}
+
+// setLevels takes the levels set by the price plan of the task
+func (m *Monitor) setLevels(status structs.Status) {
+	m.status.
+		SetTopLevel(status.LastTopLevel).
+		SetBottomLevel(status.LastBottomLevel)
+}
+
+// setExitOrders adds the stored exits to the session, they are dropped when the entry is not current anymore
+func (m *Monitor) setExitOrders(limitID string, takeProfit, stopLoss *models.Order) {
+	if limit := m.ordersList.Get(OrderTypeLimit); limit == nil || limit.ID != limitID {
+		return
+	}
+
+	m.ordersList.SetTakeProfit(takeProfit)
+	m.ordersList.SetStopLoss(stopLoss)
+
+	m.ordersChangedAt = time.Now()
+}
+
+// startSession makes the stored limit order the current session
+func (m *Monitor) startSession(limitOrder *models.Order) {
+	// the order is sent once it is read back from the repository
+	limitOrder.Status = OrderStatusNotFound
+
+	m.ordersList = ordersList{}
+	m.ordersList.SetLimit(limitOrder)
+
+	m.lastOrder = limitOrder
+	m.noLastOrder = false
+
+	m.sessionStartedAt = time.Now()
+	m.ordersChangedAt = m.sessionStartedAt
+}
+
+// scheduleAllowed reports whether the schedule allows new entries, the change of the reason is logged
+func (u *orderUseCase) scheduleAllowed(m *Monitor, symbol string) bool {
+	allowed, reason := u.tradingAllowed(m.settings, time.Now())
+	if reason != m.blockedBy {
+		m.blockedBy = reason
+
+		u.logRus.
+			WithField("symbol", symbol).
+			WithField("allowed", allowed).
+			Infof("Schedule [%s] %s", symbol, reason)
+	}
+
+	return allowed
+}
+
+func chkCreateOrders(o ordersList) bool {
+	if o.Has(OrderTypeLimit) && o.Get(OrderTypeLimit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentTakeProfit) == false && o.Get(OrderTypeCurrentTakeProfit) == nil &&
+		o.Has(OrderTypeCurrentStopLoss) == false && o.Get(OrderTypeCurrentStopLoss) == nil {
+
+		return true
+	}
+	return false
+}
+
+func chkTakeProfitCancel(o ordersList) bool {
+	if o.Has(OrderTypeLimit) && o.Get(OrderTypeLimit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentTakeProfit) && o.Get(OrderTypeCurrentTakeProfit).Status == OrderStatusNew &&
+		o.Has(OrderTypeCurrentStopLoss) && o.Get(OrderTypeCurrentStopLoss).Status == OrderStatusFilled {
+
+		return true
+	}
+
+	return false
+}
+
+func chkStopLossCancel(o ordersList) bool {
+	if o.Has(OrderTypeLimit) && o.Get(OrderTypeLimit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentTakeProfit) && o.Get(OrderTypeCurrentTakeProfit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentStopLoss) && o.Get(OrderTypeCurrentStopLoss).Status == OrderStatusNew {
+
+		return true
+	}
+	return false
+}
+
+func chkCreateLimitOrderTakeProfit(o ordersList) bool {
+	if o.Has(OrderTypeLimit) && o.Get(OrderTypeLimit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentStopLoss) && (o.Get(OrderTypeCurrentStopLoss).Status == OrderStatusCanceled || o.Get(OrderTypeCurrentStopLoss).Status == OrderStatusExpired) &&
+		o.Has(OrderTypeCurrentTakeProfit) && o.Get(OrderTypeCurrentTakeProfit).Status == OrderStatusFilled {
+
+		return true
+	}
+	return false
+}
+
+func chkCreateLimitOrderStopLoss(o ordersList) bool {
+	if o.Has(OrderTypeLimit) && o.Get(OrderTypeLimit).Status == OrderStatusFilled &&
+		o.Has(OrderTypeCurrentTakeProfit) && (o.Get(OrderTypeCurrentTakeProfit).Status == OrderStatusCanceled || o.Get(OrderTypeCurrentTakeProfit).Status == OrderStatusExpired) &&
+		o.Has(OrderTypeCurrentStopLoss) && o.Get(OrderTypeCurrentStopLoss).Status == OrderStatusFilled {
+
+		return true
+	}
+	return false
+}
@@ -0,0 +1,1377 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	"binance/internal/repository/mongo/structs"
+	"binance/internal/repository/postgres"
+	orderStructs "binance/internal/usecasees/structs"
+	"binance/models"
+
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testOrderStore backs the OrderRepo mock with the orders stored by the monitor and their events
+type testOrderStore struct {
+	mu     sync.Mutex
+	orders []models.Order
+	events []models.OrderEvent
+
+	// gate holds the stored orders until it is closed, held is sent the first held one
+	gate chan struct{}
+	held chan struct{}
+}
+
+func (s *testOrderStore) store(m *models.Order, c postgres.OrderChange) error {
+	if s.gate != nil {
+		select {
+		case s.held <- struct{}{}:
+		default:
+		}
+
+		<-s.gate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := *m
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = time.Now()
+	}
+
+	s.orders = append(s.orders, o)
+	s.appendEvent(&o, models.OrderEventCreated, c)
+
+	return nil
+}
+
+// appendEvent records the event of the order as the repository does, it is called with the lock held
+func (s *testOrderStore) appendEvent(o *models.Order, event string, c postgres.OrderChange) {
+	s.events = append(s.events, models.OrderEvent{
+		OrderRef:  o.ID,
+		SessionID: o.SessionID,
+		Symbol:    o.Symbol,
+		Event:     event,
+		Source:    c.Source,
+		Status:    o.Status,
+		Price:     o.Price,
+		OrderID:   o.OrderID,
+		Payload:   c.Payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// record appends the event with the stored state of the order, the order which is not stored has the state of o
+func (s *testOrderStore) record(o *models.Order, event string, c postgres.OrderChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.orders {
+		if s.orders[i].ID == o.ID {
+			s.appendEvent(&s.orders[i], event, c)
+
+			return nil
+		}
+	}
+
+	s.appendEvent(o, event, c)
+
+	return nil
+}
+
+func (s *testOrderStore) timeline(orderID string) []models.OrderEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []models.OrderEvent
+	for _, e := range s.events {
+		if e.OrderRef == orderID {
+			out = append(out, e)
+		}
+	}
+
+	return out
+}
+
+func (s *testOrderStore) getLast(symbol string) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.orders) - 1; i >= 0; i-- {
+		if s.orders[i].Symbol == symbol && s.orders[i].Type == OrderTypeLimit {
+			o := s.orders[i]
+
+			return &o, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (s *testOrderStore) getBySessionID(sessionID string) []models.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []models.Order
+	for _, o := range s.orders {
+		if o.SessionID == sessionID {
+			out = append(out, o)
+		}
+	}
+
+	return out
+}
+
+func (s *testOrderStore) getByID(id string) (*models.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range s.orders {
+		if o.ID == id {
+			return &o, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+// update changes the order, the event is recorded when the order is changed
+func (s *testOrderStore) update(id string, event string, c postgres.OrderChange, f func(o *models.Order)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.orders {
+		if s.orders[i].ID != id {
+			continue
+		}
+
+		before := s.orders[i]
+		f(&s.orders[i])
+
+		if before != s.orders[i] {
+			s.appendEvent(&s.orders[i], event, c)
+		}
+	}
+
+	return nil
+}
+
+// claim counts the attempts of the pending order as the repository does
+func (s *testOrderStore) claim(id string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.orders {
+		o := &s.orders[i]
+
+		if o.ID != id || o.Status != OrderStatusInProgress || (o.ClaimedAt != nil && time.Since(*o.ClaimedAt) < ttl) {
+			continue
+		}
+
+		now := time.Now()
+
+		o.SubmitAttempts++
+		o.ClaimedAt = &now
+
+		return o.SubmitAttempts, nil
+	}
+
+	return 0, postgres.ErrNotClaimed
+}
+
+func (s *testOrderStore) release(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.orders {
+		if s.orders[i].ID == id && s.orders[i].Status == OrderStatusInProgress {
+			s.orders[i].ClaimedAt = nil
+		}
+	}
+
+	return nil
+}
+
+func (s *testOrderStore) list() []models.Order {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.Order(nil), s.orders...)
+}
+
+// testGridStore backs the GridRepo mock with the grids stored by the monitor
+type testGridStore struct {
+	mu     sync.Mutex
+	grids  []models.Grid
+	levels map[string][]models.GridLevel
+}
+
+func (s *testGridStore) store(m *models.Grid, levels []models.GridLevel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.grids = append(s.grids, *m)
+	s.levels[m.ID] = append([]models.GridLevel(nil), levels...)
+
+	return nil
+}
+
+func (s *testGridStore) getActive(symbol, market string) (*models.Grid, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.grids) - 1; i >= 0; i-- {
+		g := s.grids[i]
+
+		if g.Symbol == symbol && g.Market == market && g.Status != GridStatusStopped {
+			return &g, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (s *testGridStore) getLevels(gridID string) []models.GridLevel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.GridLevel(nil), s.levels[gridID]...)
+}
+
+func (s *testGridStore) setStatus(id string, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.grids {
+		if s.grids[i].ID == id {
+			s.grids[i].Status = status
+		}
+	}
+
+	return nil
+}
+
+func (s *testGridStore) closeCycle(m *models.GridLevel, profit float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.grids {
+		if s.grids[i].ID == m.GridID {
+			s.grids[i].RealizedProfit += profit
+			s.grids[i].Cycles++
+		}
+	}
+
+	return s.updateLevel(m, profit, 1)
+}
+
+func (s *testGridStore) updateLevel(m *models.GridLevel, profit float64, cycles int) error {
+	levels := s.levels[m.GridID]
+
+	for i := range levels {
+		if levels[i].Level != m.Level {
+			continue
+		}
+
+		l := *m
+		l.Profit = levels[i].Profit + profit
+		l.Cycles = levels[i].Cycles + cycles
+
+		levels[i] = l
+	}
+
+	return nil
+}
+
+func (s *testGridStore) list() []models.Grid {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.Grid(nil), s.grids...)
+}
+
+// testExchange answers the futures and the spot API, the entry orders are filled at once
+type testExchange struct {
+	mu     sync.Mutex
+	orders map[string]orderStructs.FeatureOrderResp
+	posts  int
+
+	depth []byte
+	// positions are the futures position amounts by the position side
+	positions  map[string]string
+	spotOrders map[string]orderStructs.Order
+	spotLists  map[string]*testOrderList
+	// rejects is the number of the next futures market orders rejected by the exchange
+	rejects int
+
+	// errs keeps the error of the request until the mock reads it
+	errs map[*url.URL]error
+}
+
+// response and err split one request between the two return values of the ClientCtrl mock
+func (e *testExchange) response(method string, u *url.URL, body []byte, useApiKey bool) []byte {
+	resp, err := e.send(method, u, body, useApiKey)
+
+	e.mu.Lock()
+	e.errs[u] = err
+	e.mu.Unlock()
+
+	return resp
+}
+
+func (e *testExchange) err(_ string, u *url.URL, _ []byte, _ bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	err := e.errs[u]
+	delete(e.errs, u)
+
+	return err
+}
+
+// testOrderList is the spot OCO, legs are the client order ids of the take profit and the stop loss
+type testOrderList struct {
+	id     int64
+	status string
+	legs   [2]string
+}
+
+func newTestExchange() *testExchange {
+	return &testExchange{
+		orders:     make(map[string]orderStructs.FeatureOrderResp),
+		errs:       make(map[*url.URL]error),
+		depth:      []byte(`{"bids":[["19499.9","1.0"]],"asks":[["19500.1","5.0"]]}`),
+		positions:  make(map[string]string),
+		spotOrders: make(map[string]orderStructs.Order),
+		spotLists:  make(map[string]*testOrderList),
+	}
+}
+
+func (e *testExchange) send(method string, u *url.URL, _ []byte, _ bool) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	q := u.Query()
+
+	switch {
+	case u.Path == featureSymbolPrice:
+		return []byte(`{"symbol":"BTCUSDT","price":"19500.0"}`), nil
+	case u.Path == featureDepth:
+		return e.depth, nil
+	case u.Path == featureExchangeInfo:
+		return []byte(`{"symbols":[{"symbol":"BTCUSDT","filters":[{"filterType":"PRICE_FILTER","tickSize":"0.10"},{"filterType":"LOT_SIZE","minQty":"0.001","maxQty":"1000","stepSize":"0.001"}]}]}`), nil
+	case u.Path == exchangeInfoUrlPath:
+		return []byte(`{"symbols":[{"symbol":"BTCUSDT","filters":[{"filterType":"PRICE_FILTER","tickSize":"0.01000000"},{"filterType":"LOT_SIZE","minQty":"0.00001000","maxQty":"9000.00000000","stepSize":"0.00001000"}]}]}`), nil
+	case u.Path == featureTrades:
+		return []byte(`[{"id":1,"price":"19500.0","qty":"0.5","time":1666000000000,"isBuyerMaker":false}]`), nil
+	case u.Path == featureOrder && method == "POST":
+		if _, ok := e.orders[q.Get("newClientOrderId")]; ok {
+			return nil, controllers.ErrDuplicateClientOrderID
+		}
+
+		if q.Get("type") == OrderTypeMarket && e.rejects > 0 {
+			e.rejects--
+
+			return nil, errors.New("margin is insufficient")
+		}
+
+		e.posts++
+
+		o := orderStructs.FeatureOrderResp{
+			OrderId:       int64(e.posts),
+			Symbol:        q.Get("symbol"),
+			ClientOrderId: q.Get("newClientOrderId"),
+			Type:          q.Get("type"),
+			Side:          q.Get("side"),
+			PositionSide:  q.Get("positionSide"),
+			Price:         q.Get("price"),
+			OrigQty:       q.Get("quantity"),
+			ExecutedQty:   "0",
+			Status:        OrderStatusNew,
+			AvgPrice:      "0",
+		}
+
+		if o.Type == OrderTypeMarket {
+			o.Status = OrderStatusFilled
+			o.AvgPrice = "19500.0"
+		}
+
+		e.orders[o.ClientOrderId] = o
+
+		return json.Marshal(&o)
+	case u.Path == featureOrder && method == "GET":
+		o, ok := e.orders[q.Get("origClientOrderId")]
+		if !ok {
+			return nil, controllers.ErrOrderDoesNotExist
+		}
+
+		return json.Marshal(&o)
+	case u.Path == featureOrder && method == "DELETE":
+		o, ok := e.orders[q.Get("origClientOrderId")]
+		if !ok || o.Status != OrderStatusNew {
+			return nil, controllers.ErrOrderDoesNotExist
+		}
+
+		o.Status = OrderStatusCanceled
+		e.orders[o.ClientOrderId] = o
+
+		return json.Marshal(&o)
+	case u.Path == featurePositionInfo:
+		// the exchange holds the positions of the test symbol only
+		out := make([]orderStructs.Position, 0, len(e.positions))
+		for side, amount := range e.positions {
+			out = append(out, orderStructs.Position{Symbol: testSymbol, PositionAmt: amount, PositionSide: side})
+		}
+
+		return json.Marshal(out)
+	case u.Path == featureOpenOrders:
+		out := make([]orderStructs.FeatureOrderResp, 0, len(e.orders))
+		for _, o := range e.orders {
+			if o.Status == OrderStatusNew {
+				out = append(out, o)
+			}
+		}
+
+		return json.Marshal(out)
+	case u.Path == priceUrlPath:
+		return []byte(`{"symbol":"BTCUSDT","price":"19500.00"}`), nil
+	case u.Path == orderUrlPath && method == "POST":
+		e.posts++
+
+		o := orderStructs.Order{
+			Symbol:              q.Get("symbol"),
+			OrderId:             int64(e.posts),
+			OrderListId:         -1,
+			ClientOrderId:       q.Get("newClientOrderId"),
+			Price:               q.Get("price"),
+			OrigQty:             q.Get("quantity"),
+			ExecutedQty:         q.Get("quantity"),
+			CummulativeQuoteQty: "19.5",
+			Status:              OrderStatusFilled,
+			Type:                q.Get("type"),
+			Side:                q.Get("side"),
+		}
+
+		e.spotOrders[o.ClientOrderId] = o
+
+		return json.Marshal(&orderStructs.LimitOrder{
+			Symbol:        o.Symbol,
+			OrderID:       o.OrderId,
+			OrderListID:   -1,
+			ClientOrderID: o.ClientOrderId,
+			Status:        o.Status,
+			Type:          o.Type,
+			Side:          o.Side,
+		})
+	case u.Path == orderUrlPath && method == "GET":
+		o, ok := e.spotOrders[q.Get("origClientOrderId")]
+		if !ok {
+			return nil, controllers.ErrOrderDoesNotExist
+		}
+
+		return json.Marshal(&o)
+	case u.Path == orderOCO && method == "POST":
+		e.posts++
+
+		l := &testOrderList{
+			id:     int64(e.posts),
+			status: OrderListStatusExecuting,
+			legs:   [2]string{q.Get("limitClientOrderId"), q.Get("stopClientOrderId")},
+		}
+
+		for i, id := range l.legs {
+			e.spotOrders[id] = orderStructs.Order{
+				Symbol:              q.Get("symbol"),
+				OrderId:             l.id*10 + int64(i),
+				OrderListId:         int(l.id),
+				ClientOrderId:       id,
+				OrigQty:             q.Get("quantity"),
+				ExecutedQty:         "0",
+				CummulativeQuoteQty: "0",
+				Status:              OrderStatusNew,
+				Side:                q.Get("side"),
+			}
+		}
+
+		e.spotLists[q.Get("listClientOrderId")] = l
+
+		return e.orderList(q.Get("listClientOrderId"), l)
+	case u.Path == orderList && method == "GET":
+		l, ok := e.spotLists[q.Get("origClientOrderId")]
+		if !ok {
+			return nil, errors.New("Order list does not exist.")
+		}
+
+		return e.orderList(q.Get("origClientOrderId"), l)
+	}
+
+	return nil, errors.New("unexpected request " + method + " " + u.Path)
+}
+
+func (e *testExchange) orderList(listClientOrderID string, l *testOrderList) ([]byte, error) {
+	orders := make([]map[string]interface{}, 0, len(l.legs))
+	for _, id := range l.legs {
+		orders = append(orders, map[string]interface{}{
+			"symbol":        e.spotOrders[id].Symbol,
+			"orderId":       e.spotOrders[id].OrderId,
+			"clientOrderId": id,
+		})
+	}
+
+	return json.Marshal(map[string]interface{}{
+		"orderListId":       l.id,
+		"contingencyType":   "OCO",
+		"listOrderStatus":   l.status,
+		"listClientOrderId": listClientOrderID,
+		"orders":            orders,
+	})
+}
+
+// fillSpotLeg fills the leg of the OCO, the other leg is expired
+func (e *testExchange) fillSpotLeg(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, l := range e.spotLists {
+		if l.legs[0] != id && l.legs[1] != id {
+			continue
+		}
+
+		for _, leg := range l.legs {
+			o := e.spotOrders[leg]
+
+			if leg == id {
+				o.Status = OrderStatusFilled
+				o.ExecutedQty = o.OrigQty
+				o.CummulativeQuoteQty = "19.6"
+			} else {
+				o.Status = OrderStatusExpired
+			}
+
+			e.spotOrders[leg] = o
+		}
+
+		l.status = OrderListStatusAllDone
+	}
+}
+
+// fillOrder fills the futures order by its price
+func (e *testExchange) fillOrder(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	o := e.orders[id]
+	o.Status = OrderStatusFilled
+	o.ExecutedQty = o.OrigQty
+	o.AvgPrice = o.Price
+
+	e.orders[id] = o
+}
+
+// openOrders returns the open futures orders by the price
+func (e *testExchange) openOrders() map[string]orderStructs.FeatureOrderResp {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	out := make(map[string]orderStructs.FeatureOrderResp)
+	for _, o := range e.orders {
+		if o.Status == OrderStatusNew {
+			out[o.Price] = o
+		}
+	}
+
+	return out
+}
+
+func (e *testExchange) postCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.posts
+}
+
+func (e *testExchange) spotOrder(clientOrderID string) orderStructs.Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.spotOrders[clientOrderID]
+}
+
+func Test_Monitor(t *testing.T) {
+	t.Run("session flow", func(t *testing.T) {
+		c := newMonitoring("session_flow")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+
+		u := c.initOrderUseCase()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- u.Monitoring(ctx, testSymbol, nil)
+		}()
+
+		// the entry is filled, the take profit and the stop loss are sent
+		assert.Eventually(t, func() bool {
+			list := store.list()
+			if len(list) != 3 {
+				return false
+			}
+
+			for _, o := range list {
+				switch o.Type {
+				case OrderTypeLimit:
+					if o.Status != OrderStatusFilled {
+						return false
+					}
+				default:
+					if o.Status != OrderStatusNew {
+						return false
+					}
+				}
+			}
+
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// no order is stored or sent twice
+		time.Sleep(500 * time.Millisecond)
+
+		list := store.list()
+		assert.Len(t, list, 3)
+		assert.Equal(t, 3, exchange.postCount())
+
+		// the session is tagged with the settings version it is opened by
+		for _, o := range list {
+			assert.Equal(t, list[0].SessionID, o.SessionID)
+			assert.Equal(t, 3, o.SettingsVersion)
+		}
+
+		// the timeline of the entry is recorded from the creation to the fill
+		for _, o := range list {
+			if o.Type != OrderTypeLimit {
+				continue
+			}
+
+			var events []string
+			for _, e := range store.timeline(o.ID) {
+				events = append(events, e.Event)
+
+				assert.Equal(t, models.OrderSourcePoll, e.Source)
+			}
+
+			if assert.GreaterOrEqual(t, len(events), 5) {
+				assert.Equal(t, []string{
+					models.OrderEventCreated,
+					models.OrderEventSubmitted,
+					models.OrderEventAcknowledged,
+					models.OrderEventStatus,
+					models.OrderEventStatus,
+				}, events[:5])
+			}
+		}
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+	})
+
+	t.Run("stalled step task", func(t *testing.T) {
+		c := newMonitoring("stalled_step_task")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+		store.gate = make(chan struct{})
+		store.held = make(chan struct{}, 1)
+
+		u := c.initOrderUseCase()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- u.Monitoring(ctx, testSymbol, nil)
+		}()
+
+		select {
+		case <-store.held:
+		case <-time.After(2 * time.Second):
+			t.Fatal("entry is not stored")
+		}
+
+		u.monitorsMu.Lock()
+		m := u.monitors[testSymbol]
+		u.monitorsMu.Unlock()
+
+		// the events are applied while the entry waits for the repository
+		depth := m.Snapshot().depth
+		assert.Eventually(t, func() bool {
+			return m.Snapshot().depth != depth
+		}, time.Second, 10*time.Millisecond)
+
+		// the monitor is stopped once the task returns, the entry is not sent
+		cancel()
+		close(store.gate)
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+
+		assert.Equal(t, 0, exchange.postCount())
+	})
+
+	t.Run("restart after the entry is placed", func(t *testing.T) {
+		c := newMonitoring("restart_after_placed")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+
+		// the process crashed after the exchange accepted the entry, the claim is expired
+		claimedAt := time.Now().Add(-2 * outboxClaimTTL)
+		entry := models.Order{
+			ID:             uuid.NewString(),
+			SessionID:      uuid.NewString(),
+			Symbol:         testSymbol,
+			Side:           SideSell,
+			PositionSide:   PositionSideShort,
+			Quantity:       0.003,
+			ActualPrice:    19500,
+			Price:          19499.775,
+			Status:         OrderStatusInProgress,
+			Try:            1,
+			Type:           OrderTypeLimit,
+			ExitModel:      "STATIC",
+			SafeDelta:      45,
+			TriggerDelta:   0.45,
+			SubmitAttempts: 1,
+			ClaimedAt:      &claimedAt,
+		}
+
+		assert.NoError(t, store.store(&entry, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		exchange.orders[entry.ID] = orderStructs.FeatureOrderResp{
+			OrderId:       42,
+			Symbol:        testSymbol,
+			ClientOrderId: entry.ID,
+			Type:          OrderTypeMarket,
+			Side:          SideSell,
+			PositionSide:  PositionSideShort,
+			OrigQty:       "0.003",
+			ExecutedQty:   "0.003",
+			Status:        OrderStatusFilled,
+			AvgPrice:      "19500.0",
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.initOrderUseCase().Monitoring(ctx, testSymbol, nil)
+		}()
+
+		// the entry is acknowledged by the lookup, only the exits are sent
+		assert.Eventually(t, func() bool {
+			o, err := store.getByID(entry.ID)
+
+			return err == nil && o.Status == OrderStatusFilled && len(store.list()) == 3
+		}, 5*time.Second, 10*time.Millisecond)
+
+		o, err := store.getByID(entry.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(42), o.OrderID)
+		}
+
+		for _, e := range store.timeline(entry.ID) {
+			assert.NotEqual(t, models.OrderEventSubmitted, e.Event)
+		}
+
+		assert.Eventually(t, func() bool {
+			return exchange.postCount() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+	})
+
+	t.Run("spot session flow", func(t *testing.T) {
+		c := newMonitoring("spot_session_flow")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSpotSessionFlowMocks()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.initSpotOrderUseCase().Monitoring(ctx, testSymbol, nil)
+		}()
+
+		// the entry is filled, both legs of the OCO are sent and tracked by the list
+		var takeProfit models.Order
+
+		assert.Eventually(t, func() bool {
+			list := store.list()
+			if len(list) != 3 {
+				return false
+			}
+
+			for _, o := range list {
+				switch o.Type {
+				case OrderTypeLimit:
+					if o.Status != OrderStatusFilled || o.Side != SideBuy {
+						return false
+					}
+				case OrderTypeCurrentTakeProfit:
+					takeProfit = o
+					fallthrough
+				default:
+					if o.Status != OrderStatusNew || o.OrderID == 0 || o.Side != SideSell {
+						return false
+					}
+				}
+			}
+
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// the entry and the OCO
+		assert.Equal(t, 2, exchange.postCount())
+
+		// the entry is sent with the precision of the symbol filters
+		for _, o := range store.list() {
+			if o.Type == OrderTypeLimit {
+				sent := exchange.spotOrder(o.ID)
+				assert.Regexp(t, `^\d+\.\d{2}$`, sent.Price)
+				assert.Regexp(t, `^\d+\.\d{5}$`, sent.OrigQty)
+			}
+		}
+
+		first := takeProfit.SessionID
+
+		exchange.fillSpotLeg(takeProfit.ID)
+
+		// the stop loss is expired by the exchange and the next session is opened
+		assert.Eventually(t, func() bool {
+			var closed, opened bool
+
+			for _, o := range store.list() {
+				switch {
+				case o.SessionID == first && o.Type == OrderTypeCurrentStopLoss:
+					closed = o.Status == OrderStatusExpired
+				case o.SessionID != first && o.Type == OrderTypeLimit:
+					opened = true
+				}
+			}
+
+			return closed && opened
+		}, 5*time.Second, 10*time.Millisecond)
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+	})
+
+	t.Run("grid flow", func(t *testing.T) {
+		c := newMonitoring("grid_flow")
+		c.Mocks.initBaseMocks()
+
+		grids, exchange := c.Mocks.initGridFlowMocks()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.initOrderUseCase().Monitoring(ctx, testSymbol, nil)
+		}()
+
+		// the levels are 19000, 19250, 19500, 19750 and 20000, the price is 19500
+		// so the grid buys on the two levels below it
+		assert.Eventually(t, func() bool {
+			open := exchange.openOrders()
+
+			return len(open) == 2 && open["19000.0"].Side == SideBuy && open["19250.0"].Side == SideBuy
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// the filled buy is sold a level higher
+		exchange.fillOrder(exchange.openOrders()["19250.0"].ClientOrderId)
+
+		assert.Eventually(t, func() bool {
+			sell, ok := exchange.openOrders()["19500.0"]
+
+			return ok && sell.Side == SideSell && sell.PositionSide == PositionSideLong
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// the filled sell closes the cycle and the level buys again
+		exchange.fillOrder(exchange.openOrders()["19500.0"].ClientOrderId)
+
+		assert.Eventually(t, func() bool {
+			open := exchange.openOrders()
+
+			return len(open) == 2 && open["19250.0"].Side == SideBuy
+		}, 5*time.Second, 10*time.Millisecond)
+
+		list := grids.list()
+		assert.Len(t, list, 1)
+		assert.Equal(t, GridStatusActive, list[0].Status)
+		assert.Equal(t, 1, list[0].Cycles)
+		assert.InDelta(t, 0.75, list[0].RealizedProfit, 1e-9)
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+
+		// the restarted monitor resumes the stored grid and places nothing twice
+		posts := exchange.postCount()
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		go func() {
+			done <- c.initOrderUseCase().Monitoring(ctx, testSymbol, nil)
+		}()
+
+		time.Sleep(gridSyncInterval + 500*time.Millisecond)
+
+		assert.Len(t, grids.list(), 1)
+		assert.Equal(t, posts, exchange.postCount())
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+	})
+
+	t.Run("signal flow", func(t *testing.T) {
+		c := newMonitoring("signal_flow")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSignalFlowMocks()
+
+		u := c.initOrderUseCase()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error, 1)
+		go func() {
+			done <- u.Monitoring(ctx, testSymbol, nil)
+		}()
+
+		open := func(key string) (*orderStructs.SignalResult, error) {
+			return u.Signal(context.Background(), &orderStructs.Signal{
+				Symbol:         "btcusdt",
+				Side:           "buy",
+				TakeProfit:     19800,
+				StopLoss:       19300,
+				Quantity:       0.005,
+				IdempotencyKey: key,
+			})
+		}
+
+		var created *orderStructs.SignalResult
+
+		// the signal waits for the monitor to load the symbol
+		assert.Eventually(t, func() bool {
+			var err error
+			created, err = open("alert-1")
+
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		if !assert.NotNil(t, created) {
+			cancel()
+			return
+		}
+
+		assert.False(t, created.Duplicate)
+		assert.Len(t, created.OrderIDs, 1)
+
+		// the retry returns the session of the first time
+		retry, err := open("alert-1")
+		assert.NoError(t, err)
+		assert.True(t, retry.Duplicate)
+		assert.Equal(t, created.SessionID, retry.SessionID)
+		assert.Equal(t, created.OrderIDs, retry.OrderIDs)
+
+		_, err = open("alert-2")
+		assert.ErrorIs(t, err, orderStructs.ErrSignalConflict)
+
+		// the quantity losing over the max risk at the stop loss is rejected
+		_, err = u.Signal(context.Background(), &orderStructs.Signal{
+			Symbol:         testSymbol,
+			Side:           "BUY",
+			StopLoss:       18000,
+			Quantity:       0.01,
+			IdempotencyKey: "alert-3",
+		})
+		assert.ErrorIs(t, err, orderStructs.ErrSignalRejected)
+
+		// the entry is filled by market, the exits are placed at the prices of the signal
+		assert.Eventually(t, func() bool {
+			list := store.getBySessionID(created.SessionID)
+			if len(list) != 3 {
+				return false
+			}
+
+			for _, o := range list {
+				if o.Status != OrderStatusNew && o.Type != OrderTypeLimit {
+					return false
+				}
+			}
+
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+
+		for _, o := range store.getBySessionID(created.SessionID) {
+			assert.Equal(t, 0.005, o.Quantity)
+
+			switch o.Type {
+			case OrderTypeCurrentTakeProfit:
+				assert.Equal(t, float64(19800), o.StopPrice)
+			case OrderTypeCurrentStopLoss:
+				assert.Equal(t, float64(19300), o.StopPrice)
+			}
+		}
+
+		exchange.mu.Lock()
+		exchange.positions[PositionSideLong] = "0.005"
+		exchange.mu.Unlock()
+
+		closeSignal := &orderStructs.Signal{
+			Symbol:         testSymbol,
+			Action:         orderStructs.SignalActionClose,
+			IdempotencyKey: "alert-4",
+		}
+
+		// the close order rejected by the exchange is not a duplicate, the retry sends it again
+		exchange.mu.Lock()
+		exchange.rejects = 1
+		exchange.mu.Unlock()
+
+		_, err = u.Signal(context.Background(), closeSignal)
+		assert.Error(t, err)
+
+		closed, err := u.Signal(context.Background(), closeSignal)
+		if assert.NoError(t, err) {
+			// the take profit and the stop loss are canceled, the position is closed by market
+			assert.False(t, closed.Duplicate)
+			assert.Equal(t, created.SessionID, closed.SessionID)
+			assert.Contains(t, closed.OrderIDs, signalCloseID(closeSignal))
+			assert.Len(t, exchange.openOrders(), 0)
+		}
+
+		retry, err = u.Signal(context.Background(), closeSignal)
+		assert.NoError(t, err)
+		assert.True(t, retry.Duplicate)
+
+		// the next signal opens a new session once the orders are synced
+		assert.Eventually(t, func() bool {
+			_, err := open("alert-5")
+
+			return err == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		cancel()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("monitor is not stopped")
+		}
+	})
+
+	t.Run("drain", func(t *testing.T) {
+		c := newMonitoring("drain")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+
+		drain := make(chan struct{})
+		close(drain)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- c.initOrderUseCase().Monitoring(context.Background(), testSymbol, drain)
+		}()
+
+		select {
+		case err := <-done:
+			assert.NoError(t, err)
+		case <-time.After(2 * time.Second):
+			t.Fatal("monitor is not drained")
+		}
+
+		assert.Empty(t, store.list())
+		assert.Equal(t, 0, exchange.postCount())
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		c := newMonitoring("invalid_settings")
+		c.Mocks.initBaseMocks()
+
+		// the step is less than the exchange min quantity
+		store, exchange := c.Mocks.initFlowMocks(&structs.Settings{
+			ID:         primitive.NewObjectID(),
+			Symbol:     testSymbol,
+			Step:       0.0005,
+			Delta:      45,
+			DepthLimit: 50,
+			Status:     structs.Enabled.ToString(),
+		})
+
+		disabled := make(chan structs.SettingsChange, 1)
+		c.Mocks.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Disabled, mock.AnythingOfType("structs.SettingsChange")).
+			Run(func(args mock.Arguments) {
+				disabled <- args.Get(2).(structs.SettingsChange)
+			}).
+			Return(nil).Once()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go func() {
+			_ = c.initOrderUseCase().Monitoring(ctx, testSymbol, nil)
+		}()
+
+		select {
+		case change := <-disabled:
+			assert.Equal(t, structs.SettingsAuthorMonitor, change.Author)
+			assert.Equal(t, "invalid settings: step 0.0005 is less than the exchange min quantity 0.001", change.Reason)
+		case <-time.After(2 * time.Second):
+			t.Fatal("symbol is not disabled")
+		}
+
+		// the monitor does not trade the invalid settings
+		time.Sleep(200 * time.Millisecond)
+
+		assert.Empty(t, store.list())
+		assert.Equal(t, 0, exchange.postCount())
+	})
+}
+
+func (m *testCaseMocks) initSessionFlowMocks() (*testOrderStore, *testExchange) {
+	return m.initFlowMocks(&structs.Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     testSymbol,
+		Step:       0.003,
+		Delta:      45,
+		DepthLimit: 50,
+		Status:     structs.Enabled.ToString(),
+		Version:    3,
+	})
+}
+
+func (m *testCaseMocks) initSignalFlowMocks() (*testOrderStore, *testExchange) {
+	return m.initFlowMocks(&structs.Settings{
+		ID:            primitive.NewObjectID(),
+		Symbol:        testSymbol,
+		Step:          0.003,
+		Limit:         0.02,
+		Delta:         45,
+		DepthLimit:    50,
+		Status:        structs.Enabled.ToString(),
+		Strategy:      structs.StrategySignal.ToString(),
+		SignalMaxRisk: 10,
+	})
+}
+
+func (m *testCaseMocks) initSpotSessionFlowMocks() (*testOrderStore, *testExchange) {
+	store, exchange := m.initFlowMocks(&structs.Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     testSymbol,
+		Step:       0.001,
+		Delta:      45,
+		DepthLimit: 50,
+		Status:     structs.Enabled.ToString(),
+		Market:     structs.MarketSpot.ToString(),
+	})
+
+	// the bids pressure opens the long session
+	exchange.depth = []byte(`{"bids":[["19499.9","5.0"]],"asks":[["19500.1","1.0"]]}`)
+
+	return store, exchange
+}
+
+func (m *testCaseMocks) initFlowMocks(settings *structs.Settings) (*testOrderStore, *testExchange) {
+	store := &testOrderStore{}
+	exchange := newTestExchange()
+
+	// Client Mocks
+	m.clientCtrl.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(exchange.response, exchange.err)
+
+	// Settings Mocks
+	m.settingsRepo.On("Load", testSymbol).
+		Return(settings, nil)
+
+	// Order Mocks
+	m.orderRepo.On("Store", mock.AnythingOfType("*models.Order"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(store.store)
+
+	m.orderRepo.On("Record", mock.AnythingOfType("*models.Order"), mock.AnythingOfType("string"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(store.record)
+
+	m.orderRepo.On("GetLast", testSymbol).
+		Return(
+			func(symbol string) *models.Order {
+				o, _ := store.getLast(symbol)
+				return o
+			},
+			func(symbol string) error {
+				_, err := store.getLast(symbol)
+				return err
+			},
+		)
+
+	m.orderRepo.On("GetBySessionID", mock.AnythingOfType("string")).
+		Return(store.getBySessionID, nil)
+
+	m.orderRepo.On("GetByID", mock.AnythingOfType("string")).
+		Return(
+			func(id string) *models.Order {
+				o, _ := store.getByID(id)
+				return o
+			},
+			func(id string) error {
+				_, err := store.getByID(id)
+				return err
+			},
+		)
+
+	m.orderRepo.On("SetStatus", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(func(id string, status string, c postgres.OrderChange) error {
+			return store.update(id, models.OrderEventStatus, c, func(o *models.Order) { o.Status = status })
+		})
+
+	m.orderRepo.On("SetOrderID", mock.AnythingOfType("string"), mock.AnythingOfType("int64"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(func(id string, orderID int64, c postgres.OrderChange) error {
+			return store.update(id, models.OrderEventAcknowledged, c, func(o *models.Order) { o.OrderID = orderID })
+		})
+
+	m.orderRepo.On("SetActualPrice", mock.AnythingOfType("string"), mock.AnythingOfType("float64"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(func(id string, price float64, c postgres.OrderChange) error {
+			return store.update(id, models.OrderEventPrice, c, func(o *models.Order) { o.ActualPrice = price })
+		})
+
+	// the claim is taken once, the result is read by the second function
+	var claimed sync.Map
+
+	m.orderRepo.On("Claim", mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).
+		Return(
+			func(id string, ttl time.Duration) int {
+				attempts, err := store.claim(id, ttl)
+				claimed.Store(id, err)
+
+				return attempts
+			},
+			func(id string, _ time.Duration) error {
+				err, _ := claimed.LoadAndDelete(id)
+				if err == nil {
+					return nil
+				}
+
+				return err.(error)
+			},
+		)
+
+	m.orderRepo.On("Release", mock.AnythingOfType("string")).
+		Return(store.release)
+
+	m.initGridMocks()
+
+	return store, exchange
+}
+
+func (m *testCaseMocks) initGridMocks() {
+	grids := &testGridStore{levels: make(map[string][]models.GridLevel)}
+
+	m.gridRepo.On("Store", mock.AnythingOfType("*models.Grid"), mock.AnythingOfType("[]models.GridLevel")).
+		Return(grids.store)
+
+	m.gridRepo.On("GetActive", testSymbol, mock.AnythingOfType("string")).
+		Return(
+			func(symbol, market string) *models.Grid {
+				g, _ := grids.getActive(symbol, market)
+				return g
+			},
+			func(symbol, market string) error {
+				_, err := grids.getActive(symbol, market)
+				return err
+			},
+		)
+
+	m.gridRepo.On("GetLevels", mock.AnythingOfType("string")).
+		Return(grids.getLevels, nil)
+
+	m.gridRepo.On("SetStatus", mock.AnythingOfType("string"), mock.AnythingOfType("string")).
+		Return(grids.setStatus)
+
+	m.gridRepo.On("UpdateLevel", mock.AnythingOfType("*models.GridLevel")).
+		Return(func(l *models.GridLevel) error {
+			grids.mu.Lock()
+			defer grids.mu.Unlock()
+
+			return grids.updateLevel(l, 0, 0)
+		})
+
+	m.gridRepo.On("CloseCycle", mock.AnythingOfType("*models.GridLevel"), mock.AnythingOfType("float64")).
+		Return(grids.closeCycle)
+
+	m.gridStore = grids
+}
+
+func (m *testCaseMocks) initGridFlowMocks() (*testGridStore, *testExchange) {
+	_, exchange := m.initFlowMocks(&structs.Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     testSymbol,
+		Step:       0.003,
+		Status:     structs.Enabled.ToString(),
+		Strategy:   structs.StrategyGrid.ToString(),
+		MinPrice:   19000,
+		MaxPrice:   20000,
+		GridLevels: 5,
+	})
+
+	return m.gridStore, exchange
+}
@@ -2,6 +2,8 @@ package usecasees
 
 import (
 	"errors"
+	"sync"
+
 	"github.com/sirupsen/logrus"
 
 	"binance/internal/controllers"
@@ -22,11 +24,15 @@ const (
 	// featureURL          = "https://testnet.binancefuture.com"
 	featureOrder        = "/fapi/v1/order"
 	featurePositionInfo = "/fapi/v2/positionRisk"
+	featureAccount      = "/fapi/v2/account"
 	featureBatchOrders  = "/fapi/v1/batchOrders"
 	featureSymbolPrice  = "/fapi/v1/ticker/price"
 	featureTicker24hr   = "/fapi/v1/ticker/24hr"
 	featureDepth        = "/fapi/v1/depth"
 	featureTrades       = "/fapi/v1/trades"
+	featureKlines       = "/fapi/v1/klines"
+	featureOpenOrders   = "/fapi/v1/openOrders"
+	featureUserTrades   = "/fapi/v1/userTrades"
 
 	BNB  = "BNB"
 	BTC  = "BTC"
@@ -51,13 +57,15 @@ const (
 	SideSell = "SELL"
 	SideBuy  = "BUY"
 
-	OrderStatusNew        = "NEW"
-	OrderStatusCanceled   = "CANCELED"
-	OrderStatusFilled     = "FILLED"
-	OrderStatusExpired    = "EXPIRED"
-	OrderStatusNotFound   = "NOT_FOUND"
-	OrderStatusInProgress = "IN PROGRESS"
-	OrderStatusError      = "ERROR"
+	OrderStatusNew             = "NEW"
+	OrderStatusPartiallyFilled = "PARTIALLY_FILLED"
+	OrderStatusCanceled        = "CANCELED"
+	OrderStatusFilled          = "FILLED"
+	OrderStatusExpired         = "EXPIRED"
+	OrderStatusRejected        = "REJECTED"
+	OrderStatusNotFound        = "NOT_FOUND"
+	OrderStatusInProgress      = "IN PROGRESS"
+	OrderStatusError           = "ERROR"
 
 	OrderTypeLimit = "LIMIT"
 	//OrderTypeMarket     = "MARKET"
@@ -76,6 +84,10 @@ const (
 	OrderTypeOCO   = "OCO"
 	OrderTypeBatch = "BATCH"
 
+	PositionSideLong  = "LONG"
+	PositionSideShort = "SHORT"
+	PositionSideBoth  = "BOTH"
+
 	OrderTypeLimitID      = 0
 	OrderTypeTakeProfitID = 1
 	OrderTypeStopLossID   = 2
@@ -100,9 +112,24 @@ type orderUseCase struct {
 
 	settingsRepo mongo.SettingsRepo
 	orderRepo    postgres.OrderRepo
+	gridRepo     postgres.GridRepo
+	fillRepo     postgres.FillRepo
+
+	// settingsWatcher pushes the changed settings to the monitors
+	settingsWatcher *SettingsWatcher
+
+	// market selects the exchange API, url is the base URL of the market
+	market mongoStructs.Market
 
 	priceUseCase *priceUseCase
 
+	exitDistances *exitDistanceCache
+	blackouts     *blackoutCache
+	symbolLimits  *symbolLimitsCache
+
+	monitorsMu sync.Mutex
+	monitors   map[string]*Monitor
+
 	url string
 
 	logRus *logrus.Logger
@@ -113,7 +140,11 @@ func NewOrderUseCase(
 	crypto controllers.CryptoCtrl,
 	tgm controllers.TgmCtrl,
 	settingsRepo mongo.SettingsRepo,
+	settingsWatcher *SettingsWatcher,
 	orderRepo postgres.OrderRepo,
+	gridRepo postgres.GridRepo,
+	fillRepo postgres.FillRepo,
+	market mongoStructs.Market,
 	priceUseCase *priceUseCase,
 	url string,
 	logger *logrus.Logger,
@@ -123,8 +154,16 @@ func NewOrderUseCase(
 		cryptoController: crypto,
 		tgmController:    tgm,
 		settingsRepo:     settingsRepo,
+		settingsWatcher:  settingsWatcher,
 		orderRepo:        orderRepo,
+		gridRepo:         gridRepo,
+		fillRepo:         fillRepo,
+		market:           market,
 		priceUseCase:     priceUseCase,
+		exitDistances:    newExitDistanceCache(),
+		blackouts:        newBlackoutCache(),
+		symbolLimits:     newSymbolLimitsCache(),
+		monitors:         make(map[string]*Monitor),
 		url:              url,
 		logRus:           logger,
 	}
@@ -135,8 +174,14 @@ func (u *orderUseCase) fillPricePlan(orderType string, symbol string, actualPric
 
 	out.Status = status
 	out.Symbol = symbol
-	out.SafeDelta = float64(settings.Delta)
-	out.TriggerDelta = float64(settings.Delta) / 100 * 1
+	out.SettingsVersion = settings.Version
+
+	exitDistance := u.getExitDistance(settings)
+
+	out.ExitModel = exitDistance.Model
+	out.Volatility = exitDistance.Volatility
+	out.SafeDelta = exitDistance.SafeDelta
+	out.TriggerDelta = exitDistance.TriggerDelta
 
 	switch orderType {
 	case OrderTypeLimit:
@@ -0,0 +1,54 @@
+package usecasees
+
+import (
+	"binance/internal/repository/postgres"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"net/url"
+	"strconv"
+)
+
+// orderChange is the change of the order made by the source, the payload is the exchange response or the error
+func orderChange(source string, payload []byte) postgres.OrderChange {
+	return postgres.OrderChange{
+		Source:  source,
+		Payload: string(payload),
+	}
+}
+
+// recordOrderEvent appends the event which does not change the order, the audit does not stop the trading.
+// The order which is not stored, as the liquidation and the grid ones, is recorded with the state of o.
+func (u *orderUseCase) recordOrderEvent(o *models.Order, event string, source string, payload []byte) {
+	if err := u.orderRepo.Record(o, event, orderChange(source, payload)); err != nil {
+		u.logRus.
+			WithField("func", "Record").
+			WithField("event", event).
+			WithField("orderID", o.ID).
+			Debug(err)
+	}
+}
+
+// canceledOrder is the state of the order in the cancel response, id is its client order id
+func canceledOrder(id string, out *structs.Order) *models.Order {
+	price, _ := strconv.ParseFloat(out.Price, 64)
+
+	return &models.Order{
+		ID:      id,
+		OrderID: out.OrderId,
+		Symbol:  out.Symbol,
+		Status:  out.Status,
+		Price:   price,
+	}
+}
+
+// submittedPayload is the request of the order without the signature
+func submittedPayload(q url.Values) []byte {
+	out := make(url.Values, len(q))
+	for k, v := range q {
+		if k != "signature" {
+			out[k] = v
+		}
+	}
+
+	return []byte(out.Encode())
+}
@@ -0,0 +1,55 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"time"
+)
+
+const (
+	defaultImbalanceLevels     = 20
+	defaultImbalanceMidPercent = 0.1
+	defaultWallMinRatio        = 10
+)
+
+var defaultCVDWindows = []time.Duration{
+	10 * time.Second,
+	time.Minute,
+	5 * time.Minute,
+}
+
+// orderFlowConfig builds the order flow config from the symbol settings, settings may be nil
+func orderFlowConfig(settings *mongoStructs.Settings) *structs.OrderFlowConfig {
+	out := structs.OrderFlowConfig{
+		CVDWindows:   defaultCVDWindows,
+		TopLevels:    defaultImbalanceLevels,
+		MidPercent:   defaultImbalanceMidPercent,
+		WallMinRatio: defaultWallMinRatio,
+	}
+
+	if settings == nil {
+		return &out
+	}
+
+	if len(settings.CVDWindows) > 0 {
+		out.CVDWindows = make([]time.Duration, 0, len(settings.CVDWindows))
+
+		for _, w := range settings.CVDWindows {
+			out.CVDWindows = append(out.CVDWindows, time.Duration(w)*time.Second)
+		}
+	}
+
+	if settings.ImbalanceLevels > 0 {
+		out.TopLevels = settings.ImbalanceLevels
+	}
+
+	if settings.ImbalanceMidPercent > 0 {
+		out.MidPercent = settings.ImbalanceMidPercent
+	}
+
+	if settings.WallMinRatio > 0 {
+		out.WallMinRatio = settings.WallMinRatio
+	}
+
+	return &out
+}
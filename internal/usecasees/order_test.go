@@ -4,10 +4,13 @@ import (
 	ctrlMocks "binance/internal/controllers/mocks"
 	mongoMocks "binance/internal/repository/mongo/mocks"
 	"binance/internal/repository/mongo/structs"
+	"binance/internal/repository/postgres"
 	pgMocks "binance/internal/repository/postgres/mocks"
 	orderStructs "binance/internal/usecasees/structs"
 	"binance/models"
 
+	"context"
+	"database/sql"
 	"encoding/json"
 	"net/url"
 	"testing"
@@ -28,30 +31,44 @@ type testCaseMocks struct {
 	cryptoCtrl   *ctrlMocks.CryptoCtrl
 	tgmCtrl      *ctrlMocks.TgmCtrl
 	orderRepo    *pgMocks.OrderRepo
+	gridRepo     *pgMocks.GridRepo
+	fillRepo     *pgMocks.FillRepo
 	settingsRepo *mongoMocks.SettingsRepo
 	priceRepo    *pgMocks.PriceRepo
 
+	gridStore *testGridStore
+
 	mockStructs *mockStructs
 
 	logRus *logrus.Logger
 }
 type mockStructs struct {
-	orderListJson  []byte
-	orderJson      []byte
-	openOrdersJson []byte
-	limitOrderJson []byte
+	positionsJson   []byte
+	flatJson        []byte
+	openOrdersJson  []byte
+	noOrdersJson    []byte
+	entryOrdersJson []byte
+	orderJson       []byte
+	featureOrderRes []byte
+	userTradesJson  []byte
 }
 
 const (
-	testCaseOrderSELL       = "order_sell"
+	testCaseLiquidation     = "liquidation"
 	testCaseLiquidationBUY  = "liquidation_buy"
 	testCaseLiquidationSELL = "liquidation_sell"
 	testSettingsStatusNEW   = "settings_new"
+
+	testCaseShutdownCancelEntries = "shutdown_cancel_entries"
+	testCaseSyncFills             = "sync_fills"
+	testCaseSyncFillsFirst        = "sync_fills_first"
+
+	testSymbol = "BTCUSDT"
 )
 
 func Test_OrderUseCase(t *testing.T) {
-	t.Run("order SELL", func(t *testing.T) {
-		newMonitoring(testCaseOrderSELL).run(t)
+	t.Run("liquidation", func(t *testing.T) {
+		newMonitoring(testCaseLiquidation).run(t)
 	})
 
 	t.Run("liquidation BUY", func(t *testing.T) {
@@ -65,6 +82,18 @@ func Test_OrderUseCase(t *testing.T) {
 	t.Run("settings status NEW", func(t *testing.T) {
 		newMonitoring(testSettingsStatusNEW).run(t)
 	})
+
+	t.Run("shutdown CANCEL_ENTRIES", func(t *testing.T) {
+		newMonitoring(testCaseShutdownCancelEntries).run(t)
+	})
+
+	t.Run("sync fills", func(t *testing.T) {
+		newMonitoring(testCaseSyncFills).run(t)
+	})
+
+	t.Run("first sync fills", func(t *testing.T) {
+		newMonitoring(testCaseSyncFillsFirst).run(t)
+	})
 }
 
 func newMonitoring(label string) *testCaseStruct {
@@ -75,6 +104,8 @@ func newMonitoring(label string) *testCaseStruct {
 			cryptoCtrl:   &ctrlMocks.CryptoCtrl{},
 			tgmCtrl:      &ctrlMocks.TgmCtrl{},
 			orderRepo:    &pgMocks.OrderRepo{},
+			gridRepo:     &pgMocks.GridRepo{},
+			fillRepo:     &pgMocks.FillRepo{},
 			settingsRepo: &mongoMocks.SettingsRepo{},
 			priceRepo:    &pgMocks.PriceRepo{},
 			mockStructs:  &mockStructs{},
@@ -87,19 +118,106 @@ func (c *testCaseStruct) run(t *testing.T) {
 	c.initMockStructs(t)
 
 	switch c.Label {
-	case testCaseOrderSELL:
-		c.Mocks.initOrderSELLMocks()
+	case testCaseLiquidation:
+		c.Mocks.initLiquidationMocks(structs.Liquidation, []string{PositionSideLong, PositionSideShort})
+		c.runLiquidation(t, structs.Liquidation)
 	case testCaseLiquidationBUY:
-		c.Mocks.initLiquidationBUYMocks()
+		c.Mocks.initLiquidationMocks(structs.LiquidationBUY, []string{PositionSideLong})
+		c.runLiquidation(t, structs.LiquidationBUY)
 	case testCaseLiquidationSELL:
-		c.Mocks.initLiquidationSELLMocks()
+		c.Mocks.initLiquidationMocks(structs.LiquidationSELL, []string{PositionSideShort})
+		c.runLiquidation(t, structs.LiquidationSELL)
 	case testSettingsStatusNEW:
 		c.Mocks.initSettingsStatusNewMocks()
+		c.runSupervisor(t)
+	case testCaseShutdownCancelEntries:
+		c.Mocks.initShutdownCancelEntriesMocks()
+		c.runShutdownCancelEntries(t)
+	case testCaseSyncFills:
+		c.Mocks.initSyncFillsMocks()
+		c.runSyncFills(t)
+	case testCaseSyncFillsFirst:
+		c.Mocks.initSyncFillsFirstMocks()
+		c.runSyncFills(t)
+	}
+}
+
+// runLiquidation runs the wind-down twice: the first step closes the positions,
+// the second one finds the symbol flat and disables it
+func (c *testCaseStruct) runLiquidation(t *testing.T, status structs.SymbolStatus) {
+	u := c.initOrderUseCase()
+
+	m := newMonitor(context.Background(), nil)
+	defer m.cancel()
+
+	m.settings = &structs.Settings{
+		ID:                   primitive.NewObjectID(),
+		Symbol:               testSymbol,
+		Status:               status.ToString(),
+		LiquidationOrderType: OrderTypeMarket,
 	}
 
-	assert.NoError(t, c.initOrderUseCase().Monitoring("BTCBUSD"))
+	assert.True(t, m.liquidating())
+	assert.False(t, m.newSessionsAllowed())
 
-	time.Sleep(5 * time.Second)
+	u.liquidate(m, testSymbol)
+	applyTask(m)
+	assert.NotNil(t, m.liquidation)
+
+	u.liquidate(m, testSymbol)
+	applyTask(m)
+	assert.Nil(t, m.liquidation)
+
+	c.Mocks.clientCtrl.AssertExpectations(t)
+	c.Mocks.settingsRepo.AssertExpectations(t)
+}
+
+// applyTask waits for the step task of the monitor and applies its result as the event loop does
+func applyTask(m *Monitor) {
+	(<-m.events).apply(m)
+	m.tasks.Wait()
+}
+
+func (c *testCaseStruct) runSupervisor(t *testing.T) {
+	s := NewSupervisor(c.initOrderUseCase(), c.Mocks.settingsRepo, c.Mocks.tgmCtrl, c.Mocks.logRus)
+
+	// the monitor stops right away with the cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s.sync(ctx)
+	s.wg.Wait()
+
+	assert.Empty(t, s.Running())
+
+	c.Mocks.settingsRepo.AssertExpectations(t)
+}
+
+// runShutdownCancelEntries cancels the entry order and leaves the take profit and the positions
+func (c *testCaseStruct) runShutdownCancelEntries(t *testing.T) {
+	u := c.initOrderUseCase()
+
+	report := u.applyShutdownPolicy(context.Background(), testSymbol, ShutdownCancelEntries)
+
+	assert.NoError(t, report.Err)
+	assert.Equal(t, 1, report.Canceled)
+	assert.Equal(t, 0, report.Closed)
+	assert.Len(t, report.Positions, 2)
+
+	c.Mocks.clientCtrl.AssertExpectations(t)
+}
+
+// runSyncFills stores the trades made after the last stored fill
+func (c *testCaseStruct) runSyncFills(t *testing.T) {
+	u := c.initOrderUseCase()
+
+	n, err := u.SyncFills(testSymbol)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	c.Mocks.clientCtrl.AssertExpectations(t)
+	c.Mocks.fillRepo.AssertExpectations(t)
 }
 
 func (m *testCaseMocks) initBaseMocks() {
@@ -110,279 +228,285 @@ func (m *testCaseMocks) initBaseMocks() {
 
 	// Tgm mocks
 	m.tgmCtrl.On("Send", mock.AnythingOfType("string")).Return(nil)
+	m.tgmCtrl.On("Notify", mock.AnythingOfType("string"), mock.Anything).Return(nil)
 
 	// Crypto mocks
 	m.cryptoCtrl.On("GetSignature", mock.AnythingOfType("string")).Return("630e26f39d6728d0e7feffb9", nil)
-
 }
 
-func (m *testCaseMocks) initOrderSELLMocks() {
-	// Client Mocks
-	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/orderList"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+func (m *testCaseMocks) initLiquidationMocks(status structs.SymbolStatus, closedSides []string) {
+	// Order Mocks
+	m.orderRepo.On("Record", mock.AnythingOfType("*models.Order"), mock.AnythingOfType("string"), mock.AnythingOfType("postgres.OrderChange")).
+		Return(nil)
 
+	// Client Mocks
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order"
-	}), []byte(nil), true).Return(m.mockStructs.orderJson, nil)
+		return input.Path == featureOpenOrders
+	}), []byte(nil), true).Return(m.mockStructs.openOrdersJson, nil).Once()
 
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/openOrders"
-	}), []byte(nil), true).Return(m.mockStructs.openOrdersJson, nil)
-
-	m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order/oco"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
-
-	//Order Mocks
-	m.orderRepo.On("Store", mock.AnythingOfType("*models.Order")).Return(nil)
-	m.orderRepo.On("GetLast", "BTCBUSD").Return(&models.Order{
-		ID:          8,
-		OrderID:     3145000133794585251,
-		SessionID:   "cc3336da-432f-4e9e-9152-d976732f9b8d",
-		Symbol:      "BTCBUSD",
-		Side:        "SELL",
-		Quantity:    0.0006,
-		Price:       20500,
-		ActualPrice: 19632,
-		StopPrice:   19000,
-		Status:      "FILLED",
-		Try:         1,
-		Type:        "OCO",
-		CreatedAt:   time.Now().Add(-2 * time.Hour),
-	}, nil)
+		return input.Path == featureOpenOrders
+	}), []byte(nil), true).Return(m.mockStructs.noOrdersJson, nil).Once()
 
-	// Settings Mocks
-
-	m.settingsRepo.On("Load", "BTCBUSD").Return(&structs.Settings{
-		ID:        primitive.NewObjectID(),
-		Symbol:    "BTCBUSD",
-		Limit:     0.02,
-		Step:      0.0006,
-		Delta:     0.2,
-		DeltaStep: 0.065,
-		SpotURL:   "https://www.binance.com/ru/trade/BTC_USDT?theme=dark&type=spot",
-		Status:    structs.Enabled.ToString(),
-	}, nil)
-}
-func (m *testCaseMocks) initLiquidationBUYMocks() {
-	// Client Mocks
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/orderList"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+		return input.Path == featurePositionInfo
+	}), []byte(nil), true).Return(m.mockStructs.positionsJson, nil).Once()
 
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order"
-	}), []byte(nil), true).Return(m.mockStructs.orderJson, nil)
+		return input.Path == featurePositionInfo
+	}), []byte(nil), true).Return(m.mockStructs.flatJson, nil).Once()
 
+	// the close orders are formatted by the symbol filters
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/openOrders"
-	}), []byte(nil), true).Return(m.mockStructs.openOrdersJson, nil)
-
-	m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order/oco"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
-
-	m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order"
-	}), []byte(nil), true).Return(m.mockStructs.limitOrderJson, nil)
+		return input.Path == featureExchangeInfo
+	}), []byte(nil), false).Return([]byte(`{"symbols":[{"symbol":"BTCUSDT","filters":[{"filterType":"PRICE_FILTER","tickSize":"0.10"},{"filterType":"LOT_SIZE","minQty":"0.001","maxQty":"1000","stepSize":"0.001"}]}]}`), nil).Once()
+
+	for _, side := range closedSides {
+		positionSide := side
+
+		// the take profit of the position is cancelled
+		m.clientCtrl.On("Send", "DELETE", mock.MatchedBy(func(input *url.URL) bool {
+			return input.Path == featureOrder && input.Query().Get("orderId") == map[string]string{
+				PositionSideLong:  "11",
+				PositionSideShort: "12",
+			}[positionSide]
+		}), []byte(nil), true).Return(m.mockStructs.orderJson, nil).Once()
+
+		// the position is closed with the opposite side
+		m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
+			q := input.Query()
+
+			closeSide := SideSell
+			if positionSide == PositionSideShort {
+				closeSide = SideBuy
+			}
+
+			return input.Path == featureOrder &&
+				q.Get("positionSide") == positionSide &&
+				q.Get("side") == closeSide &&
+				q.Get("type") == OrderTypeMarket &&
+				q.Get("reduceOnly") == ""
+		}), []byte(nil), true).Return(m.mockStructs.featureOrderRes, nil).Once()
+	}
 
-	//Order Mocks
-	m.orderRepo.On("Store", mock.AnythingOfType("*models.Order")).
-		Return(nil)
+	// Settings Mocks
+	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Disabled, mock.AnythingOfType("structs.SettingsChange")).
+		Return(nil).Once()
 
-	m.orderRepo.On("GetLast", "BTCBUSD").
-		Return(&models.Order{
-			ID:          8,
-			OrderID:     3145000133794585251,
-			SessionID:   "cc3336da-432f-4e9e-9152-d976732f9b8d",
-			Symbol:      "BTCBUSD",
-			Side:        "SELL",
-			Quantity:    0.0006,
-			Price:       20500,
-			ActualPrice: 19632,
-			StopPrice:   19000,
-			Status:      "FILLED",
-			Try:         1,
-			Type:        "OCO",
-			CreatedAt:   time.Now().Add(-2 * time.Hour),
-		}, nil)
+	// Grid Mocks
+	m.gridRepo.On("GetActive", testSymbol, structs.MarketFeatures.ToString()).
+		Return(nil, sql.ErrNoRows)
+}
 
-	// Settings Mocks
-	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.LiquidationSELL).
+func (m *testCaseMocks) initShutdownCancelEntriesMocks() {
+	// Order Mocks
+	m.orderRepo.On("Record", mock.AnythingOfType("*models.Order"), mock.AnythingOfType("string"), mock.AnythingOfType("postgres.OrderChange")).
 		Return(nil)
 
-	m.settingsRepo.On("Load", "BTCBUSD").
-		Return(&structs.Settings{
-			ID:        primitive.NewObjectID(),
-			Symbol:    "BTCBUSD",
-			Limit:     0.02,
-			Step:      0.0006,
-			Delta:     0.2,
-			DeltaStep: 0.065,
-			SpotURL:   "https://www.binance.com/ru/trade/BTC_USDT?theme=dark&type=spot",
-			Status:    structs.LiquidationBUY.ToString(),
-		}, nil)
-}
-func (m *testCaseMocks) initLiquidationSELLMocks() {
 	// Client Mocks
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/orderList"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+		return input.Path == featureOpenOrders
+	}), []byte(nil), true).Return(m.mockStructs.entryOrdersJson, nil).Once()
 
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order"
-	}), []byte(nil), true).Return(m.mockStructs.orderJson, nil)
+		return input.Path == featurePositionInfo
+	}), []byte(nil), true).Return(m.mockStructs.positionsJson, nil).Once()
 
-	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/openOrders"
-	}), []byte(nil), true).Return(m.mockStructs.openOrdersJson, nil)
+	m.clientCtrl.On("Send", "DELETE", mock.MatchedBy(func(input *url.URL) bool {
+		return input.Path == featureOrder && input.Query().Get("orderId") == "13"
+	}), []byte(nil), true).Return(m.mockStructs.orderJson, nil).Once()
+}
 
-	m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order/oco"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+func (m *testCaseMocks) initSyncFillsMocks() {
+	// Fill Mocks
+	m.fillRepo.On("GetLastID", testSymbol).Return(int64(41), nil).Once()
 
-	// Order Mocks
-	m.orderRepo.On("Store", mock.AnythingOfType("*models.Order")).
-		Return(nil)
+	m.fillRepo.On("Store", mock.MatchedBy(func(fills []models.Fill) bool {
+		return len(fills) == 2 &&
+			fills[0].ID == 42 && fills[0].OrderID == 1 && fills[0].Price == 19500 && fills[0].Commission == 0.0078 &&
+			fills[1].RealizedPnL == 1.5 && fills[1].Maker &&
+			fills[1].TradedAt.Equal(time.UnixMilli(1666000001000))
+	})).Return(nil).Once()
 
-	m.orderRepo.On("GetLast", "BTCBUSD").
-		Return(&models.Order{
-			ID:          8,
-			OrderID:     3145000133794585251,
-			SessionID:   "cc3336da-432f-4e9e-9152-d976732f9b8d",
-			Symbol:      "BTCBUSD",
-			Side:        "SELL",
-			Quantity:    0.0006,
-			Price:       20500,
-			ActualPrice: 19632,
-			StopPrice:   19000,
-			Status:      "FILLED",
-			Try:         1,
-			Type:        "OCO",
-			CreatedAt:   time.Now().Add(-2 * time.Hour),
-		}, nil)
+	m.fillRepo.On("Link").Return(nil).Once()
 
-	// Settings Mocks
-	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Enabled).
-		Return(nil)
-
-	m.settingsRepo.On("Load", "BTCBUSD").
-		Return(&structs.Settings{
-			ID:        primitive.NewObjectID(),
-			Symbol:    "BTCBUSD",
-			Limit:     0.02,
-			Step:      0.0006,
-			Delta:     0.2,
-			DeltaStep: 0.065,
-			SpotURL:   "https://www.binance.com/ru/trade/BTC_USDT?theme=dark&type=spot",
-			Status:    structs.LiquidationSELL.ToString(),
-		}, nil)
-}
-func (m *testCaseMocks) initSettingsStatusNewMocks() {
 	// Client Mocks
 	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/orderList"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+		return input.Path == featureUserTrades && input.Query().Get("fromId") == "42"
+	}), []byte(nil), true).Return(m.mockStructs.userTradesJson, nil).Once()
+}
 
-	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order"
-	}), []byte(nil), true).Return(m.mockStructs.orderJson, nil)
+// initSyncFillsFirstMocks starts the sync with no stored fill at the first trade of the oldest filled order
+func (m *testCaseMocks) initSyncFillsFirstMocks() {
+	// Fill Mocks
+	m.fillRepo.On("GetLastID", testSymbol).Return(int64(0), nil).Once()
 
-	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/openOrders"
-	}), []byte(nil), true).Return(m.mockStructs.openOrdersJson, nil)
+	m.fillRepo.On("Store", mock.MatchedBy(func(fills []models.Fill) bool {
+		return len(fills) == 2 && fills[0].ID == 42
+	})).Return(nil).Once()
 
-	m.clientCtrl.On("Send", "POST", mock.MatchedBy(func(input *url.URL) bool {
-		return input.Path == "/api/v3/order/oco"
-	}), []byte(nil), true).Return(m.mockStructs.orderListJson, nil)
+	m.fillRepo.On("Link").Return(nil).Once()
 
 	// Order Mocks
-	m.orderRepo.On("Store", mock.AnythingOfType("*models.Order")).
-		Return(nil)
+	m.orderRepo.On("Find", mock.MatchedBy(func(filter postgres.OrderFilter) bool {
+		return filter.Symbol == testSymbol && filter.Limit == 1 && !filter.Descending && filter.Acknowledged &&
+			len(filter.Statuses) == 1 && filter.Statuses[0] == OrderStatusFilled
+	})).Return(&postgres.OrderPage{Orders: []models.Order{{ID: "first", Symbol: testSymbol, OrderID: 1}}}, nil).Once()
 
-	m.orderRepo.On("GetLast", "BTCBUSD").
-		Return(&models.Order{
-			ID:          8,
-			OrderID:     3145000133794585251,
-			SessionID:   "cc3336da-432f-4e9e-9152-d976732f9b8d",
-			Symbol:      "BTCBUSD",
-			Side:        "SELL",
-			Quantity:    0.0006,
-			Price:       20500,
-			ActualPrice: 19632,
-			StopPrice:   19000,
-			Status:      "FILLED",
-			Try:         1,
-			Type:        "OCO",
-			CreatedAt:   time.Now().Add(-2 * time.Hour),
-		}, nil)
+	// Client Mocks
+	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
+		return input.Path == featureUserTrades && input.Query().Get("orderId") == "1" && input.Query().Get("fromId") == ""
+	}), []byte(nil), true).Return(m.mockStructs.userTradesJson, nil).Once()
 
-	// Settings Mocks
-	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Enabled).
-		Return(nil)
+	m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
+		return input.Path == featureUserTrades && input.Query().Get("fromId") == "42"
+	}), []byte(nil), true).Return(m.mockStructs.userTradesJson, nil).Once()
+}
 
-	m.settingsRepo.On("Load", "BTCBUSD").
-		Return(&structs.Settings{
-			ID:        primitive.NewObjectID(),
-			Symbol:    "BTCBUSD",
-			Limit:     0.02,
-			Step:      0.0006,
-			Delta:     0.2,
-			DeltaStep: 0.065,
-			SpotURL:   "https://www.binance.com/ru/trade/BTC_USDT?theme=dark&type=spot",
-			Status:    structs.New.ToString(),
+func (m *testCaseMocks) initSettingsStatusNewMocks() {
+	// Settings Mocks
+	m.settingsRepo.On("LoadAll").
+		Return([]structs.Settings{
+			{
+				ID:         primitive.NewObjectID(),
+				Symbol:     testSymbol,
+				Step:       0.003,
+				Delta:      45,
+				DepthLimit: 50,
+				Status:     structs.New.ToString(),
+			},
+			{
+				ID:     primitive.NewObjectID(),
+				Symbol: "ETHUSDT",
+				Status: structs.Disabled.ToString(),
+			},
+			{
+				// the session without the step is not started, the symbol is disabled
+				ID:         primitive.NewObjectID(),
+				Symbol:     "SOLUSDT",
+				Delta:      0.5,
+				DepthLimit: 50,
+				Status:     structs.Enabled.ToString(),
+			},
 		}, nil)
+
+	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Enabled, mock.MatchedBy(func(c structs.SettingsChange) bool {
+		return c.Author == structs.SettingsAuthorSupervisor
+	})).
+		Return(nil).Once()
+
+	m.settingsRepo.On("UpdateStatus", mock.AnythingOfType("primitive.ObjectID"), structs.Disabled, mock.MatchedBy(func(c structs.SettingsChange) bool {
+		return c.Author == structs.SettingsAuthorSupervisor && c.Reason == "invalid settings: SESSION: step 0 is not positive"
+	})).
+		Return(nil).Once()
+
+	// the settings are versioned already
+	m.settingsRepo.On("Track", mock.AnythingOfType("*structs.Settings")).
+		Return(false, nil)
 }
 
 func (c *testCaseStruct) initMockStructs(t *testing.T) {
 	switch c.Label {
 	default:
-		// orderList
-		orderListStruct := orderStructs.OrderList{
-			OrderListID: 1,
+		// positionRisk
+		positions := []orderStructs.Position{
+			{Symbol: testSymbol, PositionAmt: "0.003", EntryPrice: "19500.0", UnRealizedProfit: "1.5", PositionSide: PositionSideLong},
+			{Symbol: testSymbol, PositionAmt: "-0.006", EntryPrice: "19600.0", UnRealizedProfit: "-0.5", PositionSide: PositionSideShort},
+			{Symbol: testSymbol, PositionAmt: "0", EntryPrice: "0", UnRealizedProfit: "0", PositionSide: PositionSideBoth},
 		}
-		orderListJson, err := json.Marshal(&orderListStruct)
+		positionsJson, err := json.Marshal(&positions)
 		assert.NoError(t, err)
 
-		c.Mocks.mockStructs.orderListJson = orderListJson
+		c.Mocks.mockStructs.positionsJson = positionsJson
 
-		// order
-		orderStruct := orderStructs.Order{
-			Symbol:  "BTCBUSD",
-			OrderId: 1,
+		for i := range positions {
+			positions[i].PositionAmt = "0"
 		}
-		orderJson, err := json.Marshal(&orderStruct)
+		flatJson, err := json.Marshal(&positions)
 		assert.NoError(t, err)
 
-		c.Mocks.mockStructs.orderJson = orderJson
+		c.Mocks.mockStructs.flatJson = flatJson
 
 		// openOrders
-		openOrdersStruct := []orderStructs.Order{orderStruct}
-		openOrdersJson, err := json.Marshal(&openOrdersStruct)
+		openOrders := []orderStructs.FeatureOrderResp{
+			{OrderId: 11, Symbol: testSymbol, ClientOrderId: "tp-long", Type: OrderTypeCurrentTakeProfit, PositionSide: PositionSideLong},
+			{OrderId: 12, Symbol: testSymbol, ClientOrderId: "tp-short", Type: OrderTypeCurrentTakeProfit, PositionSide: PositionSideShort},
+		}
+		openOrdersJson, err := json.Marshal(&openOrders)
 		assert.NoError(t, err)
 
 		c.Mocks.mockStructs.openOrdersJson = openOrdersJson
+		c.Mocks.mockStructs.noOrdersJson = []byte("[]")
+
+		entryOrders := append(openOrders, orderStructs.FeatureOrderResp{
+			OrderId: 13, Symbol: testSymbol, ClientOrderId: "entry", Type: OrderTypeLimit, PositionSide: PositionSideShort,
+		})
+		entryOrdersJson, err := json.Marshal(&entryOrders)
+		assert.NoError(t, err)
+
+		c.Mocks.mockStructs.entryOrdersJson = entryOrdersJson
+
+		// cancelled order
+		orderJson, err := json.Marshal(&orderStructs.Order{Symbol: testSymbol, OrderId: 11})
+		assert.NoError(t, err)
+
+		c.Mocks.mockStructs.orderJson = orderJson
+
+		// created order
+		featureOrderRes, err := json.Marshal(&orderStructs.FeatureOrderResp{OrderId: 1, Symbol: testSymbol})
+		assert.NoError(t, err)
+
+		c.Mocks.mockStructs.featureOrderRes = featureOrderRes
 
-		// limitOrder
-		limitOrderStruct := orderStructs.LimitOrder{
-			OrderID: 1,
+		// userTrades
+		userTrades := []orderStructs.UserTrade{
+			{ID: 42, Symbol: testSymbol, OrderID: 1, Side: SideBuy, PositionSide: PositionSideLong, Price: "19500", Qty: "0.001", QuoteQty: "19.5", Commission: "0.0078", CommissionAsset: USDT, RealizedPnl: "0", Time: 1666000000000},
+			{ID: 43, Symbol: testSymbol, OrderID: 2, Side: SideSell, PositionSide: PositionSideLong, Price: "21000", Qty: "0.001", QuoteQty: "21", Commission: "0.0042", CommissionAsset: USDT, RealizedPnl: "1.5", Maker: true, Time: 1666000001000},
 		}
-		limitOrderJson, err := json.Marshal(&limitOrderStruct)
+		userTradesJson, err := json.Marshal(&userTrades)
 		assert.NoError(t, err)
 
-		c.Mocks.mockStructs.limitOrderJson = limitOrderJson
+		c.Mocks.mockStructs.userTradesJson = userTradesJson
 	}
 }
 func (c *testCaseStruct) initOrderUseCase() *orderUseCase {
-	return nil
+	return NewOrderUseCase(
+		c.Mocks.clientCtrl,
+		c.Mocks.cryptoCtrl,
+		c.Mocks.tgmCtrl,
+		c.Mocks.settingsRepo,
+		NewSettingsWatcher(c.Mocks.settingsRepo, c.Mocks.logRus),
+		c.Mocks.orderRepo,
+		c.Mocks.gridRepo,
+		c.Mocks.fillRepo,
+		structs.MarketFeatures,
+		c.initPriceUseCase(),
+		"https://fapi.binance.com",
+		c.Mocks.logRus,
+	)
+}
+func (c *testCaseStruct) initSpotOrderUseCase() *orderUseCase {
+	return NewOrderUseCase(
+		c.Mocks.clientCtrl,
+		c.Mocks.cryptoCtrl,
+		c.Mocks.tgmCtrl,
+		c.Mocks.settingsRepo,
+		NewSettingsWatcher(c.Mocks.settingsRepo, c.Mocks.logRus),
+		c.Mocks.orderRepo,
+		c.Mocks.gridRepo,
+		c.Mocks.fillRepo,
+		structs.MarketSpot,
+		c.initPriceUseCase(),
+		"https://api.binance.com",
+		c.Mocks.logRus,
+	)
 }
 func (c *testCaseStruct) initPriceUseCase() *priceUseCase {
 	return NewPriceUseCase(
 		c.Mocks.clientCtrl,
 		c.Mocks.tgmCtrl,
 		c.Mocks.priceRepo,
-		"https://api.binance.com",
+		"https://fapi.binance.com",
 		c.Mocks.logRus,
 	)
 }
@@ -0,0 +1,79 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	"binance/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// outboxClaimTTL is longer than the recvWindow of the orders (60s), the request of the expired claim is
+// rejected by the exchange and can not place the order after the next claim
+const outboxClaimTTL = 2 * time.Minute
+
+// submitFeaturesOrder places the stored order at most once. The orders in progress are the outbox: the
+// claim is counted before the order is sent, so the order claimed again after a crash or an unknown
+// result is looked up by its client order id first and is sent only when the exchange does not know it.
+func (u *orderUseCase) submitFeaturesOrder(o *models.Order) error {
+	attempts, err := u.orderRepo.Claim(o.ID, outboxClaimTTL)
+	if err != nil {
+		return err
+	}
+
+	if attempts > 1 {
+		placed, err := u.acknowledgeFeaturesOrder(o)
+		if err != nil || placed {
+			return err
+		}
+	}
+
+	err = u.createFeaturesLimitOrder(o)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, controllers.ErrDuplicateClientOrderID):
+		// the order is placed by the previous attempt
+		placed, err := u.acknowledgeFeaturesOrder(o)
+		if err == nil && !placed {
+			err = fmt.Errorf("order %s is duplicated but not found", o.ID)
+		}
+
+		return err
+	case errors.Is(err, controllers.ErrOrderWouldImmediatelyTrigger), errors.Is(err, controllers.ErrUnknownOrderSent):
+		// the order is rejected, it is sent again without waiting for the claim to expire
+		if err := u.orderRepo.Release(o.ID); err != nil {
+			u.logRus.WithField("func", "Release").Debug(err)
+		}
+	}
+
+	return err
+}
+
+// acknowledgeFeaturesOrder looks the order up by its client order id and stores its exchange id,
+// it reports false when the exchange does not know the order
+func (u *orderUseCase) acknowledgeFeaturesOrder(o *models.Order) (bool, error) {
+	resp, err := u.getFeatureOrderInfo(o.ID, o.Symbol)
+
+	switch {
+	case errors.Is(err, controllers.ErrOrderDoesNotExist):
+		return false, nil
+	case err != nil:
+		return false, err
+	case resp.OrderId == 0:
+		return false, fmt.Errorf("order %s has no exchange id", o.ID)
+	}
+
+	payload, err := json.Marshal(resp)
+	if err != nil {
+		return false, err
+	}
+
+	if err := u.orderRepo.SetOrderID(o.ID, resp.OrderId, orderChange(models.OrderSourcePoll, payload)); err != nil {
+		u.logRus.WithField("func", "SetOrderID").Debug(err)
+	}
+
+	return true, nil
+}
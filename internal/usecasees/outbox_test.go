@@ -0,0 +1,122 @@
+package usecasees
+
+import (
+	"binance/internal/repository/postgres"
+	orderStructs "binance/internal/usecasees/structs"
+	"binance/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SubmitFeaturesOrder(t *testing.T) {
+	newEntry := func() *models.Order {
+		return &models.Order{
+			ID:           uuid.NewString(),
+			SessionID:    uuid.NewString(),
+			Symbol:       testSymbol,
+			Side:         SideBuy,
+			PositionSide: PositionSideLong,
+			Quantity:     0.003,
+			Status:       OrderStatusInProgress,
+			Type:         OrderTypeLimit,
+		}
+	}
+
+	placed := func(o *models.Order) orderStructs.FeatureOrderResp {
+		return orderStructs.FeatureOrderResp{
+			OrderId:       42,
+			Symbol:        o.Symbol,
+			ClientOrderId: o.ID,
+			Type:          OrderTypeMarket,
+			Status:        OrderStatusFilled,
+		}
+	}
+
+	t.Run("first attempt", func(t *testing.T) {
+		c := newMonitoring("submit_first_attempt")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+		u := c.initOrderUseCase()
+
+		o := newEntry()
+		assert.NoError(t, store.store(o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		assert.NoError(t, u.submitFeaturesOrder(o))
+		assert.Equal(t, 1, exchange.postCount())
+
+		// the claim is held, the order is not sent twice
+		assert.ErrorIs(t, u.submitFeaturesOrder(o), postgres.ErrNotClaimed)
+		assert.Equal(t, 1, exchange.postCount())
+	})
+
+	t.Run("retry of the placed order", func(t *testing.T) {
+		c := newMonitoring("submit_retry_placed")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+		u := c.initOrderUseCase()
+
+		claimedAt := time.Now().Add(-2 * outboxClaimTTL)
+
+		o := newEntry()
+		o.SubmitAttempts = 1
+		o.ClaimedAt = &claimedAt
+		assert.NoError(t, store.store(o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		exchange.orders[o.ID] = placed(o)
+
+		assert.NoError(t, u.submitFeaturesOrder(o))
+		assert.Equal(t, 0, exchange.postCount())
+
+		stored, err := store.getByID(o.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(42), stored.OrderID)
+			assert.Equal(t, 2, stored.SubmitAttempts)
+		}
+	})
+
+	t.Run("retry of the lost order", func(t *testing.T) {
+		c := newMonitoring("submit_retry_lost")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+		u := c.initOrderUseCase()
+
+		claimedAt := time.Now().Add(-2 * outboxClaimTTL)
+
+		o := newEntry()
+		o.SubmitAttempts = 1
+		o.ClaimedAt = &claimedAt
+		assert.NoError(t, store.store(o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		// the exchange does not know the order, it is sent
+		assert.NoError(t, u.submitFeaturesOrder(o))
+		assert.Equal(t, 1, exchange.postCount())
+	})
+
+	t.Run("duplicated client order id", func(t *testing.T) {
+		c := newMonitoring("submit_duplicated")
+		c.Mocks.initBaseMocks()
+
+		store, exchange := c.Mocks.initSessionFlowMocks()
+		u := c.initOrderUseCase()
+
+		// the order is placed but the first claim is not stored
+		o := newEntry()
+		assert.NoError(t, store.store(o, postgres.OrderChange{Source: models.OrderSourcePoll}))
+
+		exchange.orders[o.ID] = placed(o)
+
+		assert.NoError(t, u.submitFeaturesOrder(o))
+		assert.Equal(t, 0, exchange.postCount())
+
+		stored, err := store.getByID(o.ID)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(42), stored.OrderID)
+		}
+	})
+}
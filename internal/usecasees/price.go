@@ -6,10 +6,12 @@ import (
 	"binance/internal/usecasees/structs"
 	"binance/models"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -26,11 +28,20 @@ type priceUseCase struct {
 
 	priceRepo postgres.PriceRepo
 
+	orderFlowsMu sync.Mutex
+	orderFlows   map[string]*orderFlow
+
 	url string
 
 	logger *logrus.Logger
 }
 
+type orderFlow struct {
+	mu     sync.Mutex
+	trades *structs.TradeWindow
+	walls  *structs.WallTracker
+}
+
 func NewPriceUseCase(
 	client controllers.ClientCtrl,
 	tgm controllers.TgmCtrl,
@@ -42,11 +53,28 @@ func NewPriceUseCase(
 		clientController: client,
 		tgmController:    tgm,
 		priceRepo:        priceRepo,
+		orderFlows:       make(map[string]*orderFlow),
 		url:              url,
 		logger:           logger,
 	}
 }
 
+func (u *priceUseCase) getOrderFlow(symbol string) *orderFlow {
+	u.orderFlowsMu.Lock()
+	defer u.orderFlowsMu.Unlock()
+
+	flow, ok := u.orderFlows[symbol]
+	if !ok {
+		flow = &orderFlow{
+			trades: structs.NewTradeWindow(),
+			walls:  structs.NewWallTracker(),
+		}
+		u.orderFlows[symbol] = flow
+	}
+
+	return flow
+}
+
 type Depth struct {
 	LastUpdateID int64      `json:"lastUpdateId"`
 	E            int64      `json:"E"`
@@ -142,7 +170,69 @@ func (u *priceUseCase) GetTrades(symbol string) ([]Trade, error) {
 	return out, nil
 }
 
-func (u *priceUseCase) GetTradeInfo(symbol string) (*structs.TradeInfo, error) {
+func (u *priceUseCase) GetKlines(symbol, interval string, limit int) ([]models.Candle, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(featureKlines)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set("interval", interval)
+	q.Set("limit", strconv.Itoa(limit))
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var klines [][]interface{}
+	if err := json.Unmarshal(resp, &klines); err != nil {
+		return nil, err
+	}
+
+	out := make([]models.Candle, 0, len(klines))
+
+	for _, k := range klines {
+		if len(k) < 7 {
+			return nil, fmt.Errorf("wrong kline %+v", k)
+		}
+
+		var prices [4]float64
+		for i := range prices {
+			s, ok := k[i+1].(string)
+			if !ok {
+				return nil, fmt.Errorf("wrong kline %+v", k)
+			}
+
+			if prices[i], err = strconv.ParseFloat(s, 64); err != nil {
+				return nil, err
+			}
+		}
+
+		openTime, _ := k[0].(float64)
+		closeTime, _ := k[6].(float64)
+
+		out = append(out, models.Candle{
+			Symbol:     symbol,
+			OpenPrice:  prices[0],
+			MaxPrice:   prices[1],
+			MinPrice:   prices[2],
+			ClosePrice: prices[3],
+			TimeFrame:  interval,
+			OpenTime:   time.UnixMilli(int64(openTime)),
+			CloseTime:  time.UnixMilli(int64(closeTime)),
+		})
+	}
+
+	return out, nil
+}
+
+func (u *priceUseCase) GetTradeInfo(symbol string, cfg *structs.OrderFlowConfig) (*structs.TradeInfo, error) {
 	var out structs.TradeInfo
 
 	trades, err := u.GetTrades(symbol)
@@ -150,6 +240,8 @@ func (u *priceUseCase) GetTradeInfo(symbol string) (*structs.TradeInfo, error) {
 		return nil, err
 	}
 
+	ticks := make([]structs.TradeTick, 0, len(trades))
+
 	for _, trade := range trades {
 		price, err := strconv.ParseFloat(trade.Price, 64)
 		if err != nil {
@@ -168,15 +260,33 @@ func (u *priceUseCase) GetTradeInfo(symbol string) (*structs.TradeInfo, error) {
 			out.SellerPriceSum += price
 			out.SellerQuantity += qty
 		}
+
+		ticks = append(ticks, structs.TradeTick{
+			ID:           trade.ID,
+			Price:        price,
+			Qty:          qty,
+			Time:         time.UnixMilli(trade.Time),
+			IsBuyerMaker: trade.IsBuyerMaker,
+		})
 	}
 
 	out.DeltaSeller = out.SellerQuantity / (out.BuyerQuantity + out.SellerQuantity) * 100
 	out.DeltaBuyer = out.BuyerQuantity / (out.BuyerQuantity + out.SellerQuantity) * 100
 
+	flow := u.getOrderFlow(symbol)
+
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+
+	flow.trades.Add(ticks)
+
+	out.CVD = flow.trades.CVD()
+	out.CVDWindows = flow.trades.Windows(time.Now(), cfg.CVDWindows)
+
 	return &out, nil
 }
 
-func (u *priceUseCase) GetDepthInfo(symbol string) (*structs.DepthInfo, error) {
+func (u *priceUseCase) GetDepthInfo(symbol string, cfg *structs.OrderFlowConfig) (*structs.DepthInfo, error) {
 	var out structs.DepthInfo
 
 	depth, err := u.GetDepth(symbol)
@@ -184,90 +294,88 @@ func (u *priceUseCase) GetDepthInfo(symbol string) (*structs.DepthInfo, error) {
 		return nil, err
 	}
 
-	for k, g := range depth.Asks {
-		q, err := strconv.ParseFloat(g[1], 64)
-		if err != nil {
-			return nil, err
-		}
+	asks, err := parseDepthLevels(depth.Asks)
+	if err != nil {
+		return nil, err
+	}
+
+	bids, err := parseDepthLevels(depth.Bids)
+	if err != nil {
+		return nil, err
+	}
 
-		out.AsksSum += q
+	for k, l := range asks {
+		out.AsksSum += l.Qty
 
 		if k < 10 {
 			continue
 		}
 
-		var query, price float64
-
-		price, err = strconv.ParseFloat(g[0], 64)
-		if err != nil {
-			return nil, err
-		}
-
-		query, err = strconv.ParseFloat(g[1], 64)
-		if err != nil {
-			return nil, err
-		}
-
-		if query > out.AsksMaxQuery {
-			out.AsksMaxQuery = query
-			out.AsksMaxPrice = price
+		if l.Qty > out.AsksMaxQuery {
+			out.AsksMaxQuery = l.Qty
+			out.AsksMaxPrice = l.Price
 			out.AsksMaxPosition = k
 		}
 	}
-	//
-	//for i := 0; i < out.AsksMaxPosition; i++ {
-	//	query, err := strconv.ParseFloat(depth.Asks[i][1], 64)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//
-	//	out.AsksSum += query
-	//}
-
-	for k, g := range depth.Bids {
-		q, err := strconv.ParseFloat(g[1], 64)
-		if err != nil {
-			return nil, err
-		}
 
-		out.BidsSum += q
+	for k, l := range bids {
+		out.BidsSum += l.Qty
 
 		if k < 10 {
 			continue
 		}
 
-		var query, price float64
+		if l.Qty > out.BidsMaxQuery {
+			out.BidsMaxQuery = l.Qty
+			out.BidsMaxPrice = l.Price
+			out.BidsMaxPosition = k
+		}
+	}
 
-		price, err = strconv.ParseFloat(g[0], 64)
+	out.DeltaBids = out.BidsSum / (out.BidsSum + out.AsksSum) * 100
+	out.DeltaAsks = out.AsksSum / (out.BidsSum + out.AsksSum) * 100
+
+	if len(bids) > 0 && len(asks) > 0 {
+		out.BestBidPrice = bids[0].Price
+		out.BestAskPrice = asks[0].Price
+	}
+
+	out.MidPrice = structs.MidPrice(bids, asks)
+	out.Top = structs.TopImbalance(bids, asks, cfg.TopLevels)
+	out.NearMid = structs.NearMidImbalance(bids, asks, cfg.MidPercent)
+
+	flow := u.getOrderFlow(symbol)
+
+	flow.mu.Lock()
+	defer flow.mu.Unlock()
+
+	out.Walls, out.WallEvents = flow.walls.Update(time.Now(), bids, asks, cfg.WallMinRatio)
+
+	return &out, nil
+}
+
+func parseDepthLevels(levels [][]string) ([]structs.DepthLevel, error) {
+	out := make([]structs.DepthLevel, 0, len(levels))
+
+	for _, l := range levels {
+		if len(l) < 2 {
+			return nil, fmt.Errorf("wrong depth level %+v", l)
+		}
+
+		price, err := strconv.ParseFloat(l[0], 64)
 		if err != nil {
 			return nil, err
 		}
 
-		query, err = strconv.ParseFloat(g[1], 64)
+		qty, err := strconv.ParseFloat(l[1], 64)
 		if err != nil {
 			return nil, err
 		}
 
-		if query > out.BidsMaxQuery {
-			out.BidsMaxQuery = query
-			out.BidsMaxPrice = price
-			out.BidsMaxPosition = k
-		}
+		out = append(out, structs.DepthLevel{Price: price, Qty: qty})
 	}
 
-	//for i := 0; i < out.BidsMaxPosition; i++ {
-	//	query, err := strconv.ParseFloat(depth.Bids[i][1], 64)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//
-	//	out.c += query
-	//}
-
-	out.DeltaBids = out.BidsSum / (out.BidsSum + out.AsksSum) * 100
-	out.DeltaAsks = out.AsksSum / (out.BidsSum + out.AsksSum) * 100
-
-	return &out, nil
+	return out, nil
 }
 
 func (u *priceUseCase) GetPriceChangeStatistics(symbol string) (*PriceChangeStatistics, error) {
@@ -0,0 +1,51 @@
+package usecasees
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+)
+
+const priceRetentionInterval = time.Minute
+
+// RunRetention rolls the prices up into the candles and prunes the ticks older than the retention
+// until ctx is done, the pruned ticks are moved to the archive when archive is set
+func (u *priceUseCase) RunRetention(ctx context.Context, retention time.Duration, archive bool) {
+	for {
+		if err := u.Retain(time.Now(), retention, archive); err != nil {
+			u.logger.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(priceRetentionInterval):
+		}
+	}
+}
+
+// Retain rolls up the closed minutes before now, the ticks are pruned after they are rolled up so
+// the lookups of the pruned ranges are served by the candles
+func (u *priceUseCase) Retain(now time.Time, retention time.Duration, archive bool) error {
+	rolled, err := u.priceRepo.RollUp(now)
+	if err != nil {
+		return err
+	}
+
+	var pruned int64
+
+	// the ticks are kept forever without the retention
+	if retention > 0 {
+		if pruned, err = u.priceRepo.Prune(now.Add(-retention), archive); err != nil {
+			return err
+		}
+	}
+
+	if rolled != 0 || pruned != 0 {
+		u.logger.Debugf("Prices %d candles rolled up, %d ticks pruned", rolled, pruned)
+	}
+
+	return nil
+}
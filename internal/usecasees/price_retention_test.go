@@ -0,0 +1,51 @@
+package usecasees
+
+import (
+	pgMocks "binance/internal/repository/postgres/mocks"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PriceRetention(t *testing.T) {
+	now := time.Date(2022, 11, 2, 9, 30, 15, 0, time.UTC)
+
+	t.Run("retain", func(t *testing.T) {
+		priceRepo := &pgMocks.PriceRepo{}
+		u := NewPriceUseCase(nil, nil, priceRepo, "", logrus.New())
+
+		priceRepo.On("RollUp", now).Return(int64(3), nil).Once()
+		priceRepo.On("Prune", now.Add(-24*time.Hour), true).Return(int64(120), nil).Once()
+
+		assert.NoError(t, u.Retain(now, 24*time.Hour, true))
+
+		priceRepo.AssertExpectations(t)
+	})
+
+	t.Run("no retention", func(t *testing.T) {
+		priceRepo := &pgMocks.PriceRepo{}
+		u := NewPriceUseCase(nil, nil, priceRepo, "", logrus.New())
+
+		priceRepo.On("RollUp", now).Return(int64(0), nil).Once()
+
+		assert.NoError(t, u.Retain(now, 0, false))
+
+		priceRepo.AssertExpectations(t)
+		priceRepo.AssertNotCalled(t, "Prune")
+	})
+
+	t.Run("roll up fails", func(t *testing.T) {
+		priceRepo := &pgMocks.PriceRepo{}
+		u := NewPriceUseCase(nil, nil, priceRepo, "", logrus.New())
+
+		// the ticks are not pruned before they are rolled up
+		priceRepo.On("RollUp", now).Return(int64(0), errors.New("connection refused")).Once()
+
+		assert.Error(t, u.Retain(now, time.Hour, false))
+
+		priceRepo.AssertNotCalled(t, "Prune")
+	})
+}
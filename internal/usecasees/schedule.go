@@ -0,0 +1,123 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// blackoutCache keeps the blackout files until they are modified
+type blackoutCache struct {
+	mu   sync.Mutex
+	list map[string]blackoutCacheItem
+}
+
+type blackoutCacheItem struct {
+	modTime   time.Time
+	blackouts []structs.Blackout
+}
+
+func newBlackoutCache() *blackoutCache {
+	return &blackoutCache{
+		list: make(map[string]blackoutCacheItem),
+	}
+}
+
+// get loads the JSON list of blackouts from the file
+func (c *blackoutCache) get(file string) ([]structs.Blackout, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if item, ok := c.list[file]; ok && item.modTime.Equal(info.ModTime()) {
+		return item.blackouts, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var blackouts []structs.Blackout
+	if err := json.Unmarshal(data, &blackouts); err != nil {
+		return nil, err
+	}
+
+	c.list[file] = blackoutCacheItem{
+		modTime:   info.ModTime(),
+		blackouts: blackouts,
+	}
+
+	return blackouts, nil
+}
+
+// getSchedule builds the trading schedule of the symbol settings
+func (u *orderUseCase) getSchedule(settings *mongoStructs.Settings) (*structs.Schedule, error) {
+	out := structs.Schedule{
+		Location:      time.UTC,
+		FundingBefore: time.Duration(settings.FundingBlackoutBefore) * time.Minute,
+		FundingAfter:  time.Duration(settings.FundingBlackoutAfter) * time.Minute,
+	}
+
+	if settings.Timezone != "" {
+		loc, err := time.LoadLocation(settings.Timezone)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Location = loc
+	}
+
+	for _, w := range settings.TradingWindows {
+		var window structs.TradingWindow
+		var err error
+
+		for _, d := range w.Days {
+			weekday, err := structs.ParseWeekday(d)
+			if err != nil {
+				return nil, err
+			}
+
+			window.Weekdays = append(window.Weekdays, weekday)
+		}
+
+		if window.From, err = structs.ParseClock(w.From); err != nil {
+			return nil, err
+		}
+
+		if window.To, err = structs.ParseClock(w.To); err != nil {
+			return nil, err
+		}
+
+		out.Windows = append(out.Windows, window)
+	}
+
+	if settings.BlackoutFile != "" {
+		blackouts, err := u.blackouts.get(settings.BlackoutFile)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Blackouts = blackouts
+	}
+
+	return &out, nil
+}
+
+// tradingAllowed reports whether a session can be opened at now, otherwise it returns the reason.
+// A broken schedule blocks the trading.
+func (u *orderUseCase) tradingAllowed(settings *mongoStructs.Settings, now time.Time) (bool, string) {
+	schedule, err := u.getSchedule(settings)
+	if err != nil {
+		return false, "schedule: " + err.Error()
+	}
+
+	return schedule.Allowed(now)
+}
@@ -0,0 +1,170 @@
+package usecasees
+
+import (
+	"binance/internal/repository/mongo"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"context"
+	"errors"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// settingsPollInterval is the period of the full load on the standalone server without the change streams
+	settingsPollInterval = time.Second
+	settingsWatchRetry   = 5 * time.Second
+)
+
+// SettingsWatcher pushes the changed settings to the monitors of their symbols. The changes are
+// streamed from the collection, the server without the change streams is polled.
+type SettingsWatcher struct {
+	settingsRepo mongo.SettingsRepo
+
+	mu sync.Mutex
+	// last is the last pushed settings of the symbols, rejected is the last invalid ones
+	last        map[string]*mongoStructs.Settings
+	rejected    map[string]*mongoStructs.Settings
+	subscribers map[string]map[chan *mongoStructs.Settings]struct{}
+
+	logRus *logrus.Logger
+}
+
+func NewSettingsWatcher(settingsRepo mongo.SettingsRepo, logger *logrus.Logger) *SettingsWatcher {
+	return &SettingsWatcher{
+		settingsRepo: settingsRepo,
+		last:         make(map[string]*mongoStructs.Settings),
+		rejected:     make(map[string]*mongoStructs.Settings),
+		subscribers:  make(map[string]map[chan *mongoStructs.Settings]struct{}),
+		logRus:       logger,
+	}
+}
+
+// Run watches the settings until ctx is done, the stream is reopened after a failure
+func (w *SettingsWatcher) Run(ctx context.Context) {
+	for {
+		// the changes made while the stream was closed are caught up by the full load
+		w.load()
+
+		err := w.settingsRepo.Watch(ctx, w.update)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if errors.Is(err, mongo.ErrWatchNotSupported) {
+			w.logRus.Info("Settings change streams are not supported, the settings are polled")
+			w.poll(ctx)
+
+			return
+		}
+
+		if err != nil {
+			w.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(settingsWatchRetry):
+		}
+	}
+}
+
+// Subscribe returns the channel of the changed settings of the symbol, only the latest change is kept
+// for the slow subscriber. The subscription is closed by the returned func.
+func (w *SettingsWatcher) Subscribe(symbol string) (<-chan *mongoStructs.Settings, func()) {
+	ch := make(chan *mongoStructs.Settings, 1)
+
+	w.mu.Lock()
+	if w.subscribers[symbol] == nil {
+		w.subscribers[symbol] = make(map[chan *mongoStructs.Settings]struct{})
+	}
+	w.subscribers[symbol][ch] = struct{}{}
+	w.mu.Unlock()
+
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		delete(w.subscribers[symbol], ch)
+		if len(w.subscribers[symbol]) == 0 {
+			delete(w.subscribers, symbol)
+		}
+	}
+}
+
+func (w *SettingsWatcher) poll(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(settingsPollInterval):
+		}
+
+		w.load()
+	}
+}
+
+func (w *SettingsWatcher) load() {
+	list, err := w.settingsRepo.LoadAll()
+	if err != nil {
+		w.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return
+	}
+
+	for i := range list {
+		w.update(&list[i])
+	}
+}
+
+// update pushes the settings to the subscribers of the symbol when they are valid and changed,
+// the invalid settings are skipped and the monitor keeps the last valid ones
+func (w *SettingsWatcher) update(settings *mongoStructs.Settings) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	symbol := settings.Symbol
+
+	if err := settings.Validate(); err != nil {
+		// the same invalid settings are polled again, they are reported once
+		if rejected, ok := w.rejected[symbol]; !ok || len(mongoStructs.Diff(rejected, settings)) != 0 {
+			w.logRus.Errorf("Settings [%s] version %d are skipped: %v", symbol, settings.Version, err)
+		}
+
+		w.rejected[symbol] = settings
+
+		return
+	}
+
+	delete(w.rejected, symbol)
+
+	if last, ok := w.last[symbol]; ok {
+		diff := mongoStructs.Diff(last, settings)
+		if len(diff) == 0 && last.Version == settings.Version {
+			return
+		}
+
+		for _, d := range diff {
+			w.logRus.Infof("Settings [%s] version %d %s: %v -> %v", symbol, settings.Version, d.Field, d.From, d.To)
+		}
+	}
+
+	w.last[symbol] = settings
+
+	for ch := range w.subscribers[symbol] {
+		// the subscriber has not read the previous change, it is replaced by the latest one
+		select {
+		case <-ch:
+		default:
+		}
+
+		ch <- settings
+	}
+}
@@ -0,0 +1,120 @@
+package usecasees
+
+import (
+	"binance/internal/repository/mongo"
+	mongoMocks "binance/internal/repository/mongo/mocks"
+	"binance/internal/repository/mongo/structs"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_SettingsWatcher(t *testing.T) {
+	settings := structs.Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     testSymbol,
+		Step:       0.003,
+		Delta:      45,
+		DepthLimit: 35,
+		Status:     structs.Enabled.ToString(),
+		Version:    1,
+	}
+
+	changed := settings
+	changed.DepthLimit = 45
+	changed.Version = 2
+
+	invalid := changed
+	invalid.Status = "PAUSED"
+	invalid.Version = 3
+
+	receive := func(t *testing.T, updates <-chan *structs.Settings) *structs.Settings {
+		select {
+		case s := <-updates:
+			return s
+		case <-time.After(3 * settingsPollInterval):
+			t.Fatal("no settings are pushed")
+		}
+
+		return nil
+	}
+
+	t.Run("change stream", func(t *testing.T) {
+		settingsRepo := &mongoMocks.SettingsRepo{}
+		w := NewSettingsWatcher(settingsRepo, logrus.New())
+
+		other := changed
+		other.Symbol = "ETHUSDT"
+
+		settingsRepo.On("LoadAll").Return([]structs.Settings{settings}, nil)
+		settingsRepo.On("Watch", mock.Anything, mock.Anything).
+			Return(func(ctx context.Context, f func(settings *structs.Settings)) error {
+				for _, s := range []structs.Settings{other, invalid, changed} {
+					s := s
+					f(&s)
+				}
+
+				<-ctx.Done()
+
+				return ctx.Err()
+			})
+
+		updates, unsubscribe := w.Subscribe(testSymbol)
+		defer unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Run(ctx)
+
+		assert.Equal(t, 1, receive(t, updates).Version)
+
+		// the invalid settings and the settings of the other symbol are not pushed
+		assert.Equal(t, changed, *receive(t, updates))
+	})
+
+	t.Run("polling fallback", func(t *testing.T) {
+		settingsRepo := &mongoMocks.SettingsRepo{}
+		w := NewSettingsWatcher(settingsRepo, logrus.New())
+
+		settingsRepo.On("Watch", mock.Anything, mock.Anything).Return(mongo.ErrWatchNotSupported)
+		settingsRepo.On("LoadAll").Return([]structs.Settings{settings}, nil).Twice()
+		settingsRepo.On("LoadAll").Return([]structs.Settings{changed}, nil)
+
+		updates, unsubscribe := w.Subscribe(testSymbol)
+		defer unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go w.Run(ctx)
+
+		assert.Equal(t, settings, *receive(t, updates))
+
+		// the unchanged settings are not pushed again
+		assert.Equal(t, changed, *receive(t, updates))
+	})
+
+	t.Run("latest change", func(t *testing.T) {
+		w := NewSettingsWatcher(&mongoMocks.SettingsRepo{}, logrus.New())
+
+		updates, unsubscribe := w.Subscribe(testSymbol)
+
+		w.update(&settings)
+		w.update(&changed)
+
+		// the subscriber gets the latest change only
+		assert.Equal(t, changed, *<-updates)
+		assert.Empty(t, updates)
+
+		unsubscribe()
+		w.update(&settings)
+
+		assert.Empty(t, updates)
+	})
+}
@@ -0,0 +1,222 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ShutdownLeaveOrders leaves every order on the exchange, the take profit and stop loss keep protecting the positions
+	ShutdownLeaveOrders ShutdownPolicy = "LEAVE_ORDERS"
+	// ShutdownCancelEntries cancels the entry orders which are not filled yet and leaves the protective ones
+	ShutdownCancelEntries ShutdownPolicy = "CANCEL_ENTRIES"
+	// ShutdownFlatten cancels every order and closes the positions by market
+	ShutdownFlatten ShutdownPolicy = "FLATTEN"
+)
+
+type ShutdownPolicy string
+
+func ParseShutdownPolicy(policy string) (ShutdownPolicy, error) {
+	switch p := ShutdownPolicy(strings.ToUpper(policy)); p {
+	case ShutdownLeaveOrders, ShutdownCancelEntries, ShutdownFlatten:
+		return p, nil
+	}
+
+	return "", fmt.Errorf("unknown shutdown policy '%s'", policy)
+}
+
+type ShutdownReport struct {
+	Market    mongoStructs.Market
+	Policy    ShutdownPolicy
+	StartedAt time.Time
+	Duration  time.Duration
+
+	// Pending are the symbols still creating orders when the wait timed out
+	Pending []string
+	Symbols []SymbolShutdownReport
+}
+
+type SymbolShutdownReport struct {
+	Symbol   string
+	Canceled int
+	Closed   int
+
+	// Positions are the positions left open
+	Positions []structs.Position
+	Err       error
+}
+
+func (r *ShutdownReport) String() string {
+	msg := fmt.Sprintf("[ Shutdown ]\n"+
+		"Market:\t%s\n"+
+		"Policy:\t%s\n"+
+		"Time:\t%s\n",
+		r.Market,
+		r.Policy,
+		r.Duration.Round(time.Millisecond),
+	)
+
+	if len(r.Pending) > 0 {
+		msg += fmt.Sprintf("Not sent:\t%s\n", strings.Join(r.Pending, ", "))
+	}
+
+	for _, s := range r.Symbols {
+		msg += fmt.Sprintf("\n%s\nCanceled:\t%d\nClosed:\t%d\n", s.Symbol, s.Canceled, s.Closed)
+
+		for _, p := range s.Positions {
+			msg += fmt.Sprintf("Position:\t%s %s @ %s uPnL %s\n",
+				p.PositionSide,
+				p.PositionAmt,
+				p.EntryPrice,
+				p.UnRealizedProfit,
+			)
+		}
+
+		if s.Err != nil {
+			msg += fmt.Sprintf("Error:\t%s\n", s.Err)
+		}
+	}
+
+	return msg
+}
+
+func (u *orderUseCase) registerMonitor(symbol string, m *Monitor) {
+	u.monitorsMu.Lock()
+	defer u.monitorsMu.Unlock()
+
+	u.monitors[symbol] = m
+}
+
+func (u *orderUseCase) unregisterMonitor(symbol string, m *Monitor) {
+	u.monitorsMu.Lock()
+	defer u.monitorsMu.Unlock()
+
+	if u.monitors[symbol] == m {
+		delete(u.monitors, symbol)
+	}
+}
+
+// creatingSymbols returns the symbols which have orders stored but not sent yet
+func (u *orderUseCase) creatingSymbols() []string {
+	u.monitorsMu.Lock()
+	defer u.monitorsMu.Unlock()
+
+	var out []string
+	for symbol, m := range u.monitors {
+		if m.Snapshot().ordersList.creating() {
+			out = append(out, symbol)
+		}
+	}
+
+	sort.Strings(out)
+
+	return out
+}
+
+// applyShutdownPolicy puts the orders and the positions of the symbol in the state required by the policy
+func (u *orderUseCase) applyShutdownPolicy(ctx context.Context, symbol string, policy ShutdownPolicy) SymbolShutdownReport {
+	if u.market == mongoStructs.MarketSpot {
+		return u.applySpotShutdownPolicy(symbol, policy)
+	}
+
+	out := SymbolShutdownReport{
+		Symbol: symbol,
+	}
+
+	switch policy {
+	case ShutdownCancelEntries:
+		out.Canceled, out.Err = u.cancelEntries(symbol)
+	case ShutdownFlatten:
+		out.Canceled, out.Closed, out.Err = u.flatten(ctx, symbol)
+	}
+
+	positions, err := u.getFeaturePositions(symbol)
+	if err != nil {
+		if out.Err == nil {
+			out.Err = err
+		}
+
+		return out
+	}
+
+	for _, p := range positions {
+		if amount, err := strconv.ParseFloat(p.PositionAmt, 64); err == nil && amount == 0 {
+			continue
+		}
+
+		out.Positions = append(out.Positions, p)
+	}
+
+	return out
+}
+
+// cancelEntries cancels the open orders which open a position
+func (u *orderUseCase) cancelEntries(symbol string) (int, error) {
+	openOrders, err := u.getFeatureOpenOrders(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	var canceled int
+
+	for _, o := range openOrders {
+		if o.Type != OrderTypeLimit || o.ReduceOnly || closesPosition(o.Side, o.PositionSide) ||
+			strings.HasPrefix(o.ClientOrderId, liquidationOrderPrefix) {
+			continue
+		}
+
+		if _, err := u.cancelFeatureOrder(o.OrderId, symbol, models.OrderSourceManual); err != nil {
+			u.logRus.
+				WithField("func", "cancelFeatureOrder").
+				WithField("orderID", o.ClientOrderId).
+				Debug(err)
+
+			continue
+		}
+
+		canceled++
+	}
+
+	return canceled, nil
+}
+
+// closesPosition reports whether the order of the hedge mode reduces its position, as the grid sells do
+func closesPosition(side, positionSide string) bool {
+	return (positionSide == PositionSideLong && side == SideSell) || (positionSide == PositionSideShort && side == SideBuy)
+}
+
+// flatten winds the symbol down by market until it is flat or ctx is done
+func (u *orderUseCase) flatten(ctx context.Context, symbol string) (int, int, error) {
+	l := newLiquidationState(mongoStructs.Liquidation)
+
+	construct := func(positionSide string, amount float64) *models.Order {
+		return constructCloseOrder("", symbol, positionSide, amount)
+	}
+
+	for {
+		flat, err := u.windDown(l, symbol, construct, defaultLiquidationRequote)
+		if err != nil {
+			u.logRus.
+				WithField("func", "windDown").
+				WithField("symbol", symbol).
+				Debug(err)
+		}
+
+		if flat {
+			return l.canceled, l.placed, u.stopActiveGrid(symbol)
+		}
+
+		select {
+		case <-ctx.Done():
+			return l.canceled, l.placed, fmt.Errorf("not flat: %w", ctx.Err())
+		case <-time.After(liquidationInterval):
+		}
+	}
+}
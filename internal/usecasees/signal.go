@@ -0,0 +1,514 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// signalNamespace names the sessions by the idempotency keys of the signals
+var signalNamespace = uuid.MustParse("6f1c7a52-2b0e-4c59-9a55-4c3d8b1f0e27")
+
+// signalEvent runs the signal in the event loop, the state is not shared with the webhook
+type signalEvent struct {
+	run func(m *Monitor)
+}
+
+func (e signalEvent) apply(m *Monitor) {
+	// the step task may open or close the session, the signal waits for its result
+	if m.busy {
+		m.deferred = append(m.deferred, e)
+
+		return
+	}
+
+	e.run(m)
+}
+
+type signalReply struct {
+	result *structs.SignalResult
+	err    error
+}
+
+// Signal opens or closes the session of the symbol by the signal. The signal is applied by the
+// event loop of the symbol, the retries with the same idempotency key return the first result.
+func (u *orderUseCase) Signal(ctx context.Context, s *structs.Signal) (*structs.SignalResult, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	if u.market != mongoStructs.MarketFeatures {
+		return nil, fmt.Errorf("%w: the %s market does not take signals", structs.ErrSignalRejected, u.market)
+	}
+
+	u.monitorsMu.Lock()
+	m, ok := u.monitors[s.Symbol]
+	u.monitorsMu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", structs.ErrSignalNoMonitor, s.Symbol)
+	}
+
+	reply := make(chan signalReply, 1)
+
+	e := signalEvent{run: func(m *Monitor) {
+		switch s.Action {
+		case structs.SignalActionClose:
+			u.closeSignal(m, s, reply)
+		default:
+			u.openSignal(m, s, reply)
+		}
+	}}
+
+	select {
+	case m.events <- e:
+	case <-m.ctx.Done():
+		return nil, fmt.Errorf("%w: %s", structs.ErrSignalNoMonitor, s.Symbol)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// the reply is sent once the event is applied, it may wait for the step task
+	select {
+	case r := <-reply:
+		return r.result, r.err
+	case <-m.ctx.Done():
+		return nil, fmt.Errorf("%w: %s", structs.ErrSignalNoMonitor, s.Symbol)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// signalSessionID is the session opened by the signal
+func signalSessionID(s *structs.Signal) string {
+	return uuid.NewSHA1(signalNamespace, []byte(s.Symbol+":"+s.IdempotencyKey)).String()
+}
+
+// signalCloseID is the client order id of the order closing the session by the signal
+func signalCloseID(s *structs.Signal) string {
+	return liquidationOrderPrefix + strings.ReplaceAll(uuid.NewSHA1(signalNamespace, []byte(s.Symbol+":close:"+s.IdempotencyKey)).String(), "-", "")
+}
+
+// signalsAllowed reports whether the signals can open a session, the grid and the drain block them
+func (m *Monitor) signalsAllowed() bool {
+	if m.settings == nil || m.settings.Status != mongoStructs.Enabled.ToString() || m.draining() {
+		return false
+	}
+
+	switch m.settings.GetStrategy() {
+	case mongoStructs.StrategySession, mongoStructs.StrategySignal:
+	default:
+		return false
+	}
+
+	return m.gridLoaded && m.grid == nil
+}
+
+// openSignal checks the signal against the monitor state and stores the limit order of the session in
+// the task, the session is started by the event loop. The reply is sent once the change is applied.
+func (u *orderUseCase) openSignal(m *Monitor, s *structs.Signal, reply chan<- signalReply) {
+	sessionID := signalSessionID(s)
+
+	// the checks are reported after the duplicates, the retry of an opened signal is not rejected
+	var rejected, blocked error
+
+	switch {
+	case m.settings == nil || m.liquidating():
+		rejected = fmt.Errorf("%w: %s is not tradable", structs.ErrSignalRejected, s.Symbol)
+	case !m.signalsAllowed():
+		rejected = fmt.Errorf("%w: %s does not take signals", structs.ErrSignalRejected, s.Symbol)
+	case m.actualPrice == 0:
+		rejected = fmt.Errorf("%w: no price of %s", structs.ErrSignalRejected, s.Symbol)
+	}
+
+	switch {
+	case rejected != nil:
+	case !m.noLastOrder && !m.sessionClosed():
+		blocked = fmt.Errorf("%w: session %s is open", structs.ErrSignalConflict, m.status.SessionID)
+	case !u.scheduleAllowed(m, s.Symbol):
+		blocked = fmt.Errorf("%w: %s", structs.ErrSignalRejected, m.blockedBy)
+	}
+
+	var settings mongoStructs.Settings
+	if m.settings != nil {
+		settings = *m.settings
+	}
+
+	status := *m.status
+	actualPrice := m.actualPrice
+
+	m.async(func() func(m *Monitor) {
+		var r signalReply
+		var limitOrder *models.Order
+
+		r.result, limitOrder, r.err = u.storeSignal(s, sessionID, rejected, blocked, &settings, status, actualPrice)
+
+		return func(m *Monitor) {
+			if limitOrder != nil {
+				m.status.SetSessionID(sessionID)
+				m.status.SetQuantity(limitOrder.Quantity)
+				m.startSession(limitOrder)
+			}
+
+			reply <- r
+		}
+	})
+}
+
+// storeSignal stores the limit order of the session opened by the signal, the retry returns the stored
+// session. It is called by the task, the limit order is nil when no session is opened.
+func (u *orderUseCase) storeSignal(
+	s *structs.Signal,
+	sessionID string,
+	rejected, blocked error,
+	settings *mongoStructs.Settings,
+	status structs.Status,
+	actualPrice float64,
+) (*structs.SignalResult, *models.Order, error) {
+	orders, err := u.orderRepo.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(orders) != 0 {
+		out := structs.SignalResult{
+			SessionID: sessionID,
+			Duplicate: true,
+		}
+
+		for _, o := range orders {
+			if o.Type == OrderTypeLimit {
+				out.OrderIDs = append(out.OrderIDs, o.ID)
+			}
+		}
+
+		return &out, nil, nil
+	}
+
+	if rejected != nil {
+		return nil, nil, rejected
+	}
+
+	pricePlan, err := u.fillSignalPricePlan(s, settings, status, actualPrice, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if blocked != nil {
+		return nil, nil, blocked
+	}
+
+	limitOrder, err := u.storeFeaturesLimitOrder(pricePlan, models.OrderSourceManual)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	u.logRus.
+		WithField("symbol", s.Symbol).
+		WithField("sessionID", sessionID).
+		Infof("Signal [%s] %s %v at %.2f, take profit %.2f, stop loss %.2f", s.Symbol, s.Side, limitOrder.Quantity, limitOrder.Price, limitOrder.TakeProfit, limitOrder.StopLoss)
+
+	return &structs.SignalResult{
+		SessionID: sessionID,
+		OrderIDs:  []string{limitOrder.ID},
+	}, limitOrder, nil
+}
+
+// fillSignalPricePlan builds the entry of the signal, the entry is sent by market so the exits are checked
+// against the actual price. The missing exits are set by the exit distance, the missing quantity by the risk
+// rounded down to the lot of the symbol.
+func (u *orderUseCase) fillSignalPricePlan(s *structs.Signal, settings *mongoStructs.Settings, status structs.Status, actualPrice float64, sessionID string) (*structs.PricePlan, error) {
+	status.SessionID = sessionID
+	status.OrderTry = 1
+
+	out := structs.PricePlan{
+		Symbol:      s.Symbol,
+		ActualPrice: actualPrice,
+		Side:        s.Side,
+		TakeProfit:  s.TakeProfit,
+		StopLoss:    s.StopLoss,
+		Status:      &status,
+
+		SettingsVersion: settings.Version,
+	}
+
+	exitDistance := u.getExitDistance(settings)
+
+	out.ExitModel = exitDistance.Model
+	out.Volatility = exitDistance.Volatility
+	out.SafeDelta = exitDistance.SafeDelta
+	out.TriggerDelta = exitDistance.TriggerDelta
+
+	switch s.Side {
+	case SideBuy:
+		out.PositionSide = PositionSideLong
+		out.Price = actualPrice + (out.TriggerDelta / 2)
+
+		if out.StopLoss == 0 {
+			out.StopLoss = out.Price - out.SafeDelta
+		}
+		if out.TakeProfit == 0 {
+			out.TakeProfit = out.Price + (out.SafeDelta * 3)
+		}
+	case SideSell:
+		out.PositionSide = PositionSideShort
+		out.Price = actualPrice - (out.TriggerDelta / 2)
+
+		if out.StopLoss == 0 {
+			out.StopLoss = out.Price + out.SafeDelta
+		}
+		if out.TakeProfit == 0 {
+			out.TakeProfit = out.Price - (out.SafeDelta * 3)
+		}
+	}
+
+	if err := s.CheckExits(out.Price, out.TakeProfit, out.StopLoss); err != nil {
+		return nil, err
+	}
+
+	limits, err := u.getSymbolLimits(s.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := s.Quantity
+	if quantity == 0 {
+		quantity = structs.SignalQuantity(s.Risk, out.Price, out.StopLoss, limits.StepSize)
+	}
+
+	quantity = limits.FloorQuantity(quantity)
+	risk := quantity * math.Abs(out.Price-out.StopLoss)
+
+	switch {
+	case quantity == 0 || quantity < limits.MinQty:
+		return nil, fmt.Errorf("%w: quantity %v is below the lot %v", structs.ErrSignalRejected, quantity, limits.MinQty)
+	case settings.Limit > 0 && quantity > settings.Limit:
+		return nil, fmt.Errorf("%w: quantity %v is over the limit %v", structs.ErrSignalRejected, quantity, settings.Limit)
+	case settings.SignalMaxRisk > 0 && risk > settings.SignalMaxRisk:
+		return nil, fmt.Errorf("%w: risk %.2f is over the max risk %.2f", structs.ErrSignalRejected, risk, settings.SignalMaxRisk)
+	}
+
+	status.SetQuantity(quantity)
+
+	return &out, nil
+}
+
+// closeSignal checks the session of the monitor and closes it in the task, the orders are synced by
+// the pollers so the loop state is not changed
+func (u *orderUseCase) closeSignal(m *Monitor, s *structs.Signal, reply chan<- signalReply) {
+	var blocked error
+
+	limit := m.ordersList.Get(OrderTypeLimit)
+
+	// the orders are sent by the pollers, the close waits for them
+	switch {
+	case m.noLastOrder || m.sessionClosed():
+		blocked = fmt.Errorf("%w: no open session of %s", structs.ErrSignalConflict, s.Symbol)
+	case m.ordersList.creating() || limit.Status == OrderStatusNotFound ||
+		(limit.Status == OrderStatusFilled && (!m.ordersList.Has(OrderTypeCurrentTakeProfit) || !m.ordersList.Has(OrderTypeCurrentStopLoss))):
+		blocked = fmt.Errorf("%w: the orders of session %s are being placed", structs.ErrSignalConflict, m.status.SessionID)
+	}
+
+	sessionID := m.status.SessionID
+	orders := m.ordersList.clone()
+
+	m.async(func() func(m *Monitor) {
+		var r signalReply
+
+		r.result, r.err = u.sendSignalClose(s, sessionID, blocked, orders)
+
+		return func(m *Monitor) {
+			reply <- r
+		}
+	})
+}
+
+// sendSignalClose cancels the orders of the session and closes its position by market, the retry
+// returns the close order sent the first time. The close order rejected by the exchange is sent again.
+func (u *orderUseCase) sendSignalClose(s *structs.Signal, sessionID string, blocked error, orders ordersList) (*structs.SignalResult, error) {
+	closeID := signalCloseID(s)
+
+	stored, err := u.orderRepo.GetByID(closeID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		stored = nil
+	case err != nil:
+		return nil, err
+	case stored.Status == OrderStatusInProgress:
+		return nil, fmt.Errorf("%w: close order %s is being sent", structs.ErrSignalConflict, closeID)
+	case stored.Status != OrderStatusError:
+		return &structs.SignalResult{
+			SessionID: stored.SessionID,
+			OrderIDs:  []string{stored.ID},
+			Duplicate: true,
+		}, nil
+	}
+
+	// the session of the rejected close may be seen closed once its orders are canceled, the position is checked instead
+	if stored != nil {
+		return u.resendSignalClose(s, stored, orders)
+	}
+
+	if blocked != nil {
+		return nil, blocked
+	}
+
+	out := structs.SignalResult{
+		SessionID: sessionID,
+	}
+
+	for _, o := range orders {
+		if o == nil || isFinalOrderStatus(o.Status) {
+			continue
+		}
+
+		if _, err := u.cancelFeatureClientOrder(o.ID, s.Symbol, models.OrderSourceManual); err != nil {
+			return nil, err
+		}
+
+		out.OrderIDs = append(out.OrderIDs, o.ID)
+	}
+
+	limit := orders.Get(OrderTypeLimit)
+
+	positions, err := u.getFeaturePositions(s.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range positions {
+		if p.PositionSide != limit.PositionSide {
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(p.PositionAmt, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if amount == 0 {
+			continue
+		}
+
+		// the position side may hold more than the session
+		if math.Abs(amount) > limit.Quantity {
+			amount = math.Copysign(limit.Quantity, amount)
+		}
+
+		order := constructCloseOrder(sessionID, s.Symbol, p.PositionSide, amount)
+		order.ID = closeID
+		order.Status = OrderStatusInProgress
+
+		if err := u.orderRepo.Store(order, orderChange(models.OrderSourceManual, nil)); err != nil {
+			return nil, err
+		}
+
+		if err := u.sendCloseOrder(order); err != nil {
+			return nil, err
+		}
+
+		out.OrderIDs = append(out.OrderIDs, order.ID)
+	}
+
+	u.logRus.
+		WithField("symbol", s.Symbol).
+		WithField("sessionID", out.SessionID).
+		Infof("Signal [%s] close %v", s.Symbol, out.OrderIDs)
+
+	return &out, nil
+}
+
+// resendSignalClose sends the close order rejected by the exchange again while its position is open
+func (u *orderUseCase) resendSignalClose(s *structs.Signal, stored *models.Order, orders ordersList) (*structs.SignalResult, error) {
+	out := structs.SignalResult{
+		SessionID: stored.SessionID,
+	}
+
+	// the orders are canceled by the first close, their statuses may be not synced yet
+	for _, o := range orders {
+		if o == nil || o.SessionID != stored.SessionID || isFinalOrderStatus(o.Status) {
+			continue
+		}
+
+		if _, err := u.cancelFeatureClientOrder(o.ID, s.Symbol, models.OrderSourceManual); err != nil {
+			u.logRus.
+				WithField("func", "cancelFeatureClientOrder").
+				WithField("orderID", o.ID).
+				Debug(err)
+
+			continue
+		}
+
+		out.OrderIDs = append(out.OrderIDs, o.ID)
+	}
+
+	positions, err := u.getFeaturePositions(s.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	open := false
+
+	for _, p := range positions {
+		amount, err := strconv.ParseFloat(p.PositionAmt, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.PositionSide == stored.PositionSide && amount != 0 {
+			open = true
+		}
+	}
+
+	if !open {
+		return nil, fmt.Errorf("%w: no open position of session %s", structs.ErrSignalConflict, stored.SessionID)
+	}
+
+	if err := u.orderRepo.SetStatus(stored.ID, OrderStatusInProgress, orderChange(models.OrderSourceManual, nil)); err != nil {
+		return nil, err
+	}
+
+	if err := u.sendCloseOrder(stored); err != nil {
+		return nil, err
+	}
+
+	out.OrderIDs = append(out.OrderIDs, stored.ID)
+
+	u.logRus.
+		WithField("symbol", s.Symbol).
+		WithField("sessionID", out.SessionID).
+		Infof("Signal [%s] close %v again", s.Symbol, out.OrderIDs)
+
+	return &out, nil
+}
+
+// sendCloseOrder sends the stored close order, the order rejected by the exchange is set to ERROR
+func (u *orderUseCase) sendCloseOrder(order *models.Order) error {
+	resp, err := u.createFeaturesCloseOrder(order)
+	if err != nil {
+		if err := u.orderRepo.SetStatus(order.ID, OrderStatusError, orderChange(models.OrderSourceManual, []byte(err.Error()))); err != nil {
+			u.logRus.WithField("func", "SetStatus").Debug(err)
+		}
+
+		return err
+	}
+
+	if err := u.orderRepo.SetOrderID(order.ID, resp.OrderId, orderChange(models.OrderSourceManual, nil)); err != nil {
+		u.logRus.WithField("func", "SetOrderID").Debug(err)
+	}
+
+	if err := u.orderRepo.SetStatus(order.ID, OrderStatusNew, orderChange(models.OrderSourceManual, nil)); err != nil {
+		u.logRus.WithField("func", "SetStatus").Debug(err)
+	}
+
+	return nil
+}
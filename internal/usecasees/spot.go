@@ -0,0 +1,612 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// spotEntryTimeout cancels the spot entry which is not filled, a new session is opened after it
+const spotEntryTimeout = 2 * time.Minute
+
+var errNoSpotEntry = errors.New("no spot entry")
+
+// fillSpotPricePlan plans the spot entry. Only the long positions are opened on the spot,
+// the entry is placed below the price on the bids pressure.
+func (u *orderUseCase) fillSpotPricePlan(symbol string, actualPrice float64, settings *mongoStructs.Settings, status *structs.Status, actualDepth *structs.DepthInfo) (*structs.PricePlan, error) {
+	if actualDepth.DeltaBids <= settings.DepthLimit {
+		return nil, errNoSpotEntry
+	}
+
+	exitDistance := u.getExitDistance(settings)
+
+	out := structs.PricePlan{
+		Symbol:       symbol,
+		Status:       status,
+		Side:         SideBuy,
+		ActualPrice:  actualPrice,
+		Price:        actualPrice - (exitDistance.TriggerDelta / 2),
+		ExitModel:    exitDistance.Model,
+		Volatility:   exitDistance.Volatility,
+		SafeDelta:    exitDistance.SafeDelta,
+		TriggerDelta: exitDistance.TriggerDelta,
+	}
+
+	status.SetBottomLevel(actualPrice)
+
+	return &out, nil
+}
+
+// storeSpotExitOrders stores the legs of the OCO protecting the filled entry.
+// The commission is expected in BNB, otherwise the legs sell more than the balance.
+func (u *orderUseCase) storeSpotExitOrders(settings *mongoStructs.Settings, actualPrice float64, limitOrder *models.Order) (*models.Order, *models.Order, error) {
+	exitDistance := u.getExitDistance(settings)
+
+	takeProfit := models.Order{
+		ID:          uuid.NewString(),
+		SessionID:   limitOrder.SessionID,
+		Try:         limitOrder.Try,
+		ActualPrice: actualPrice,
+		Symbol:      limitOrder.Symbol,
+		Side:        SideSell,
+		Type:        OrderTypeCurrentTakeProfit,
+		Quantity:    limitOrder.Quantity,
+		Status:      OrderStatusInProgress,
+		StopPrice:   limitOrder.Price + (exitDistance.SafeDelta * 3),
+
+		SettingsVersion: limitOrder.SettingsVersion,
+	}
+
+	stopLoss := takeProfit
+	stopLoss.ID = uuid.NewString()
+	stopLoss.Type = OrderTypeCurrentStopLoss
+	stopLoss.StopPrice = limitOrder.Price - exitDistance.SafeDelta
+
+	if err := u.orderRepo.Store(&takeProfit, orderChange(models.OrderSourcePoll, nil)); err != nil {
+		return nil, nil, err
+	}
+
+	if err := u.orderRepo.Store(&stopLoss, orderChange(models.OrderSourcePoll, nil)); err != nil {
+		return nil, nil, err
+	}
+
+	return &takeProfit, &stopLoss, nil
+}
+
+// spotStep takes the spot trading decisions, it is called by the event loop
+func (u *orderUseCase) spotStep(m *Monitor, symbol string) {
+	if m.liquidating() {
+		if time.Since(m.liquidatedAt) >= liquidationInterval {
+			u.liquidateSpot(m, symbol)
+
+			m.liquidatedAt = time.Now()
+		}
+
+		return
+	}
+
+	if m.settings == nil || m.depth == nil || m.trades == nil {
+		return
+	}
+
+	if m.noLastOrder {
+		if m.actualPrice != 0 && m.newSessionsAllowed() {
+			m.status.SetQuantity(m.settings.Step)
+
+			u.newSession(m, symbol, m.actualPrice, nil)
+		}
+
+		return
+	}
+
+	if m.ordersList.IsNil() || !m.ordersList.Has(OrderTypeLimit) {
+		return
+	}
+
+	if m.status.Quantity == 0 {
+		m.status.SetQuantity(m.settings.Step)
+	}
+
+	limitOrder := m.ordersList.Get(OrderTypeLimit)
+
+	if chkCreateOrders(m.ordersList) {
+		entry := *limitOrder
+		settings, actualPrice := m.settings, m.actualPrice
+
+		m.async(func() func(m *Monitor) {
+			takeProfit, stopLoss, err := u.storeSpotExitOrders(settings, actualPrice, &entry)
+			if err != nil {
+				u.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+
+				return nil
+			}
+
+			return func(m *Monitor) {
+				m.setExitOrders(entry.ID, takeProfit, stopLoss)
+			}
+		})
+
+		return
+	}
+
+	if limitOrder.Status == OrderStatusNew && m.canceledEntry != limitOrder.ID &&
+		!limitOrder.CreatedAt.IsZero() && time.Since(limitOrder.CreatedAt) > spotEntryTimeout {
+		u.cancelSpotEntry(m, limitOrder)
+
+		return
+	}
+
+	if m.actualPrice == 0 || !m.newSessionsAllowed() {
+		return
+	}
+
+	switch {
+	case limitOrder.Status == OrderStatusCanceled || limitOrder.Status == OrderStatusExpired:
+		u.newSession(m, symbol, m.actualPrice, nil)
+	case chkCreateLimitOrderTakeProfit(m.ordersList), chkCreateLimitOrderStopLoss(m.ordersList):
+		u.newSession(m, symbol, m.actualPrice, nil)
+	}
+}
+
+// cancelSpotEntry cancels the entry which is not filled in time out of the event loop
+func (u *orderUseCase) cancelSpotEntry(m *Monitor, limitOrder *models.Order) {
+	m.canceledEntry = limitOrder.ID
+
+	id, symbol := limitOrder.ID, limitOrder.Symbol
+
+	m.async(func() func(m *Monitor) {
+		order, err := u.cancelSpotOrder(id, symbol, models.OrderSourcePoll)
+		if err != nil {
+			u.logRus.
+				WithField("func", "cancelSpotOrder").
+				WithField("orderID", id).
+				Debug(err)
+
+			return nil
+		}
+
+		// the order is filled in part between the status check and the cancel
+		if executed, err := strconv.ParseFloat(order.ExecutedQty, 64); err == nil && executed > 0 {
+			u.logRus.
+				WithField("symbol", symbol).
+				WithField("orderID", id).
+				Errorf("spot entry is canceled with %s executed, the position is not protected", order.ExecutedQty)
+
+			u.notify(controllers.TgmEventAlert, fmt.Sprintf("[ Spot ]\nSymbol:\t%s\nEntry canceled with %s executed\nThe position is not protected", symbol, order.ExecutedQty))
+		}
+
+		return nil
+	})
+}
+
+// syncSpotOrders writes the exchange state of the session orders to the repository.
+// The entry is read by the order, the exits are read by the OCO list once it is done.
+func (u *orderUseCase) syncSpotOrders(list ordersList) {
+	if o := list[OrderTypeLimitID]; o != nil && o.Status != OrderStatusInProgress && !isFinalOrderStatus(o.Status) {
+		u.syncSpotOrderStatus(o)
+	}
+
+	takeProfit, stopLoss := list[OrderTypeTakeProfitID], list[OrderTypeStopLossID]
+	if takeProfit == nil || stopLoss == nil || takeProfit.Status == OrderStatusInProgress {
+		return
+	}
+
+	if isFinalOrderStatus(takeProfit.Status) && isFinalOrderStatus(stopLoss.Status) {
+		return
+	}
+
+	u.syncSpotOrderList(takeProfit, stopLoss)
+}
+
+func (u *orderUseCase) syncSpotOrderStatus(o *models.Order) {
+	order, err := u.getSpotOrderInfo(o.ID, o.Symbol)
+	if err != nil {
+		u.logRus.
+			WithField("orderId", o.ID).
+			WithField("func", "getSpotOrderInfo").Debug(err)
+
+		return
+	}
+
+	payload, err := json.Marshal(order)
+	if err != nil {
+		u.logRus.WithField("func", "Marshal").Debug(err)
+
+		return
+	}
+
+	change := orderChange(models.OrderSourcePoll, payload)
+
+	if o.OrderID != order.OrderId {
+		if err := u.orderRepo.SetOrderID(order.ClientOrderId, order.OrderId, change); err != nil {
+			u.logRus.WithField("func", "SetOrderID").Debug(err)
+
+			return
+		}
+	}
+
+	if o.Status != order.Status {
+		if err := u.orderRepo.SetStatus(order.ClientOrderId, order.Status, change); err != nil {
+			u.logRus.WithField("func", "SetStatus").Debug(err)
+
+			return
+		}
+	}
+
+	executed, err := strconv.ParseFloat(order.ExecutedQty, 64)
+	if err != nil || executed == 0 {
+		return
+	}
+
+	quote, err := strconv.ParseFloat(order.CummulativeQuoteQty, 64)
+	if err != nil {
+		u.logRus.WithField("func", "ParseFloat").Debug(err)
+
+		return
+	}
+
+	if err := u.orderRepo.SetActualPrice(order.ClientOrderId, quote/executed, change); err != nil {
+		u.logRus.WithField("func", "SetActualPrice").Debug(err)
+	}
+}
+
+// syncSpotOrderList tracks both legs of the OCO, the legs are read once the list is done
+func (u *orderUseCase) syncSpotOrderList(takeProfit, stopLoss *models.Order) {
+	list, err := u.getSpotOrderList(takeProfit.SessionID)
+	if err != nil {
+		u.logRus.
+			WithField("orderId", takeProfit.SessionID).
+			WithField("func", "getSpotOrderList").Debug(err)
+
+		return
+	}
+
+	payload, err := json.Marshal(list)
+	if err != nil {
+		u.logRus.WithField("func", "Marshal").Debug(err)
+
+		return
+	}
+
+	change := orderChange(models.OrderSourcePoll, payload)
+
+	legs := []*models.Order{takeProfit, stopLoss}
+
+	for _, leg := range list.Orders {
+		for _, o := range legs {
+			if o.ID != leg.ClientOrderID || o.OrderID == leg.OrderID {
+				continue
+			}
+
+			if err := u.orderRepo.SetOrderID(o.ID, leg.OrderID, change); err != nil {
+				u.logRus.WithField("func", "SetOrderID").Debug(err)
+			}
+		}
+	}
+
+	switch list.ListOrderStatus {
+	case OrderListStatusAllDone:
+		for _, o := range legs {
+			if !isFinalOrderStatus(o.Status) {
+				u.syncSpotOrderStatus(o)
+			}
+		}
+	case OrderListStatusReject:
+		u.logRus.
+			WithField("symbol", takeProfit.Symbol).
+			WithField("sessionID", takeProfit.SessionID).
+			Error("spot OCO is rejected, the position is not protected")
+
+		for _, o := range legs {
+			if err := u.orderRepo.SetStatus(o.ID, OrderStatusError, change); err != nil {
+				u.logRus.WithField("func", "SetStatus").Debug(err)
+			}
+		}
+	}
+}
+
+// spotOrderSent reports whether the stored order was sent to the exchange
+func spotOrderSent(o *models.Order) bool {
+	switch o.Status {
+	case OrderStatusInProgress, OrderStatusError:
+		return false
+	case OrderStatusCanceled:
+		return o.OrderID != 0
+	}
+
+	return true
+}
+
+// getSpotPosition returns the base asset held by the last session and the grid, it is nil when both are flat
+func (u *orderUseCase) getSpotPosition(symbol string) (*structs.Position, error) {
+	bought, sold, cost, err := u.getSpotSessionTrades(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	held, heldCost, err := u.gridHolding(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	bought += held
+	cost += heldCost
+
+	limits, err := u.getSymbolLimits(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	// the dust under the step size is not sold
+	amount := limits.FloorQuantity(bought - sold)
+	if amount <= 0 {
+		return nil, nil
+	}
+
+	out := structs.Position{
+		Symbol:       symbol,
+		PositionSide: PositionSideLong,
+		PositionAmt:  limits.FormatQuantity(amount),
+		EntryPrice:   limits.FormatPrice(cost / bought),
+	}
+
+	if price, err := u.getSpotPrice(symbol); err == nil {
+		out.MarkPrice = limits.FormatPrice(price)
+		out.UnRealizedProfit = fmt.Sprintf("%.2f", (price-cost/bought)*amount)
+	}
+
+	return &out, nil
+}
+
+// getSpotSessionTrades returns the quantities bought and sold by the last session and the cost of the bought one,
+// the orders are read from the exchange
+func (u *orderUseCase) getSpotSessionTrades(symbol string) (float64, float64, float64, error) {
+	last, err := u.orderRepo.GetLast(symbol)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, 0, 0, nil
+		}
+
+		return 0, 0, 0, err
+	}
+
+	list, err := u.orderRepo.GetBySessionID(last.SessionID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var bought, sold, cost float64
+
+	for i := range list {
+		o := &list[i]
+
+		if !spotOrderSent(o) {
+			continue
+		}
+
+		info, err := u.getSpotOrderInfo(o.ID, symbol)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		executed, err := strconv.ParseFloat(info.ExecutedQty, 64)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		quote, err := strconv.ParseFloat(info.CummulativeQuoteQty, 64)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+
+		switch o.Side {
+		case SideBuy:
+			bought += executed
+			cost += quote
+		case SideSell:
+			sold += executed
+		}
+	}
+
+	return bought, sold, cost, nil
+}
+
+// cancelSpotEntries cancels the open entries, the OCO legs and the grid sells are left
+func (u *orderUseCase) cancelSpotEntries(symbol string) (int, error) {
+	openOrders, err := u.getSpotOpenOrders(symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	var canceled int
+
+	for _, o := range openOrders {
+		if o.Type != OrderTypeLimit || o.OrderListId != -1 || o.Side == SideSell ||
+			strings.HasPrefix(o.ClientOrderId, liquidationOrderPrefix) {
+			continue
+		}
+
+		if _, err := u.cancelSpotOrder(o.ClientOrderId, symbol, models.OrderSourceManual); err != nil {
+			u.logRus.
+				WithField("func", "cancelSpotOrder").
+				WithField("orderID", o.ClientOrderId).
+				Debug(err)
+
+			continue
+		}
+
+		canceled++
+	}
+
+	return canceled, nil
+}
+
+// flattenSpot cancels every open order of the symbol and sells the position of the last session and the grid
+// by market, the grid is stopped. It returns the position seen before the sale.
+func (u *orderUseCase) flattenSpot(symbol string) (int, int, *structs.Position, error) {
+	openOrders, err := u.getSpotOpenOrders(symbol)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	if len(openOrders) > 0 {
+		if err := u.cancelSpotOpenOrders(symbol); err != nil {
+			return 0, 0, nil, err
+		}
+	}
+
+	position, err := u.getSpotPosition(symbol)
+	if err != nil {
+		return len(openOrders), 0, nil, err
+	}
+
+	if position == nil {
+		return len(openOrders), 0, nil, u.stopActiveGrid(symbol)
+	}
+
+	amount, err := strconv.ParseFloat(position.PositionAmt, 64)
+	if err != nil {
+		return len(openOrders), 0, position, err
+	}
+
+	// the symbol traded by the grid only has no session
+	var sessionID string
+
+	last, err := u.orderRepo.GetLast(symbol)
+	switch {
+	case err == nil:
+		sessionID = last.SessionID
+	case err != sql.ErrNoRows:
+		return len(openOrders), 0, position, err
+	}
+
+	o := constructCloseOrder(sessionID, symbol, "", amount)
+	o.Status = OrderStatusInProgress
+
+	// the sale is a part of the session, so it is counted by getSpotPosition
+	if err := u.orderRepo.Store(o, orderChange(models.OrderSourceManual, nil)); err != nil {
+		return len(openOrders), 0, position, err
+	}
+
+	resp, err := u.createSpotCloseOrder(o)
+	if err != nil {
+		if err := u.orderRepo.SetStatus(o.ID, OrderStatusError, orderChange(models.OrderSourceManual, []byte(err.Error()))); err != nil {
+			u.logRus.WithField("func", "SetStatus").Debug(err)
+		}
+
+		return len(openOrders), 0, position, err
+	}
+
+	if err := u.orderRepo.SetOrderID(o.ID, resp.OrderID, orderChange(models.OrderSourceManual, nil)); err != nil {
+		u.logRus.WithField("func", "SetOrderID").Debug(err)
+	}
+
+	if err := u.orderRepo.SetStatus(o.ID, resp.Status, orderChange(models.OrderSourceManual, nil)); err != nil {
+		u.logRus.WithField("func", "SetStatus").Debug(err)
+	}
+
+	// the base asset of the grid is sold with the session one
+	return len(openOrders), 1, position, u.stopActiveGrid(symbol)
+}
+
+// liquidateSpot runs one step of the spot wind-down out of the event loop. The spot holds the long positions only,
+// LIQUIDATION_SELL cancels the entries and leaves the position.
+func (u *orderUseCase) liquidateSpot(m *Monitor, symbol string) {
+	l := u.startLiquidation(m, symbol)
+
+	list := m.ordersList.clone()
+	settings := m.settings
+
+	m.async(func() func(m *Monitor) {
+		for _, o := range list {
+			if o == nil || o.Status != OrderStatusInProgress {
+				continue
+			}
+
+			if err := u.orderRepo.SetStatus(o.ID, OrderStatusCanceled, orderChange(models.OrderSourceManual, nil)); err != nil {
+				u.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+			}
+		}
+
+		if !liquidates(l.status, PositionSideLong, 1) {
+			canceled, err := u.cancelSpotEntries(symbol)
+			l.canceled += canceled
+
+			if err != nil {
+				u.logRus.
+					WithField("func", "cancelSpotEntries").
+					WithField("symbol", symbol).
+					Debug(err)
+
+				return nil
+			}
+
+			return u.finishLiquidation(l, settings, symbol)
+		}
+
+		canceled, closed, position, err := u.flattenSpot(symbol)
+		l.canceled += canceled
+		l.placed += closed
+
+		if position != nil {
+			if _, ok := l.positions[PositionSideLong]; !ok {
+				l.positions[PositionSideLong] = *position
+			}
+		}
+
+		if err != nil {
+			u.logRus.
+				WithField("func", "flattenSpot").
+				WithField("symbol", symbol).
+				Debug(err)
+
+			return nil
+		}
+
+		return u.finishLiquidation(l, settings, symbol)
+	})
+}
+
+// applySpotShutdownPolicy is applyShutdownPolicy of the spot market
+func (u *orderUseCase) applySpotShutdownPolicy(symbol string, policy ShutdownPolicy) SymbolShutdownReport {
+	out := SymbolShutdownReport{
+		Symbol: symbol,
+	}
+
+	switch policy {
+	case ShutdownCancelEntries:
+		out.Canceled, out.Err = u.cancelSpotEntries(symbol)
+	case ShutdownFlatten:
+		out.Canceled, out.Closed, _, out.Err = u.flattenSpot(symbol)
+	}
+
+	position, err := u.getSpotPosition(symbol)
+	if err != nil {
+		if out.Err == nil {
+			out.Err = err
+		}
+
+		return out
+	}
+
+	if position != nil {
+		out.Positions = append(out.Positions, *position)
+	}
+
+	return out
+}
@@ -0,0 +1,366 @@
+package usecasees
+
+import (
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+)
+
+const (
+	OrderListStatusExecuting = "EXECUTING"
+	OrderListStatusAllDone   = "ALL_DONE"
+	OrderListStatusReject    = "REJECT"
+)
+
+func (u *orderUseCase) createSpotLimitOrder(order *models.Order) error {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return err
+	}
+
+	// the precision of the quantity and the price is the one of the symbol filters
+	limits, err := u.getSymbolLimits(order.Symbol)
+	if err != nil {
+		return err
+	}
+
+	baseURL.Path = path.Join(orderUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", order.Symbol)
+	q.Set("side", order.Side)
+	q.Set("type", OrderTypeLimit)
+	q.Set("timeInForce", "GTC")
+	q.Set("quantity", limits.FormatQuantity(order.Quantity))
+	q.Set("price", limits.FormatPrice(order.Price))
+	q.Set("newClientOrderId", order.ID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	u.recordOrderEvent(order, models.OrderEventSubmitted, models.OrderSourcePoll, submittedPayload(q))
+
+	resp, err := u.clientController.Send(http.MethodPost, baseURL, nil, true)
+	if err != nil {
+		return err
+	}
+
+	var respOrder structs.LimitOrder
+	if err := json.Unmarshal(resp, &respOrder); err != nil {
+		return err
+	}
+
+	if respOrder.OrderID == 0 {
+		if err := u.orderRepo.SetStatus(order.ID, OrderStatusError, orderChange(models.OrderSourcePoll, resp)); err != nil {
+			return err
+		}
+
+		return fmt.Errorf("err OrderId == 0 : %s", resp)
+	}
+
+	if err := u.orderRepo.SetOrderID(order.ID, respOrder.OrderID, orderChange(models.OrderSourcePoll, resp)); err != nil {
+		u.logRus.WithField("func", "SetOrderID").Debug(err)
+	}
+
+	return nil
+}
+
+// createSpotOCOOrder protects the position with both exits at once, the list is named by the session.
+// When one leg is filled the exchange expires the other one.
+func (u *orderUseCase) createSpotOCOOrder(takeProfit, stopLoss *models.Order) (*structs.OrderList, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := u.getSymbolLimits(takeProfit.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderOCO)
+
+	triggerDelta := stopLoss.StopPrice / 100 * 0.01
+
+	q := baseURL.Query()
+	q.Set("symbol", takeProfit.Symbol)
+	q.Set("side", takeProfit.Side)
+	q.Set("quantity", limits.FormatQuantity(takeProfit.Quantity))
+	q.Set("price", limits.FormatPrice(takeProfit.StopPrice))
+	q.Set("stopPrice", limits.FormatPrice(stopLoss.StopPrice+triggerDelta))
+	q.Set("stopLimitPrice", limits.FormatPrice(stopLoss.StopPrice))
+	q.Set("stopLimitTimeInForce", "GTC")
+	q.Set("listClientOrderId", takeProfit.SessionID)
+	q.Set("limitClientOrderId", takeProfit.ID)
+	q.Set("stopClientOrderId", stopLoss.ID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	u.recordOrderEvent(takeProfit, models.OrderEventSubmitted, models.OrderSourcePoll, submittedPayload(q))
+	u.recordOrderEvent(stopLoss, models.OrderEventSubmitted, models.OrderSourcePoll, submittedPayload(q))
+
+	resp, err := u.clientController.Send(http.MethodPost, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out structs.OrderList
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	if out.OrderListID == 0 {
+		return nil, fmt.Errorf("err OrderListId == 0 : %s", resp)
+	}
+
+	return &out, nil
+}
+
+// createSpotCloseOrder sells the position by market
+func (u *orderUseCase) createSpotCloseOrder(order *models.Order) (*structs.LimitOrder, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := u.getSymbolLimits(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", order.Symbol)
+	q.Set("side", order.Side)
+	q.Set("type", OrderTypeMarket)
+	q.Set("quantity", limits.FormatQuantity(order.Quantity))
+	q.Set("newClientOrderId", order.ID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	// the spot is closed by the liquidation and the shutdown
+	u.recordOrderEvent(order, models.OrderEventSubmitted, models.OrderSourceManual, submittedPayload(q))
+
+	resp, err := u.clientController.Send(http.MethodPost, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out structs.LimitOrder
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	if out.OrderID == 0 {
+		return nil, fmt.Errorf("err OrderId == 0 : %s", resp)
+	}
+
+	return &out, nil
+}
+
+func (u *orderUseCase) getSpotOrderInfo(orderID string, symbol string) (*structs.Order, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set("origClientOrderId", orderID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out structs.Order
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// getSpotOrderList returns the OCO by its list client order id
+func (u *orderUseCase) getSpotOrderList(listClientOrderID string) (*structs.OrderList, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderList)
+
+	q := baseURL.Query()
+	q.Set("origClientOrderId", listClientOrderID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out structs.OrderList
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+func (u *orderUseCase) getSpotOpenOrders(symbol string) ([]structs.Order, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderOpenUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []structs.Order
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// cancelSpotOrder cancels the order by its client order id, source is recorded in the order events
+func (u *orderUseCase) cancelSpotOrder(clientOrderID string, symbol string, source string) (*structs.Order, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL.Path = path.Join(orderUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set("origClientOrderId", clientOrderID)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodDelete, baseURL, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var out structs.Order
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return nil, err
+	}
+
+	u.recordOrderEvent(canceledOrder(clientOrderID, &out), models.OrderEventCanceled, source, resp)
+
+	return &out, nil
+}
+
+// cancelSpotOpenOrders cancels every open order of the symbol, the OCO lists included
+func (u *orderUseCase) cancelSpotOpenOrders(symbol string) error {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return err
+	}
+
+	baseURL.Path = path.Join(orderOpenUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+	q.Set("recvWindow", "60000")
+	q.Set("timestamp", fmt.Sprintf("%d000", time.Now().Unix()))
+
+	sig := u.cryptoController.GetSignature(q.Encode())
+	q.Set("signature", sig)
+
+	baseURL.RawQuery = q.Encode()
+
+	if _, err := u.clientController.Send(http.MethodDelete, baseURL, nil, true); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (u *orderUseCase) getSpotPrice(symbol string) (float64, error) {
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return 0, err
+	}
+
+	baseURL.Path = path.Join(priceUrlPath)
+
+	q := baseURL.Query()
+	q.Set("symbol", symbol)
+
+	baseURL.RawQuery = q.Encode()
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var out struct {
+		Symbol string `json:"symbol"`
+		Price  string `json:"price"`
+	}
+
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(out.Price, 64)
+}
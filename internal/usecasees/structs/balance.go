@@ -0,0 +1,147 @@
+package structs
+
+import (
+	"math"
+	"sort"
+	"strconv"
+)
+
+// FeatureAccount is the futures account of /fapi/v2/account, the amounts are strings as the exchange sends them
+type FeatureAccount struct {
+	TotalWalletBalance    string                   `json:"totalWalletBalance"`
+	TotalUnrealizedProfit string                   `json:"totalUnrealizedProfit"`
+	TotalMarginBalance    string                   `json:"totalMarginBalance"`
+	AvailableBalance      string                   `json:"availableBalance"`
+	Assets                []FeatureAccountAsset    `json:"assets"`
+	Positions             []FeatureAccountPosition `json:"positions"`
+}
+
+type FeatureAccountAsset struct {
+	Asset            string `json:"asset"`
+	WalletBalance    string `json:"walletBalance"`
+	UnrealizedProfit string `json:"unrealizedProfit"`
+	MarginBalance    string `json:"marginBalance"`
+	AvailableBalance string `json:"availableBalance"`
+}
+
+type FeatureAccountPosition struct {
+	Symbol           string `json:"symbol"`
+	PositionSide     string `json:"positionSide"`
+	PositionAmt      string `json:"positionAmt"`
+	EntryPrice       string `json:"entryPrice"`
+	UnrealizedProfit string `json:"unrealizedProfit"`
+	Notional         string `json:"notional"`
+}
+
+// Balance is the breakdown of the account, Weight is the share of the margin balance in percents
+type Balance struct {
+	Wallet     float64
+	Unrealized float64
+	Margin     float64
+	Available  float64
+	Assets     []BalanceItem
+	Positions  []BalanceItem
+}
+
+type BalanceItem struct {
+	Name       string
+	Amount     float64
+	Price      float64
+	Value      float64
+	Unrealized float64
+	Weight     float64
+}
+
+// Breakdown parses the account and weighs its assets and open positions by the margin balance, the
+// items are sorted by the weight from the largest one
+func (a *FeatureAccount) Breakdown() (*Balance, error) {
+	var out Balance
+	var err error
+
+	for _, f := range []struct {
+		dst *float64
+		src string
+	}{
+		{&out.Wallet, a.TotalWalletBalance},
+		{&out.Unrealized, a.TotalUnrealizedProfit},
+		{&out.Margin, a.TotalMarginBalance},
+		{&out.Available, a.AvailableBalance},
+	} {
+		if *f.dst, err = parseAmount(f.src); err != nil {
+			return nil, err
+		}
+	}
+
+	weight := func(value float64) float64 {
+		if out.Margin == 0 {
+			return 0
+		}
+
+		return math.Abs(value) / out.Margin * 100
+	}
+
+	for _, asset := range a.Assets {
+		item := BalanceItem{Name: asset.Asset}
+
+		if item.Value, err = parseAmount(asset.MarginBalance); err != nil {
+			return nil, err
+		}
+		if item.Unrealized, err = parseAmount(asset.UnrealizedProfit); err != nil {
+			return nil, err
+		}
+		if item.Amount, err = parseAmount(asset.WalletBalance); err != nil {
+			return nil, err
+		}
+
+		if item.Amount == 0 && item.Value == 0 {
+			continue
+		}
+
+		item.Weight = weight(item.Value)
+		out.Assets = append(out.Assets, item)
+	}
+
+	for _, position := range a.Positions {
+		item := BalanceItem{Name: position.Symbol + " " + position.PositionSide}
+
+		if item.Amount, err = parseAmount(position.PositionAmt); err != nil {
+			return nil, err
+		}
+
+		if item.Amount == 0 {
+			continue
+		}
+
+		if item.Price, err = parseAmount(position.EntryPrice); err != nil {
+			return nil, err
+		}
+		if item.Value, err = parseAmount(position.Notional); err != nil {
+			return nil, err
+		}
+		if item.Unrealized, err = parseAmount(position.UnrealizedProfit); err != nil {
+			return nil, err
+		}
+
+		item.Weight = weight(item.Value)
+		out.Positions = append(out.Positions, item)
+	}
+
+	for _, items := range [][]BalanceItem{out.Assets, out.Positions} {
+		items := items
+
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Weight > items[j].Weight
+		})
+	}
+
+	return &out, nil
+}
+
+// parseAmount parses the amount of the exchange, the empty one is zero
+func parseAmount(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseFloat(s, 64)
+}
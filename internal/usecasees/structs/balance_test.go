@@ -0,0 +1,50 @@
+package structs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FeatureAccountBreakdown(t *testing.T) {
+	account := FeatureAccount{
+		TotalWalletBalance:    "1000",
+		TotalUnrealizedProfit: "-20",
+		TotalMarginBalance:    "980",
+		AvailableBalance:      "700",
+		Assets: []FeatureAccountAsset{
+			{Asset: "BNB", WalletBalance: "0", MarginBalance: "0"},
+			{Asset: "USDT", WalletBalance: "1000", UnrealizedProfit: "-20", MarginBalance: "980"},
+		},
+		Positions: []FeatureAccountPosition{
+			{Symbol: "BTCUSDT", PositionSide: "LONG", PositionAmt: "0.010", EntryPrice: "20000", UnrealizedProfit: "-2", Notional: "198"},
+			{Symbol: "BTCUSDT", PositionSide: "SHORT", PositionAmt: "0.000", Notional: "0"},
+			{Symbol: "ETHUSDT", PositionSide: "SHORT", PositionAmt: "-0.5", EntryPrice: "1500", UnrealizedProfit: "-18", Notional: "-768"},
+		},
+	}
+
+	got, err := account.Breakdown()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, float64(980), got.Margin)
+
+	// the empty assets and the closed positions are skipped
+	if assert.Len(t, got.Assets, 1) {
+		assert.Equal(t, "USDT", got.Assets[0].Name)
+		assert.InDelta(t, 100, got.Assets[0].Weight, 1e-9)
+	}
+
+	// the short position is weighed by its absolute notional
+	if assert.Len(t, got.Positions, 2) {
+		assert.Equal(t, "ETHUSDT SHORT", got.Positions[0].Name)
+		assert.InDelta(t, 768.0/980*100, got.Positions[0].Weight, 1e-9)
+		assert.Equal(t, "BTCUSDT LONG", got.Positions[1].Name)
+	}
+
+	account.TotalMarginBalance = "n/a"
+
+	_, err = account.Breakdown()
+	assert.Error(t, err)
+}
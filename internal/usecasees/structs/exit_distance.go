@@ -0,0 +1,90 @@
+package structs
+
+import (
+	"binance/models"
+	"math"
+)
+
+type ExitModel string
+
+const (
+	ExitModelStatic      ExitModel = "STATIC"
+	ExitModelATR         ExitModel = "ATR"
+	ExitModelRealizedVol ExitModel = "REALIZED_VOL"
+)
+
+func (m ExitModel) ToString() string {
+	return string(m)
+}
+
+// ExitDistance is the distance from the entry price used for the stop loss (SafeDelta)
+// and for the entry price offset (TriggerDelta).
+type ExitDistance struct {
+	Model        ExitModel
+	Volatility   float64
+	SafeDelta    float64
+	TriggerDelta float64
+}
+
+// ATR returns the average true range of the candles. Candles must be in chronological order.
+func ATR(candles []models.Candle) float64 {
+	if len(candles) < 2 {
+		return 0
+	}
+
+	var sum float64
+	for i := 1; i < len(candles); i++ {
+		sum += candles[i].TrueRange(candles[i-1].ClosePrice)
+	}
+
+	return sum / float64(len(candles)-1)
+}
+
+// RealizedVolatility returns the standard deviation of the close to close log returns
+// scaled to the whole window and expressed in price units of the last close.
+// Candles must be in chronological order.
+func RealizedVolatility(candles []models.Candle) float64 {
+	if len(candles) < 3 {
+		return 0
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].ClosePrice <= 0 || candles[i].ClosePrice <= 0 {
+			continue
+		}
+
+		returns = append(returns, math.Log(candles[i].ClosePrice/candles[i-1].ClosePrice))
+	}
+
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	return math.Sqrt(variance) * math.Sqrt(float64(len(returns))) * candles[len(candles)-1].ClosePrice
+}
+
+// Clamp bounds v by min and max. A zero bound is treated as not set.
+func Clamp(v, min, max float64) float64 {
+	if min > 0 && v < min {
+		return min
+	}
+
+	if max > 0 && v > max {
+		return max
+	}
+
+	return v
+}
@@ -0,0 +1,54 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ATR(t *testing.T) {
+	candles := []models.Candle{
+		{OpenPrice: 100, ClosePrice: 102, MaxPrice: 103, MinPrice: 99},
+		{OpenPrice: 102, ClosePrice: 101, MaxPrice: 104, MinPrice: 100},
+		{OpenPrice: 101, ClosePrice: 108, MaxPrice: 109, MinPrice: 106},
+	}
+
+	// true ranges: max(4, 2, 2) = 4; max(3, 8, 5) = 8
+	assert.Equal(t, float64(6), structs.ATR(candles))
+
+	assert.Equal(t, float64(0), structs.ATR(candles[:1]))
+}
+
+func Test_RealizedVolatility(t *testing.T) {
+	flat := []models.Candle{
+		{ClosePrice: 100},
+		{ClosePrice: 100},
+		{ClosePrice: 100},
+	}
+	assert.Equal(t, float64(0), structs.RealizedVolatility(flat))
+
+	candles := []models.Candle{
+		{ClosePrice: 100},
+		{ClosePrice: 101},
+		{ClosePrice: 100},
+		{ClosePrice: 101},
+	}
+
+	v := structs.RealizedVolatility(candles)
+	assert.True(t, v > 0)
+
+	r := math.Log(101.0 / 100.0)
+	mean := r / 3
+	variance := (2*(r-mean)*(r-mean) + (-r-mean)*(-r-mean)) / 2
+	assert.InDelta(t, math.Sqrt(variance)*math.Sqrt(3)*101, v, 1e-9)
+}
+
+func Test_Clamp(t *testing.T) {
+	assert.Equal(t, float64(25), structs.Clamp(10, 25, 120))
+	assert.Equal(t, float64(120), structs.Clamp(500, 25, 120))
+	assert.Equal(t, float64(60), structs.Clamp(60, 25, 120))
+	assert.Equal(t, float64(500), structs.Clamp(500, 25, 0))
+}
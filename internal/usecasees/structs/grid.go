@@ -0,0 +1,30 @@
+package structs
+
+import "fmt"
+
+// GridPrices splits the range into the prices of the levels, the first one is min and the last one is max
+func GridPrices(min, max float64, levels int) ([]float64, error) {
+	if levels < 2 {
+		return nil, fmt.Errorf("grid needs 2 levels at least, got %d", levels)
+	}
+
+	if min <= 0 || max <= min {
+		return nil, fmt.Errorf("grid range %.2f - %.2f is empty", min, max)
+	}
+
+	step := (max - min) / float64(levels-1)
+
+	out := make([]float64, levels)
+	for i := range out {
+		out[i] = min + step*float64(i)
+	}
+
+	out[levels-1] = max
+
+	return out, nil
+}
+
+// GridProfit is the profit of the quantity bought on the level and sold a level higher
+func GridProfit(buyPrice, sellPrice, quantity float64) float64 {
+	return (sellPrice - buyPrice) * quantity
+}
@@ -0,0 +1,25 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GridPrices(t *testing.T) {
+	prices, err := structs.GridPrices(19800, 20500, 6)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{19800, 19940, 20080, 20220, 20360, 20500}, prices)
+
+	_, err = structs.GridPrices(19800, 20500, 1)
+	assert.Error(t, err)
+
+	_, err = structs.GridPrices(20500, 19800, 6)
+	assert.Error(t, err)
+
+	_, err = structs.GridPrices(0, 20500, 6)
+	assert.Error(t, err)
+
+	assert.InDelta(t, 0.42, structs.GridProfit(19800, 19940, 0.003), 1e-9)
+}
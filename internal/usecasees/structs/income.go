@@ -0,0 +1,169 @@
+package structs
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the income types of the futures account
+const (
+	IncomeRealizedPnL = "REALIZED_PNL"
+	IncomeCommission  = "COMMISSION"
+	IncomeFundingFee  = "FUNDING_FEE"
+	IncomeTransfer    = "TRANSFER"
+)
+
+// incomeCSVTime is the time layout of the transaction history export, the time is in UTC
+const incomeCSVTime = "2006-01-02 15:04:05"
+
+// incomeCSVColumns is the column order of the export without the header
+var incomeCSVColumns = []string{"time", "type", "amount", "asset", "symbol"}
+
+// Income is the change of the futures balance returned by /fapi/v1/income
+type Income struct {
+	Symbol     string `json:"symbol"`
+	IncomeType string `json:"incomeType"`
+	Income     string `json:"income"`
+	Asset      string `json:"asset"`
+	Info       string `json:"info"`
+	Time       int64  `json:"time"`
+	TranID     int64  `json:"tranId"`
+	TradeID    string `json:"tradeId"`
+}
+
+// IncomeRow is the row of the transaction history exported as CSV
+type IncomeRow struct {
+	Time       time.Time
+	IncomeType string
+	Amount     float64
+	Asset      string
+	Symbol     string
+}
+
+// ReadIncomeCSV reads the transaction history export: Time(UTC),Type,Amount,Asset,Symbol. The header
+// may reorder the columns, the lines starting with # are skipped.
+func ReadIncomeCSV(r io.Reader) ([]IncomeRow, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := incomeCSVIndex(incomeCSVColumns)
+
+	var out []IncomeRow
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 1 && isIncomeCSVHeader(record) {
+			names := make([]string, len(record))
+			for i, v := range record {
+				names[i] = incomeCSVColumn(v)
+			}
+
+			columns = incomeCSVIndex(names)
+
+			for _, c := range incomeCSVColumns[:4] {
+				if _, ok := columns[c]; !ok {
+					return nil, fmt.Errorf("no %s column in the header", c)
+				}
+			}
+
+			continue
+		}
+
+		row, err := parseIncomeRow(record, columns)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		out = append(out, *row)
+	}
+
+	return out, nil
+}
+
+func parseIncomeRow(record []string, columns map[string]int) (*IncomeRow, error) {
+	field := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+
+		return strings.TrimSpace(record[i])
+	}
+
+	t, err := time.Parse(incomeCSVTime, field("time"))
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := strconv.ParseFloat(field("amount"), 64)
+	if err != nil {
+		return nil, err
+	}
+
+	out := IncomeRow{
+		Time:       t.UTC(),
+		IncomeType: strings.ToUpper(field("type")),
+		Amount:     amount,
+		Asset:      field("asset"),
+		Symbol:     field("symbol"),
+	}
+
+	if out.IncomeType == "" || out.Asset == "" {
+		return nil, errors.New("no type or asset")
+	}
+
+	return &out, nil
+}
+
+// isIncomeCSVHeader reports whether the first record is the header, the header has no time
+func isIncomeCSVHeader(record []string) bool {
+	if len(record) == 0 {
+		return false
+	}
+
+	_, err := time.Parse(incomeCSVTime, strings.TrimSpace(record[0]))
+
+	return err != nil
+}
+
+// incomeCSVColumn maps the header name to the column, "Time(UTC)" is time and "Coin" is asset.
+// The export may start with the byte order mark.
+func incomeCSVColumn(v string) string {
+	v = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(v, "\ufeff")))
+
+	if i := strings.IndexByte(v, '('); i != -1 {
+		v = strings.TrimSpace(v[:i])
+	}
+
+	switch v {
+	case "coin":
+		return "asset"
+	case "change":
+		return "amount"
+	}
+
+	return v
+}
+
+func incomeCSVIndex(names []string) map[string]int {
+	out := make(map[string]int, len(names))
+	for i, v := range names {
+		out[v] = i
+	}
+
+	return out
+}
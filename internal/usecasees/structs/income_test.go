@@ -0,0 +1,62 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadIncomeCSV(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		rows, err := structs.ReadIncomeCSV(strings.NewReader(
+			"Time(UTC),Type,Amount,Asset,Symbol\n" +
+				"# the export of October\n" +
+				"2022-10-17 08:00:00,FUNDING_FEE,-0.0123,USDT,BTCUSDT\n" +
+				"2022-10-17 09:15:30,TRANSFER,100,USDT,\n",
+		))
+		assert.NoError(t, err)
+
+		if assert.Len(t, rows, 2) {
+			assert.Equal(t, time.Date(2022, 10, 17, 8, 0, 0, 0, time.UTC), rows[0].Time)
+			assert.Equal(t, structs.IncomeFundingFee, rows[0].IncomeType)
+			assert.Equal(t, -0.0123, rows[0].Amount)
+			assert.Equal(t, "BTCUSDT", rows[0].Symbol)
+
+			assert.Equal(t, structs.IncomeTransfer, rows[1].IncomeType)
+			assert.Empty(t, rows[1].Symbol)
+		}
+	})
+
+	t.Run("reordered header", func(t *testing.T) {
+		rows, err := structs.ReadIncomeCSV(strings.NewReader(
+			"Symbol,Coin,Change,Type,Time(UTC)\n" +
+				"BTCUSDT,USDT,1.5,realized_pnl,2022-10-17 10:00:00\n",
+		))
+		assert.NoError(t, err)
+
+		if assert.Len(t, rows, 1) {
+			assert.Equal(t, structs.IncomeRealizedPnL, rows[0].IncomeType)
+			assert.Equal(t, 1.5, rows[0].Amount)
+			assert.Equal(t, "USDT", rows[0].Asset)
+		}
+	})
+
+	t.Run("no header", func(t *testing.T) {
+		rows, err := structs.ReadIncomeCSV(strings.NewReader("2022-10-17 10:00:00,COMMISSION,-0.0078,USDT,BTCUSDT\n"))
+		assert.NoError(t, err)
+		assert.Len(t, rows, 1)
+	})
+
+	t.Run("wrong amount", func(t *testing.T) {
+		_, err := structs.ReadIncomeCSV(strings.NewReader("2022-10-17 10:00:00,COMMISSION,x,USDT,BTCUSDT\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("no asset column", func(t *testing.T) {
+		_, err := structs.ReadIncomeCSV(strings.NewReader("Time(UTC),Type,Amount\n"))
+		assert.Error(t, err)
+	})
+}
@@ -136,6 +136,33 @@ type Order struct {
 	OrigQuoteOrderQty   string `json:"origQuoteOrderQty"`
 }
 
+type Position struct {
+	Symbol           string `json:"symbol"`
+	PositionAmt      string `json:"positionAmt"`
+	EntryPrice       string `json:"entryPrice"`
+	MarkPrice        string `json:"markPrice"`
+	UnRealizedProfit string `json:"unRealizedProfit"`
+	PositionSide     string `json:"positionSide"`
+}
+
+// UserTrade is the trade of the account on the futures market
+type UserTrade struct {
+	ID              int64  `json:"id"`
+	Symbol          string `json:"symbol"`
+	OrderID         int64  `json:"orderId"`
+	Side            string `json:"side"`
+	PositionSide    string `json:"positionSide"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	QuoteQty        string `json:"quoteQty"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	RealizedPnl     string `json:"realizedPnl"`
+	Buyer           bool   `json:"buyer"`
+	Maker           bool   `json:"maker"`
+	Time            int64  `json:"time"`
+}
+
 type FeatureOrderResp struct {
 	OrderId       int64  `json:"orderId,omitempty"`
 	Symbol        string `json:"symbol,omitempty"`
@@ -239,12 +266,11 @@ type Err struct {
 }
 
 func (e *Err) Send(tgm controllers.TgmCtrl) error {
-	if err := tgm.Send(fmt.Sprintf("[ Err createOCOOrder ]\n"+
-		"Code:\t%d\n"+
-		"Msg:\t%s",
-		e.Code,
-		e.Msg,
-	)); err != nil {
+	if err := tgm.Notify(controllers.TgmEventError, controllers.TgmError{
+		Op:   "createOCOOrder",
+		Code: e.Code,
+		Msg:  e.Msg,
+	}); err != nil {
 		return err
 	}
 
@@ -270,7 +296,12 @@ type PricePlan struct {
 	DeltaPrice             float64
 	SafeDelta              float64
 	TriggerDelta           float64
+	ExitModel              ExitModel
+	Volatility             float64
+	TakeProfit             float64 // the exits fixed by a signal, zero means the exit distance
+	StopLoss               float64
 	Status                 *Status
+	SettingsVersion        int // the version of the symbol settings the plan is made by
 	DepthInfo              *DepthInfo
 	TradeInfo              *TradeInfo
 }
@@ -284,6 +315,10 @@ type TradeInfo struct {
 
 	DeltaSeller float64
 	DeltaBuyer  float64
+
+	// CVD is the cumulative taker volume delta since the tracking started
+	CVD        float64
+	CVDWindows []CVDWindow
 }
 
 type DepthInfo struct {
@@ -300,6 +335,16 @@ type DepthInfo struct {
 
 	DeltaBids float64
 	DeltaAsks float64
+
+	BestBidPrice float64
+	BestAskPrice float64
+
+	MidPrice float64
+	Top      BookImbalance
+	NearMid  BookImbalance
+
+	Walls      []Wall
+	WallEvents []WallEvent
 }
 
 func (p *PricePlan) SetSide(s string) *PricePlan {
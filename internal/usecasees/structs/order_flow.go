@@ -0,0 +1,337 @@
+package structs
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	SideBid = "BID"
+	SideAsk = "ASK"
+
+	WallAppeared WallEventType = "APPEARED"
+	WallChanged  WallEventType = "CHANGED"
+	WallPulled   WallEventType = "PULLED"
+	WallConsumed WallEventType = "CONSUMED"
+
+	// wallChangeRatio is the relative size change reported as WallChanged
+	wallChangeRatio = 0.2
+)
+
+type WallEventType string
+
+type OrderFlowConfig struct {
+	CVDWindows   []time.Duration
+	TopLevels    int
+	MidPercent   float64
+	WallMinRatio float64
+}
+
+type TradeTick struct {
+	ID           int64
+	Price        float64
+	Qty          float64
+	Time         time.Time
+	IsBuyerMaker bool
+}
+
+// CVDWindow is the taker volume over the last Window. Complete is false while the
+// collected trades do not cover the whole window yet.
+type CVDWindow struct {
+	Window     time.Duration
+	BuyVolume  float64
+	SellVolume float64
+	Delta      float64
+	Complete   bool
+}
+
+type TradeWindow struct {
+	since  time.Time
+	lastID int64
+	cvd    float64
+	ticks  []TradeTick
+}
+
+func NewTradeWindow() *TradeWindow {
+	return &TradeWindow{}
+}
+
+// Add appends the trades not seen yet. Trades must be in chronological order.
+func (w *TradeWindow) Add(ticks []TradeTick) {
+	for _, t := range ticks {
+		if t.ID <= w.lastID {
+			continue
+		}
+
+		// trades between two polls were missed, the history is not continuous anymore
+		if w.lastID == 0 || t.ID != w.lastID+1 {
+			w.since = t.Time
+		}
+
+		w.lastID = t.ID
+		w.ticks = append(w.ticks, t)
+
+		if t.IsBuyerMaker {
+			w.cvd -= t.Qty
+		} else {
+			w.cvd += t.Qty
+		}
+	}
+}
+
+// CVD is the cumulative volume delta since the tracking started.
+func (w *TradeWindow) CVD() float64 {
+	return w.cvd
+}
+
+// Windows returns the volume delta for every window and drops the trades older than the longest one.
+func (w *TradeWindow) Windows(now time.Time, windows []time.Duration) []CVDWindow {
+	out := make([]CVDWindow, 0, len(windows))
+
+	var maxWindow time.Duration
+
+	for _, window := range windows {
+		if window > maxWindow {
+			maxWindow = window
+		}
+
+		from := now.Add(-window)
+
+		c := CVDWindow{
+			Window:   window,
+			Complete: !w.since.IsZero() && !w.since.After(from),
+		}
+
+		for i := len(w.ticks) - 1; i >= 0 && w.ticks[i].Time.After(from); i-- {
+			if w.ticks[i].IsBuyerMaker {
+				c.SellVolume += w.ticks[i].Qty
+			} else {
+				c.BuyVolume += w.ticks[i].Qty
+			}
+		}
+
+		c.Delta = c.BuyVolume - c.SellVolume
+
+		out = append(out, c)
+	}
+
+	from := now.Add(-maxWindow)
+	i := sort.Search(len(w.ticks), func(i int) bool {
+		return w.ticks[i].Time.After(from)
+	})
+	w.ticks = append(w.ticks[:0], w.ticks[i:]...)
+
+	return out
+}
+
+type DepthLevel struct {
+	Price float64
+	Qty   float64
+}
+
+// BookImbalance is (BidsSum - AsksSum) / (BidsSum + AsksSum), from -1 (asks only) to 1 (bids only).
+type BookImbalance struct {
+	BidsSum   float64
+	AsksSum   float64
+	Imbalance float64
+}
+
+func newBookImbalance(bidsSum, asksSum float64) BookImbalance {
+	out := BookImbalance{
+		BidsSum: bidsSum,
+		AsksSum: asksSum,
+	}
+
+	if bidsSum+asksSum > 0 {
+		out.Imbalance = (bidsSum - asksSum) / (bidsSum + asksSum)
+	}
+
+	return out
+}
+
+func MidPrice(bids, asks []DepthLevel) float64 {
+	if len(bids) == 0 || len(asks) == 0 {
+		return 0
+	}
+
+	return (bids[0].Price + asks[0].Price) / 2
+}
+
+// TopImbalance is the imbalance over the best levels of the book. Levels are ordered from the best price.
+func TopImbalance(bids, asks []DepthLevel, levels int) BookImbalance {
+	var bidsSum, asksSum float64
+
+	for i := 0; i < levels && i < len(bids); i++ {
+		bidsSum += bids[i].Qty
+	}
+
+	for i := 0; i < levels && i < len(asks); i++ {
+		asksSum += asks[i].Qty
+	}
+
+	return newBookImbalance(bidsSum, asksSum)
+}
+
+// NearMidImbalance is the imbalance over the levels within percent of the mid price.
+func NearMidImbalance(bids, asks []DepthLevel, percent float64) BookImbalance {
+	mid := MidPrice(bids, asks)
+	delta := mid / 100 * percent
+
+	var bidsSum, asksSum float64
+
+	for _, l := range bids {
+		if l.Price < mid-delta {
+			break
+		}
+		bidsSum += l.Qty
+	}
+
+	for _, l := range asks {
+		if l.Price > mid+delta {
+			break
+		}
+		asksSum += l.Qty
+	}
+
+	return newBookImbalance(bidsSum, asksSum)
+}
+
+type Wall struct {
+	Side       string
+	Price      float64
+	Qty        float64
+	InitialQty float64
+	MaxQty     float64
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+type WallEvent struct {
+	Type    WallEventType
+	Wall    Wall
+	PrevQty float64
+	Time    time.Time
+}
+
+type wallKey struct {
+	side  string
+	price float64
+}
+
+// WallTracker follows the levels which are at least minRatio times bigger than the
+// average level of their side between the depth updates.
+type WallTracker struct {
+	walls map[wallKey]*Wall
+}
+
+func NewWallTracker() *WallTracker {
+	return &WallTracker{
+		walls: make(map[wallKey]*Wall),
+	}
+}
+
+func (t *WallTracker) Update(now time.Time, bids, asks []DepthLevel, minRatio float64) ([]Wall, []WallEvent) {
+	var events []WallEvent
+
+	events = append(events, t.updateSide(now, SideBid, bids, minRatio)...)
+	events = append(events, t.updateSide(now, SideAsk, asks, minRatio)...)
+
+	for key, w := range t.walls {
+		if w.LastSeen.Equal(now) {
+			continue
+		}
+
+		eventType := WallPulled
+
+		// the price went through the wall
+		switch key.side {
+		case SideBid:
+			if len(bids) > 0 && bids[0].Price < w.Price {
+				eventType = WallConsumed
+			}
+		case SideAsk:
+			if len(asks) > 0 && asks[0].Price > w.Price {
+				eventType = WallConsumed
+			}
+		}
+
+		events = append(events, WallEvent{
+			Type:    eventType,
+			Wall:    *w,
+			PrevQty: w.Qty,
+			Time:    now,
+		})
+
+		delete(t.walls, key)
+	}
+
+	walls := make([]Wall, 0, len(t.walls))
+	for _, w := range t.walls {
+		walls = append(walls, *w)
+	}
+
+	sort.Slice(walls, func(i, j int) bool {
+		return walls[i].Qty > walls[j].Qty
+	})
+
+	return walls, events
+}
+
+func (t *WallTracker) updateSide(now time.Time, side string, levels []DepthLevel, minRatio float64) []WallEvent {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, l := range levels {
+		sum += l.Qty
+	}
+
+	threshold := sum / float64(len(levels)) * minRatio
+
+	var events []WallEvent
+
+	for _, l := range levels {
+		key := wallKey{side: side, price: l.Price}
+
+		w, ok := t.walls[key]
+		if !ok {
+			if l.Qty < threshold {
+				continue
+			}
+
+			w = &Wall{
+				Side:       side,
+				Price:      l.Price,
+				Qty:        l.Qty,
+				InitialQty: l.Qty,
+				MaxQty:     l.Qty,
+				FirstSeen:  now,
+				LastSeen:   now,
+			}
+			t.walls[key] = w
+
+			events = append(events, WallEvent{Type: WallAppeared, Wall: *w, Time: now})
+
+			continue
+		}
+
+		// a tracked wall is kept until it falls under the half of the threshold
+		if l.Qty < threshold/2 {
+			continue
+		}
+
+		prevQty := w.Qty
+
+		w.Qty = l.Qty
+		w.MaxQty = math.Max(w.MaxQty, l.Qty)
+		w.LastSeen = now
+
+		if math.Abs(l.Qty-prevQty) >= prevQty*wallChangeRatio {
+			events = append(events, WallEvent{Type: WallChanged, Wall: *w, PrevQty: prevQty, Time: now})
+		}
+	}
+
+	return events
+}
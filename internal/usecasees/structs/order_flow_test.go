@@ -0,0 +1,94 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TradeWindow(t *testing.T) {
+	now := time.Now()
+	w := structs.NewTradeWindow()
+
+	w.Add([]structs.TradeTick{
+		{ID: 1, Qty: 1, Time: now.Add(-90 * time.Second)},
+		{ID: 2, Qty: 2, Time: now.Add(-30 * time.Second), IsBuyerMaker: true},
+		{ID: 3, Qty: 3, Time: now.Add(-5 * time.Second)},
+	})
+
+	// already seen trades are skipped
+	w.Add([]structs.TradeTick{
+		{ID: 3, Qty: 3, Time: now.Add(-5 * time.Second)},
+	})
+
+	assert.Equal(t, float64(2), w.CVD())
+
+	windows := w.Windows(now, []time.Duration{10 * time.Second, time.Minute, 5 * time.Minute})
+
+	assert.Equal(t, structs.CVDWindow{Window: 10 * time.Second, BuyVolume: 3, Delta: 3, Complete: true}, windows[0])
+	assert.Equal(t, structs.CVDWindow{Window: time.Minute, BuyVolume: 3, SellVolume: 2, Delta: 1, Complete: true}, windows[1])
+	assert.Equal(t, structs.CVDWindow{Window: 5 * time.Minute, BuyVolume: 4, SellVolume: 2, Delta: 2, Complete: false}, windows[2])
+
+	// a gap in the trade ids restarts the coverage
+	w.Add([]structs.TradeTick{
+		{ID: 10, Qty: 1, Time: now.Add(-time.Second)},
+	})
+
+	windows = w.Windows(now, []time.Duration{10 * time.Second})
+	assert.False(t, windows[0].Complete)
+	assert.Equal(t, float64(4), windows[0].Delta)
+}
+
+func Test_Imbalance(t *testing.T) {
+	bids := []structs.DepthLevel{{Price: 99, Qty: 3}, {Price: 98, Qty: 1}, {Price: 90, Qty: 100}}
+	asks := []structs.DepthLevel{{Price: 101, Qty: 1}, {Price: 102, Qty: 1}, {Price: 110, Qty: 100}}
+
+	assert.Equal(t, float64(100), structs.MidPrice(bids, asks))
+
+	top := structs.TopImbalance(bids, asks, 2)
+	assert.Equal(t, structs.BookImbalance{BidsSum: 4, AsksSum: 2, Imbalance: float64(2) / 6}, top)
+
+	near := structs.NearMidImbalance(bids, asks, 1)
+	assert.Equal(t, structs.BookImbalance{BidsSum: 3, AsksSum: 1, Imbalance: 0.5}, near)
+}
+
+func Test_WallTracker(t *testing.T) {
+	now := time.Now()
+	tracker := structs.NewWallTracker()
+
+	bids := []structs.DepthLevel{{Price: 99, Qty: 1}, {Price: 98, Qty: 1}, {Price: 97, Qty: 30}, {Price: 96, Qty: 1}}
+	asks := []structs.DepthLevel{{Price: 101, Qty: 1}, {Price: 102, Qty: 40}, {Price: 103, Qty: 1}, {Price: 104, Qty: 1}}
+
+	walls, events := tracker.Update(now, bids, asks, 2)
+	assert.Len(t, walls, 2)
+	assert.Len(t, events, 2)
+	assert.Equal(t, structs.WallAppeared, events[0].Type)
+	assert.Equal(t, float64(102), walls[0].Price)
+
+	// the bid wall grows, the ask wall is taken by the price
+	now = now.Add(time.Second)
+	bids[2].Qty = 60
+	asks = []structs.DepthLevel{{Price: 103, Qty: 1}, {Price: 104, Qty: 1}}
+
+	walls, events = tracker.Update(now, bids, asks, 2)
+	assert.Len(t, walls, 1)
+	assert.Equal(t, float64(60), walls[0].Qty)
+	assert.Equal(t, float64(30), walls[0].InitialQty)
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, structs.WallChanged, events[0].Type)
+	assert.Equal(t, float64(30), events[0].PrevQty)
+	assert.Equal(t, structs.WallConsumed, events[1].Type)
+	assert.Equal(t, structs.SideAsk, events[1].Wall.Side)
+
+	// the bid wall is removed while the price stays above it
+	now = now.Add(time.Second)
+	bids = []structs.DepthLevel{{Price: 99, Qty: 1}, {Price: 98, Qty: 1}, {Price: 96, Qty: 1}}
+
+	walls, events = tracker.Update(now, bids, asks, 2)
+	assert.Len(t, walls, 0)
+	assert.Len(t, events, 1)
+	assert.Equal(t, structs.WallPulled, events[0].Type)
+}
@@ -3,7 +3,6 @@ package structs
 import (
 	"binance/internal/controllers"
 	"binance/models"
-	"fmt"
 
 	"github.com/sirupsen/logrus"
 )
@@ -69,8 +68,7 @@ func (p *Pattern) BUYPatterns(candles []models.Candle) bool {
 func (p *Pattern) basePattern(candles []models.Candle) bool {
 	if candles[0].Body().WeightPercent < minPercent {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Base", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Base", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "basePattern").
 				WithField("method", "Pattern").
@@ -91,8 +89,7 @@ func (p *Pattern) patternHammer(candles []models.Candle) bool {
 		candles[0].UpperShadow().WeightPercent < minPercent &&
 		candles[0].LowerShadow().WeightPercent > maxPercent {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Молот", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Молот", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "patternHammer").
 				WithField("method", "Pattern").
@@ -112,8 +109,7 @@ func (p *Pattern) patternInvertedHammer(candles []models.Candle) bool {
 		candles[0].UpperShadow().WeightPercent > maxPercent &&
 		candles[0].LowerShadow().WeightPercent < minPercent {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Перевернутый молот", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Перевернутый молот", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "patternInvertedHammer").
 				WithField("method", "Pattern").
@@ -144,8 +140,7 @@ func (p *Pattern) patternThreeWhiteSoldiers(candles []models.Candle) bool {
 		candles[0].Trend() == models.TrendUp &&
 		candles[0].LowerShadow().WeightPercent < minPercent {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Три белых солдата", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Три белых солдата", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "patternInvertedHammer").
 				WithField("method", "Pattern").
@@ -166,8 +161,7 @@ func (p *Pattern) patternShootingStar(candles []models.Candle) bool {
 		candles[0].LowerShadow().WeightPercent < minPercent &&
 		candles[0].Trend() == models.TrendDown {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Падающая звезда", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Падающая звезда", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "patternGallows").
 				WithField("method", "Pattern").
@@ -188,8 +182,7 @@ func (p *Pattern) patternGallows(candles []models.Candle) bool {
 		candles[0].LowerShadow().WeightPercent > maxPercent &&
 		candles[0].Trend() == models.TrendDown {
 
-		if err := p.tgmController.Send(
-			fmt.Sprintf("[ Pattern Detected ]\n%s\n%+v", "Висельник", candles)); err != nil {
+		if err := p.tgmController.Notify(controllers.TgmEventPattern, controllers.TgmPattern{Name: "Висельник", Candles: candles}); err != nil {
 			p.logger.
 				WithField("func", "patternGallows").
 				WithField("method", "Pattern").
@@ -0,0 +1,137 @@
+package structs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FundingInterval is the interval of the futures funding settlement, at 00:00, 08:00 and 16:00 UTC
+const FundingInterval = 8 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday,
+	"MON": time.Monday,
+	"TUE": time.Tuesday,
+	"WED": time.Wednesday,
+	"THU": time.Thursday,
+	"FRI": time.Friday,
+	"SAT": time.Saturday,
+}
+
+func ParseWeekday(v string) (time.Weekday, error) {
+	d, ok := weekdays[strings.ToUpper(strings.TrimSpace(v))]
+	if !ok {
+		return 0, fmt.Errorf("unknown weekday '%s'", v)
+	}
+
+	return d, nil
+}
+
+// ParseClock parses "HH:MM" into the duration since midnight
+func ParseClock(v string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(v))
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// TradingWindow allows trading on the weekdays from From to To since midnight. A window with
+// To before From ends on the next day. Empty Weekdays mean every day.
+type TradingWindow struct {
+	Weekdays []time.Weekday
+	From     time.Duration
+	To       time.Duration
+}
+
+func (w *TradingWindow) startsOn(d time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+
+	for _, v := range w.Weekdays {
+		if v == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Contains reports whether the local time t is in the window
+func (w *TradingWindow) Contains(t time.Time) bool {
+	clock := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()))
+
+	if w.From <= w.To {
+		return w.startsOn(t.Weekday()) && clock >= w.From && clock < w.To
+	}
+
+	// the window goes over midnight
+	if clock >= w.From {
+		return w.startsOn(t.Weekday())
+	}
+
+	return clock < w.To && w.startsOn((t.Weekday()+6)%7)
+}
+
+type Blackout struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Reason string    `json:"reason"`
+}
+
+// Schedule tells when a new session can be opened
+type Schedule struct {
+	Location *time.Location
+	// Windows are the allowed trading windows, no window allows trading all the time
+	Windows []TradingWindow
+
+	// FundingBefore and FundingAfter block the trading around every funding settlement
+	FundingBefore time.Duration
+	FundingAfter  time.Duration
+
+	Blackouts []Blackout
+}
+
+// Allowed reports whether trading is allowed at now, otherwise it returns the reason
+func (s *Schedule) Allowed(now time.Time) (bool, string) {
+	for _, b := range s.Blackouts {
+		if !now.Before(b.From) && now.Before(b.To) {
+			return false, fmt.Sprintf("blackout %s", b.Reason)
+		}
+	}
+
+	if s.FundingBefore > 0 || s.FundingAfter > 0 {
+		prev := now.UTC().Truncate(FundingInterval)
+		next := prev.Add(FundingInterval)
+
+		if now.Sub(prev) < s.FundingAfter {
+			return false, fmt.Sprintf("funding %s", prev.Format("15:04"))
+		}
+
+		if next.Sub(now) <= s.FundingBefore {
+			return false, fmt.Sprintf("funding %s", next.Format("15:04"))
+		}
+	}
+
+	if len(s.Windows) == 0 {
+		return true, ""
+	}
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	local := now.In(loc)
+
+	for i := range s.Windows {
+		if s.Windows[i].Contains(local) {
+			return true, ""
+		}
+	}
+
+	return false, "out of trading hours"
+}
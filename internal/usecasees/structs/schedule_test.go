@@ -0,0 +1,99 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseClock(t *testing.T) {
+	v, err := structs.ParseClock("09:30")
+	assert.NoError(t, err)
+	assert.Equal(t, 9*time.Hour+30*time.Minute, v)
+
+	_, err = structs.ParseClock("25:00")
+	assert.Error(t, err)
+
+	d, err := structs.ParseWeekday("fri")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Friday, d)
+
+	_, err = structs.ParseWeekday("FRIDAY")
+	assert.Error(t, err)
+}
+
+func Test_TradingWindow(t *testing.T) {
+	w := structs.TradingWindow{
+		Weekdays: []time.Weekday{time.Monday},
+		From:     9 * time.Hour,
+		To:       17 * time.Hour,
+	}
+
+	// 2022-10-17 is a Monday
+	assert.True(t, w.Contains(time.Date(2022, 10, 17, 9, 0, 0, 0, time.UTC)))
+	assert.False(t, w.Contains(time.Date(2022, 10, 17, 17, 0, 0, 0, time.UTC)))
+	assert.False(t, w.Contains(time.Date(2022, 10, 18, 10, 0, 0, 0, time.UTC)))
+
+	// the window of Monday night ends on Tuesday
+	night := structs.TradingWindow{
+		Weekdays: []time.Weekday{time.Monday},
+		From:     22 * time.Hour,
+		To:       2 * time.Hour,
+	}
+
+	assert.True(t, night.Contains(time.Date(2022, 10, 17, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, night.Contains(time.Date(2022, 10, 18, 1, 0, 0, 0, time.UTC)))
+	assert.False(t, night.Contains(time.Date(2022, 10, 17, 1, 0, 0, 0, time.UTC)))
+}
+
+func Test_Schedule(t *testing.T) {
+	moscow := time.FixedZone("MSK", 3*60*60)
+
+	s := structs.Schedule{
+		Location: moscow,
+		Windows: []structs.TradingWindow{
+			{From: 10 * time.Hour, To: 20 * time.Hour},
+		},
+		FundingBefore: 5 * time.Minute,
+		FundingAfter:  2 * time.Minute,
+		Blackouts: []structs.Blackout{
+			{
+				From:   time.Date(2022, 11, 2, 18, 0, 0, 0, time.UTC),
+				To:     time.Date(2022, 11, 2, 19, 0, 0, 0, time.UTC),
+				Reason: "FOMC",
+			},
+		},
+	}
+
+	// 10:00 UTC is 13:00 MSK
+	allowed, _ := s.Allowed(time.Date(2022, 10, 17, 10, 0, 0, 0, time.UTC))
+	assert.True(t, allowed)
+
+	// 06:00 UTC is 09:00 MSK
+	allowed, reason := s.Allowed(time.Date(2022, 10, 17, 6, 0, 0, 0, time.UTC))
+	assert.False(t, allowed)
+	assert.Equal(t, "out of trading hours", reason)
+
+	// the funding at 16:00 UTC
+	allowed, reason = s.Allowed(time.Date(2022, 10, 17, 15, 56, 0, 0, time.UTC))
+	assert.False(t, allowed)
+	assert.Equal(t, "funding 16:00", reason)
+
+	allowed, _ = s.Allowed(time.Date(2022, 10, 17, 16, 1, 0, 0, time.UTC))
+	assert.False(t, allowed)
+
+	allowed, _ = s.Allowed(time.Date(2022, 10, 17, 16, 2, 0, 0, time.UTC))
+	assert.True(t, allowed)
+
+	allowed, reason = s.Allowed(time.Date(2022, 11, 2, 18, 30, 0, 0, time.UTC))
+	assert.False(t, allowed)
+	assert.Equal(t, "blackout FOMC", reason)
+
+	// no window allows the trading all the time
+	always := structs.Schedule{}
+
+	allowed, _ = always.Allowed(time.Date(2022, 10, 17, 3, 0, 0, 0, time.UTC))
+	assert.True(t, allowed)
+}
@@ -0,0 +1,96 @@
+package structs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	SignalActionOpen  = "OPEN"
+	SignalActionClose = "CLOSE"
+)
+
+var (
+	ErrSignalInvalid   = errors.New("invalid signal")
+	ErrSignalRejected  = errors.New("signal rejected")
+	ErrSignalConflict  = errors.New("signal conflicts with the session")
+	ErrSignalNoMonitor = errors.New("symbol is not monitored")
+)
+
+// Signal is the trade signal of an external alerting tool. The session is opened by market with Quantity
+// or with the quantity losing Risk at the stop loss. The retries of the signal carry the same IdempotencyKey.
+type Signal struct {
+	Symbol         string  `json:"symbol"`
+	Action         string  `json:"action"`
+	Side           string  `json:"side"`
+	Quantity       float64 `json:"quantity"`
+	Risk           float64 `json:"risk"`
+	TakeProfit     float64 `json:"take_profit"`
+	StopLoss       float64 `json:"stop_loss"`
+	IdempotencyKey string  `json:"idempotency_key"`
+}
+
+type SignalResult struct {
+	SessionID string   `json:"session_id"`
+	OrderIDs  []string `json:"order_ids"`
+	// Duplicate is set when the signal was already processed, the result is the one of the first time
+	Duplicate bool `json:"duplicate"`
+}
+
+// Validate normalizes the signal and checks its fields, OPEN is the default action
+func (s *Signal) Validate() error {
+	s.Symbol = strings.ToUpper(strings.TrimSpace(s.Symbol))
+	s.Action = strings.ToUpper(strings.TrimSpace(s.Action))
+	s.Side = strings.ToUpper(strings.TrimSpace(s.Side))
+
+	if s.Action == "" {
+		s.Action = SignalActionOpen
+	}
+
+	switch {
+	case s.Symbol == "":
+		return fmt.Errorf("%w: no symbol", ErrSignalInvalid)
+	case s.IdempotencyKey == "":
+		return fmt.Errorf("%w: no idempotency key", ErrSignalInvalid)
+	case s.Action != SignalActionOpen && s.Action != SignalActionClose:
+		return fmt.Errorf("%w: unknown action '%s'", ErrSignalInvalid, s.Action)
+	case s.Action == SignalActionClose:
+		return nil
+	case s.Side != "BUY" && s.Side != "SELL":
+		return fmt.Errorf("%w: unknown side '%s'", ErrSignalInvalid, s.Side)
+	case s.Quantity < 0 || s.Risk < 0 || s.TakeProfit < 0 || s.StopLoss < 0:
+		return fmt.Errorf("%w: negative value", ErrSignalInvalid)
+	case (s.Quantity == 0) == (s.Risk == 0):
+		return fmt.Errorf("%w: either quantity or risk is required", ErrSignalInvalid)
+	}
+
+	return nil
+}
+
+// CheckExits checks the exits are on the profit and the loss sides of the entry price
+func (s *Signal) CheckExits(price, takeProfit, stopLoss float64) error {
+	switch s.Side {
+	case "BUY":
+		if stopLoss >= price || takeProfit <= price {
+			return fmt.Errorf("%w: BUY needs stop loss %.2f < price %.2f < take profit %.2f", ErrSignalInvalid, stopLoss, price, takeProfit)
+		}
+	case "SELL":
+		if stopLoss <= price || takeProfit >= price {
+			return fmt.Errorf("%w: SELL needs take profit %.2f < price %.2f < stop loss %.2f", ErrSignalInvalid, takeProfit, price, stopLoss)
+		}
+	}
+
+	return nil
+}
+
+// SignalQuantity is the quantity losing risk at the stop loss, it is rounded down to the lot
+func SignalQuantity(risk, price, stopLoss, lot float64) float64 {
+	distance := math.Abs(price - stopLoss)
+	if distance == 0 {
+		return 0
+	}
+
+	return math.Floor(risk/distance/lot+1e-9) * lot
+}
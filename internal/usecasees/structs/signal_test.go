@@ -0,0 +1,48 @@
+package structs_test
+
+import (
+	"binance/internal/usecasees/structs"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SignalValidate(t *testing.T) {
+	s := structs.Signal{Symbol: " btcusdt", Side: "buy", Risk: 10, IdempotencyKey: "alert-1"}
+	assert.NoError(t, s.Validate())
+	assert.Equal(t, "BTCUSDT", s.Symbol)
+	assert.Equal(t, structs.SignalActionOpen, s.Action)
+	assert.Equal(t, "BUY", s.Side)
+
+	for _, s := range []structs.Signal{
+		{Side: "BUY", Quantity: 0.003, IdempotencyKey: "1"},
+		{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.003},
+		{Symbol: "BTCUSDT", Side: "LONG", Quantity: 0.003, IdempotencyKey: "1"},
+		{Symbol: "BTCUSDT", Side: "BUY", IdempotencyKey: "1"},
+		{Symbol: "BTCUSDT", Side: "BUY", Quantity: 0.003, Risk: 10, IdempotencyKey: "1"},
+		{Symbol: "BTCUSDT", Side: "BUY", Quantity: -1, IdempotencyKey: "1"},
+		{Symbol: "BTCUSDT", Action: "REVERSE", IdempotencyKey: "1"},
+	} {
+		s := s
+		assert.True(t, errors.Is(s.Validate(), structs.ErrSignalInvalid), "%+v", s)
+	}
+
+	closeSignal := structs.Signal{Symbol: "BTCUSDT", Action: "close", IdempotencyKey: "1"}
+	assert.NoError(t, closeSignal.Validate())
+}
+
+func Test_SignalExits(t *testing.T) {
+	buy := structs.Signal{Side: "BUY"}
+	assert.NoError(t, buy.CheckExits(19500, 19700, 19400))
+	assert.Error(t, buy.CheckExits(19500, 19400, 19700))
+
+	sell := structs.Signal{Side: "SELL"}
+	assert.NoError(t, sell.CheckExits(19500, 19400, 19700))
+	assert.Error(t, sell.CheckExits(19500, 19700, 19400))
+
+	// 10 USDT at 100 USDT from the entry
+	assert.InDelta(t, 0.1, structs.SignalQuantity(10, 19500, 19400, 0.001), 1e-9)
+	assert.InDelta(t, 0.033, structs.SignalQuantity(10, 19500, 19200, 0.001), 1e-9)
+	assert.Equal(t, 0.0, structs.SignalQuantity(10, 19500, 19500, 0.001))
+}
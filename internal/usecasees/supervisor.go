@@ -0,0 +1,422 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	"binance/internal/repository/mongo"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	supervisorInterval = 5 * time.Second
+
+	monitorBackoffMin = 5 * time.Second
+	monitorBackoffMax = 5 * time.Minute
+
+	// monitorStableTime resets the restart counter of a monitor running without a crash
+	monitorStableTime = 10 * time.Minute
+
+	MonitorStateRunning  MonitorState = "RUNNING"
+	MonitorStateDraining MonitorState = "DRAINING"
+	MonitorStateBackoff  MonitorState = "BACKOFF"
+)
+
+type MonitorState string
+
+// MonitorInfo describes a supervised symbol monitor.
+type MonitorInfo struct {
+	Symbol    string       `json:"symbol"`
+	Market    string       `json:"market"`
+	State     MonitorState `json:"state"`
+	StartedAt time.Time    `json:"started_at"`
+	Restarts  int          `json:"restarts"`
+	LastError string       `json:"last_error,omitempty"`
+	RestartAt time.Time    `json:"restart_at,omitempty"`
+}
+
+type monitorHandle struct {
+	info MonitorInfo
+
+	cancel    context.CancelFunc
+	drain     chan struct{}
+	drainOnce sync.Once
+}
+
+func (h *monitorHandle) startDrain() {
+	h.drainOnce.Do(func() {
+		close(h.drain)
+	})
+}
+
+type Supervisor struct {
+	orderUseCase  *orderUseCase
+	settingsRepo  mongo.SettingsRepo
+	tgmController controllers.TgmCtrl
+
+	mu       sync.Mutex
+	monitors map[string]*monitorHandle
+	wg       sync.WaitGroup
+
+	// stopping is set by Shutdown, no monitor is started or restarted after it
+	stopping bool
+
+	logRus *logrus.Logger
+}
+
+func NewSupervisor(
+	orderUseCase *orderUseCase,
+	settingsRepo mongo.SettingsRepo,
+	tgm controllers.TgmCtrl,
+	logger *logrus.Logger,
+) *Supervisor {
+	return &Supervisor{
+		orderUseCase:  orderUseCase,
+		settingsRepo:  settingsRepo,
+		tgmController: tgm,
+		monitors:      make(map[string]*monitorHandle),
+		logRus:        logger,
+	}
+}
+
+// Run starts and stops the symbol monitors according to the settings collection until ctx is done.
+func (s *Supervisor) Run(ctx context.Context) {
+	ticker := time.NewTicker(supervisorInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.wg.Wait()
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Running returns the supervised monitors.
+func (s *Supervisor) Running() []MonitorInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]MonitorInfo, 0, len(s.monitors))
+	for _, h := range s.monitors {
+		out = append(out, h.info)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Symbol < out[j].Symbol
+	})
+
+	return out
+}
+
+func (s *Supervisor) sync(ctx context.Context) {
+	if s.isStopping() {
+		return
+	}
+
+	list, err := s.settingsRepo.LoadAll()
+	if err != nil {
+		s.logRus.
+			WithError(err).
+			Error(string(debug.Stack()))
+
+		return
+	}
+
+	// the liquidation is done by the monitor, it disables the symbol once flat
+	enabled := make(map[string]bool)
+	for _, settings := range list {
+		// the symbols of the other market are supervised by its own supervisor
+		if settings.GetMarket() != s.orderUseCase.market {
+			continue
+		}
+
+		// the edits made in the collection are versioned here, the bot versions its own changes
+		if _, err := s.settingsRepo.Track(&settings); err != nil {
+			s.logRus.
+				WithError(err).
+				Error(string(debug.Stack()))
+		}
+
+		status := mongoStructs.SymbolStatus(settings.Status)
+
+		// the symbol of the invalid settings is disabled, the other symbols are traded
+		if status == mongoStructs.New || status == mongoStructs.Enabled {
+			if err := settings.Validate(); err != nil {
+				s.orderUseCase.disableSymbol(&settings, mongoStructs.SettingsAuthorSupervisor, err)
+
+				continue
+			}
+		}
+
+		switch status {
+		case mongoStructs.New:
+			if err := s.settingsRepo.UpdateStatus(settings.ID, mongoStructs.Enabled, mongoStructs.SettingsChange{Author: mongoStructs.SettingsAuthorSupervisor, Reason: "new symbol"}); err != nil {
+				s.logRus.
+					WithError(err).
+					Error(string(debug.Stack()))
+
+				continue
+			}
+
+			enabled[settings.Symbol] = true
+		case mongoStructs.Enabled, mongoStructs.Liquidation, mongoStructs.LiquidationBUY, mongoStructs.LiquidationSELL:
+			enabled[settings.Symbol] = true
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopping {
+		return
+	}
+
+	for symbol := range enabled {
+		h, ok := s.monitors[symbol]
+		if !ok {
+			s.start(ctx, symbol, 0)
+
+			continue
+		}
+
+		if h.info.State == MonitorStateBackoff && !time.Now().Before(h.info.RestartAt) {
+			s.start(ctx, symbol, h.info.Restarts)
+		}
+	}
+
+	for symbol, h := range s.monitors {
+		if enabled[symbol] {
+			continue
+		}
+
+		switch h.info.State {
+		case MonitorStateRunning:
+			h.info.State = MonitorStateDraining
+			h.startDrain()
+
+			s.notify("Drain", symbol, "")
+		case MonitorStateBackoff:
+			delete(s.monitors, symbol)
+		}
+	}
+}
+
+// start runs the monitor of the symbol, s.mu must be held
+func (s *Supervisor) start(ctx context.Context, symbol string, restarts int) {
+	monitorCtx, cancel := context.WithCancel(ctx)
+
+	h := &monitorHandle{
+		info: MonitorInfo{
+			Symbol:    symbol,
+			Market:    s.orderUseCase.market.ToString(),
+			State:     MonitorStateRunning,
+			StartedAt: time.Now(),
+			Restarts:  restarts,
+		},
+		cancel: cancel,
+		drain:  make(chan struct{}),
+	}
+	s.monitors[symbol] = h
+
+	s.notify("Init", symbol, "")
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+
+		err := s.runMonitor(monitorCtx, symbol, h.drain)
+
+		s.stopped(ctx, h, err)
+	}()
+}
+
+func (s *Supervisor) runMonitor(ctx context.Context, symbol string, drain <-chan struct{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return s.orderUseCase.Monitoring(ctx, symbol, drain)
+}
+
+func (s *Supervisor) stopped(ctx context.Context, h *monitorHandle, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbol := h.info.Symbol
+
+	if s.monitors[symbol] != h {
+		return
+	}
+
+	if ctx.Err() != nil || s.stopping || (h.info.State == MonitorStateDraining && err == nil) {
+		delete(s.monitors, symbol)
+
+		s.notify("Stop", symbol, "")
+
+		return
+	}
+
+	if err == nil {
+		err = fmt.Errorf("monitor stopped")
+	}
+
+	if time.Since(h.info.StartedAt) > monitorStableTime {
+		h.info.Restarts = 0
+	}
+
+	backoff := monitorBackoffMin
+	for i := 0; i < h.info.Restarts && backoff < monitorBackoffMax; i++ {
+		backoff *= 2
+	}
+
+	if backoff > monitorBackoffMax {
+		backoff = monitorBackoffMax
+	}
+
+	h.info.State = MonitorStateBackoff
+	h.info.Restarts++
+	h.info.LastError = err.Error()
+	h.info.RestartAt = time.Now().Add(backoff)
+
+	s.logRus.
+		WithField("symbol", symbol).
+		WithField("market", s.orderUseCase.market).
+		WithField("restarts", h.info.Restarts).
+		WithField("backoff", backoff).
+		Error(err)
+
+	s.notify("Crash", symbol, fmt.Sprintf("Restart in %s", backoff))
+}
+
+// name is the symbol in the notifications, the spot symbols are marked
+func (s *Supervisor) name(symbol string) string {
+	if s.orderUseCase.market == mongoStructs.MarketSpot {
+		return fmt.Sprintf("%s %s", symbol, mongoStructs.MarketSpot)
+	}
+
+	return symbol
+}
+
+// notify queues the event of the monitor, the queue does not block the caller holding s.mu
+func (s *Supervisor) notify(action, symbol, detail string) {
+	if err := s.tgmController.Notify(controllers.TgmEventMonitor, controllers.TgmMonitor{
+		Action: action,
+		Symbol: s.name(symbol),
+		Detail: detail,
+	}); err != nil {
+		s.logRus.
+			WithField("func", "notify").
+			Debug(err)
+	}
+}
+
+func (s *Supervisor) isStopping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopping
+}
+
+// Shutdown stops the new sessions, lets the orders being created be sent, stops the monitors,
+// applies the policy to every supervised symbol and posts the report to Telegram.
+// ctx bounds the whole shutdown.
+func (s *Supervisor) Shutdown(ctx context.Context, policy ShutdownPolicy) *ShutdownReport {
+	report := &ShutdownReport{
+		Market:    s.orderUseCase.market,
+		Policy:    policy,
+		StartedAt: time.Now(),
+	}
+
+	s.mu.Lock()
+	s.stopping = true
+
+	symbols := make([]string, 0, len(s.monitors))
+	for symbol, h := range s.monitors {
+		symbols = append(symbols, symbol)
+
+		if h.info.State == MonitorStateRunning {
+			h.info.State = MonitorStateDraining
+		}
+		h.startDrain()
+	}
+	s.mu.Unlock()
+
+	sort.Strings(symbols)
+
+	report.Pending = s.waitCreating(ctx)
+
+	s.mu.Lock()
+	for _, h := range s.monitors {
+		if h.cancel != nil {
+			h.cancel()
+		}
+	}
+	s.mu.Unlock()
+
+	if !s.waitMonitors(ctx) {
+		s.logRus.
+			WithField("func", "Shutdown").
+			Error("monitors are not stopped")
+	}
+
+	for _, symbol := range symbols {
+		report.Symbols = append(report.Symbols, s.orderUseCase.applyShutdownPolicy(ctx, symbol, policy))
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+
+	if err := s.tgmController.Notify(controllers.TgmEventReport, report.String()); err != nil {
+		s.logRus.
+			WithField("func", "Shutdown").
+			Debug(err)
+	}
+
+	return report
+}
+
+// waitCreating waits until no monitor has an order stored but not sent, it returns the symbols left
+func (s *Supervisor) waitCreating(ctx context.Context) []string {
+	for {
+		creating := s.orderUseCase.creatingSymbols()
+		if len(creating) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return creating
+		case <-time.After(chkTime):
+		}
+	}
+}
+
+// waitMonitors reports whether all the monitors are stopped before ctx is done
+func (s *Supervisor) waitMonitors(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
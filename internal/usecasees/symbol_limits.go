@@ -0,0 +1,187 @@
+package usecasees
+
+import (
+	"binance/internal/controllers"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	exchangeInfoUrlPath = "/api/v3/exchangeInfo"
+	featureExchangeInfo = "/fapi/v1/exchangeInfo"
+
+	// symbolLimitsTTL is the lifetime of the exchange filters, they are changed by the exchange rarely
+	symbolLimitsTTL = time.Hour
+)
+
+type symbolLimitsCache struct {
+	mu   sync.Mutex
+	list map[string]symbolLimitsCacheItem
+}
+
+type symbolLimitsCacheItem struct {
+	limits    mongoStructs.SymbolLimits
+	expiredAt time.Time
+}
+
+func newSymbolLimitsCache() *symbolLimitsCache {
+	return &symbolLimitsCache{
+		list: make(map[string]symbolLimitsCacheItem),
+	}
+}
+
+func (c *symbolLimitsCache) get(symbol string) (mongoStructs.SymbolLimits, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	item, ok := c.list[symbol]
+	if !ok || time.Now().After(item.expiredAt) {
+		return mongoStructs.SymbolLimits{}, false
+	}
+
+	return item.limits, true
+}
+
+func (c *symbolLimitsCache) set(symbol string, limits mongoStructs.SymbolLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.list[symbol] = symbolLimitsCacheItem{
+		limits:    limits,
+		expiredAt: time.Now().Add(symbolLimitsTTL),
+	}
+}
+
+// checkSettings validates the settings and their quantities by the exchange filters, the filters are
+// not checked when the exchange info is not loaded
+func (u *orderUseCase) checkSettings(settings *mongoStructs.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	limits, err := u.getSymbolLimits(settings.Symbol)
+	if err != nil {
+		u.logRus.
+			WithField("func", "getSymbolLimits").
+			Debug(err)
+
+		return nil
+	}
+
+	return settings.ValidateLimits(limits)
+}
+
+// disableSymbol disables the symbol of the invalid settings, its monitor is drained by the supervisor
+func (u *orderUseCase) disableSymbol(settings *mongoStructs.Settings, author string, reason error) {
+	u.logRus.Errorf("Settings [%s] version %d are invalid, the symbol is disabled: %v", settings.Symbol, settings.Version, reason)
+
+	if err := u.settingsRepo.UpdateStatus(settings.ID, mongoStructs.Disabled, mongoStructs.SettingsChange{
+		Author: author,
+		Reason: fmt.Sprintf("invalid settings: %v", reason),
+	}); err != nil {
+		u.logRus.
+			WithField("func", "UpdateStatus").
+			Debug(err)
+	}
+
+	u.notify(controllers.TgmEventSettings, controllers.TgmSettings{
+		Symbol: settings.Symbol,
+		Reason: reason.Error(),
+		Status: mongoStructs.Disabled.ToString(),
+	})
+}
+
+// getSymbolLimits returns the LOT_SIZE and PRICE_FILTER filters of the symbol on the market of the use case
+func (u *orderUseCase) getSymbolLimits(symbol string) (mongoStructs.SymbolLimits, error) {
+	if limits, ok := u.symbolLimits.get(symbol); ok {
+		return limits, nil
+	}
+
+	baseURL, err := url.Parse(u.url)
+	if err != nil {
+		return mongoStructs.SymbolLimits{}, err
+	}
+
+	// the futures exchange info lists all the symbols
+	baseURL.Path = path.Join(featureExchangeInfo)
+
+	if u.market == mongoStructs.MarketSpot {
+		baseURL.Path = path.Join(exchangeInfoUrlPath)
+
+		q := baseURL.Query()
+		q.Set("symbol", symbol)
+
+		baseURL.RawQuery = q.Encode()
+	}
+
+	resp, err := u.clientController.Send(http.MethodGet, baseURL, nil, false)
+	if err != nil {
+		return mongoStructs.SymbolLimits{}, err
+	}
+
+	var out struct {
+		Symbols []struct {
+			Symbol  string `json:"symbol"`
+			Filters []struct {
+				FilterType string `json:"filterType"`
+				MinQty     string `json:"minQty"`
+				MaxQty     string `json:"maxQty"`
+				StepSize   string `json:"stepSize"`
+				TickSize   string `json:"tickSize"`
+			} `json:"filters"`
+		} `json:"symbols"`
+	}
+
+	if err := json.Unmarshal(resp, &out); err != nil {
+		return mongoStructs.SymbolLimits{}, err
+	}
+
+	for _, s := range out.Symbols {
+		if s.Symbol != symbol {
+			continue
+		}
+
+		var limits mongoStructs.SymbolLimits
+		var lotSize bool
+
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "LOT_SIZE":
+				lotSize = true
+
+				if limits.MinQty, err = strconv.ParseFloat(f.MinQty, 64); err != nil {
+					return mongoStructs.SymbolLimits{}, err
+				}
+
+				if limits.MaxQty, err = strconv.ParseFloat(f.MaxQty, 64); err != nil {
+					return mongoStructs.SymbolLimits{}, err
+				}
+
+				if limits.StepSize, err = strconv.ParseFloat(f.StepSize, 64); err != nil {
+					return mongoStructs.SymbolLimits{}, err
+				}
+			case "PRICE_FILTER":
+				if limits.TickSize, err = strconv.ParseFloat(f.TickSize, 64); err != nil {
+					return mongoStructs.SymbolLimits{}, err
+				}
+			}
+		}
+
+		if !lotSize {
+			break
+		}
+
+		u.symbolLimits.set(symbol, limits)
+
+		return limits, nil
+	}
+
+	return mongoStructs.SymbolLimits{}, fmt.Errorf("no LOT_SIZE filter of %s", symbol)
+}
@@ -3,86 +3,389 @@ package usecasees
 import (
 	"binance/internal/controllers"
 	"binance/internal/repository/mongo"
+	mongoStructs "binance/internal/repository/mongo/structs"
 	"binance/internal/repository/postgres"
+	"context"
+	"errors"
 	"fmt"
 	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// tgmMaxMessage is the text limit of the telegram message, the longer replies are cut
+	tgmMaxMessage = 4096
+
+	tgmLastDefault = 10
+	tgmLastMax     = 50
+)
+
+// errTgmUsage is the invalid arguments of the command, the reply carries the usage of the command
+var errTgmUsage = errors.New("invalid arguments")
+
+// tgmCommand is the bot command, run returns the reply to the chat, the empty one is not sent
+type tgmCommand struct {
+	name  string
+	usage string
+	help  string
+	run   func(ctx context.Context, msg *tgmBotAPI.Message, args []string) (string, error)
+}
+
 type tgmUseCase struct {
 	priceUseCase  *priceUseCase
 	orderUseCase  *orderUseCase
 	settingsRepo  mongo.SettingsRepo
 	orderRepo     postgres.OrderRepo
-	tgmController *controllers.TgmController
-	logger        *logrus.Logger
+	auditRepo     postgres.AuditRepo
+	tgmController controllers.TgmCtrl
+
+	commands  []tgmCommand
+	aliases   map[string]string
+	callbacks []tgmCallback
+
+	// pending are the manual orders waiting for the confirmation by the token of the buttons
+	pendingMu sync.Mutex
+	pending   map[string]tgmPendingOrder
+
+	loc    *time.Location
+	logger *logrus.Logger
 }
 
+// NewTgmUseCase builds the command processor of the bot, the commands trade with the futures use case
 func NewTgmUseCase(
 	priceUseCase *priceUseCase,
 	orderUseCase *orderUseCase,
 	settingsRepo mongo.SettingsRepo,
 	orderRepo postgres.OrderRepo,
-	tgmController *controllers.TgmController,
+	auditRepo postgres.AuditRepo,
+	tgmController controllers.TgmCtrl,
 	logger *logrus.Logger,
 ) *tgmUseCase {
-	return &tgmUseCase{
+	u := &tgmUseCase{
 		priceUseCase:  priceUseCase,
 		orderUseCase:  orderUseCase,
 		settingsRepo:  settingsRepo,
 		orderRepo:     orderRepo,
+		auditRepo:     auditRepo,
 		tgmController: tgmController,
-		logger:        logger,
+		pending:       make(map[string]tgmPendingOrder),
+		aliases: map[string]string{
+			"start": "help",
+			"stat":  "statistics",
+		},
+		loc:    time.UTC,
+		logger: logger,
 	}
-}
 
-func (u *tgmUseCase) CommandProcessor() {
 	loc, err := time.LoadLocation("Europe/Moscow")
 	if err != nil {
-		u.logger.WithField("method", "CommandProcessor").Debug(err)
+		logger.WithField("method", "NewTgmUseCase").Debug(err)
+	} else {
+		u.loc = loc
+	}
+
+	u.commands = []tgmCommand{
+		{name: "set_actual", usage: "/set_actual [SYMBOL]", help: "sync the open orders with the exchange", run: u.setActualProc},
+		{name: "set_avg_price", usage: "/set_avg_price [SYMBOL]", help: "set the average fill prices of the orders filled for 24h", run: u.setAvgPriceProc},
+		{name: "order", usage: "/order BUY|SELL SYMBOL QUANTITY [TAKE_PROFIT STOP_LOSS]", help: "open the session by the market order after the confirmation", run: u.orderProc},
+		{name: "sell_all", usage: "/sell_all", help: "close the long positions of all the futures symbols after the confirmation", run: u.sellAllProc},
+		{name: "buy_all", usage: "/buy_all", help: "close the short positions of all the futures symbols after the confirmation", run: u.buyAllProc},
+		{name: "calc_balance", usage: "/calc_balance", help: "show the balance weights", run: u.calcBalanceProc},
+		{name: "last", usage: "/last [N] [SYMBOL]", help: fmt.Sprintf("show the last N orders, %d by default", tgmLastDefault), run: u.lastProc},
+		{name: "symbols", usage: "/symbols", help: "control the symbols with the buttons", run: u.symbolsProc},
+		{name: "audit", usage: "/audit [N]", help: fmt.Sprintf("show the last N actions of the buttons, %d by default", tgmLastDefault), run: u.auditProc},
+		{name: "ping", usage: "/ping", help: "bot status", run: u.pingProc},
+		{name: "statistics", usage: "/statistics [SYMBOL]", help: "show the orders and the profit of the symbols for 24h", run: u.orderStatProc},
+		{name: "help", usage: "/help", help: "show the commands", run: u.helpProc},
+	}
+
+	u.callbacks = []tgmCallback{
+		{action: tgmActionSymbols, run: u.symbolsCallback},
+		{action: tgmActionSymbol, run: u.symbolCallback},
+		{action: tgmActionEnable, audit: true, run: u.statusCallback(mongoStructs.Enabled)},
+		{action: tgmActionDisable, audit: true, run: u.statusCallback(mongoStructs.Disabled)},
+		{action: tgmActionDepth, run: u.depthCallback},
+		{action: tgmActionDepthSet, audit: true, run: u.depthSetCallback},
+		{action: tgmActionClose, run: u.closeCallback},
+		{action: tgmActionCloseConfirm, audit: true, run: u.closeConfirmCallback},
+		{action: tgmActionOrderConfirm, audit: true, run: u.orderConfirmCallback},
+		{action: tgmActionOrderReject, audit: true, run: u.orderRejectCallback},
+		{action: tgmActionCloseAllConfirm, audit: true, run: u.closeAllConfirmCallback},
+		{action: tgmActionCloseAllReject, audit: true, run: u.closeAllRejectCallback},
 	}
 
-	for update := range u.tgmController.GetUpdates() {
-		if u.tgmController.CheckChatID(update.Message.Chat.ID) {
+	return u
+}
+
+// CommandProcessor replies to the commands of the bot chat until the context is done
+func (u *tgmUseCase) CommandProcessor(ctx context.Context) {
+	updates := u.tgmController.GetUpdates()
 
-			switch update.Message.Command() {
-			case "ping":
-				u.pingProc(loc)
-			case "stat":
-				u.orderStatProc()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			switch {
+			case update.CallbackQuery != nil:
+				u.handleCallback(ctx, update.CallbackQuery)
+			case update.Message != nil:
+				u.handleMessage(ctx, update.Message)
 			}
 		}
 	}
 }
 
-func (u *tgmUseCase) orderStatProc() {
+// handleMessage replies to the command of the bot chat, the commands sending the keyboards reply nothing
+func (u *tgmUseCase) handleMessage(ctx context.Context, msg *tgmBotAPI.Message) {
+	if msg.Chat == nil || !msg.IsCommand() || !u.tgmController.CheckChatID(msg.Chat.ID) {
+		return
+	}
+
+	reply := u.handle(ctx, msg)
+	if reply == "" {
+		return
+	}
+
+	if err := u.tgmController.Send(reply); err != nil {
+		u.logger.
+			WithError(err).
+			Error(string(debug.Stack()))
+	}
+}
+
+// handle runs the command of the message and returns the reply
+func (u *tgmUseCase) handle(ctx context.Context, msg *tgmBotAPI.Message) string {
+	name := msg.Command()
+	if alias, ok := u.aliases[name]; ok {
+		name = alias
+	}
+
+	cmd, ok := u.command(name)
+	if !ok {
+		return fmt.Sprintf("Unknown command /%s\n\n%s", msg.Command(), u.help())
+	}
+
+	reply, err := cmd.run(ctx, msg, strings.Fields(msg.CommandArguments()))
+	if err != nil {
+		u.logger.
+			WithField("command", name).
+			Debug(err)
+
+		if errors.Is(err, errTgmUsage) {
+			return fmt.Sprintf("Error: %v\nUsage: %s", err, cmd.usage)
+		}
+
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// the limit counts the characters, the reply is cut on the runes
+	if r := []rune(reply); len(r) > tgmMaxMessage {
+		reply = string(r[:tgmMaxMessage-3]) + "..."
+	}
+
+	return reply
+}
+
+func (u *tgmUseCase) command(name string) (tgmCommand, bool) {
+	for _, cmd := range u.commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return tgmCommand{}, false
+}
+
+func (u *tgmUseCase) help() string {
+	msg := "[ Commands ]\n"
+
+	for _, cmd := range u.commands {
+		msg += fmt.Sprintf("%s - %s\n", cmd.usage, cmd.help)
+	}
+
+	return msg
+}
+
+func (u *tgmUseCase) helpProc(_ context.Context, _ *tgmBotAPI.Message, _ []string) (string, error) {
+	return u.help(), nil
+}
+
+func (u *tgmUseCase) pingProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("%w: no arguments are expected", errTgmUsage)
+	}
+
+	return fmt.Sprintf(
+		"PONG [ %s ]",
+		time.Now().In(u.loc).Format(time.RFC822),
+	), nil
+}
+
+// symbolArg parses the optional symbol of the command, the symbols of the enabled futures settings
+// are returned without it
+func (u *tgmUseCase) symbolArg(args []string) ([]string, error) {
+	switch len(args) {
+	case 0:
+		return u.futuresSymbols()
+	case 1:
+		return []string{strings.ToUpper(args[0])}, nil
+	}
+
+	return nil, fmt.Errorf("%w: one symbol is expected", errTgmUsage)
+}
+
+// futuresSymbols lists the enabled symbols of the futures market
+func (u *tgmUseCase) futuresSymbols() ([]string, error) {
+	list, err := u.futuresSettings()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.Symbol)
+	}
+
+	return out, nil
+}
+
+func (u *tgmUseCase) futuresSettings() ([]mongoStructs.Settings, error) {
+	list, err := u.settingsRepo.LoadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []mongoStructs.Settings
+
+	for _, s := range list {
+		if s.GetMarket() == mongoStructs.MarketFeatures && s.Status == mongoStructs.Enabled.ToString() {
+			out = append(out, s)
+		}
+	}
+
+	return out, nil
+}
+
+func (u *tgmUseCase) lastProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	filter := postgres.OrderFilter{
+		Descending: true,
+		Limit:      tgmLastDefault,
+	}
+
+	if len(args) > 2 {
+		return "", fmt.Errorf("%w: N and symbol are expected", errTgmUsage)
+	}
+
+	for _, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			filter.Symbol = strings.ToUpper(arg)
+
+			continue
+		}
+
+		if n < 1 || n > tgmLastMax {
+			return "", fmt.Errorf("%w: N is out of [1, %d]", errTgmUsage, tgmLastMax)
+		}
+
+		filter.Limit = n
+	}
+
+	page, err := u.orderRepo.Find(filter)
+	if err != nil {
+		return "", err
+	}
+
+	if len(page.Orders) == 0 {
+		return "No orders", nil
+	}
+
+	msg := "[ Last Orders ]\n"
+
+	for _, o := range page.Orders {
+		price := o.ActualPrice
+		if price == 0 {
+			price = o.Price
+		}
+
+		msg += fmt.Sprintf(
+			"%s %s %s %s %.3f @ %.2f %s\n",
+			o.CreatedAt.In(u.loc).Format("02.01 15:04"),
+			o.Symbol,
+			o.Side,
+			o.Type,
+			o.Quantity,
+			price,
+			o.Status,
+		)
+	}
+
+	return msg, nil
+}
+
+func (u *tgmUseCase) orderStatProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	symbols, err := u.symbolArg(args)
+	if err != nil {
+		return "", err
+	}
+
 	msg := "[ Orders Stat ]\n"
 
 	eTime := time.Now()
 	sTime := eTime.Add(-24 * time.Hour)
 
-	for _, symbol := range SymbolList {
-		orders, err := u.orderRepo.GetLastWithInterval(symbol, sTime, eTime)
-		if err != nil {
-			u.logger.
-				WithError(err).
-				Error(string(debug.Stack()))
-		}
+	var listed int
 
+	for _, symbol := range symbols {
 		var canceled, filled float64
 
-		for _, order := range orders {
-			switch order.Status {
-			case "FILLED":
-				filled++
-			case "CANCELED":
-				canceled++
+		filter := postgres.OrderFilter{
+			Symbol:   symbol,
+			Statuses: []string{OrderStatusFilled, OrderStatusCanceled},
+			From:     sTime,
+			To:       eTime,
+			Limit:    postgres.MaxOrderLimit,
+		}
+
+		for {
+			page, err := u.orderRepo.Find(filter)
+			if err != nil {
+				return "", err
+			}
+
+			for _, order := range page.Orders {
+				switch order.Status {
+				case OrderStatusFilled:
+					filled++
+				case OrderStatusCanceled:
+					canceled++
+				}
+			}
+
+			if page.NextCursor == "" {
+				break
 			}
+
+			filter.Cursor = page.NextCursor
 		}
 
 		total := canceled + filled
+		if total == 0 {
+			continue
+		}
+
+		listed++
 
 		msg += fmt.Sprintf(
 			"Symbol:\t%s\n"+
@@ -97,21 +400,20 @@ func (u *tgmUseCase) orderStatProc() {
 			filled/total*100,
 			canceled/total*100,
 		)
-	}
 
-	if err := u.tgmController.Send(msg); err != nil {
-		u.logger.
-			WithError(err).
-			Error(string(debug.Stack()))
+		pnl, err := u.orderUseCase.fillRepo.DailyPnL(symbol, sTime, eTime)
+		if err != nil {
+			return "", err
+		}
+
+		for _, p := range pnl {
+			msg += fmt.Sprintf("PnL %s:\t%.4f (fee %.4f %s)\n", p.Key, p.RealizedPnL, p.Commission, p.CommissionAsset)
+		}
 	}
-}
 
-func (u *tgmUseCase) pingProc(loc *time.Location) {
-	if err := u.tgmController.Send(
-		fmt.Sprintf(
-			"PONG [ %s ]",
-			time.Now().In(loc).Format(time.RFC822),
-		)); err != nil {
-		u.logger.WithField("method", "pingProc").Debug(err)
+	if listed == 0 {
+		return "No orders for 24h", nil
 	}
+
+	return msg, nil
 }
@@ -0,0 +1,574 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/google/uuid"
+)
+
+// the actions of the buttons, the callback data is the action and its arguments joined by the separator.
+// Telegram limits the data by 64 bytes, so the actions are short.
+const (
+	tgmActionSymbols      = "syms"
+	tgmActionSymbol       = "sym"
+	tgmActionEnable       = "on"
+	tgmActionDisable      = "off"
+	tgmActionDepth        = "depth"
+	tgmActionDepthSet     = "depth_set"
+	tgmActionClose        = "close"
+	tgmActionCloseConfirm = "close_ok"
+	tgmActionOrderConfirm = "order_ok"
+	tgmActionOrderReject  = "order_no"
+	// the close of all the positions carries the liquidation mode of the sides
+	tgmActionCloseAllConfirm = "all_ok"
+	tgmActionCloseAllReject  = "all_no"
+
+	tgmCallbackSeparator = ":"
+
+	// tgmConfirmTTL is the time to confirm the manual order, the expired buttons do nothing
+	tgmConfirmTTL = 5 * time.Minute
+)
+
+// tgmDepthLimitPresets are the depth limits set by the buttons, the callback carries the index of the preset
+var tgmDepthLimitPresets = []float64{10, 20, 35, 50, 75, 100}
+
+var (
+	errTgmDenied  = errors.New("the action is not allowed")
+	errTgmExpired = errors.New("the order is expired")
+)
+
+// tgmCallback is the action of the button, run edits the message of the button and returns the answer to the user.
+// The audited callbacks change the state and are recorded, the navigation is not.
+type tgmCallback struct {
+	action string
+	audit  bool
+	run    func(ctx context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error)
+}
+
+type tgmPendingOrder struct {
+	signal    structs.Signal
+	expiredAt time.Time
+}
+
+func tgmButton(text string, action string, args ...string) tgmBotAPI.InlineKeyboardButton {
+	return tgmBotAPI.NewInlineKeyboardButtonData(text, strings.Join(append([]string{action}, args...), tgmCallbackSeparator))
+}
+
+// handleCallback authorizes the pressed button, runs its action and records it
+func (u *tgmUseCase) handleCallback(ctx context.Context, q *tgmBotAPI.CallbackQuery) {
+	parts := strings.Split(q.Data, tgmCallbackSeparator)
+	action, args := parts[0], parts[1:]
+
+	record := models.AuditRecord{
+		Source:  models.AuditSourceTelegram,
+		Action:  action,
+		Payload: q.Data,
+	}
+
+	if len(args) != 0 {
+		record.Target = args[0]
+	}
+
+	if q.From != nil {
+		record.UserID = q.From.ID
+		record.UserName = q.From.String()
+	}
+
+	if q.Message != nil && q.Message.Chat != nil {
+		record.ChatID = q.Message.Chat.ID
+	}
+
+	var answer string
+	var err error
+
+	cb, ok := u.callback(action)
+
+	switch {
+	case q.Message == nil || q.Message.Chat == nil || q.From == nil ||
+		!u.tgmController.CheckChatID(q.Message.Chat.ID) || !u.tgmController.CheckUserID(q.From.ID):
+		err = errTgmDenied
+	case !ok:
+		err = fmt.Errorf("unknown action '%s'", action)
+	default:
+		answer, err = cb.run(ctx, q, args)
+	}
+
+	switch {
+	case errors.Is(err, errTgmDenied):
+		record.Result = models.AuditResultDenied
+		record.Error = err.Error()
+	case err != nil:
+		record.Result = models.AuditResultFailed
+		record.Error = err.Error()
+	default:
+		record.Result = models.AuditResultOK
+	}
+
+	if err != nil {
+		u.logger.
+			WithField("action", action).
+			WithField("user", record.UserName).
+			Debug(err)
+
+		answer = fmt.Sprintf("Error: %v", err)
+	}
+
+	// the navigation is recorded only when it is denied or fails
+	if cb.audit || err != nil {
+		u.audit(&record)
+	}
+
+	if err := u.tgmController.AnswerCallback(q.ID, answer); err != nil {
+		u.logger.
+			WithField("func", "AnswerCallback").
+			Debug(err)
+	}
+}
+
+// audit records the action of the button, the audit does not stop the bot
+func (u *tgmUseCase) audit(record *models.AuditRecord) {
+	if err := u.auditRepo.Store(record); err != nil {
+		u.logger.
+			WithField("func", "Store").
+			WithField("action", record.Action).
+			Debug(err)
+	}
+}
+
+func (u *tgmUseCase) callback(action string) (tgmCallback, bool) {
+	for _, cb := range u.callbacks {
+		if cb.action == action {
+			return cb, true
+		}
+	}
+
+	return tgmCallback{}, false
+}
+
+// symbolsProc sends the keyboard of the symbols, the button of the symbol opens its controls
+func (u *tgmUseCase) symbolsProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("%w: no arguments are expected", errTgmUsage)
+	}
+
+	keyboard, err := u.symbolsKeyboard()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := u.tgmController.SendKeyboard("[ Symbols ]", keyboard); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+func (u *tgmUseCase) symbolsKeyboard() (tgmBotAPI.InlineKeyboardMarkup, error) {
+	list, err := u.settingsRepo.LoadAll()
+	if err != nil {
+		return tgmBotAPI.InlineKeyboardMarkup{}, err
+	}
+
+	if len(list) == 0 {
+		return tgmBotAPI.InlineKeyboardMarkup{}, errors.New("no symbols")
+	}
+
+	var rows [][]tgmBotAPI.InlineKeyboardButton
+
+	for _, s := range list {
+		rows = append(rows, tgmBotAPI.NewInlineKeyboardRow(
+			tgmButton(fmt.Sprintf("%s %s\t%s", s.Symbol, s.GetMarket(), s.Status), tgmActionSymbol, s.Symbol),
+		))
+	}
+
+	return tgmBotAPI.NewInlineKeyboardMarkup(rows...), nil
+}
+
+func (u *tgmUseCase) symbolsCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, _ []string) (string, error) {
+	keyboard, err := u.symbolsKeyboard()
+	if err != nil {
+		return "", err
+	}
+
+	return "", u.tgmController.UpdateKeyboard(q.Message.MessageID, "[ Symbols ]", &keyboard)
+}
+
+func (u *tgmUseCase) symbolCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	settings, err := u.callbackSettings(args)
+	if err != nil {
+		return "", err
+	}
+
+	return "", u.showSymbol(q.Message.MessageID, settings)
+}
+
+// showSymbol edits the message to the settings of the symbol with its controls
+func (u *tgmUseCase) showSymbol(msgID int, settings *mongoStructs.Settings) error {
+	text := fmt.Sprintf(
+		"[ %s ]\n"+
+			"Market:\t%s\n"+
+			"Strategy:\t%s\n"+
+			"Status:\t%s\n"+
+			"Depth limit:\t%v\n"+
+			"Version:\t%d\n",
+		settings.Symbol,
+		settings.GetMarket(),
+		settings.GetStrategy(),
+		settings.Status,
+		settings.DepthLimit,
+		settings.Version,
+	)
+
+	status := tgmButton("Disable", tgmActionDisable, settings.Symbol)
+	if settings.Status != mongoStructs.Enabled.ToString() {
+		status = tgmButton("Enable", tgmActionEnable, settings.Symbol)
+	}
+
+	keyboard := tgmBotAPI.NewInlineKeyboardMarkup(
+		tgmBotAPI.NewInlineKeyboardRow(
+			status,
+			tgmButton("Depth limit", tgmActionDepth, settings.Symbol),
+		),
+		tgmBotAPI.NewInlineKeyboardRow(
+			tgmButton("Close position", tgmActionClose, settings.Symbol),
+		),
+		tgmBotAPI.NewInlineKeyboardRow(
+			tgmButton("« Symbols", tgmActionSymbols),
+			tgmButton("Refresh", tgmActionSymbol, settings.Symbol),
+		),
+	)
+
+	return u.tgmController.UpdateKeyboard(msgID, text, &keyboard)
+}
+
+// callbackSettings loads the settings of the symbol of the callback
+func (u *tgmUseCase) callbackSettings(args []string) (*mongoStructs.Settings, error) {
+	if len(args) == 0 || args[0] == "" {
+		return nil, errors.New("no symbol")
+	}
+
+	return u.settingsRepo.Load(args[0])
+}
+
+// tgmChange is the settings change made by the button, the user is kept in the reason
+func tgmChange(q *tgmBotAPI.CallbackQuery, reason string) mongoStructs.SettingsChange {
+	return mongoStructs.SettingsChange{
+		Author: mongoStructs.SettingsAuthorTelegram,
+		Reason: fmt.Sprintf("%s by %s", reason, q.From.String()),
+	}
+}
+
+// statusCallback enables or disables the symbol, its monitor is started or drained by the supervisor
+func (u *tgmUseCase) statusCallback(status mongoStructs.SymbolStatus) func(ctx context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	return func(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+		settings, err := u.callbackSettings(args)
+		if err != nil {
+			return "", err
+		}
+
+		if err := u.settingsRepo.UpdateStatus(settings.ID, status, tgmChange(q, "status "+status.ToString())); err != nil {
+			return "", err
+		}
+
+		if settings, err = u.settingsRepo.Load(settings.Symbol); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s is %s", settings.Symbol, status), u.showSymbol(q.Message.MessageID, settings)
+	}
+}
+
+func (u *tgmUseCase) depthCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	settings, err := u.callbackSettings(args)
+	if err != nil {
+		return "", err
+	}
+
+	var row []tgmBotAPI.InlineKeyboardButton
+	var rows [][]tgmBotAPI.InlineKeyboardButton
+
+	for i, preset := range tgmDepthLimitPresets {
+		text := strconv.FormatFloat(preset, 'f', -1, 64)
+		if preset == settings.DepthLimit {
+			text = "• " + text
+		}
+
+		row = append(row, tgmButton(text, tgmActionDepthSet, settings.Symbol, strconv.Itoa(i)))
+
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+
+	if len(row) != 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgmBotAPI.NewInlineKeyboardRow(tgmButton("« Back", tgmActionSymbol, settings.Symbol)))
+
+	keyboard := tgmBotAPI.NewInlineKeyboardMarkup(rows...)
+
+	return "", u.tgmController.UpdateKeyboard(
+		q.Message.MessageID,
+		fmt.Sprintf("[ %s ]\nDepth limit:\t%v\n", settings.Symbol, settings.DepthLimit),
+		&keyboard,
+	)
+}
+
+// depthSetCallback sets the depth limit of the preset, the settings are validated before the change
+func (u *tgmUseCase) depthSetCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errors.New("no depth limit preset")
+	}
+
+	i, err := strconv.Atoi(args[1])
+	if err != nil || i < 0 || i >= len(tgmDepthLimitPresets) {
+		return "", fmt.Errorf("unknown depth limit preset '%s'", args[1])
+	}
+
+	settings, err := u.callbackSettings(args)
+	if err != nil {
+		return "", err
+	}
+
+	changed := *settings
+	changed.DepthLimit = tgmDepthLimitPresets[i]
+
+	if err := changed.Validate(); err != nil {
+		return "", err
+	}
+
+	if err := u.settingsRepo.UpdateDepthLimit(settings.ID, changed.DepthLimit, tgmChange(q, "depth limit preset")); err != nil {
+		return "", err
+	}
+
+	if settings, err = u.settingsRepo.Load(settings.Symbol); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s depth limit is %v", settings.Symbol, changed.DepthLimit), u.showSymbol(q.Message.MessageID, settings)
+}
+
+func (u *tgmUseCase) closeCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	settings, err := u.callbackSettings(args)
+	if err != nil {
+		return "", err
+	}
+
+	keyboard := tgmBotAPI.NewInlineKeyboardMarkup(
+		tgmBotAPI.NewInlineKeyboardRow(
+			tgmButton("Close", tgmActionCloseConfirm, settings.Symbol),
+			tgmButton("« Back", tgmActionSymbol, settings.Symbol),
+		),
+	)
+
+	return "", u.tgmController.UpdateKeyboard(
+		q.Message.MessageID,
+		fmt.Sprintf("Close the positions of %s?\nThe orders are cancelled and the symbol is disabled when it is flat.", settings.Symbol),
+		&keyboard,
+	)
+}
+
+// closeConfirmCallback liquidates the symbol, the monitor closes the positions and disables it
+func (u *tgmUseCase) closeConfirmCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	settings, err := u.callbackSettings(args)
+	if err != nil {
+		return "", err
+	}
+
+	if err := u.settingsRepo.UpdateStatus(settings.ID, mongoStructs.Liquidation, tgmChange(q, "close position")); err != nil {
+		return "", err
+	}
+
+	if settings, err = u.settingsRepo.Load(settings.Symbol); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s is closing", settings.Symbol), u.showSymbol(q.Message.MessageID, settings)
+}
+
+// addPendingOrder keeps the order until it is confirmed and returns its token, the expired orders are dropped
+func (u *tgmUseCase) addPendingOrder(s structs.Signal) string {
+	u.pendingMu.Lock()
+	defer u.pendingMu.Unlock()
+
+	now := time.Now()
+
+	for token, o := range u.pending {
+		if now.After(o.expiredAt) {
+			delete(u.pending, token)
+		}
+	}
+
+	token := strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	u.pending[token] = tgmPendingOrder{
+		signal:    s,
+		expiredAt: now.Add(tgmConfirmTTL),
+	}
+
+	return token
+}
+
+// takePendingOrder removes the order of the token, the order is taken once
+func (u *tgmUseCase) takePendingOrder(token string) (structs.Signal, error) {
+	u.pendingMu.Lock()
+	defer u.pendingMu.Unlock()
+
+	o, ok := u.pending[token]
+	delete(u.pending, token)
+
+	if !ok || time.Now().After(o.expiredAt) {
+		return structs.Signal{}, errTgmExpired
+	}
+
+	return o.signal, nil
+}
+
+func (u *tgmUseCase) orderConfirmCallback(ctx context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errTgmExpired
+	}
+
+	s, err := u.takePendingOrder(args[0])
+	if err != nil {
+		return "", u.closeOrderMessage(q, err.Error(), err)
+	}
+
+	text, err := u.placeOrder(ctx, s)
+	if err != nil {
+		return "", u.closeOrderMessage(q, fmt.Sprintf("Order\t%s %s %v\nError: %v", s.Side, s.Symbol, s.Quantity, err), err)
+	}
+
+	return "Confirmed", u.closeOrderMessage(q, text+fmt.Sprintf("Confirmed by %s\n", q.From.String()), nil)
+}
+
+func (u *tgmUseCase) orderRejectCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	if len(args) == 0 {
+		return "", errTgmExpired
+	}
+
+	s, err := u.takePendingOrder(args[0])
+	if err != nil {
+		return "", u.closeOrderMessage(q, err.Error(), err)
+	}
+
+	return "Rejected", u.closeOrderMessage(q, fmt.Sprintf("Order\t%s %s %v\nRejected by %s\n", s.Side, s.Symbol, s.Quantity, q.From.String()), nil)
+}
+
+// closeAllConfirmCallback closes the positions of the sides on all the futures symbols by market
+func (u *tgmUseCase) closeAllConfirmCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	status, err := closeAllStatus(args)
+	if err != nil {
+		return "", u.closeOrderMessage(q, err.Error(), err)
+	}
+
+	closed, failed, err := u.orderUseCase.closePositions(status)
+	if err != nil {
+		return "", u.closeOrderMessage(q, fmt.Sprintf("[ %s ]\nError: %v", status, err), err)
+	}
+
+	text := fmt.Sprintf("[ %s ]\n", status)
+
+	if len(closed) == 0 && len(failed) == 0 {
+		text += "No open positions\n"
+	}
+
+	for _, symbol := range closed {
+		text += fmt.Sprintf("%s\tclosing\n", symbol)
+	}
+
+	for _, symbol := range failed {
+		text += fmt.Sprintf("%s\tfailed\n", symbol)
+	}
+
+	return "Confirmed", u.closeOrderMessage(q, text+fmt.Sprintf("Confirmed by %s\n", q.From.String()), nil)
+}
+
+func (u *tgmUseCase) closeAllRejectCallback(_ context.Context, q *tgmBotAPI.CallbackQuery, args []string) (string, error) {
+	status, err := closeAllStatus(args)
+	if err != nil {
+		return "", u.closeOrderMessage(q, err.Error(), err)
+	}
+
+	return "Rejected", u.closeOrderMessage(q, fmt.Sprintf("[ %s ]\nRejected by %s\n", status, q.From.String()), nil)
+}
+
+func closeAllStatus(args []string) (mongoStructs.SymbolStatus, error) {
+	if len(args) != 0 {
+		switch status := mongoStructs.SymbolStatus(args[0]); status {
+		case mongoStructs.LiquidationBUY, mongoStructs.LiquidationSELL:
+			return status, nil
+		}
+	}
+
+	return "", errors.New("unknown positions to close")
+}
+
+// closeOrderMessage removes the buttons of the order message, the error of the action is returned over the edit one
+func (u *tgmUseCase) closeOrderMessage(q *tgmBotAPI.CallbackQuery, text string, err error) error {
+	if editErr := u.tgmController.UpdateKeyboard(q.Message.MessageID, text, nil); editErr != nil {
+		u.logger.
+			WithField("func", "UpdateKeyboard").
+			Debug(editErr)
+	}
+
+	return err
+}
+
+func (u *tgmUseCase) auditProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	limit := tgmLastDefault
+
+	switch len(args) {
+	case 0:
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > tgmLastMax {
+			return "", fmt.Errorf("%w: N is out of [1, %d]", errTgmUsage, tgmLastMax)
+		}
+
+		limit = n
+	default:
+		return "", fmt.Errorf("%w: one N is expected", errTgmUsage)
+	}
+
+	records, err := u.auditRepo.GetLast(limit)
+	if err != nil {
+		return "", err
+	}
+
+	if len(records) == 0 {
+		return "No actions", nil
+	}
+
+	msg := "[ Audit ]\n"
+
+	for _, r := range records {
+		msg += fmt.Sprintf(
+			"%s %s %s %s %s",
+			r.CreatedAt.In(u.loc).Format("02.01 15:04"),
+			r.UserName,
+			r.Action,
+			r.Target,
+			r.Result,
+		)
+
+		if r.Error != "" {
+			msg += ": " + r.Error
+		}
+
+		msg += "\n"
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,462 @@
+package usecasees
+
+import (
+	ctrlMocks "binance/internal/controllers/mocks"
+	mongoMocks "binance/internal/repository/mongo/mocks"
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/repository/postgres"
+	pgMocks "binance/internal/repository/postgres/mocks"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const testChatID = 42
+
+type tgmMocks struct {
+	clientCtrl   *ctrlMocks.ClientCtrl
+	tgmCtrl      *ctrlMocks.TgmCtrl
+	settingsRepo *mongoMocks.SettingsRepo
+	orderRepo    *pgMocks.OrderRepo
+	fillRepo     *pgMocks.FillRepo
+	auditRepo    *pgMocks.AuditRepo
+}
+
+func initTgmUseCase() (*tgmUseCase, tgmMocks) {
+	m := tgmMocks{
+		clientCtrl:   &ctrlMocks.ClientCtrl{},
+		tgmCtrl:      &ctrlMocks.TgmCtrl{},
+		settingsRepo: &mongoMocks.SettingsRepo{},
+		orderRepo:    &pgMocks.OrderRepo{},
+		fillRepo:     &pgMocks.FillRepo{},
+		auditRepo:    &pgMocks.AuditRepo{},
+	}
+
+	cryptoCtrl := &ctrlMocks.CryptoCtrl{}
+	cryptoCtrl.On("GetSignature", mock.AnythingOfType("string")).Return("630e26f39d6728d0e7feffb9", nil)
+
+	orderUseCase := NewOrderUseCase(
+		m.clientCtrl,
+		cryptoCtrl,
+		m.tgmCtrl,
+		m.settingsRepo,
+		nil,
+		m.orderRepo,
+		&pgMocks.GridRepo{},
+		m.fillRepo,
+		mongoStructs.MarketFeatures,
+		nil,
+		"https://fapi.binance.com",
+		logrus.New(),
+	)
+
+	return NewTgmUseCase(nil, orderUseCase, m.settingsRepo, m.orderRepo, m.auditRepo, m.tgmCtrl, logrus.New()), m
+}
+
+func tgmCommandMessage(text string) *tgmBotAPI.Message {
+	name := strings.Fields(text)[0]
+
+	return &tgmBotAPI.Message{
+		MessageID: 7,
+		Chat:      &tgmBotAPI.Chat{ID: testChatID},
+		Text:      text,
+		Entities: []tgmBotAPI.MessageEntity{
+			{Type: "bot_command", Offset: 0, Length: len(name)},
+		},
+	}
+}
+
+func Test_TgmCommands(t *testing.T) {
+	enabled := mongoStructs.Settings{
+		ID:     primitive.NewObjectID(),
+		Symbol: testSymbol,
+		Status: mongoStructs.Enabled.ToString(),
+	}
+
+	disabled := mongoStructs.Settings{
+		ID:     primitive.NewObjectID(),
+		Symbol: ETHUSDT,
+		Status: mongoStructs.Disabled.ToString(),
+	}
+
+	spot := mongoStructs.Settings{
+		ID:     primitive.NewObjectID(),
+		Symbol: BNBBUSD,
+		Market: mongoStructs.MarketSpot.ToString(),
+		Status: mongoStructs.Enabled.ToString(),
+	}
+
+	t.Run("help and usage", func(t *testing.T) {
+		u, _ := initTgmUseCase()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/start"))
+		assert.Contains(t, reply, "/calc_balance - ")
+
+		reply = u.handle(context.Background(), tgmCommandMessage("/unknown"))
+		assert.True(t, strings.HasPrefix(reply, "Unknown command /unknown"))
+
+		reply = u.handle(context.Background(), tgmCommandMessage("/order HOLD BTCUSDT 0.01"))
+		assert.Equal(t, "Error: invalid arguments: unknown side 'HOLD'\nUsage: /order BUY|SELL SYMBOL QUANTITY [TAKE_PROFIT STOP_LOSS]", reply)
+
+		reply = u.handle(context.Background(), tgmCommandMessage("/order BUY BTCUSDT -1"))
+		assert.Contains(t, reply, "'-1' is not a positive number")
+
+		reply = u.handle(context.Background(), tgmCommandMessage("/last 100"))
+		assert.Contains(t, reply, "N is out of [1, 50]")
+	})
+
+	t.Run("order", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		var token string
+
+		m.tgmCtrl.On("SendKeyboard", "Confirm the order\tBUY BTCUSDT 0.01\nTake profit:\t21000\nStop loss:\t19000\n", mock.MatchedBy(func(k tgmBotAPI.InlineKeyboardMarkup) bool {
+			data := *k.InlineKeyboard[0][0].CallbackData
+			token = strings.TrimPrefix(data, tgmActionOrderConfirm+tgmCallbackSeparator)
+
+			return len(data) <= 64 && *k.InlineKeyboard[0][1].CallbackData == tgmActionOrderReject+tgmCallbackSeparator+token
+		})).Return(8, nil).Once()
+
+		// the order waits for the confirmation
+		reply := u.handle(context.Background(), tgmCommandMessage("/order buy btcusdt 0.01 21000 19000"))
+		assert.Empty(t, reply)
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", mock.Anything).Return(true)
+		m.tgmCtrl.On("AnswerCallback", "q", mock.Anything).Return(nil)
+
+		// the symbol is not monitored, the error of the signal is shown
+		m.tgmCtrl.On("UpdateKeyboard", 8, "Order\tBUY BTCUSDT 0.01\nError: symbol is not monitored: BTCUSDT", (*tgmBotAPI.InlineKeyboardMarkup)(nil)).Return(nil).Once()
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionOrderConfirm && r.Result == models.AuditResultFailed && r.Target == token
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery(tgmActionOrderConfirm+tgmCallbackSeparator+token))
+
+		// the order is taken once
+		m.tgmCtrl.On("UpdateKeyboard", 8, "the order is expired", (*tgmBotAPI.InlineKeyboardMarkup)(nil)).Return(nil).Once()
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionOrderReject && r.Error == "the order is expired"
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery(tgmActionOrderReject+tgmCallbackSeparator+token))
+
+		m.tgmCtrl.AssertExpectations(t)
+		m.auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("sell all", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		exchange := newTestExchange()
+		exchange.positions[PositionSideLong] = "0.01"
+		exchange.positions[PositionSideShort] = "-0.02"
+
+		m.clientCtrl.On("Send", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(exchange.response, exchange.err)
+		m.orderRepo.On("Record", mock.AnythingOfType("*models.Order"), mock.AnythingOfType("string"), mock.AnythingOfType("postgres.OrderChange")).
+			Return(nil)
+
+		// the close waits for the confirmation
+		m.tgmCtrl.On("SendKeyboard", mock.MatchedBy(func(text string) bool {
+			return strings.HasPrefix(text, "Close the long positions of all the futures symbols?")
+		}), mock.MatchedBy(func(k tgmBotAPI.InlineKeyboardMarkup) bool {
+			return *k.InlineKeyboard[0][0].CallbackData == "all_ok:LIQUIDATION_BUY" && *k.InlineKeyboard[0][1].CallbackData == "all_no:LIQUIDATION_BUY"
+		})).Return(8, nil).Once()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/sell_all"))
+		assert.Empty(t, reply)
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", int64(7)).Return(true)
+		m.tgmCtrl.On("AnswerCallback", "q", "Confirmed").Return(nil).Once()
+		m.tgmCtrl.On("UpdateKeyboard", 8, "[ LIQUIDATION_BUY ]\nBTCUSDT\tclosing\nConfirmed by operator\n", (*tgmBotAPI.InlineKeyboardMarkup)(nil)).Return(nil).Once()
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionCloseAllConfirm && r.Result == models.AuditResultOK
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("all_ok:LIQUIDATION_BUY"))
+
+		// the long position is closed by market, the symbols keep their status
+		assert.Equal(t, 1, exchange.postCount())
+		m.tgmCtrl.AssertExpectations(t)
+		m.auditRepo.AssertExpectations(t)
+		m.settingsRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("long reply", func(t *testing.T) {
+		u, _ := initTgmUseCase()
+
+		u.commands = append(u.commands, tgmCommand{name: "long", run: func(context.Context, *tgmBotAPI.Message, []string) (string, error) {
+			return strings.Repeat("ж", tgmMaxMessage+1), nil
+		}})
+
+		// the reply is cut on the characters
+		reply := []rune(u.handle(context.Background(), tgmCommandMessage("/long")))
+		assert.Len(t, reply, tgmMaxMessage)
+		assert.Equal(t, "ж...", string(reply[tgmMaxMessage-4:]))
+	})
+
+	t.Run("last", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.orderRepo.On("Find", postgres.OrderFilter{Symbol: ETHUSDT, Descending: true, Limit: 2}).
+			Return(&postgres.OrderPage{Orders: []models.Order{
+				{Symbol: ETHUSDT, Side: SideBuy, Type: OrderTypeLimit, Quantity: 0.5, Price: 1500, ActualPrice: 1499.5, Status: OrderStatusFilled},
+				{Symbol: ETHUSDT, Side: SideSell, Type: OrderTypeLimit, Quantity: 0.5, Price: 1520, Status: OrderStatusNew},
+			}}, nil).Once()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/last ethusdt 2"))
+
+		lines := strings.Split(strings.TrimSpace(reply), "\n")
+		if assert.Len(t, lines, 3) {
+			assert.True(t, strings.HasSuffix(lines[1], "ETHUSDT BUY LIMIT 0.500 @ 1499.50 FILLED"))
+			assert.True(t, strings.HasSuffix(lines[2], "ETHUSDT SELL LIMIT 0.500 @ 1520.00 NEW"))
+		}
+	})
+
+	t.Run("statistics", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.settingsRepo.On("LoadAll").Return([]mongoStructs.Settings{enabled, disabled, spot}, nil)
+		m.orderRepo.On("Find", mock.MatchedBy(func(f postgres.OrderFilter) bool {
+			return f.Symbol == testSymbol && f.Cursor == ""
+		})).Return(&postgres.OrderPage{
+			Orders:     []models.Order{{Status: OrderStatusFilled}, {Status: OrderStatusCanceled}},
+			NextCursor: "next",
+		}, nil).Once()
+		m.orderRepo.On("Find", mock.MatchedBy(func(f postgres.OrderFilter) bool {
+			return f.Cursor == "next"
+		})).Return(&postgres.OrderPage{
+			Orders: []models.Order{{Status: OrderStatusFilled}, {Status: OrderStatusFilled}},
+		}, nil).Once()
+		m.fillRepo.On("DailyPnL", testSymbol, mock.Anything, mock.Anything).
+			Return([]models.PnL{{Key: "2022-11-05", CommissionAsset: USDT, RealizedPnL: 1.5, Commission: 0.1}}, nil).Once()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/stat"))
+		assert.Contains(t, reply, "Total:\t4\nFilled:\t3\nCanceled:\t1\nFilled/Canceled:\t75/25\n")
+		assert.Contains(t, reply, "PnL 2022-11-05:\t1.5000 (fee 0.1000 USDT)")
+
+		m.orderRepo.AssertExpectations(t)
+	})
+
+	t.Run("calc balance", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		account, err := json.Marshal(structs.FeatureAccount{
+			TotalWalletBalance: "1000",
+			TotalMarginBalance: "1000",
+			AvailableBalance:   "800",
+			Assets:             []structs.FeatureAccountAsset{{Asset: USDT, WalletBalance: "1000", MarginBalance: "1000"}},
+			Positions:          []structs.FeatureAccountPosition{{Symbol: testSymbol, PositionSide: PositionSideLong, PositionAmt: "0.01", EntryPrice: "20000", Notional: "200"}},
+		})
+		assert.NoError(t, err)
+
+		m.clientCtrl.On("Send", "GET", mock.MatchedBy(func(input *url.URL) bool {
+			return input.Path == featureAccount
+		}), []byte(nil), true).Return(account, nil).Once()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/calc_balance"))
+		assert.Contains(t, reply, "Available:\t800.00\n")
+		assert.Contains(t, reply, "USDT:\t1000.0000 (100.00%)\n")
+		assert.Contains(t, reply, "BTCUSDT LONG:\t0.01 @ 20000.00\t200.00 (20.00%)")
+	})
+
+	t.Run("set avg price", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.orderRepo.On("Find", mock.MatchedBy(func(f postgres.OrderFilter) bool {
+			return f.Symbol == testSymbol && f.Statuses[0] == OrderStatusFilled
+		})).Return(&postgres.OrderPage{Orders: []models.Order{
+			{ID: "a", SessionID: "s", ActualPrice: 20000},
+			{ID: "b", SessionID: "s", ActualPrice: 20100},
+		}}, nil).Once()
+		m.fillRepo.On("GetBySessionID", "s").Return([]models.Fill{
+			{ClientOrderID: "a", Price: 19990, Quantity: 0.01, QuoteQuantity: 199.9},
+			{ClientOrderID: "a", Price: 20010, Quantity: 0.03, QuoteQuantity: 600.3},
+			{ClientOrderID: "b", Price: 20100, Quantity: 0.04, QuoteQuantity: 804},
+		}, nil).Once()
+		m.orderRepo.On("SetActualPrice", "a", mock.MatchedBy(func(price float64) bool {
+			return price > 20004.99 && price < 20005.01
+		}), mock.MatchedBy(func(c postgres.OrderChange) bool {
+			return c.Source == models.OrderSourceManual
+		})).Return(nil).Once()
+
+		reply := u.handle(context.Background(), tgmCommandMessage("/set_avg_price BTCUSDT"))
+		assert.Equal(t, "Updated 1 of 2 filled orders", reply)
+
+		m.orderRepo.AssertExpectations(t)
+		m.fillRepo.AssertExpectations(t)
+	})
+}
+
+func tgmCallbackQuery(data string) *tgmBotAPI.CallbackQuery {
+	return &tgmBotAPI.CallbackQuery{
+		ID:      "q",
+		From:    &tgmBotAPI.User{ID: 7, UserName: "operator"},
+		Message: &tgmBotAPI.Message{MessageID: 8, Chat: &tgmBotAPI.Chat{ID: testChatID}},
+		Data:    data,
+	}
+}
+
+func Test_TgmCallbacks(t *testing.T) {
+	settings := mongoStructs.Settings{
+		ID:         primitive.NewObjectID(),
+		Symbol:     testSymbol,
+		Step:       0.003,
+		Delta:      45,
+		DepthLimit: 35,
+		Status:     mongoStructs.Enabled.ToString(),
+		Version:    1,
+	}
+
+	disabled := settings
+	disabled.Status = mongoStructs.Disabled.ToString()
+	disabled.Version = 2
+
+	t.Run("denied", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", int64(7)).Return(false)
+		m.tgmCtrl.On("AnswerCallback", "q", "Error: the action is not allowed").Return(nil).Once()
+		m.auditRepo.On("Store", &models.AuditRecord{
+			Source:   models.AuditSourceTelegram,
+			ChatID:   testChatID,
+			UserID:   7,
+			UserName: "operator",
+			Action:   tgmActionDisable,
+			Target:   testSymbol,
+			Payload:  "off:BTCUSDT",
+			Result:   models.AuditResultDenied,
+			Error:    "the action is not allowed",
+		}).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("off:BTCUSDT"))
+
+		m.tgmCtrl.AssertExpectations(t)
+		m.auditRepo.AssertExpectations(t)
+		m.settingsRepo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", int64(7)).Return(true)
+		m.settingsRepo.On("Load", testSymbol).Return(&settings, nil).Once()
+		m.settingsRepo.On("UpdateStatus", settings.ID, mongoStructs.Disabled, mongoStructs.SettingsChange{
+			Author: mongoStructs.SettingsAuthorTelegram,
+			Reason: "status DISABLED by operator",
+		}).Return(nil).Once()
+		m.settingsRepo.On("Load", testSymbol).Return(&disabled, nil).Once()
+		m.tgmCtrl.On("UpdateKeyboard", 8, mock.MatchedBy(func(text string) bool {
+			return strings.Contains(text, "Status:\tDISABLED\n")
+		}), mock.MatchedBy(func(k *tgmBotAPI.InlineKeyboardMarkup) bool {
+			return *k.InlineKeyboard[0][0].CallbackData == "on:BTCUSDT"
+		})).Return(nil).Once()
+		m.tgmCtrl.On("AnswerCallback", "q", "BTCUSDT is DISABLED").Return(nil).Once()
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionDisable && r.Result == models.AuditResultOK
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("off:BTCUSDT"))
+
+		m.tgmCtrl.AssertExpectations(t)
+		m.settingsRepo.AssertExpectations(t)
+		m.auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("depth limit preset", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", int64(7)).Return(true)
+		m.tgmCtrl.On("AnswerCallback", "q", mock.Anything).Return(nil)
+		m.settingsRepo.On("Load", testSymbol).Return(&settings, nil)
+
+		// the navigation is not recorded
+		m.tgmCtrl.On("UpdateKeyboard", 8, "[ BTCUSDT ]\nDepth limit:\t35\n", mock.MatchedBy(func(k *tgmBotAPI.InlineKeyboardMarkup) bool {
+			return k.InlineKeyboard[0][2].Text == "• 35" && *k.InlineKeyboard[1][0].CallbackData == "depth_set:BTCUSDT:3"
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("depth:BTCUSDT"))
+
+		m.auditRepo.AssertNotCalled(t, "Store", mock.Anything)
+
+		// the forged preset is rejected
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionDepthSet && r.Result == models.AuditResultFailed
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("depth_set:BTCUSDT:9"))
+
+		m.settingsRepo.On("UpdateDepthLimit", settings.ID, float64(50), mock.Anything).Return(nil).Once()
+		m.tgmCtrl.On("UpdateKeyboard", 8, mock.Anything, mock.Anything).Return(nil).Once()
+		m.auditRepo.On("Store", mock.MatchedBy(func(r *models.AuditRecord) bool {
+			return r.Action == tgmActionDepthSet && r.Result == models.AuditResultOK && r.Payload == "depth_set:BTCUSDT:3"
+		})).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("depth_set:BTCUSDT:3"))
+
+		m.settingsRepo.AssertExpectations(t)
+		m.auditRepo.AssertExpectations(t)
+	})
+
+	t.Run("close position", func(t *testing.T) {
+		u, m := initTgmUseCase()
+
+		m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+		m.tgmCtrl.On("CheckUserID", int64(7)).Return(true)
+		m.tgmCtrl.On("AnswerCallback", "q", "BTCUSDT is closing").Return(nil).Once()
+		m.tgmCtrl.On("UpdateKeyboard", 8, mock.Anything, mock.Anything).Return(nil).Once()
+		m.settingsRepo.On("Load", testSymbol).Return(&settings, nil)
+		m.settingsRepo.On("UpdateStatus", settings.ID, mongoStructs.Liquidation, mock.Anything).Return(nil).Once()
+		m.auditRepo.On("Store", mock.Anything).Return(nil).Once()
+
+		u.handleCallback(context.Background(), tgmCallbackQuery("close_ok:BTCUSDT"))
+
+		m.tgmCtrl.AssertExpectations(t)
+		m.settingsRepo.AssertExpectations(t)
+	})
+}
+
+func Test_TgmCommandProcessor(t *testing.T) {
+	u, m := initTgmUseCase()
+
+	updates := make(chan tgmBotAPI.Update, 3)
+
+	foreign := tgmCommandMessage("/ping")
+	foreign.Chat = &tgmBotAPI.Chat{ID: 1}
+
+	updates <- tgmBotAPI.Update{}
+	updates <- tgmBotAPI.Update{Message: foreign}
+	updates <- tgmBotAPI.Update{Message: tgmCommandMessage("/ping")}
+	close(updates)
+
+	m.tgmCtrl.On("GetUpdates").Return(tgmBotAPI.UpdatesChannel(updates))
+	m.tgmCtrl.On("CheckChatID", int64(1)).Return(false)
+	m.tgmCtrl.On("CheckChatID", int64(testChatID)).Return(true)
+	m.tgmCtrl.On("Send", mock.MatchedBy(func(text string) bool {
+		return strings.HasPrefix(text, "PONG")
+	})).Return(nil).Once()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// the processor stops on the closed updates
+	u.CommandProcessor(ctx)
+
+	m.tgmCtrl.AssertExpectations(t)
+}
@@ -0,0 +1,327 @@
+package usecasees
+
+import (
+	mongoStructs "binance/internal/repository/mongo/structs"
+	"binance/internal/repository/postgres"
+	"binance/internal/usecasees/structs"
+	"binance/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	tgmBotAPI "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// orderProc asks to confirm the market order of the session, the message id is the idempotency key of the signal
+func (u *tgmUseCase) orderProc(_ context.Context, msg *tgmBotAPI.Message, args []string) (string, error) {
+	if len(args) != 3 && len(args) != 5 {
+		return "", fmt.Errorf("%w: side, symbol and quantity are expected", errTgmUsage)
+	}
+
+	s := structs.Signal{
+		Side:           strings.ToUpper(args[0]),
+		Symbol:         strings.ToUpper(args[1]),
+		IdempotencyKey: fmt.Sprintf("tgm-%d-%d", msg.Chat.ID, msg.MessageID),
+	}
+
+	if s.Side != SideBuy && s.Side != SideSell {
+		return "", fmt.Errorf("%w: unknown side '%s'", errTgmUsage, args[0])
+	}
+
+	values := make([]float64, len(args)-2)
+	for i, arg := range args[2:] {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || v <= 0 {
+			return "", fmt.Errorf("%w: '%s' is not a positive number", errTgmUsage, arg)
+		}
+
+		values[i] = v
+	}
+
+	s.Quantity = values[0]
+	if len(values) == 3 {
+		s.TakeProfit, s.StopLoss = values[1], values[2]
+	}
+
+	if err := s.Validate(); err != nil {
+		return "", fmt.Errorf("%w: %v", errTgmUsage, err)
+	}
+
+	token := u.addPendingOrder(s)
+
+	text := fmt.Sprintf("Confirm the order\t%s %s %v\n", s.Side, s.Symbol, s.Quantity)
+	if s.TakeProfit != 0 {
+		text += fmt.Sprintf("Take profit:\t%v\nStop loss:\t%v\n", s.TakeProfit, s.StopLoss)
+	}
+
+	if _, err := u.tgmController.SendKeyboard(text, tgmBotAPI.NewInlineKeyboardMarkup(
+		tgmBotAPI.NewInlineKeyboardRow(
+			tgmButton("Confirm", tgmActionOrderConfirm, token),
+			tgmButton("Reject", tgmActionOrderReject, token),
+		),
+	)); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+// placeOrder opens the session of the confirmed order
+func (u *tgmUseCase) placeOrder(ctx context.Context, s structs.Signal) (string, error) {
+	result, err := u.orderUseCase.Signal(ctx, &s)
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf(
+		"Order\t%s %s %v\n"+
+			"Session:\t%s\n"+
+			"Orders:\t%s\n",
+		s.Side,
+		s.Symbol,
+		s.Quantity,
+		result.SessionID,
+		strings.Join(result.OrderIDs, ", "),
+	)
+
+	if result.Duplicate {
+		out += "The order is placed already\n"
+	}
+
+	return out, nil
+}
+
+// sellAllProc asks to confirm the close of the long positions of all the futures symbols
+func (u *tgmUseCase) sellAllProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	return u.closeAll(args, mongoStructs.LiquidationBUY, "long")
+}
+
+// buyAllProc asks to confirm the close of the short positions of all the futures symbols
+func (u *tgmUseCase) buyAllProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	return u.closeAll(args, mongoStructs.LiquidationSELL, "short")
+}
+
+// closeAll sends the buttons confirming the close, the callback carries the liquidation mode of the sides
+func (u *tgmUseCase) closeAll(args []string, status mongoStructs.SymbolStatus, side string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("%w: no arguments are expected", errTgmUsage)
+	}
+
+	if _, err := u.tgmController.SendKeyboard(
+		fmt.Sprintf("Close the %s positions of all the futures symbols?\nTheir orders are cancelled, the symbols keep their status.", side),
+		tgmBotAPI.NewInlineKeyboardMarkup(
+			tgmBotAPI.NewInlineKeyboardRow(
+				tgmButton("Confirm", tgmActionCloseAllConfirm, status.ToString()),
+				tgmButton("Reject", tgmActionCloseAllReject, status.ToString()),
+			),
+		),
+	); err != nil {
+		return "", err
+	}
+
+	return "", nil
+}
+
+func (u *tgmUseCase) calcBalanceProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	if len(args) != 0 {
+		return "", fmt.Errorf("%w: no arguments are expected", errTgmUsage)
+	}
+
+	account, err := u.orderUseCase.getFeatureAccount()
+	if err != nil {
+		return "", err
+	}
+
+	balance, err := account.Breakdown()
+	if err != nil {
+		return "", err
+	}
+
+	out := fmt.Sprintf(
+		"[ Balance ]\n"+
+			"Wallet:\t%.2f\n"+
+			"Unrealized:\t%.2f\n"+
+			"Margin:\t%.2f\n"+
+			"Available:\t%.2f\n",
+		balance.Wallet,
+		balance.Unrealized,
+		balance.Margin,
+		balance.Available,
+	)
+
+	if len(balance.Assets) != 0 {
+		out += "\n[ Assets ]\n"
+	}
+
+	for _, a := range balance.Assets {
+		out += fmt.Sprintf("%s:\t%.4f (%.2f%%)\n", a.Name, a.Value, a.Weight)
+	}
+
+	if len(balance.Positions) != 0 {
+		out += "\n[ Positions ]\n"
+	}
+
+	for _, p := range balance.Positions {
+		out += fmt.Sprintf(
+			"%s:\t%v @ %.2f\t%.2f (%.2f%%)\tPnL %.2f\n",
+			p.Name,
+			p.Amount,
+			p.Price,
+			p.Value,
+			p.Weight,
+			p.Unrealized,
+		)
+	}
+
+	return out, nil
+}
+
+// setActualProc writes the exchange state of the open orders to the repository
+func (u *tgmUseCase) setActualProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	symbols, err := u.symbolArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	var synced int
+
+	for _, symbol := range symbols {
+		filter := postgres.OrderFilter{
+			Symbol:   symbol,
+			Statuses: []string{OrderStatusNew, OrderStatusPartiallyFilled},
+			Limit:    postgres.MaxOrderLimit,
+		}
+
+		// the synced orders leave the filter, so the cursor is taken before the sync
+		var orders []models.Order
+
+		for {
+			page, err := u.orderRepo.Find(filter)
+			if err != nil {
+				return "", err
+			}
+
+			orders = append(orders, page.Orders...)
+
+			if page.NextCursor == "" {
+				break
+			}
+
+			filter.Cursor = page.NextCursor
+		}
+
+		for i := range orders {
+			u.orderUseCase.syncOrderStatus(&orders[i])
+			synced++
+		}
+	}
+
+	return fmt.Sprintf("Synced %d open orders of %s", synced, strings.Join(symbols, ", ")), nil
+}
+
+// setAvgPriceProc sets the actual price of the orders filled for 24h to the average price of their fills
+func (u *tgmUseCase) setAvgPriceProc(_ context.Context, _ *tgmBotAPI.Message, args []string) (string, error) {
+	symbols, err := u.symbolArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	eTime := time.Now()
+	sTime := eTime.Add(-24 * time.Hour)
+
+	var filled, updated int
+
+	for _, symbol := range symbols {
+		filter := postgres.OrderFilter{
+			Symbol:   symbol,
+			Statuses: []string{OrderStatusFilled},
+			From:     sTime,
+			To:       eTime,
+			Limit:    postgres.MaxOrderLimit,
+		}
+
+		// the fills are read once for the session
+		avgPrices := make(map[string]map[string]float64)
+
+		for {
+			page, err := u.orderRepo.Find(filter)
+			if err != nil {
+				return "", err
+			}
+
+			for _, o := range page.Orders {
+				if o.SessionID == "" {
+					continue
+				}
+
+				filled++
+
+				prices, ok := avgPrices[o.SessionID]
+				if !ok {
+					fills, err := u.orderUseCase.fillRepo.GetBySessionID(o.SessionID)
+					if err != nil {
+						return "", err
+					}
+
+					prices = fillAvgPrices(fills)
+					avgPrices[o.SessionID] = prices
+				}
+
+				price, ok := prices[o.ID]
+				if !ok || math.Abs(price-o.ActualPrice) < 1e-9 {
+					continue
+				}
+
+				payload, err := json.Marshal(struct {
+					AvgPrice float64 `json:"avg_price"`
+				}{price})
+				if err != nil {
+					return "", err
+				}
+
+				if err := u.orderRepo.SetActualPrice(o.ID, price, orderChange(models.OrderSourceManual, payload)); err != nil {
+					return "", err
+				}
+
+				updated++
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+
+			filter.Cursor = page.NextCursor
+		}
+	}
+
+	return fmt.Sprintf("Updated %d of %d filled orders", updated, filled), nil
+}
+
+// fillAvgPrices is the volume weighted price of the fills by the client order id
+func fillAvgPrices(fills []models.Fill) map[string]float64 {
+	quote := make(map[string]float64)
+	quantity := make(map[string]float64)
+
+	for _, f := range fills {
+		quoteQuantity := f.QuoteQuantity
+		if quoteQuantity == 0 {
+			quoteQuantity = f.Price * f.Quantity
+		}
+
+		quote[f.ClientOrderID] += quoteQuantity
+		quantity[f.ClientOrderID] += f.Quantity
+	}
+
+	out := make(map[string]float64, len(quote))
+	for id, q := range quantity {
+		if q != 0 {
+			out[id] = quote[id] / q
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,26 @@
+-- +migrate Up
+-- the orders are named by their client order id, the first migration created the serial id
+alter table orders
+    alter column id drop default,
+    alter column id type text using id::text,
+    add column if not exists session_id   text,
+    add column if not exists actual_price real,
+    add column if not exists try          integer;
+
+drop sequence if exists orders_id_seq;
+
+-- +migrate Down
+-- the serial id is restored while the orders have the numeric ids only
+create sequence if not exists orders_id_seq;
+
+alter table orders
+    drop column if exists session_id,
+    drop column if exists actual_price,
+    drop column if exists try,
+    alter column id type integer using id::integer,
+    alter column id set default nextval('orders_id_seq');
+
+alter sequence orders_id_seq owned by orders.id;
+
+select setval('orders_id_seq', coalesce(max(id), 0) + 1, false)
+from orders;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +migrate Up
+create table if not exists features_orders
+(
+    id            text primary key,
+    order_id      bigint,
+    session_id    text,
+    symbol        text,
+    side          text,
+    position_side text,
+    quantity      real,
+    actual_price  real,
+    price         real,
+    stop_price    real,
+    try           integer,
+    status        text,
+    type          text,
+    created_at    timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+-- +migrate Down
+drop table if exists features_orders;
\ No newline at end of file
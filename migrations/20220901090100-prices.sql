@@ -0,0 +1,11 @@
+-- +migrate Up
+create table if not exists prices
+(
+    id         serial primary key,
+    symbol     text,
+    price      real,
+    created_at timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+-- +migrate Down
+drop table if exists prices;
\ No newline at end of file
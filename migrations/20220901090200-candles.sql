@@ -0,0 +1,17 @@
+-- +migrate Up
+create table if not exists candles
+(
+    id          serial primary key,
+    symbol      text,
+    open_price  real,
+    close_price real,
+    max_price   real,
+    min_price   real,
+    time_frame  text,
+    open_time   timestamp with time zone,
+    close_time  timestamp with time zone,
+    created_at  timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+-- +migrate Down
+drop table if exists candles;
\ No newline at end of file
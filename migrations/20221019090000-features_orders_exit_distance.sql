@@ -0,0 +1,13 @@
+-- +migrate Up
+alter table features_orders
+    add column if not exists exit_model    text default '',
+    add column if not exists volatility    real default 0,
+    add column if not exists safe_delta    real default 0,
+    add column if not exists trigger_delta real default 0;
+
+-- +migrate Down
+alter table features_orders
+    drop column if exists exit_model,
+    drop column if exists volatility,
+    drop column if exists safe_delta,
+    drop column if exists trigger_delta;
\ No newline at end of file
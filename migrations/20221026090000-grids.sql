@@ -0,0 +1,34 @@
+-- +migrate Up
+create table if not exists grids
+(
+    id              text primary key,
+    symbol          text,
+    market          text,
+    min_price       real,
+    max_price       real,
+    levels          integer,
+    quantity        real,
+    realized_profit real    default 0,
+    cycles          integer default 0,
+    status          text,
+    created_at      timestamp with time zone default CURRENT_TIMESTAMP,
+    updated_at      timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create table if not exists grid_levels
+(
+    grid_id    text references grids (id),
+    level      integer,
+    state      text,
+    order_id   text    default '',
+    quantity   real    default 0,
+    buy_price  real    default 0,
+    profit     real    default 0,
+    cycles     integer default 0,
+    updated_at timestamp with time zone default CURRENT_TIMESTAMP,
+    primary key (grid_id, level)
+);
+
+-- +migrate Down
+drop table if exists grid_levels;
+drop table if exists grids;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- +migrate Up
+alter table features_orders
+    add column if not exists take_profit real default 0,
+    add column if not exists stop_loss   real default 0;
+
+-- +migrate Down
+alter table features_orders
+    drop column if exists take_profit,
+    drop column if exists stop_loss;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +migrate Up
+create index if not exists orders_symbol_created_at_idx on orders (symbol, created_at);
+create index if not exists orders_session_id_idx on orders (session_id);
+
+create index if not exists features_orders_symbol_created_at_idx on features_orders (symbol, created_at);
+create index if not exists features_orders_session_id_idx on features_orders (session_id);
+
+create index if not exists prices_symbol_created_at_idx on prices (symbol, created_at);
+
+-- +migrate Down
+drop index if exists prices_symbol_created_at_idx;
+
+drop index if exists features_orders_session_id_idx;
+drop index if exists features_orders_symbol_created_at_idx;
+
+drop index if exists orders_session_id_idx;
+drop index if exists orders_symbol_created_at_idx;
\ No newline at end of file
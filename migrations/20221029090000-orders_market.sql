@@ -0,0 +1,121 @@
+-- +migrate Up
+alter table orders
+    add column if not exists market        text default 'SPOT',
+    add column if not exists position_side text default '',
+    add column if not exists exit_model    text default '',
+    add column if not exists volatility    real default 0,
+    add column if not exists safe_delta    real default 0,
+    add column if not exists trigger_delta real default 0,
+    add column if not exists take_profit   real default 0,
+    add column if not exists stop_loss     real default 0;
+
+update orders
+set market = 'SPOT'
+where market is null;
+
+alter table orders
+    alter column market set not null;
+
+-- the futures orders are moved to the orders, an id taken by a spot order aborts the migration
+-- and the futures orders are kept
+insert into orders (id, order_id, session_id, symbol, side, quantity, actual_price, price, stop_price, try, status, type,
+                    created_at, market, position_side, exit_model, volatility, safe_delta, trigger_delta, take_profit,
+                    stop_loss)
+select id,
+       order_id,
+       session_id,
+       symbol,
+       side,
+       quantity,
+       actual_price,
+       price,
+       stop_price,
+       try,
+       status,
+       type,
+       created_at,
+       'USDT_M',
+       coalesce(position_side, ''),
+       exit_model,
+       volatility,
+       safe_delta,
+       trigger_delta,
+       take_profit,
+       stop_loss
+from features_orders;
+
+drop table features_orders;
+
+drop index if exists orders_symbol_created_at_idx;
+create index if not exists orders_market_symbol_created_at_idx on orders (market, symbol, created_at, id);
+
+-- +migrate Down
+create table if not exists features_orders
+(
+    id            text primary key,
+    order_id      bigint,
+    session_id    text,
+    symbol        text,
+    side          text,
+    position_side text,
+    quantity      real,
+    actual_price  real,
+    price         real,
+    stop_price    real,
+    try           integer,
+    status        text,
+    type          text,
+    exit_model    text    default '',
+    volatility    real    default 0,
+    safe_delta    real    default 0,
+    trigger_delta real    default 0,
+    take_profit   real    default 0,
+    stop_loss     real    default 0,
+    created_at    timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+insert into features_orders (id, order_id, session_id, symbol, side, position_side, quantity, actual_price, price,
+                             stop_price, try, status, type, exit_model, volatility, safe_delta, trigger_delta,
+                             take_profit, stop_loss, created_at)
+select id,
+       order_id,
+       session_id,
+       symbol,
+       side,
+       position_side,
+       quantity,
+       actual_price,
+       price,
+       stop_price,
+       try,
+       status,
+       type,
+       exit_model,
+       volatility,
+       safe_delta,
+       trigger_delta,
+       take_profit,
+       stop_loss,
+       created_at
+from orders
+where market <> 'SPOT';
+
+delete
+from orders
+where market <> 'SPOT';
+
+create index if not exists features_orders_symbol_created_at_idx on features_orders (symbol, created_at);
+create index if not exists features_orders_session_id_idx on features_orders (session_id);
+
+drop index if exists orders_market_symbol_created_at_idx;
+create index if not exists orders_symbol_created_at_idx on orders (symbol, created_at);
+
+alter table orders
+    drop column if exists market,
+    drop column if exists position_side,
+    drop column if exists exit_model,
+    drop column if exists volatility,
+    drop column if exists safe_delta,
+    drop column if exists trigger_delta,
+    drop column if exists take_profit,
+    drop column if exists stop_loss;
\ No newline at end of file
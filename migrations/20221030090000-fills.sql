@@ -0,0 +1,31 @@
+-- +migrate Up
+create table if not exists fills
+(
+    id               bigint,
+    market           text,
+    symbol           text,
+    order_id         bigint,
+    client_order_id  text    default '',
+    session_id       text    default '',
+    side             text,
+    position_side    text    default '',
+    price            double precision,
+    quantity         double precision,
+    quote_quantity   double precision,
+    commission       double precision,
+    commission_asset text,
+    realized_pnl     double precision default 0,
+    maker            boolean default false,
+    traded_at        timestamp with time zone,
+    created_at       timestamp with time zone default CURRENT_TIMESTAMP,
+    primary key (market, symbol, id)
+);
+
+create index if not exists fills_market_order_id_idx on fills (market, order_id);
+create index if not exists fills_market_session_id_idx on fills (market, session_id);
+create index if not exists fills_market_symbol_traded_at_idx on fills (market, symbol, traded_at);
+create index if not exists orders_market_order_id_idx on orders (market, order_id);
+
+-- +migrate Down
+drop index if exists orders_market_order_id_idx;
+drop table if exists fills;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +migrate Up
+create table if not exists incomes
+(
+    id          text primary key,
+    tran_id     bigint default 0,
+    trade_id    text   default '',
+    source      text,
+    symbol      text   default '',
+    income_type text,
+    income      double precision,
+    asset       text,
+    info        text   default '',
+    income_at   timestamp with time zone,
+    created_at  timestamp with time zone default CURRENT_TIMESTAMP
+);
+
+create index if not exists incomes_source_income_at_idx on incomes (source, income_at);
+create index if not exists incomes_income_at_idx on incomes (income_at, symbol, income_type);
+
+-- +migrate Down
+drop table if exists incomes;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +migrate Up
+create table if not exists order_events
+(
+    id         bigserial primary key,
+    order_ref  text,
+    session_id text    default '',
+    market     text,
+    symbol     text,
+    event      text,
+    source     text,
+    status     text    default '',
+    price      real    default 0,
+    order_id   bigint  default 0,
+    payload    text    default '',
+    created_at timestamp with time zone default clock_timestamp()
+);
+
+create index if not exists order_events_market_session_id_idx on order_events (market, session_id, created_at, id);
+create index if not exists order_events_order_ref_idx on order_events (order_ref);
+
+-- +migrate Down
+drop table if exists order_events;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +migrate Up
+delete from candles a using candles b
+where a.symbol = b.symbol and a.time_frame = b.time_frame and a.open_time = b.open_time and a.id < b.id;
+
+create unique index if not exists candles_symbol_time_frame_open_time_idx on candles (symbol, time_frame, open_time);
+
+create table if not exists prices_archive
+(
+    id         integer primary key,
+    symbol     text,
+    price      real,
+    created_at timestamp with time zone
+);
+
+create index if not exists prices_archive_symbol_created_at_idx on prices_archive (symbol, created_at);
+
+-- +migrate Down
+drop index if exists prices_archive_symbol_created_at_idx;
+drop table if exists prices_archive;
+
+drop index if exists candles_symbol_time_frame_open_time_idx;
\ No newline at end of file
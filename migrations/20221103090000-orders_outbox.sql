@@ -0,0 +1,11 @@
+-- +migrate Up
+alter table orders add column if not exists submit_attempts integer not null default 0;
+alter table orders add column if not exists claimed_at timestamp with time zone;
+
+create index if not exists orders_market_outbox_idx on orders (market, created_at) where status = 'IN PROGRESS';
+
+-- +migrate Down
+drop index if exists orders_market_outbox_idx;
+
+alter table orders drop column if exists claimed_at;
+alter table orders drop column if exists submit_attempts;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- +migrate Up
+alter table orders add column if not exists settings_version integer not null default 0;
+
+-- +migrate Down
+alter table orders drop column if exists settings_version;
\ No newline at end of file
@@ -0,0 +1,286 @@
+// Package migrations keeps the schema history of the postgres database and, in the sqlite
+// directory, of the sqlite one. The files are in the sql-migrate format and are embedded in
+// the binary, the applied ones are recorded in the gorp_migrations table as sql-migrate does.
+package migrations
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	migrationsTable = "gorp_migrations"
+
+	upMarker   = "-- +migrate Up"
+	downMarker = "-- +migrate Down"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+var ErrNoMigration = errors.New("no migration to apply")
+
+type Migration struct {
+	ID   string
+	Up   string
+	Down string
+}
+
+// MigrationStatus is the migration and the time it is applied at, AppliedAt is nil when it is pending
+type MigrationStatus struct {
+	ID        string
+	AppliedAt *time.Time
+}
+
+// Load returns the embedded migrations ordered by id
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+// LoadSQLite returns the embedded migrations of the sqlite database ordered by id
+func LoadSQLite() ([]Migration, error) {
+	fsys, err := fs.Sub(sqliteFiles, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	return load(fsys)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(names)
+
+	out := make([]Migration, 0, len(names))
+
+	for _, name := range names {
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, err := Parse(path.Base(name), src)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, *m)
+	}
+
+	return out, nil
+}
+
+// Parse splits the file into the up and the down sections
+func Parse(id string, src []byte) (*Migration, error) {
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(src))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch strings.TrimSpace(line) {
+		case upMarker:
+			section = &up
+
+			continue
+		case downMarker:
+			section = &down
+
+			continue
+		}
+
+		if section == nil {
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("migration %s: statement out of the up and down sections", id)
+			}
+
+			continue
+		}
+
+		section.WriteString(line)
+		section.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	out := Migration{
+		ID:   id,
+		Up:   strings.TrimSpace(up.String()),
+		Down: strings.TrimSpace(down.String()),
+	}
+
+	if out.Up == "" {
+		return nil, fmt.Errorf("migration %s: no up section", id)
+	}
+
+	return &out, nil
+}
+
+type Migrator struct {
+	conn       *sqlx.DB
+	migrations []Migration
+}
+
+func NewMigrator(conn *sqlx.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		conn:       conn,
+		migrations: migrations,
+	}
+}
+
+func (m *Migrator) init() error {
+	// the sqlite driver reads the time from the plain timestamp columns only
+	column := "timestamp with time zone"
+	if m.conn.DriverName() == "sqlite3" {
+		column = "timestamp"
+	}
+
+	_, err := m.conn.Exec("CREATE TABLE IF NOT EXISTS " + migrationsTable + " (id text primary key, applied_at " + column + ")")
+
+	return err
+}
+
+func (m *Migrator) applied() (map[string]time.Time, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		ID        string    `db:"id"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+
+	if err := m.conn.Select(&rows, "SELECT id, applied_at FROM "+migrationsTable); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		out[r.ID] = r.AppliedAt
+	}
+
+	return out, nil
+}
+
+// Up applies the pending migrations in order, it returns the ids of the applied ones
+func (m *Migrator) Up() ([]string, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.ID]; ok {
+			continue
+		}
+
+		if err := m.exec(migration.ID, migration.Up, "INSERT INTO "+migrationsTable+" (id, applied_at) VALUES ($1, $2)", time.Now().UTC()); err != nil {
+			return out, err
+		}
+
+		out = append(out, migration.ID)
+	}
+
+	return out, nil
+}
+
+// Down rolls the last applied migrations back, steps is their number
+func (m *Migrator) Down(steps int) ([]string, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+
+	for i := len(m.migrations) - 1; i >= 0 && len(out) < steps; i-- {
+		migration := m.migrations[i]
+
+		if _, ok := applied[migration.ID]; !ok {
+			continue
+		}
+
+		if err := m.exec(migration.ID, migration.Down, "DELETE FROM "+migrationsTable+" WHERE id = $1"); err != nil {
+			return out, err
+		}
+
+		out = append(out, migration.ID)
+	}
+
+	if len(out) == 0 {
+		return nil, ErrNoMigration
+	}
+
+	return out, nil
+}
+
+// Status returns the migrations with the time they are applied at
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, 0, len(m.migrations))
+
+	for _, migration := range m.migrations {
+		s := MigrationStatus{
+			ID: migration.ID,
+		}
+
+		if t, ok := applied[migration.ID]; ok {
+			s.AppliedAt = &t
+		}
+
+		out = append(out, s)
+	}
+
+	return out, nil
+}
+
+// exec runs the section and records it in one transaction
+func (m *Migrator) exec(id, section, record string, args ...interface{}) error {
+	tx, err := m.conn.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if section != "" {
+		if _, err := tx.Exec(section); err != nil {
+			_ = tx.Rollback()
+
+			return fmt.Errorf("migration %s: %w", id, err)
+		}
+	}
+
+	if _, err := tx.Exec(record, append([]interface{}{id}, args...)...); err != nil {
+		_ = tx.Rollback()
+
+		return fmt.Errorf("migration %s: %w", id, err)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,161 @@
+package migrations
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    *Migration
+		wantErr bool
+	}{
+		{
+			name: "up and down",
+			src:  "-- +migrate Up\ncreate table a (id text);\n\n-- +migrate Down\ndrop table a;",
+			want: &Migration{ID: "1-a.sql", Up: "create table a (id text);", Down: "drop table a;"},
+		},
+		{
+			name: "no down",
+			src:  "\n-- +migrate Up\ncreate index b on a (id);\n",
+			want: &Migration{ID: "1-a.sql", Up: "create index b on a (id);"},
+		},
+		{
+			name:    "no up",
+			src:     "-- +migrate Down\ndrop table a;",
+			wantErr: true,
+		},
+		{
+			name:    "statement out of the sections",
+			src:     "create table a (id text);\n-- +migrate Up\ncreate table b (id text);",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse("1-a.sql", []byte(tt.src))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_Load(t *testing.T) {
+	t.Run("ordered by id", func(t *testing.T) {
+		got, err := load(fstest.MapFS{
+			"2-b.sql": {Data: []byte("-- +migrate Up\nb")},
+			"1-a.sql": {Data: []byte("-- +migrate Up\na")},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Migration{{ID: "1-a.sql", Up: "a"}, {ID: "2-b.sql", Up: "b"}}, got)
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		got, err := Load()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.True(t, sort.SliceIsSorted(got, func(i, j int) bool { return got[i].ID < got[j].ID }))
+
+		for _, m := range got {
+			assert.NotEmpty(t, m.Down, m.ID)
+		}
+	})
+
+	// the history creates every table and index of db.sql
+	t.Run("matches db.sql", func(t *testing.T) {
+		schema, err := os.ReadFile("../db.sql")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		got, err := Load()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		var up strings.Builder
+		for _, m := range got {
+			up.WriteString(m.Up)
+		}
+
+		objects := regexp.MustCompile(`create (table|index|unique index) (\w+)`).FindAllStringSubmatch(string(schema), -1)
+		assert.NotEmpty(t, objects)
+
+		// the first migration has no "if not exists", it is applied as it is shipped
+		for _, o := range objects {
+			assert.Regexp(t, `create `+o[1]+` (if not exists )?`+o[2]+`\b`, up.String())
+		}
+
+		columns := regexp.MustCompile(`(?m)^\s+(\w+)\s+(text|bigint|bigserial|real|integer|serial|timestamp|double|boolean)`).FindAllStringSubmatch(string(schema), -1)
+		for _, c := range columns {
+			assert.Contains(t, up.String(), c[1], c[1])
+		}
+	})
+}
+
+func Test_SQLite(t *testing.T) {
+	conn, err := sqlx.Connect("sqlite3", ":memory:")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// the memory database lives with the connection
+	conn.SetMaxOpenConns(1)
+
+	list, err := LoadSQLite()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	migrator := NewMigrator(conn, list)
+
+	applied, err := migrator.Up()
+	assert.NoError(t, err)
+	assert.Len(t, applied, len(list))
+
+	// the sqlite schema has every table and index of db.sql
+	schema, err := os.ReadFile("../db.sql")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, o := range regexp.MustCompile(`create (table|index|unique index) (\w+)`).FindAllStringSubmatch(string(schema), -1) {
+		var n int
+
+		assert.NoError(t, conn.Get(&n, "SELECT count(*) FROM sqlite_master WHERE name = ?", o[2]))
+		assert.Equal(t, 1, n, o[2])
+	}
+
+	status, err := migrator.Status()
+	if assert.NoError(t, err) && assert.NotEmpty(t, status) {
+		assert.NotNil(t, status[0].AppliedAt)
+	}
+
+	rolledBack, err := migrator.Down(len(list))
+	assert.NoError(t, err)
+	assert.Len(t, rolledBack, len(list))
+
+	var tables int
+	assert.NoError(t, conn.Get(&tables, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('gorp_migrations', 'sqlite_sequence')"))
+	assert.Zero(t, tables)
+}
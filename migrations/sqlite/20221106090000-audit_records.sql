@@ -0,0 +1,20 @@
+-- +migrate Up
+create table if not exists audit_records
+(
+    id         integer primary key autoincrement,
+    source     text,
+    chat_id    bigint default 0,
+    user_id    bigint default 0,
+    user_name  text   default '',
+    action     text,
+    target     text   default '',
+    payload    text   default '',
+    result     text,
+    error      text   default '',
+    created_at timestamp default (rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', 'now'), '0'), '.') || '+00:00')
+);
+
+create index if not exists audit_records_created_at_idx on audit_records (created_at, id);
+
+-- +migrate Down
+drop table if exists audit_records;
\ No newline at end of file
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// the sources of the operator actions
+const (
+	AuditSourceTelegram = "telegram"
+)
+
+// the results of the operator actions, DENIED is the action of the user out of the allowed ones
+const (
+	AuditResultOK     = "OK"
+	AuditResultDenied = "DENIED"
+	AuditResultFailed = "FAILED"
+)
+
+// AuditRecord is the action of the operator, the records are only appended
+type AuditRecord struct {
+	ID       int64  `db:"id" json:"id"`
+	Source   string `db:"source" json:"source"`
+	ChatID   int64  `db:"chat_id" json:"chat_id"`
+	UserID   int64  `db:"user_id" json:"user_id"`
+	UserName string `db:"user_name" json:"user_name,omitempty"`
+	Action   string `db:"action" json:"action"`
+	// Target is the symbol or the pending order of the action
+	Target    string    `db:"target" json:"target,omitempty"`
+	Payload   string    `db:"payload" json:"payload,omitempty"`
+	Result    string    `db:"result" json:"result"`
+	Error     string    `db:"error" json:"error,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 )
 
@@ -94,6 +95,10 @@ func (c *Candle) LowerShadow() *Shadow {
 	return &s
 }
 
+func (c *Candle) TrueRange(prevClosePrice float64) float64 {
+	return math.Max(c.MaxPrice-c.MinPrice, math.Max(math.Abs(c.MaxPrice-prevClosePrice), math.Abs(c.MinPrice-prevClosePrice)))
+}
+
 func (c *Candle) Trend() Trend {
 	switch true {
 	case c.ClosePrice > c.OpenPrice:
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Fill is the trade of the order on the exchange, the order is linked by OrderID
+// and ClientOrderID is empty until the order is stored
+type Fill struct {
+	ID              int64     `db:"id" json:"id"`
+	Market          string    `db:"market" json:"market,omitempty"`
+	Symbol          string    `db:"symbol" json:"symbol,omitempty"`
+	OrderID         int64     `db:"order_id" json:"order_id,omitempty"`
+	ClientOrderID   string    `db:"client_order_id" json:"client_order_id,omitempty"`
+	SessionID       string    `db:"session_id" json:"session_id,omitempty"`
+	Side            string    `db:"side" json:"side,omitempty"`
+	PositionSide    string    `db:"position_side" json:"position_side,omitempty"`
+	Price           float64   `db:"price" json:"price,omitempty"`
+	Quantity        float64   `db:"quantity" json:"quantity,omitempty"`
+	QuoteQuantity   float64   `db:"quote_quantity" json:"quote_quantity,omitempty"`
+	Commission      float64   `db:"commission" json:"commission,omitempty"`
+	CommissionAsset string    `db:"commission_asset" json:"commission_asset,omitempty"`
+	RealizedPnL     float64   `db:"realized_pnl" json:"realized_pnl,omitempty"`
+	Maker           bool      `db:"maker" json:"maker,omitempty"`
+	TradedAt        time.Time `db:"traded_at" json:"traded_at"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+}
+
+// PnL sums the fills of the session, the day or the settings version, the commissions are summed by the asset
+type PnL struct {
+	// Key is the session id, the day as 2006-01-02 or the settings version
+	Key             string  `db:"key" json:"key"`
+	CommissionAsset string  `db:"commission_asset" json:"commission_asset"`
+	Trades          int     `db:"trades" json:"trades"`
+	Volume          float64 `db:"volume" json:"volume"`
+	RealizedPnL     float64 `db:"realized_pnl" json:"realized_pnl"`
+	Commission      float64 `db:"commission" json:"commission"`
+}
+
+// Net is the realized profit without the commission, it is valid when the commission
+// is paid in the quote asset
+func (p *PnL) Net() float64 {
+	return p.RealizedPnL - p.Commission
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Grid is the grid of the symbol, it buys on the levels from MinPrice to MaxPrice and sells a level higher
+type Grid struct {
+	ID             string    `db:"id" json:"id"`
+	Symbol         string    `db:"symbol" json:"symbol"`
+	Market         string    `db:"market" json:"market"`
+	MinPrice       float64   `db:"min_price" json:"min_price"`
+	MaxPrice       float64   `db:"max_price" json:"max_price"`
+	Levels         int       `db:"levels" json:"levels"`
+	Quantity       float64   `db:"quantity" json:"quantity"`
+	RealizedProfit float64   `db:"realized_profit" json:"realized_profit"`
+	Cycles         int       `db:"cycles" json:"cycles"`
+	Status         string    `db:"status" json:"status"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// GridLevel is the state of the gap between the Level price and the next one.
+// OrderID is the client order id of the open order of the level.
+type GridLevel struct {
+	GridID    string    `db:"grid_id" json:"grid_id"`
+	Level     int       `db:"level" json:"level"`
+	State     string    `db:"state" json:"state"`
+	OrderID   string    `db:"order_id" json:"order_id"`
+	Quantity  float64   `db:"quantity" json:"quantity"`
+	BuyPrice  float64   `db:"buy_price" json:"buy_price"`
+	Profit    float64   `db:"profit" json:"profit"`
+	Cycles    int       `db:"cycles" json:"cycles"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
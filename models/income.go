@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// the sources of the incomes
+const (
+	IncomeSourceAPI = "API"
+	IncomeSourceCSV = "CSV"
+)
+
+// Income is the change of the futures balance: the realized profit, the commission, the funding fee,
+// the transfer and so on. TranID is 0 for the incomes imported from the CSV export.
+type Income struct {
+	ID         string    `db:"id" json:"id"`
+	TranID     int64     `db:"tran_id" json:"tran_id,omitempty"`
+	TradeID    string    `db:"trade_id" json:"trade_id,omitempty"`
+	Source     string    `db:"source" json:"source"`
+	Symbol     string    `db:"symbol" json:"symbol,omitempty"`
+	IncomeType string    `db:"income_type" json:"income_type"`
+	Income     float64   `db:"income" json:"income"`
+	Asset      string    `db:"asset" json:"asset"`
+	Info       string    `db:"info" json:"info,omitempty"`
+	IncomeAt   time.Time `db:"income_at" json:"income_at"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// IncomeSummary sums the incomes of the day by the symbol, the type and the asset
+type IncomeSummary struct {
+	// Day is the UTC day as 2006-01-02
+	Day        string  `db:"day" json:"day"`
+	Symbol     string  `db:"symbol" json:"symbol"`
+	IncomeType string  `db:"income_type" json:"income_type"`
+	Asset      string  `db:"asset" json:"asset"`
+	Count      int     `db:"count" json:"count"`
+	Income     float64 `db:"income" json:"income"`
+}
@@ -3,18 +3,30 @@ package models
 import "time"
 
 type Order struct {
-	ID           string    `db:"id" json:"id,omitempty"`
-	OrderID      int64     `db:"order_id" json:"order_id,omitempty"`
-	SessionID    string    `db:"session_id" json:"session_id,omitempty"`
-	Symbol       string    `db:"symbol" json:"symbol,omitempty"`
-	Side         string    `db:"side" json:"side,omitempty"`
-	PositionSide string    `db:"position_side" json:"position_side,omitempty"`
-	Quantity     float64   `db:"quantity" json:"quantity,omitempty"`
-	Price        float64   `db:"price" json:"price,omitempty"`
-	ActualPrice  float64   `db:"actual_price" json:"actual_price,omitempty"`
-	StopPrice    float64   `db:"stop_price" json:"stop_price,omitempty"`
-	Status       string    `db:"status" json:"status,omitempty"`
-	Try          int       `db:"try" json:"try,omitempty"`
-	Type         string    `db:"type" json:"type,omitempty"`
-	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	ID           string  `db:"id" json:"id,omitempty"`
+	OrderID      int64   `db:"order_id" json:"order_id,omitempty"`
+	SessionID    string  `db:"session_id" json:"session_id,omitempty"`
+	Market       string  `db:"market" json:"market,omitempty"`
+	Symbol       string  `db:"symbol" json:"symbol,omitempty"`
+	Side         string  `db:"side" json:"side,omitempty"`
+	PositionSide string  `db:"position_side" json:"position_side,omitempty"`
+	Quantity     float64 `db:"quantity" json:"quantity,omitempty"`
+	Price        float64 `db:"price" json:"price,omitempty"`
+	ActualPrice  float64 `db:"actual_price" json:"actual_price,omitempty"`
+	StopPrice    float64 `db:"stop_price" json:"stop_price,omitempty"`
+	Status       string  `db:"status" json:"status,omitempty"`
+	Try          int     `db:"try" json:"try,omitempty"`
+	Type         string  `db:"type" json:"type,omitempty"`
+	ExitModel    string  `db:"exit_model" json:"exit_model,omitempty"`
+	Volatility   float64 `db:"volatility" json:"volatility,omitempty"`
+	SafeDelta    float64 `db:"safe_delta" json:"safe_delta,omitempty"`
+	TriggerDelta float64 `db:"trigger_delta" json:"trigger_delta,omitempty"`
+	TakeProfit   float64 `db:"take_profit" json:"take_profit,omitempty"`
+	StopLoss     float64 `db:"stop_loss" json:"stop_loss,omitempty"`
+	// SubmitAttempts counts the claims of the order by the submitter, ClaimedAt is the last one
+	SubmitAttempts int        `db:"submit_attempts" json:"submit_attempts,omitempty"`
+	ClaimedAt      *time.Time `db:"claimed_at" json:"claimed_at,omitempty"`
+	// SettingsVersion is the version of the symbol settings the session is opened by
+	SettingsVersion int       `db:"settings_version" json:"settings_version,omitempty"`
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
 }
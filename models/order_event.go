@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// the events of the order timeline
+const (
+	OrderEventCreated      = "CREATED"
+	OrderEventSubmitted    = "SUBMITTED"
+	OrderEventAcknowledged = "ACKNOWLEDGED"
+	OrderEventStatus       = "STATUS"
+	OrderEventPrice        = "PRICE"
+	OrderEventCanceled     = "CANCELED"
+	OrderEventDeleted      = "DELETED"
+)
+
+// the sources of the order events: the pollers of the monitor, the exchange stream and
+// the operator with the signals, the liquidation and the shutdown
+const (
+	OrderSourcePoll   = "poll"
+	OrderSourceStream = "stream"
+	OrderSourceManual = "manual"
+)
+
+// OrderEvent is the change of the order, the events are only appended. Status, Price and OrderID
+// are the state of the order after the event.
+type OrderEvent struct {
+	ID        int64     `db:"id" json:"id"`
+	OrderRef  string    `db:"order_ref" json:"order_ref"`
+	SessionID string    `db:"session_id" json:"session_id,omitempty"`
+	Market    string    `db:"market" json:"market"`
+	Symbol    string    `db:"symbol" json:"symbol"`
+	Event     string    `db:"event" json:"event"`
+	Source    string    `db:"source" json:"source"`
+	Status    string    `db:"status" json:"status,omitempty"`
+	Price     float64   `db:"price" json:"price,omitempty"`
+	OrderID   int64     `db:"order_id" json:"order_id,omitempty"`
+	Payload   string    `db:"payload" json:"payload,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}